@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+// ErrLocked is returned by the client when the agent is running but does not
+// hold a key.
+var ErrLocked = errors.New("agent is locked")
+
+const dialTimeout = 2 * time.Second
+
+// Client talks to a running agent over its unix socket.
+type Client struct {
+	SocketPath string
+}
+
+// NewClient returns a Client for the agent listening on socketPath. An empty
+// socketPath uses SocketPath().
+func NewClient(socketPath string) *Client {
+	if socketPath == "" {
+		socketPath = SocketPath()
+	}
+	return &Client{SocketPath: socketPath}
+}
+
+func (c *Client) do(req Request) (Response, error) {
+	conn, err := net.DialTimeout("unix", c.SocketPath, dialTimeout)
+	if err != nil {
+		return Response{}, fmt.Errorf("connecting to agent: %w", err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Response{}, fmt.Errorf("sending request: %v", err)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return Response{}, fmt.Errorf("reading response: %v", err)
+	}
+
+	if !resp.OK {
+		if req.Op == OpKey {
+			return resp, ErrLocked
+		}
+		return resp, errors.New(resp.Error)
+	}
+
+	return resp, nil
+}
+
+// Status returns the current state of the agent.
+func (c *Client) Status() (Response, error) {
+	return c.do(Request{Op: OpStatus})
+}
+
+// Unlock hands the master password to the agent, which verifies it and keeps
+// the derived key.
+func (c *Client) Unlock(password []byte) (Response, error) {
+	return c.do(Request{Op: OpUnlock, Password: password})
+}
+
+// Lock asks the agent to wipe its key.
+func (c *Client) Lock() (Response, error) {
+	return c.do(Request{Op: OpLock})
+}
+
+// Stop asks the agent to wipe its key and exit.
+func (c *Client) Stop() (Response, error) {
+	return c.do(Request{Op: OpStop})
+}
+
+// Key returns the derived key held by the agent and the KDF it was derived
+// with, or ErrLocked.
+func (c *Client) Key() ([]byte, model.KDFParams, error) {
+	resp, err := c.do(Request{Op: OpKey})
+	if err != nil {
+		return nil, model.KDFParams{}, err
+	}
+	return resp.Key, resp.KDF, nil
+}
+
+// GetKeyManager returns a key manager for the CLI commands. If an unlocked agent
+// is running, its key is used and the user is not prompted. If the agent is
+// running but locked, the user is prompted once and the agent is unlocked with
+// that password, so the following commands don't prompt. Without an agent,
+// this behaves like prompting for the master password directly.
+func GetKeyManager(r io.Reader) (*model.MasterAESKeyManager, error) {
+	return PromptKeyManager(os.Stdout, r)
+}
+
+// PromptKeyManager is GetKeyManager with the Master Password prompt written to
+// 'w', for the commands whose stdout is their output
+func PromptKeyManager(w io.Writer, r io.Reader) (*model.MasterAESKeyManager, error) {
+	client := NewClient("")
+
+	key, kdf, keyErr := client.Key()
+	if keyErr == nil {
+		return model.NewMasterAESKeyManagerFromKey(key, kdf), nil
+	}
+
+	passB, err := utils.PromptPassword(w, true, r)
+	if err != nil {
+		return nil, err
+	}
+
+	// No agent to talk to, so fall back to a one-off key manager
+	if !errors.Is(keyErr, ErrLocked) {
+		return utils.NewKeyManager(string(passB))
+	}
+
+	if _, err := client.Unlock(passB); err != nil {
+		return nil, fmt.Errorf("unlocking agent: %v", err)
+	}
+
+	key, kdf, err = client.Key()
+	if err != nil {
+		return nil, err
+	}
+	return model.NewMasterAESKeyManagerFromKey(key, kdf), nil
+}
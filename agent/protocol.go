@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+// Op is the name of an operation that a client can ask the agent to perform.
+type Op string
+
+const (
+	OpStatus Op = "status"
+	OpUnlock Op = "unlock"
+	OpLock   Op = "lock"
+	OpKey    Op = "key"
+	OpStop   Op = "stop"
+)
+
+const (
+	// SocketEnv is the environment variable that overrides the default socket
+	// path, in the same spirit as SSH_AUTH_SOCK.
+	SocketEnv = "GOPASS_AGENT_SOCK"
+	// SocketName is the file name of the socket inside of the socket dir.
+	SocketName = "agent.sock"
+)
+
+// Request is a single message sent from a client to the agent. Each
+// connection carries exactly one Request and one Response.
+type Request struct {
+	Op Op `json:"op"`
+	// Password is only set for OpUnlock.
+	Password []byte `json:"password,omitempty"`
+}
+
+// Response is the agent's answer to a Request.
+type Response struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+	// Unlocked is whether the agent currently holds a key.
+	Unlocked bool `json:"unlocked"`
+	// ExpiresAt is the time in UnixMilli when the held key will be wiped,
+	// unless it is used again before then.
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+	// Pid is the process id of the agent.
+	Pid int `json:"pid"`
+	// Key is the derived AES key and KDF is what it was derived with. They
+	// are only set in response to OpKey.
+	Key []byte          `json:"key,omitempty"`
+	KDF model.KDFParams `json:"kdf"`
+}
+
+// SocketPath returns the path to the per-user socket the agent listens on. It
+// prefers GOPASS_AGENT_SOCK, then $XDG_RUNTIME_DIR/gopass, and falls back to
+// the gopass config directory.
+func SocketPath() string {
+	return utils.SocketPath(SocketEnv, SocketName)
+}
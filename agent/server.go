@@ -0,0 +1,258 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+// UnlockFunc verifies the master password and returns the derived AES key, the
+// KDF it was derived with, and how long the agent should hold on to it.
+type UnlockFunc func(password []byte) (key []byte, kdf model.KDFParams, timeout time.Duration, err error)
+
+// Server is the unlock agent. It holds the derived AES key in memory and wipes
+// it once it has not been used for the configured timeout.
+type Server struct {
+	unlock UnlockFunc
+	now    func() time.Time
+
+	mu        sync.Mutex
+	key       []byte
+	kdf       model.KDFParams
+	timeout   time.Duration
+	expiresAt time.Time
+	timer     *time.Timer
+
+	listener net.Listener
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewServer returns a new, locked Server that uses 'unlock' to verify
+// passwords.
+func NewServer(unlock UnlockFunc) *Server {
+	return &Server{
+		unlock: unlock,
+		now:    time.Now,
+		done:   make(chan struct{}),
+	}
+}
+
+// Listen creates the unix socket at socketPath with utils.ListenUnix. A stale
+// socket from a previous agent is removed, but a live agent is never replaced.
+func Listen(socketPath string) (net.Listener, error) {
+	if _, err := os.Stat(socketPath); err == nil {
+		if _, err := NewClient(socketPath).Status(); err == nil {
+			return nil, errors.New("an agent is already running")
+		}
+	}
+
+	return utils.ListenUnix(socketPath)
+}
+
+// Serve accepts connections on l until Stop is called or l fails.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	s.listener = l
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+				return err
+			}
+		}
+
+		go s.handle(conn)
+	}
+}
+
+// Stop locks the agent and closes the listener.
+func (s *Server) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+		s.Lock()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.listener != nil {
+			s.listener.Close()
+		}
+	})
+}
+
+// Done is closed once Stop has been called.
+func (s *Server) Done() <-chan struct{} {
+	return s.done
+}
+
+// Lock wipes the held key, if any.
+func (s *Server) Lock() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lockLocked()
+}
+
+func (s *Server) lockLocked() {
+	for i := range s.key {
+		s.key[i] = 0
+	}
+	s.key = nil
+	s.expiresAt = time.Time{}
+
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+// touchLocked pushes the expiry back by the timeout, the same way LastVisited
+// measures inactivity.
+func (s *Server) touchLocked() {
+	s.expiresAt = s.now().Add(s.timeout)
+
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.timer = time.AfterFunc(s.timeout, s.expire)
+}
+
+// expire is called by the timer. The expiry is checked again as the key may
+// have been used between the timer firing and the lock being taken.
+func (s *Server) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.key != nil && !s.now().Before(s.expiresAt) {
+		s.lockLocked()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		json.NewEncoder(conn).Encode(Response{Error: fmt.Sprintf("decoding request: %v", err)})
+		return
+	}
+
+	resp := s.Do(req)
+	json.NewEncoder(conn).Encode(resp)
+
+	if req.Op == OpStop {
+		s.Stop()
+	}
+}
+
+// Do performs a single Request and returns the Response.
+func (s *Server) Do(req Request) Response {
+	switch req.Op {
+	case OpStatus, OpStop:
+		return s.status()
+	case OpLock:
+		s.Lock()
+		return s.status()
+	case OpUnlock:
+		key, kdf, timeout, err := s.unlock(req.Password)
+		for i := range req.Password {
+			req.Password[i] = 0
+		}
+		if err != nil {
+			return Response{Error: err.Error(), Pid: os.Getpid()}
+		}
+		if timeout <= 0 {
+			timeout = time.Duration(utils.THIRTY_MINUTES) * time.Millisecond
+		}
+
+		s.mu.Lock()
+		s.lockLocked()
+		s.key = key
+		s.kdf = kdf
+		s.timeout = timeout
+		s.touchLocked()
+		s.mu.Unlock()
+
+		return s.status()
+	case OpKey:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.key == nil {
+			return Response{Error: "agent is locked", Pid: os.Getpid()}
+		}
+		s.touchLocked()
+
+		key := make([]byte, len(s.key))
+		copy(key, s.key)
+		return Response{
+			OK:        true,
+			Unlocked:  true,
+			ExpiresAt: s.expiresAt.UnixMilli(),
+			Pid:       os.Getpid(),
+			Key:       key,
+			KDF:       s.kdf,
+		}
+	}
+
+	return Response{Error: fmt.Sprintf("unknown op '%s'", req.Op), Pid: os.Getpid()}
+}
+
+func (s *Server) status() Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := Response{
+		OK:       true,
+		Unlocked: s.key != nil,
+		Pid:      os.Getpid(),
+	}
+	if s.key != nil {
+		resp.ExpiresAt = s.expiresAt.UnixMilli()
+	}
+	return resp
+}
+
+// VerifyMasterPassword is the default UnlockFunc. It checks the password
+// against the config and derives the AES key with it, using the KDF from the
+// config's header. The timeout is the Timeout from the config.
+func VerifyMasterPassword(password []byte) ([]byte, model.KDFParams, time.Duration, error) {
+	km, err := utils.NewKeyManager(string(password))
+	if err != nil {
+		return nil, model.KDFParams{}, 0, err
+	}
+	defer km.Close()
+
+	cfg, err := utils.CheckConfig("", km)
+	if err != nil {
+		return nil, model.KDFParams{}, 0, fmt.Errorf("checking config: %v", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword(cfg.MasterPassword, password); err != nil {
+		return nil, model.KDFParams{}, 0, errors.New("incorrect master password")
+	}
+
+	cached, err := km.GetEncryptionKey()
+	if err != nil {
+		return nil, model.KDFParams{}, 0, fmt.Errorf("deriving key: %v", err)
+	}
+
+	// The manager wipes its copy when it is closed
+	key := make([]byte, len(cached))
+	copy(key, cached)
+
+	return key, km.KDF(), time.Duration(cfg.Timeout) * time.Millisecond, nil
+}
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/model"
+)
+
+var testKey = bytes.Repeat([]byte{7}, 32)
+
+// startTestAgent starts an agent on a temporary socket. The unlock function
+// accepts "mastahpass" and hands out testKey.
+func startTestAgent(t *testing.T, timeout time.Duration) (*Server, *Client) {
+	// Unix socket paths have a short length limit, so t.TempDir() is too long
+	dir, err := os.MkdirTemp("", "gopass-agent")
+	assert.NoError(t, err)
+	socketPath := path.Join(dir, SocketName)
+
+	l, err := Listen(socketPath)
+	assert.NoError(t, err)
+
+	server := NewServer(func(password []byte) ([]byte, model.KDFParams, time.Duration, error) {
+		if string(password) != "mastahpass" {
+			return nil, model.KDFParams{}, 0, errors.New("incorrect master password")
+		}
+		key := make([]byte, len(testKey))
+		copy(key, testKey)
+		return key, model.DefaultKDF(), timeout, nil
+	})
+	go server.Serve(l)
+
+	t.Cleanup(func() {
+		server.Stop()
+		os.RemoveAll(dir)
+	})
+
+	return server, NewClient(socketPath)
+}
+
+func TestAgentUnlockAndLock(t *testing.T) {
+	assert := assert.New(t)
+	_, client := startTestAgent(t, time.Minute)
+
+	resp, err := client.Status()
+	assert.NoError(err)
+	assert.False(resp.Unlocked)
+	assert.Equal(os.Getpid(), resp.Pid)
+
+	_, _, err = client.Key()
+	assert.ErrorIs(err, ErrLocked)
+
+	_, err = client.Unlock([]byte("wrong"))
+	assert.Error(err)
+
+	resp, err = client.Unlock([]byte("mastahpass"))
+	assert.NoError(err)
+	assert.True(resp.Unlocked)
+	assert.Greater(resp.ExpiresAt, time.Now().UnixMilli())
+
+	key, kdf, err := client.Key()
+	assert.NoError(err)
+	assert.Equal(testKey, key)
+	assert.Equal(model.DefaultKDF(), kdf)
+
+	resp, err = client.Lock()
+	assert.NoError(err)
+	assert.False(resp.Unlocked)
+
+	_, _, err = client.Key()
+	assert.ErrorIs(err, ErrLocked)
+}
+
+func TestAgentExpiresKey(t *testing.T) {
+	assert := assert.New(t)
+	_, client := startTestAgent(t, 100*time.Millisecond)
+
+	_, err := client.Unlock([]byte("mastahpass"))
+	assert.NoError(err)
+
+	_, _, err = client.Key()
+	assert.NoError(err)
+
+	time.Sleep(300 * time.Millisecond)
+
+	_, _, err = client.Key()
+	assert.ErrorIs(err, ErrLocked)
+}
+
+func TestAgentStop(t *testing.T) {
+	assert := assert.New(t)
+	server, client := startTestAgent(t, time.Minute)
+
+	_, err := client.Unlock([]byte("mastahpass"))
+	assert.NoError(err)
+
+	_, err = client.Stop()
+	assert.NoError(err)
+
+	select {
+	case <-server.Done():
+	case <-time.After(time.Second):
+		t.Fatal("agent did not stop")
+	}
+
+	_, err = client.Status()
+	assert.Error(err)
+}
+
+func TestListenRefusesRunningAgent(t *testing.T) {
+	_, client := startTestAgent(t, time.Minute)
+
+	_, err := Listen(client.SocketPath)
+	assert.Error(t, err)
+}
+
+func TestSocketPath(t *testing.T) {
+	t.Setenv(SocketEnv, "/tmp/custom.sock")
+	assert.Equal(t, "/tmp/custom.sock", SocketPath())
+
+	t.Setenv(SocketEnv, "")
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	assert.Equal(t, "/run/user/1000/gopass/agent.sock", SocketPath())
+}
@@ -0,0 +1,205 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"go-pass/agent"
+)
+
+// agentCmd represents the agent command
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Runs the background unlock agent",
+	Long: fmt.Sprintf(`%s
+
+'agent' runs a background agent that holds your derived encryption key in
+memory, so the vault commands stop prompting for your Master Password. The
+agent starts locked. Use 'unlock' to give it your Master Password, and 'lock'
+to wipe the key again. The key is also wiped once it has not been used for
+the timeout in your config (see 'config update_timeout').
+
+The agent listens on a unix socket that only your user can access. The path can
+be overridden with the GOPASS_AGENT_SOCK environment variable.
+
+Ex.
+	$ gopass agent --detach
+	$ gopass unlock
+	Master Password: <insert master password here>
+	$ gopass vault get github
+`, LongDescriptionText),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := AgentCmdHandler(cmd, args); err != nil {
+			fmt.Printf("Error with 'agent' command: %v\n", err)
+			return
+		}
+	},
+}
+
+// agentStatusCmd represents the agent status command
+var agentStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Shows whether the agent is running and unlocked",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := AgentStatusCmdHandler(cmd, args); err != nil {
+			fmt.Printf("Error with 'agent status' command: %v\n", err)
+			return
+		}
+	},
+}
+
+// agentStopCmd represents the agent stop command
+var agentStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Wipes the key and stops the agent",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := AgentStopCmdHandler(cmd, args); err != nil {
+			fmt.Printf("Error with 'agent stop' command: %v\n", err)
+			return
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+
+	agentCmd.AddCommand(agentStatusCmd)
+	agentCmd.AddCommand(agentStopCmd)
+
+	agentCmd.Flags().BoolP("detach", "d", false, "Run the agent in the background")
+}
+
+// AgentCmdHandler is the handler function that runs the agent in the
+// foreground, or starts a detached copy of itself with '--detach'.
+func AgentCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("no arguments needed for 'agent'. see 'help' for more guidance")
+	}
+
+	detach, err := cmd.Flags().GetBool("detach")
+	if err != nil {
+		return fmt.Errorf("agent::getting bool from flag: %v", err)
+	}
+
+	socketPath := agent.SocketPath()
+
+	if detach {
+		return StartDetachedAgent(socketPath)
+	}
+
+	return RunAgent(socketPath)
+}
+
+// RunAgent listens on socketPath and serves until the agent is stopped or the
+// process is interrupted. The key is wiped on the way out.
+func RunAgent(socketPath string) error {
+	l, err := agent.Listen(socketPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(socketPath)
+
+	server := agent.NewServer(agent.VerifyMasterPassword)
+
+	// The agent outlives the terminal it was started from
+	signal.Ignore(syscall.SIGHUP)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sigs:
+			server.Stop()
+		case <-server.Done():
+		}
+	}()
+
+	fmt.Printf("Agent listening on %s\n", socketPath)
+	return server.Serve(l)
+}
+
+// StartDetachedAgent starts 'gopass agent' as a background process and waits
+// for it to start answering on socketPath.
+func StartDetachedAgent(socketPath string) error {
+	client := agent.NewClient(socketPath)
+	if _, err := client.Status(); err == nil {
+		return errors.New("an agent is already running")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	child := exec.Command(exe, "agent")
+	child.Env = append(os.Environ(), fmt.Sprintf("%s=%s", agent.SocketEnv, socketPath))
+	if err := child.Start(); err != nil {
+		return fmt.Errorf("starting agent: %v", err)
+	}
+	pid := child.Process.Pid
+	if err := child.Process.Release(); err != nil {
+		return err
+	}
+
+	for range 50 {
+		if _, err := client.Status(); err == nil {
+			fmt.Printf("Agent started (pid %d) on %s\n", pid, socketPath)
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return errors.New("agent did not start in time")
+}
+
+// AgentStatusCmdHandler is the handler function for 'agent status'
+func AgentStatusCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("no arguments needed for 'agent status'. see 'help' for more guidance")
+	}
+
+	resp, err := agent.NewClient("").Status()
+	if err != nil {
+		fmt.Println("Agent is not running.")
+		return nil
+	}
+
+	PrintAgentStatus(resp, time.Now())
+	return nil
+}
+
+// PrintAgentStatus prints the state of the agent to the terminal
+func PrintAgentStatus(resp agent.Response, now time.Time) {
+	fmt.Printf("Agent is running (pid %d)\n", resp.Pid)
+	if !resp.Unlocked {
+		fmt.Println("Status: locked")
+		return
+	}
+
+	remaining := time.UnixMilli(resp.ExpiresAt).Sub(now).Round(time.Second)
+	fmt.Println("Status: unlocked")
+	fmt.Printf("Locks in: %s\n", remaining)
+}
+
+// AgentStopCmdHandler is the handler function for 'agent stop'
+func AgentStopCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("no arguments needed for 'agent stop'. see 'help' for more guidance")
+	}
+
+	if _, err := agent.NewClient("").Stop(); err != nil {
+		return err
+	}
+
+	fmt.Println("Agent stopped.")
+	return nil
+}
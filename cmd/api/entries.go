@@ -0,0 +1,425 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"go-pass/cmd/vault"
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+const (
+	// MAX_BODY_BYTES is the largest request body that is read
+	MAX_BODY_BYTES = 1 << 20
+	// DEFAULT_GENERATE_LENGTH is the length of a generated password, like
+	// 'generate'
+	DEFAULT_GENERATE_LENGTH = 24
+)
+
+// EntrySummary is an entry without its secrets, as listed
+type EntrySummary struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Username  string   `json:"username"`
+	URLs      []string `json:"urls,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Folder    string   `json:"folder,omitempty"`
+	CreatedAt int64    `json:"created_at,omitempty"`
+	UpdatedAt int64    `json:"updated_at"`
+}
+
+// Entry is an entry with its secrets decrypted
+type Entry struct {
+	EntrySummary
+	Password string  `json:"password"`
+	Notes    string  `json:"notes,omitempty"`
+	Fields   []Field `json:"fields,omitempty"`
+	HasTOTP  bool    `json:"has_totp"`
+	HasSSH   bool    `json:"has_ssh_key"`
+}
+
+// Field is a custom field, or a single field asked for by name
+type Field struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// EntryInput is the body of a request to add or update an entry. On update,
+// only what is set is changed.
+type EntryInput struct {
+	Name     *string   `json:"name"`
+	Username *string   `json:"username"`
+	Password *string   `json:"password"`
+	Notes    *string   `json:"notes"`
+	URLs     *[]string `json:"urls"`
+	Tags     *[]string `json:"tags"`
+	Folder   *string   `json:"folder"`
+	// Fields are added, or replace the field with the same name
+	Fields []Field `json:"fields"`
+	// RemoveFields are the names of fields to remove, on update
+	RemoveFields []string `json:"remove_fields"`
+	// Force adds the entry even if one with the same name and username
+	// exists
+	Force bool `json:"force"`
+}
+
+// GenerateInput is the body of a request to generate a password
+type GenerateInput struct {
+	// Length defaults to DEFAULT_GENERATE_LENGTH
+	Length int `json:"length"`
+	// SpecialChars defaults to vault.DefaultChars
+	SpecialChars *string `json:"special_chars"`
+	// Add, if set, adds an entry with the password, and needs ScopeWrite
+	Add *EntryInput `json:"add"`
+}
+
+// GenerateResult is the answer to a request to generate a password
+type GenerateResult struct {
+	Password string        `json:"password"`
+	Entry    *EntrySummary `json:"entry,omitempty"`
+}
+
+func (s *Server) listEntries(r *http.Request) (int, any, error) {
+	entries, err := s.readEntries()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	list := make([]EntrySummary, len(entries))
+	for i, e := range entries {
+		list[i] = summarize(e)
+	}
+	return http.StatusOK, list, nil
+}
+
+func (s *Server) getEntry(r *http.Request) (int, any, error) {
+	entries, err := s.readEntries()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	idx, err := resolve(entries, r.PathValue("entry"))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	d, err := crypt.DecryptEntry(entries[idx], s.key)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	e := Entry{
+		EntrySummary: summarize(entries[idx]),
+		Password:     d.Password,
+		Notes:        d.Notes,
+		HasTOTP:      d.TOTP != "",
+		HasSSH:       d.SSHKey != "",
+	}
+	for _, f := range d.Fields {
+		e.Fields = append(e.Fields, Field(f))
+	}
+	return http.StatusOK, e, nil
+}
+
+func (s *Server) getField(r *http.Request) (int, any, error) {
+	entries, err := s.readEntries()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	idx, err := resolve(entries, r.PathValue("entry"))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	name := r.PathValue("field")
+	value, err := vault.EntryField(entries[idx], name, s.key)
+	if err != nil {
+		return 0, nil, apiError(http.StatusNotFound, "%v", err)
+	}
+	return http.StatusOK, Field{Name: name, Value: value}, nil
+}
+
+func (s *Server) addEntry(r *http.Request) (int, any, error) {
+	var in EntryInput
+	if err := decodeBody(r, &in); err != nil {
+		return 0, nil, err
+	}
+
+	summary, err := s.add(in, time.Now().UnixMilli())
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusCreated, summary, nil
+}
+
+// add adds the entry in 'in' the way 'vault add' does
+func (s *Server) add(in EntryInput, t int64) (EntrySummary, error) {
+	if in.Name == nil || *in.Name == "" {
+		return EntrySummary{}, apiError(http.StatusBadRequest, "'name' is needed")
+	}
+	if in.Password == nil || *in.Password == "" {
+		return EntrySummary{}, apiError(http.StatusBadRequest, "'password' is needed")
+	}
+	if len(in.RemoveFields) > 0 {
+		return EntrySummary{}, apiError(http.StatusBadRequest, "'remove_fields' is only for updates")
+	}
+	if err := in.validate(); err != nil {
+		return EntrySummary{}, err
+	}
+
+	pass, err := crypt.EncryptPassword([]byte(*in.Password), s.key)
+	if err != nil {
+		return EntrySummary{}, err
+	}
+
+	ui := model.UserInput{Password: []byte(pass)}
+	if in.Username != nil {
+		ui.Username = *in.Username
+	}
+	if in.Notes != nil {
+		ui.Notes = *in.Notes
+	}
+	if err := vault.ApplyMetadata(&ui.Metadata, in.metadataFlags(), s.key); err != nil {
+		return EntrySummary{}, apiError(http.StatusBadRequest, "%v", err)
+	}
+
+	if in.Force {
+		err = vault.ForceAddToVault(*in.Name, ui, s.cfg, t, s.key)
+	} else {
+		err = vault.AddToVault(*in.Name, ui, s.cfg, t, s.key)
+	}
+	if errors.Is(err, vault.ErrDuplicateEntry) {
+		return EntrySummary{}, apiError(http.StatusConflict, "%v", err)
+	}
+	if err != nil {
+		return EntrySummary{}, err
+	}
+
+	// The entry is added to the end of the vault
+	entries, err := s.readEntries()
+	if err != nil {
+		return EntrySummary{}, err
+	}
+	return summarize(entries[len(entries)-1]), nil
+}
+
+func (s *Server) updateEntry(r *http.Request) (int, any, error) {
+	var in EntryInput
+	if err := decodeBody(r, &in); err != nil {
+		return 0, nil, err
+	}
+	if in.Force {
+		return 0, nil, apiError(http.StatusBadRequest, "'force' is only for adding")
+	}
+	if err := in.validate(); err != nil {
+		return 0, nil, err
+	}
+
+	return s.writeEntries(r.PathValue("entry"), func(entries []model.VaultEntry, idx int) ([]model.VaultEntry, any, error) {
+		e := &entries[idx]
+		name, username := e.Name, e.Username
+
+		if in.Name != nil {
+			if *in.Name == "" {
+				return nil, nil, apiError(http.StatusBadRequest, "'name' cannot be empty")
+			}
+			e.Name = *in.Name
+		}
+		if in.Username != nil {
+			e.Username = *in.Username
+		}
+		if e.Name != name || e.Username != username {
+			others := slices.Delete(slices.Clone(entries), idx, idx+1)
+			if vault.FindDuplicate(others, e.Name, e.Username) >= 0 {
+				return nil, nil, apiError(http.StatusConflict, "%v", vault.ErrDuplicateEntry)
+			}
+		}
+		if in.Password != nil {
+			if *in.Password == "" {
+				return nil, nil, apiError(http.StatusBadRequest, "'password' cannot be empty")
+			}
+			pass, err := crypt.EncryptPassword([]byte(*in.Password), s.key)
+			if err != nil {
+				return nil, nil, err
+			}
+			e.Password = []byte(pass)
+		}
+		if in.Notes != nil {
+			e.Notes = *in.Notes
+		}
+		if err := vault.ApplyMetadata(&e.Metadata, in.metadataFlags(), s.key); err != nil {
+			return nil, nil, apiError(http.StatusBadRequest, "%v", err)
+		}
+		e.UpdatedAt = time.Now().UnixMilli()
+
+		return entries, summarize(*e), nil
+	})
+}
+
+func (s *Server) deleteEntry(r *http.Request) (int, any, error) {
+	return s.writeEntries(r.PathValue("entry"), func(entries []model.VaultEntry, idx int) ([]model.VaultEntry, any, error) {
+		deleted := summarize(entries[idx])
+		return slices.Delete(entries, idx, idx+1), deleted, nil
+	})
+}
+
+func (s *Server) generate(r *http.Request) (int, any, error) {
+	var in GenerateInput
+	if err := decodeBody(r, &in); err != nil {
+		return 0, nil, err
+	}
+	if in.Length == 0 {
+		in.Length = DEFAULT_GENERATE_LENGTH
+	}
+	if in.Length < 0 {
+		return 0, nil, apiError(http.StatusBadRequest, "'length' must be at least 1")
+	}
+
+	special := vault.DefaultChars
+	if in.SpecialChars != nil {
+		special = *in.SpecialChars
+	}
+
+	password, err := vault.GeneratePassword(in.Length, special)
+	if err != nil {
+		return 0, nil, err
+	}
+	result := GenerateResult{Password: string(password)}
+
+	if in.Add == nil {
+		return http.StatusOK, result, nil
+	}
+
+	token, _ := s.authenticate(r)
+	if !token.Scope.Allows(ScopeWrite) {
+		return 0, nil, apiError(
+			http.StatusForbidden,
+			"this token's scope is '%s', '%s' is needed to add the password", token.Scope, ScopeWrite,
+		)
+	}
+	if in.Add.Password != nil {
+		return 0, nil, apiError(http.StatusBadRequest, "'add' cannot have a password")
+	}
+
+	add := *in.Add
+	add.Password = &result.Password
+	summary, err := s.add(add, time.Now().UnixMilli())
+	if err != nil {
+		return 0, nil, err
+	}
+	result.Entry = &summary
+	return http.StatusCreated, result, nil
+}
+
+// readEntries reads the vault. It never writes it, as requests with the read
+// token end up here too; IDs are given to older entries by the first write.
+func (s *Server) readEntries() ([]model.VaultEntry, error) {
+	return utils.LoadVault(s.cfg.VaultName, s.key)
+}
+
+// writeEntries resolves 'query', lets 'change' change the entries and writes
+// them back to the vault. The value 'change' returns is answered with.
+func (s *Server) writeEntries(
+	query string,
+	change func(entries []model.VaultEntry, idx int) ([]model.VaultEntry, any, error),
+) (int, any, error) {
+	f, entries, err := utils.ReadVault(s.cfg.VaultName, s.key)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+
+	idx, err := resolve(entries, query)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	entries, body, err := change(entries, idx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	ciphertext, err := crypt.EncryptVault(entries, s.key)
+	if err != nil {
+		return 0, nil, fmt.Errorf("encrypting vault: %v", err)
+	}
+	if err := utils.WriteToFile(f.Name(), model.FileVault, ciphertext); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, body, nil
+}
+
+// resolve finds the entry 'query' refers to the way 'vault get' does, see
+// vault.ResolveEntry. A name that matches several entries is a conflict.
+func resolve(entries []model.VaultEntry, query string) (int, error) {
+	idx, err := vault.ResolveEntry(entries, query, nil)
+	if errors.Is(err, vault.ErrNotFound) {
+		return -1, apiError(http.StatusNotFound, "%v", err)
+	}
+	if err != nil {
+		return -1, apiError(http.StatusConflict, "%v", err)
+	}
+	return idx, nil
+}
+
+// decodeBody decodes the JSON body of 'r' into 'v'. An empty body leaves 'v'
+// as it is.
+func decodeBody(r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, MAX_BODY_BYTES))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return apiError(http.StatusBadRequest, "invalid body: %v", err)
+	}
+	return nil
+}
+
+func summarize(e model.VaultEntry) EntrySummary {
+	return EntrySummary{
+		ID:        e.ID,
+		Name:      e.Name,
+		Username:  e.Username,
+		URLs:      e.URLs,
+		Tags:      e.Tags,
+		Folder:    e.Folder,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+}
+
+// validate checks the custom fields in the input have names
+func (in EntryInput) validate() error {
+	for _, f := range in.Fields {
+		if f.Name == "" {
+			return apiError(http.StatusBadRequest, "every field needs a name")
+		}
+	}
+	return nil
+}
+
+// metadataFlags returns the metadata in the input as the flags 'add' and
+// 'update' take, so it is applied the same way
+func (in EntryInput) metadataFlags() vault.MetadataFlags {
+	mf := vault.MetadataFlags{RemoveFields: in.RemoveFields}
+	if in.URLs != nil {
+		mf.URLs, mf.SetURLs = *in.URLs, true
+	}
+	if in.Tags != nil {
+		mf.Tags, mf.SetTags = *in.Tags, true
+	}
+	if in.Folder != nil {
+		mf.Folder, mf.SetFolder = *in.Folder, true
+	}
+	for _, f := range in.Fields {
+		mf.Fields = append(mf.Fields, model.CustomField(f))
+	}
+	return mf
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+const (
+	// SocketEnv is the environment variable that overrides the default socket
+	// path
+	SocketEnv = "GOPASS_API_SOCK"
+	// SocketName is the file name of the socket inside of the socket dir.
+	SocketName = "api.sock"
+	// TOKEN_BYTES is how many random bytes a token has
+	TOKEN_BYTES = 32
+)
+
+// Scope is what a token allows
+type Scope string
+
+const (
+	// ScopeRead allows reading entries and generating passwords
+	ScopeRead Scope = "read"
+	// ScopeWrite allows everything ScopeRead does, and changing the vault
+	ScopeWrite Scope = "write"
+)
+
+// Allows returns true if a token with scope 's' may do what needs 'needed'
+func (s Scope) Allows(needed Scope) bool {
+	return s == ScopeWrite || s == needed
+}
+
+// Token is a bearer token, and the scope it was given
+type Token struct {
+	Value string
+	Scope Scope
+}
+
+// NewToken returns a new random token with the given scope
+func NewToken(scope Scope) (Token, error) {
+	b := make([]byte, TOKEN_BYTES)
+	if _, err := rand.Read(b); err != nil {
+		return Token{}, fmt.Errorf("generating token: %v", err)
+	}
+	return Token{Value: hex.EncodeToString(b), Scope: scope}, nil
+}
+
+// SocketPath returns the path to the per-user socket 'serve' listens on. It
+// prefers GOPASS_API_SOCK, then $XDG_RUNTIME_DIR/gopass, and falls back to the
+// gopass config directory.
+func SocketPath() string {
+	return utils.SocketPath(SocketEnv, SocketName)
+}
+
+// Server is the JSON API over the vault. Every request needs one of its
+// tokens, and requests are handled one at a time, as each reads and writes
+// the vault file.
+type Server struct {
+	cfg    *model.Config
+	key    *model.MasterAESKeyManager
+	tokens []Token
+	mux    *http.ServeMux
+
+	// mu serializes requests, so writes to the vault don't race
+	mu sync.Mutex
+}
+
+// NewServer returns a Server for the vault in 'cfg', that accepts 'tokens'
+func NewServer(cfg *model.Config, key *model.MasterAESKeyManager, tokens ...Token) *Server {
+	s := &Server{
+		cfg:    cfg,
+		key:    key,
+		tokens: tokens,
+		mux:    http.NewServeMux(),
+	}
+
+	s.handle("GET /v1/entries", ScopeRead, s.listEntries)
+	s.handle("POST /v1/entries", ScopeWrite, s.addEntry)
+	s.handle("GET /v1/entries/{entry}", ScopeRead, s.getEntry)
+	s.handle("PATCH /v1/entries/{entry}", ScopeWrite, s.updateEntry)
+	s.handle("DELETE /v1/entries/{entry}", ScopeWrite, s.deleteEntry)
+	s.handle("GET /v1/entries/{entry}/fields/{field}", ScopeRead, s.getField)
+	s.handle("POST /v1/generate", ScopeRead, s.generate)
+
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handlerFunc handles an API request, returning the value to answer with as
+// JSON, or an error
+type handlerFunc func(r *http.Request) (status int, body any, err error)
+
+// handle registers 'h' for 'pattern', for tokens that allow 'scope'
+func (s *Server) handle(pattern string, scope Scope, h handlerFunc) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		token, ok := s.authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, errorBody{"missing or invalid token"})
+			return
+		}
+		if !token.Scope.Allows(scope) {
+			writeJSON(w, http.StatusForbidden, errorBody{
+				fmt.Sprintf("this token's scope is '%s', '%s' is needed", token.Scope, scope),
+			})
+			return
+		}
+
+		s.mu.Lock()
+		status, body, err := h(r)
+		s.mu.Unlock()
+
+		if err != nil {
+			var apiErr *Error
+			if !errors.As(err, &apiErr) {
+				apiErr = &Error{Status: http.StatusInternalServerError, Message: err.Error()}
+			}
+			writeJSON(w, apiErr.Status, errorBody{apiErr.Message})
+			return
+		}
+		writeJSON(w, status, body)
+	})
+}
+
+// authenticate returns the token the request's bearer token matches
+func (s *Server) authenticate(r *http.Request) (Token, bool) {
+	value, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || value == "" {
+		return Token{}, false
+	}
+
+	for _, t := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Value), []byte(value)) == 1 {
+			return t, true
+		}
+	}
+	return Token{}, false
+}
+
+// Error is an error with the HTTP status to answer with
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// apiError returns an *Error with the status and formatted message
+func apiError(status int, format string, a ...any) error {
+	return &Error{Status: status, Message: fmt.Sprintf(format, a...)}
+}
+
+// errorBody is the JSON body of a failed request
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	if body == nil {
+		w.WriteHeader(status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,259 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/cmd/vault"
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+// testClient makes requests to the API with a token
+type testClient struct {
+	t     *testing.T
+	url   string
+	token string
+}
+
+// do sends 'body' as JSON, if it isn't nil, and decodes the answer into 'out',
+// if it isn't nil. It returns the status.
+func (c testClient) do(method, path string, body, out any) int {
+	c.t.Helper()
+
+	var b bytes.Buffer
+	if body != nil {
+		assert.NoError(c.t, json.NewEncoder(&b).Encode(body))
+	}
+
+	req, err := http.NewRequest(method, c.url+path, &b)
+	assert.NoError(c.t, err)
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(c.t, err)
+	defer resp.Body.Close()
+
+	if out != nil {
+		assert.NoError(c.t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp.StatusCode
+}
+
+// startTestServer serves the API over a new test vault holding a 'github'
+// entry, and returns clients with a read and a write token, and the vault's key
+func startTestServer(t *testing.T) (read, write testClient, key *model.MasterAESKeyManager) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	t.Cleanup(func() { testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD)) })
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(t, err)
+	vaultFile, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(t, err)
+	vaultFile.Close()
+	cfg := &model.Config{VaultName: testutils.TEST_VAULT_NAME}
+
+	pass, err := crypt.EncryptPassword([]byte("hunter2"), key)
+	assert.NoError(t, err)
+	fields, err := crypt.EncryptFields([]model.CustomField{{Name: "pin", Value: "1234", Secret: true}}, key)
+	assert.NoError(t, err)
+	assert.NoError(t, vault.AddToVault("github", model.UserInput{
+		Username: "octocat",
+		Password: []byte(pass),
+		Notes:    "work account",
+		Metadata: model.Metadata{URLs: []string{"https://github.com"}, Fields: fields},
+	}, cfg, 1, key))
+
+	readToken, err := NewToken(ScopeRead)
+	assert.NoError(t, err)
+	writeToken, err := NewToken(ScopeWrite)
+	assert.NoError(t, err)
+	assert.NotEqual(t, readToken.Value, writeToken.Value)
+
+	ts := httptest.NewServer(NewServer(cfg, key, readToken, writeToken))
+	t.Cleanup(ts.Close)
+
+	return testClient{t, ts.URL, readToken.Value}, testClient{t, ts.URL, writeToken.Value}, key
+}
+
+func TestAPIAuth(t *testing.T) {
+	assert := assert.New(t)
+	read, write, _ := startTestServer(t)
+
+	var e errorBody
+	noToken := testClient{t, read.url, ""}
+	assert.Equal(http.StatusUnauthorized, noToken.do("GET", "/v1/entries", nil, &e))
+	assert.Equal("missing or invalid token", e.Error)
+
+	wrongToken := testClient{t, read.url, "wrong"}
+	assert.Equal(http.StatusUnauthorized, wrongToken.do("GET", "/v1/entries", nil, nil))
+
+	// The read token can't change the vault
+	name, password := "gitlab", "pw"
+	input := EntryInput{Name: &name, Password: &password}
+	assert.Equal(http.StatusForbidden, read.do("POST", "/v1/entries", input, &e))
+	assert.Contains(e.Error, "'write' is needed")
+	assert.Equal(http.StatusForbidden, read.do("DELETE", "/v1/entries/github", nil, nil))
+	assert.Equal(http.StatusForbidden, read.do("PATCH", "/v1/entries/github", input, nil))
+	assert.Equal(http.StatusForbidden, read.do("POST", "/v1/generate", GenerateInput{Add: &EntryInput{Name: &name}}, nil))
+
+	var list []EntrySummary
+	assert.Equal(http.StatusOK, write.do("GET", "/v1/entries", nil, &list))
+	assert.Len(list, 1)
+}
+
+func TestAPIRead(t *testing.T) {
+	assert := assert.New(t)
+	read, _, _ := startTestServer(t)
+
+	var list []EntrySummary
+	assert.Equal(http.StatusOK, read.do("GET", "/v1/entries", nil, &list))
+	assert.Len(list, 1)
+	assert.Equal("github", list[0].Name)
+	assert.Equal("octocat", list[0].Username)
+	assert.Equal([]string{"https://github.com"}, list[0].URLs)
+
+	var e Entry
+	assert.Equal(http.StatusOK, read.do("GET", "/v1/entries/github", nil, &e))
+	assert.Equal("hunter2", e.Password)
+	assert.Equal("work account", e.Notes)
+	assert.Equal([]Field{{Name: "pin", Value: "1234", Secret: true}}, e.Fields)
+
+	// By ID too
+	var byID Entry
+	assert.Equal(http.StatusOK, read.do("GET", "/v1/entries/"+list[0].ID, nil, &byID))
+	assert.Equal(e, byID)
+
+	var f Field
+	assert.Equal(http.StatusOK, read.do("GET", "/v1/entries/github/fields/password", nil, &f))
+	assert.Equal(Field{Name: "password", Value: "hunter2"}, f)
+	assert.Equal(http.StatusOK, read.do("GET", "/v1/entries/github/fields/pin", nil, &f))
+	assert.Equal("1234", f.Value)
+
+	var errBody errorBody
+	assert.Equal(http.StatusNotFound, read.do("GET", "/v1/entries/github/fields/nope", nil, &errBody))
+	assert.Contains(errBody.Error, "no field named 'nope'")
+	assert.Equal(http.StatusNotFound, read.do("GET", "/v1/entries/gitlab", nil, nil))
+
+	var gen GenerateResult
+	assert.Equal(http.StatusOK, read.do("POST", "/v1/generate", GenerateInput{Length: 40}, &gen))
+	assert.Len(gen.Password, 40)
+	assert.Nil(gen.Entry)
+	assert.Equal(http.StatusOK, read.do("POST", "/v1/generate", nil, &gen))
+	assert.Len(gen.Password, 24)
+}
+
+func TestAPIReadDoesNotWrite(t *testing.T) {
+	assert := assert.New(t)
+	read, _, key := startTestServer(t)
+
+	// An entry from before entries had IDs
+	f, entries, err := utils.ReadVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	f.Close()
+	entries[0].ID = ""
+	ciphertext, err := crypt.EncryptVault(entries, key)
+	assert.NoError(err)
+	assert.NoError(utils.WriteToFile(f.Name(), model.FileVault, ciphertext))
+
+	before, err := os.ReadFile(f.Name())
+	assert.NoError(err)
+
+	var list []EntrySummary
+	assert.Equal(http.StatusOK, read.do("GET", "/v1/entries", nil, &list))
+	assert.Len(list, 1)
+	assert.Equal(http.StatusOK, read.do("GET", "/v1/entries/github", nil, nil))
+
+	after, err := os.ReadFile(f.Name())
+	assert.NoError(err)
+	assert.Equal(before, after)
+}
+
+func TestAPIWrite(t *testing.T) {
+	assert := assert.New(t)
+	_, write, _ := startTestServer(t)
+
+	name, username, password := "gitlab", "me", "pw1"
+	urls := []string{"https://gitlab.com"}
+	input := EntryInput{
+		Name:     &name,
+		Username: &username,
+		Password: &password,
+		URLs:     &urls,
+		Fields:   []Field{{Name: "token", Value: "glpat", Secret: true}},
+	}
+
+	var added EntrySummary
+	assert.Equal(http.StatusCreated, write.do("POST", "/v1/entries", input, &added))
+	assert.Equal("gitlab", added.Name)
+	assert.NotEmpty(added.ID)
+	assert.Equal(urls, added.URLs)
+
+	// Adding it again is a conflict, unless forced
+	var errBody errorBody
+	assert.Equal(http.StatusConflict, write.do("POST", "/v1/entries", input, &errBody))
+	assert.Contains(errBody.Error, "already exists")
+	input.Force = true
+	assert.Equal(http.StatusCreated, write.do("POST", "/v1/entries", input, nil))
+	assert.Equal(http.StatusConflict, write.do("GET", "/v1/entries/gitlab", nil, &errBody))
+	assert.Contains(errBody.Error, "matches 2 entries")
+
+	// Only what is given is updated
+	newPassword := "pw2"
+	assert.Equal(http.StatusOK, write.do("PATCH", "/v1/entries/"+added.ID, EntryInput{
+		Password:     &newPassword,
+		RemoveFields: []string{"token"},
+	}, nil))
+
+	// Renaming it to another entry's name and username is a conflict
+	github, octocat := "github", "octocat"
+	assert.Equal(http.StatusConflict, write.do("PATCH", "/v1/entries/"+added.ID, EntryInput{
+		Name:     &github,
+		Username: &octocat,
+	}, &errBody))
+	assert.Contains(errBody.Error, "already exists")
+
+	var e Entry
+	assert.Equal(http.StatusOK, write.do("GET", "/v1/entries/"+added.ID, nil, &e))
+	assert.Equal("pw2", e.Password)
+	assert.Equal("gitlab", e.Name)
+	assert.Equal("me", e.Username)
+	assert.Equal(urls, e.URLs)
+	assert.Empty(e.Fields)
+
+	assert.Equal(http.StatusBadRequest, write.do("PATCH", "/v1/entries/"+added.ID, map[string]any{"nope": 1}, nil))
+	assert.Equal(http.StatusBadRequest, write.do("POST", "/v1/entries", EntryInput{Name: &name}, nil))
+
+	var deleted EntrySummary
+	assert.Equal(http.StatusOK, write.do("DELETE", "/v1/entries/"+added.ID, nil, &deleted))
+	assert.Equal(added.ID, deleted.ID)
+	assert.Equal(http.StatusNotFound, write.do("GET", "/v1/entries/"+added.ID, nil, nil))
+
+	// A generated password can be added straight away
+	genName := "aws"
+	var gen GenerateResult
+	assert.Equal(http.StatusCreated, write.do("POST", "/v1/generate", GenerateInput{
+		Length: 16,
+		Add:    &EntryInput{Name: &genName, Username: &username},
+	}, &gen))
+	assert.Len(gen.Password, 16)
+	assert.Equal("aws", gen.Entry.Name)
+
+	var f Field
+	assert.Equal(http.StatusOK, write.do("GET", "/v1/entries/aws/fields/password", nil, &f))
+	assert.Equal(gen.Password, f.Value)
+
+	var list []EntrySummary
+	assert.Equal(http.StatusOK, write.do("GET", "/v1/entries", nil, &list))
+	assert.Len(list, 3)
+}
@@ -29,6 +29,8 @@ func init() {
 	rootCmd.AddCommand(configCmd)
 
 	configCmd.AddCommand(config.ChangeMasterpassCmd)
+	configCmd.AddCommand(config.RotateKeyCmd)
+	configCmd.AddCommand(config.UpdateKeystoreCmd)
 	configCmd.AddCommand(config.UpdateTimeoutCmd)
 	configCmd.AddCommand(config.ViewCmd)
 
@@ -39,4 +41,8 @@ func initConfigFlags() {
 	config.UpdateTimeoutCmd.Flags().IntP("hours", "q", 0, "the hours you want to add to timeout")
 	config.UpdateTimeoutCmd.Flags().
 		IntP("minutes", "m", 30, "the minutes you want to add to timeout")
+
+	config.UpdateKeystoreCmd.Flags().
+		String("keystore", "", "where to keep the encryption key, 'keyring' or 'file'")
+	config.UpdateKeystoreCmd.MarkFlagRequired("keystore")
 }
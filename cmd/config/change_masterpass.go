@@ -13,7 +13,7 @@ import (
 	"github.com/spf13/cobra"
 	"golang.org/x/crypto/bcrypt"
 
-	"go-pass/crypt"
+	"go-pass/agent"
 	"go-pass/model"
 	"go-pass/utils"
 )
@@ -24,6 +24,11 @@ var ChangeMasterpassCmd = &cobra.Command{
 	Short: "Changes your master password, used for logging in",
 	Long: `'change_masterpass' changes the master password, used to login
 
+As your vault is encrypted with a key derived from the master password, your
+config, your vault and all of your backups are re-encrypted with the new one.
+If anything fails along the way, every file is left as it was. A running agent
+is locked afterwards.
+
 Ex.
 	$gopass config change_masterpass
 	Master Password: <master_pass>
@@ -42,12 +47,21 @@ Ex.
 
 // ChangeMasterpassCmdHandler handles the 'change_masterpass' command
 func ChangeMasterpassCmdHandler(cmd *cobra.Command, args []string) error {
-	passB, err := utils.GetPasswordFromUser(true, os.Stdin)
+	// The current password is always prompted for, rather than taken from the
+	// agent, as the keystore may need it to read the key
+	fmt.Println(strings.Repeat("*", 24))
+	fmt.Println("Input current password:")
+	fmt.Println(strings.Repeat("*", 24))
+	password, err := utils.GetPasswordFromUser(true, os.Stdin)
 	if err != nil {
 		return err
 	}
 
-	keyring := model.NewMasterAESKeyManager(string(passB))
+	keyring, err := utils.NewKeyManager(string(password))
+	if err != nil {
+		return err
+	}
+	defer keyring.Close()
 
 	cfg, err := utils.CheckConfig("", keyring)
 	if err != nil {
@@ -58,6 +72,11 @@ func ChangeMasterpassCmdHandler(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("error: %w", err)
 	}
+
+	// The agent holds a key derived from the old password
+	if _, err := agent.NewClient("").Lock(); err == nil {
+		fmt.Println("Locked the agent, run 'gopass unlock' with your new Master Password.")
+	}
 	return nil
 }
 
@@ -65,15 +84,7 @@ func ChangeMasterpassCmdHandler(cmd *cobra.Command, args []string) error {
 // This will return an error, otherwise it'll print a success message to the
 // terminal indicating success.
 func ChangeMasterpass(cfg *model.Config, key *model.MasterAESKeyManager) error {
-	fmt.Println(strings.Repeat("*", 24))
-	fmt.Println("Input current password:")
-	fmt.Println(strings.Repeat("*", 24))
-	password, err := utils.GetPasswordFromUser(true, os.Stdin)
-	if err != nil {
-		return err
-	}
-
-	if err := bcrypt.CompareHashAndPassword(cfg.MasterPassword, password); err != nil {
+	if err := bcrypt.CompareHashAndPassword(cfg.MasterPassword, []byte(key.Masterpassword)); err != nil {
 		fmt.Println("passwords don't match")
 		return errors.New("passwords don't match")
 	}
@@ -99,27 +110,36 @@ func ChangeMasterpass(cfg *model.Config, key *model.MasterAESKeyManager) error {
 		return errors.New("passwords do not match")
 	}
 
-	bNewPass, err := bcrypt.GenerateFromPassword(newPass, bcrypt.DefaultCost)
-	if err != nil {
+	if err := ReKeyMasterpass("", key, newPass); err != nil {
 		return err
 	}
 
-	cfg.MasterPassword = bNewPass
-	cfgB, err := crypt.EncryptConfig(cfg, key)
+	fmt.Println("Success! Master Password changed.")
+	return nil
+}
+
+// ReKeyMasterpass changes the master password to 'newPass'. As every key is
+// derived from the master password, the config, the vault with every password
+// in it, and every backup are re-encrypted under it, and the keystore is
+// updated for keystores that are protected by the master password. If anything
+// fails, every file is left as it was.
+func ReKeyMasterpass(cfgName string, key *model.MasterAESKeyManager, newPass []byte) error {
+	bNewPass, err := bcrypt.GenerateFromPassword(newPass, bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 
-	cfgFile, err := os.OpenFile(utils.CONFIG_FILE, os.O_RDWR, 0o600)
+	baseKey, err := key.Store.Get([]byte(key.Masterpassword))
 	if err != nil {
-		return err
+		return fmt.Errorf("reading key: %v", err)
 	}
-	defer cfgFile.Close()
 
-	if err := utils.WriteToFile(cfgFile.Name(), model.FileConfig, cfgB); err != nil {
-		return err
+	err = utils.ReKeyFiles(cfgName, key, string(newPass), baseKey, func(cfg *model.Config) {
+		cfg.MasterPassword = bNewPass
+	})
+	if err != nil {
+		return fmt.Errorf("re-keying vault: %v", err)
 	}
 
-	fmt.Println("Success! Master Password changed.")
 	return nil
 }
@@ -1,8 +1,64 @@
 package config
 
-import "testing"
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
 
 func TestChangeMasterpass(t *testing.T) {
 	// This is tough, for the same reason noted in the utils/input_test.go for
 	// `TestGetPasswordFromUser`.
 }
+
+func TestReKeyMasterpass(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	useTempBackups(t)
+	assert := assert.New(t)
+
+	// The file keystore is protected by the master password, so it has to be
+	// re-encrypted along with the vault
+	store := model.NewFileKeyStore(path.Join(t.TempDir(), "key.enc"))
+	store.KDF = cheapKDF
+	key := model.NewMasterAESKeyManagerWithStore(string(testutils.TEST_MASTER_PASSWORD), store)
+	defer key.Close()
+	assert.NoError(key.UseKDF(cheapKDF))
+	assert.NoError(key.InitializeKeychain())
+
+	bPass, err := bcrypt.GenerateFromPassword(testutils.TEST_MASTER_PASSWORD, bcrypt.MinCost)
+	assert.NoError(err)
+
+	cfgFile, err := utils.CreateConfig(testutils.TEST_VAULT_NAME, bPass, testutils.TEST_CONFIG_NAME, key)
+	assert.NoError(err)
+	defer cfgFile.Close()
+
+	vaultFile, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	defer vaultFile.Close()
+
+	assert.NoError(ReKeyMasterpass(testutils.TEST_CONFIG_NAME, key, []byte("newpass")))
+
+	_, err = store.Get(testutils.TEST_MASTER_PASSWORD)
+	assert.Error(err)
+
+	newKey := model.NewMasterAESKeyManagerWithStore("newpass", store)
+	defer newKey.Close()
+	cfg, err := utils.CheckConfig(testutils.TEST_CONFIG_NAME, newKey)
+	assert.NoError(err)
+	assert.NoError(bcrypt.CompareHashAndPassword(cfg.MasterPassword, []byte("newpass")))
+
+	f, err := os.Open(path.Join(utils.VAULT_PATH, testutils.TEST_VAULT_NAME))
+	assert.NoError(err)
+	defer f.Close()
+	_, err = crypt.DecryptVault(f, newKey)
+	assert.NoError(err)
+}
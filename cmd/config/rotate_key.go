@@ -0,0 +1,90 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package config
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"go-pass/agent"
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+// rotateKeyCmd represents the rotate-key command
+var RotateKeyCmd = &cobra.Command{
+	Use:   "rotate-key",
+	Short: "Replaces the key stored in your keystore with a new one",
+	Long: `'rotate-key' generates a new random key to replace the one stored in your
+keyring (or key file), and re-encrypts your config, your vault and all of your
+backups under it. The old key is only replaced after every file has been
+written, and if anything fails along the way, every file is left as it was.
+
+The Master Password is always prompted for, and a running agent is locked
+afterwards, as it holds a key derived from the old one.
+
+Ex.
+	$ gopass config rotate-key
+	Master Password: <master_pass>
+	Success! Key rotated.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := RotateKeyCmdHandler(cmd, args); err != nil {
+			fmt.Println("Error with 'rotate-key' command: ", err)
+			return
+		}
+	},
+}
+
+// RotateKeyCmdHandler handles the 'rotate-key' command
+func RotateKeyCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("no arguments needed for 'rotate-key'. see 'help' for more guidance")
+	}
+
+	passB, err := utils.GetPasswordFromUser(true, os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	keyring, err := utils.NewKeyManager(string(passB))
+	if err != nil {
+		return err
+	}
+	defer keyring.Close()
+
+	if err := RotateKey("", keyring, time.Now()); err != nil {
+		return err
+	}
+
+	if _, err := agent.NewClient("").Lock(); err == nil {
+		fmt.Println("Locked the agent, run 'gopass unlock' to unlock it with the new key.")
+	}
+
+	fmt.Println("Success! Key rotated.")
+	return nil
+}
+
+// RotateKey generates a new base key and re-encrypts every file under it. The
+// new key is stored in key.Store once every file has been written.
+func RotateKey(cfgName string, key *model.MasterAESKeyManager, now time.Time) error {
+	newKey := make([]byte, model.KEY_SIZE)
+	if _, err := rand.Read(newKey); err != nil {
+		return fmt.Errorf("generating key: %v", err)
+	}
+
+	err := utils.ReKeyFiles(cfgName, key, key.Masterpassword, newKey, func(cfg *model.Config) {
+		cfg.KeyRotatedAt = now.UnixMilli()
+	})
+	if err != nil {
+		return fmt.Errorf("rotating key: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+func TestRotateKey(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	useTempBackups(t)
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+	defer key.Close()
+
+	cfgFile, err := utils.CreateConfig(
+		testutils.TEST_VAULT_NAME,
+		testutils.TEST_MASTER_PASSWORD,
+		testutils.TEST_CONFIG_NAME,
+		key,
+	)
+	assert.NoError(err)
+	defer cfgFile.Close()
+
+	pass, err := crypt.EncryptPassword([]byte("hunter2"), key)
+	assert.NoError(err)
+	vault, err := crypt.EncryptVault([]model.VaultEntry{{Name: "test1", Password: []byte(pass)}}, key)
+	assert.NoError(err)
+	vaultPath := path.Join(utils.VAULT_PATH, testutils.TEST_VAULT_NAME)
+	assert.NoError(utils.WriteToFile(vaultPath, model.FileVault, vault))
+
+	oldBaseKey, err := key.Store.Get(nil)
+	assert.NoError(err)
+
+	now := time.Now()
+	assert.NoError(RotateKey(testutils.TEST_CONFIG_NAME, key, now))
+
+	newBaseKey, err := key.Store.Get(nil)
+	assert.NoError(err)
+	assert.NotEqual(oldBaseKey, newBaseKey)
+
+	// A new manager reads the new key from the keystore
+	rotated := model.NewTestMasterAESKeyManager(string(testutils.TEST_MASTER_PASSWORD))
+	defer rotated.Close()
+
+	cfg, err := utils.CheckConfig(testutils.TEST_CONFIG_NAME, rotated)
+	assert.NoError(err)
+	assert.Equal(now.UnixMilli(), cfg.KeyRotatedAt)
+
+	f, err := os.Open(vaultPath)
+	assert.NoError(err)
+	defer f.Close()
+	entries, err := crypt.DecryptVault(f, rotated)
+	assert.NoError(err)
+	assert.Len(entries, 1)
+
+	decrypted, err := crypt.DecryptPassword(entries[0].Password, rotated)
+	assert.NoError(err)
+	assert.Equal("hunter2", decrypted)
+
+	// The old key can no longer read anything
+	old := model.NewMasterAESKeyManagerWithStore(
+		string(testutils.TEST_MASTER_PASSWORD),
+		model.NewStaticKeyStore(oldBaseKey),
+	)
+	defer old.Close()
+	_, err = utils.CheckConfig(testutils.TEST_CONFIG_NAME, old)
+	assert.Error(err)
+}
@@ -0,0 +1,124 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+// updateKeystoreCmd represents the update_keystore command
+var UpdateKeystoreCmd = &cobra.Command{
+	Use:   "update_keystore",
+	Short: "Moves your encryption key to a different keystore",
+	Long: `'update_keystore' moves the key that your vault is encrypted with to a
+different keystore. There is one flag, '--keystore', which has to be present.
+
+	keyring: your OS keyring (Secret Service, Keychain or Credential Manager)
+	file:    a file under your config directory, protected by your Master
+	         Password, for machines without an OS keyring
+
+The Master Password is always prompted for, as the file keystore is encrypted
+with it.
+
+Ex.
+	$ gopass config update_keystore --keystore file
+	Master Password: <master_pass>
+	Success! Key moved from 'keyring' to 'file'.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := UpdateKeystoreCmdHandler(cmd, args); err != nil {
+			fmt.Println("Error with 'update_keystore' command: ", err)
+			return
+		}
+	},
+}
+
+// UpdateKeystoreCmdHandler handles the 'update_keystore' command
+func UpdateKeystoreCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("no arguments needed for 'update_keystore'. see 'help' for more guidance")
+	}
+
+	keystore, err := cmd.Flags().GetString("keystore")
+	if err != nil {
+		return err
+	}
+
+	settings, err := utils.ReadSettings()
+	if err != nil {
+		return err
+	}
+	if settings.KeyStore == keystore {
+		return fmt.Errorf("key is already in '%s'", keystore)
+	}
+
+	to, err := utils.NewKeyStore(keystore)
+	if err != nil {
+		return err
+	}
+
+	passB, err := utils.GetPasswordFromUser(true, os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	keyring, err := utils.NewKeyManager(string(passB))
+	if err != nil {
+		return err
+	}
+	defer keyring.Close()
+
+	if err := MoveKey("", keyring, to); err != nil {
+		return err
+	}
+
+	from := settings.KeyStore
+	settings.KeyStore = keystore
+	if err := utils.WriteSettings(settings); err != nil {
+		return fmt.Errorf("key copied to '%s', but saving settings failed: %v", keystore, err)
+	}
+
+	if err := keyring.DeleteKeychain(); err != nil && !errors.Is(err, model.ErrKeyNotFound) {
+		fmt.Printf("Could not remove the key from '%s': %v\n", from, err)
+	}
+
+	fmt.Printf("Success! Key moved from '%s' to '%s'.\n", from, keystore)
+	return nil
+}
+
+// MoveKey copies the key of 'key' into 'to', and checks that the config can be
+// decrypted with it. The key is left in the old keystore.
+func MoveKey(cfgName string, key *model.MasterAESKeyManager, to model.KeyStore) error {
+	// Decrypting the config confirms the Master Password before anything is
+	// written
+	if _, err := utils.CheckConfig(cfgName, key); err != nil {
+		return err
+	}
+
+	masterPassword := []byte(key.Masterpassword)
+
+	baseKey, err := key.Store.Get(masterPassword)
+	if err != nil {
+		return fmt.Errorf("reading key: %v", err)
+	}
+
+	if err := to.Set(baseKey, masterPassword); err != nil {
+		return fmt.Errorf("storing key: %v", err)
+	}
+
+	moved := model.NewMasterAESKeyManagerWithStore(key.Masterpassword, to)
+	defer moved.Close()
+	if _, err := utils.CheckConfig(cfgName, moved); err != nil {
+		return fmt.Errorf("checking moved key: %v", err)
+	}
+
+	return nil
+}
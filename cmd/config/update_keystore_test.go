@@ -0,0 +1,56 @@
+package config
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+// useTempBackups points utils.BACKUP_PATH at a temporary directory for the
+// length of the test, so backups left over elsewhere aren't re-encrypted
+func useTempBackups(t *testing.T) {
+	oldBackupPath := utils.BACKUP_PATH
+	utils.BACKUP_PATH = t.TempDir()
+	t.Cleanup(func() { utils.BACKUP_PATH = oldBackupPath })
+}
+
+// cheapKDF keeps the file keystore fast in tests
+var cheapKDF = model.KDFParams{ID: model.KDFArgon2id, Time: 1, Memory: 1024, Threads: 1}
+
+func TestMoveKey(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+	defer key.Close()
+
+	cfgFile, err := utils.CreateConfig(
+		testutils.TEST_VAULT_NAME,
+		testutils.TEST_MASTER_PASSWORD,
+		testutils.TEST_CONFIG_NAME,
+		key,
+	)
+	assert.NoError(err)
+	defer cfgFile.Close()
+
+	to := model.NewFileKeyStore(path.Join(t.TempDir(), "key.enc"))
+	to.KDF = cheapKDF
+	assert.NoError(MoveKey(testutils.TEST_CONFIG_NAME, key, to))
+
+	// The key in the file is the same key, so the config still decrypts
+	moved := model.NewMasterAESKeyManagerWithStore(string(testutils.TEST_MASTER_PASSWORD), to)
+	defer moved.Close()
+	_, err = utils.CheckConfig(testutils.TEST_CONFIG_NAME, moved)
+	assert.NoError(err)
+
+	wrong := model.NewTestMasterAESKeyManager("wrong")
+	defer wrong.Close()
+	assert.Error(MoveKey(testutils.TEST_CONFIG_NAME, wrong, to))
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"go-pass/agent"
 	"go-pass/crypt"
 	"go-pass/model"
 	"go-pass/utils"
@@ -38,12 +39,11 @@ Ex.
 // This command handler will update the timeout field in the config with the
 // flags as the new values
 func UpdateTimeoutCmdHandler(cmd *cobra.Command, args []string) error {
-	passB, err := utils.GetPasswordFromUser(true, os.Stdin)
+	keyring, err := agent.GetKeyManager(os.Stdin)
 	if err != nil {
 		return err
 	}
-
-	keyring := model.NewMasterAESKeyManager(string(passB))
+	defer keyring.Close()
 
 	cfg, err := utils.CheckConfig("", keyring)
 	if err != nil {
@@ -11,6 +11,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"go-pass/agent"
 	"go-pass/model"
 	"go-pass/utils"
 )
@@ -35,12 +36,11 @@ Ex.
 
 // ViewCmdHandler handles the config's 'view' command
 func ViewCmdHandler(cmd *cobra.Command, args []string) error {
-	passB, err := utils.GetPasswordFromUser(true, os.Stdin)
+	keyring, err := agent.GetKeyManager(os.Stdin)
 	if err != nil {
 		return err
 	}
-
-	keyring := model.NewMasterAESKeyManager(string(passB))
+	defer keyring.Close()
 
 	cfg, err := utils.CheckConfig("", keyring)
 	if err != nil {
@@ -61,6 +61,11 @@ func PrintConfig(cfg *model.Config) {
 	fmt.Printf("Vault name: %s\n", cfg.VaultName)
 	fmt.Printf("Master Password: ******\n")
 	fmt.Printf("Timeout: %s\n", convertTimeMsToDuration(cfg.Timeout))
+	if cfg.KeyRotatedAt != 0 {
+		fmt.Printf("Key last rotated: %s\n", time.UnixMilli(cfg.KeyRotatedAt).Format(time.DateTime))
+	} else {
+		fmt.Printf("Key last rotated: never\n")
+	}
 	fmt.Println(strings.Repeat("*", 24))
 }
 
@@ -0,0 +1,302 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"go-pass/cmd/vault"
+	"go-pass/credential"
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+const (
+	// DOCKER_CREDENTIAL_FOLDER is the folder docker's credentials are kept in.
+	// Entries in other folders are never given to docker.
+	DOCKER_CREDENTIAL_FOLDER = "docker"
+	// DOCKER_CREDENTIAL_BINARY is the name docker runs the helper as, for
+	// '"credsStore": "gopass"'
+	DOCKER_CREDENTIAL_BINARY = "docker-credential-gopass"
+)
+
+// dockerCredentialCmd represents the docker-credential command
+var dockerCredentialCmd = &cobra.Command{
+	Use:   "docker-credential <get|store|erase|list>",
+	Short: "A docker credential helper backed by your vault",
+	Long: fmt.Sprintf(`%s
+
+'docker-credential' lets docker keep registry logins in your vault rather than
+in ~/.docker/config.json. Docker runs it as '%s', so link that
+name to gopass somewhere on your PATH, and set '"credsStore": "gopass"' in
+~/.docker/config.json:
+	$ ln -s "$(command -v gopass)" /usr/local/bin/%s
+
+Docker's logins are kept in the '%s' folder, one entry per registry, named
+after its server URL. 'get' also finds an entry in the folder with a URL for
+the same host, and 'list' shows every entry in it.
+
+The Master Password is asked for on the terminal, unless the agent is unlocked.
+`, LongDescriptionText, DOCKER_CREDENTIAL_BINARY, DOCKER_CREDENTIAL_BINARY, DOCKER_CREDENTIAL_FOLDER),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := DockerCredentialCmdHandler(cmd, args); err != nil {
+			// Docker reads the error from stdout, and needs a failing exit
+			// status
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(dockerCredentialCmd)
+}
+
+// DockerCredentialCmdHandler is the handler function that reads the request
+// from docker and runs DockerCredentialGet, DockerCredentialStore,
+// DockerCredentialErase or DockerCredentialList.
+func DockerCredentialCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("exactly 1 argument, the action, is needed. see 'help' for correct usage")
+	}
+
+	var (
+		serverURL string
+		c         credential.DockerCredential
+		err       error
+	)
+	switch action := args[0]; action {
+	case "get", "erase":
+		serverURL, err = credential.ReadDockerServerURL(os.Stdin)
+	case "store":
+		c, err = credential.ReadDocker(os.Stdin)
+	case "list":
+	default:
+		return fmt.Errorf("unknown credential action '%s'", action)
+	}
+	if err != nil {
+		return err
+	}
+
+	keyring, err := helperKeyManager()
+	if err != nil {
+		return err
+	}
+	defer keyring.Close()
+
+	cfg, err := utils.CheckConfig("", keyring)
+	if err != nil {
+		return fmt.Errorf("error checking config: %v", err)
+	}
+
+	var answer any
+	switch args[0] {
+	case "get":
+		answer, err = DockerCredentialGet(cfg, serverURL, keyring)
+	case "store":
+		err = DockerCredentialStore(cfg, c, time.Now().UnixMilli(), keyring)
+	case "erase":
+		err = DockerCredentialErase(cfg, serverURL, keyring)
+	case "list":
+		answer, err = DockerCredentialList(cfg, keyring)
+	}
+	if err != nil || answer == nil {
+		return err
+	}
+
+	return json.NewEncoder(os.Stdout).Encode(answer)
+}
+
+// DockerCredentialGet returns the login for 'serverURL'. The entry named after
+// it is used, or else the best match among the entries in
+// DOCKER_CREDENTIAL_FOLDER, see credential.Match. If there is neither,
+// credential.ErrDockerNotFound is returned.
+func DockerCredentialGet(
+	cfg *model.Config,
+	serverURL string,
+	key *model.MasterAESKeyManager,
+) (credential.DockerCredential, error) {
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	if err != nil {
+		return credential.DockerCredential{}, err
+	}
+	f.Close()
+
+	idx := dockerEntry(entries, serverURL)
+	if idx < 0 {
+		q, err := credential.ParseURL(serverURL)
+		if err != nil {
+			return credential.DockerCredential{}, credential.ErrDockerNotFound
+		}
+
+		var docker []model.VaultEntry
+		var indexes []int
+		for i, e := range entries {
+			if isDockerEntry(e) {
+				docker = append(docker, e)
+				indexes = append(indexes, i)
+			}
+		}
+
+		matches := credential.Match(docker, q)
+		if len(matches) == 0 {
+			return credential.DockerCredential{}, credential.ErrDockerNotFound
+		}
+		idx = indexes[matches[0]]
+	}
+
+	e := entries[idx]
+	secret, err := crypt.DecryptPassword(e.Password, key)
+	if err != nil {
+		return credential.DockerCredential{}, fmt.Errorf("decrypting password: %v", err)
+	}
+
+	return credential.DockerCredential{
+		ServerURL: serverURL,
+		Username:  e.Username,
+		Secret:    secret,
+	}, nil
+}
+
+// DockerCredentialStore saves the login in 'c' to the entry named after its
+// server URL in DOCKER_CREDENTIAL_FOLDER, adding the entry if there isn't one.
+// A login that would duplicate another entry returns vault.ErrDuplicateEntry.
+func DockerCredentialStore(
+	cfg *model.Config,
+	c credential.DockerCredential,
+	t int64,
+	key *model.MasterAESKeyManager,
+) error {
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	secret, err := crypt.EncryptPassword([]byte(c.Secret), key)
+	if err != nil {
+		return err
+	}
+
+	// The login can't duplicate an entry outside of the docker folder
+	idx := dockerEntry(entries, c.ServerURL)
+	others := entries
+	if idx >= 0 {
+		others = slices.Delete(slices.Clone(entries), idx, idx+1)
+	}
+	if vault.FindDuplicate(others, c.ServerURL, c.Username) >= 0 {
+		return fmt.Errorf("'%s': %w", c.ServerURL, vault.ErrDuplicateEntry)
+	}
+
+	if idx >= 0 {
+		entries[idx].Username = c.Username
+		entries[idx].Password = []byte(secret)
+		entries[idx].UpdatedAt = t
+	} else {
+		id, err := model.NewEntryID()
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, model.VaultEntry{
+			ID:       id,
+			Name:     c.ServerURL,
+			Username: c.Username,
+			Password: []byte(secret),
+			Metadata: model.Metadata{
+				URLs:   []string{c.ServerURL},
+				Folder: DOCKER_CREDENTIAL_FOLDER,
+			},
+			CreatedAt: t,
+			UpdatedAt: t,
+		})
+	}
+
+	ciphertext, err := crypt.EncryptVault(entries, key)
+	if err != nil {
+		return fmt.Errorf("encrypting vault: %v", err)
+	}
+	return utils.WriteToFile(f.Name(), model.FileVault, ciphertext)
+}
+
+// DockerCredentialErase removes the entry named after 'serverURL' from
+// DOCKER_CREDENTIAL_FOLDER, or returns credential.ErrDockerNotFound
+func DockerCredentialErase(
+	cfg *model.Config,
+	serverURL string,
+	key *model.MasterAESKeyManager,
+) error {
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	idx := dockerEntry(entries, serverURL)
+	if idx < 0 {
+		return credential.ErrDockerNotFound
+	}
+	entries = append(entries[:idx], entries[idx+1:]...)
+
+	ciphertext, err := crypt.EncryptVault(entries, key)
+	if err != nil {
+		return fmt.Errorf("encrypting vault: %v", err)
+	}
+	return utils.WriteToFile(f.Name(), model.FileVault, ciphertext)
+}
+
+// DockerCredentialList returns the username of every entry in
+// DOCKER_CREDENTIAL_FOLDER by its server URL, which is its first URL, or its
+// name if it has none
+func DockerCredentialList(
+	cfg *model.Config,
+	key *model.MasterAESKeyManager,
+) (map[string]string, error) {
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	list := map[string]string{}
+	for _, e := range entries {
+		if !isDockerEntry(e) {
+			continue
+		}
+
+		serverURL := e.Name
+		if len(e.URLs) > 0 {
+			serverURL = e.URLs[0]
+		}
+		list[serverURL] = e.Username
+	}
+	return list, nil
+}
+
+// dockerEntry returns the index of the entry in DOCKER_CREDENTIAL_FOLDER named
+// after 'serverURL', or -1
+func dockerEntry(entries []model.VaultEntry, serverURL string) int {
+	for i, e := range entries {
+		if isDockerEntry(e) && e.Name == serverURL {
+			return i
+		}
+	}
+	return -1
+}
+
+// isDockerEntry returns true if the entry is in DOCKER_CREDENTIAL_FOLDER, or a
+// folder inside it
+func isDockerEntry(e model.VaultEntry) bool {
+	return e.Folder == DOCKER_CREDENTIAL_FOLDER ||
+		strings.HasPrefix(e.Folder, DOCKER_CREDENTIAL_FOLDER+"/")
+}
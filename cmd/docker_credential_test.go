@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/cmd/vault"
+	"go-pass/credential"
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+func TestDockerCredential(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+	vaultFile, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	vaultFile.Close()
+	cfg := &model.Config{VaultName: testutils.TEST_VAULT_NAME}
+
+	// An entry outside the docker folder is never handed out
+	pass, err := crypt.EncryptPassword([]byte("not for docker"), key)
+	assert.NoError(err)
+	assert.NoError(vault.AddToVault("ghcr.io", model.UserInput{
+		Username: "me",
+		Password: []byte(pass),
+		Metadata: model.Metadata{URLs: []string{"https://ghcr.io"}},
+	}, cfg, 1, key))
+
+	_, err = DockerCredentialGet(cfg, "ghcr.io", key)
+	assert.ErrorIs(err, credential.ErrDockerNotFound)
+
+	hub := "https://index.docker.io/v1/"
+	assert.NoError(DockerCredentialStore(cfg, credential.DockerCredential{
+		ServerURL: hub, Username: "me", Secret: "token1",
+	}, 2, key))
+	assert.NoError(DockerCredentialStore(cfg, credential.DockerCredential{
+		ServerURL: "ghcr.io", Username: "bot", Secret: "token2",
+	}, 2, key))
+
+	// The login of the entry outside the docker folder isn't duplicated
+	err = DockerCredentialStore(cfg, credential.DockerCredential{
+		ServerURL: "ghcr.io", Username: "me", Secret: "token2",
+	}, 2, key)
+	assert.ErrorIs(err, vault.ErrDuplicateEntry)
+
+	c, err := DockerCredentialGet(cfg, hub, key)
+	assert.NoError(err)
+	assert.Equal(credential.DockerCredential{ServerURL: hub, Username: "me", Secret: "token1"}, c)
+
+	// Found by host, as well as by name
+	c, err = DockerCredentialGet(cfg, "index.docker.io", key)
+	assert.NoError(err)
+	assert.Equal("token1", c.Secret)
+
+	// Storing again replaces the login
+	assert.NoError(DockerCredentialStore(cfg, credential.DockerCredential{
+		ServerURL: hub, Username: "other", Secret: "token3",
+	}, 3, key))
+	c, err = DockerCredentialGet(cfg, hub, key)
+	assert.NoError(err)
+	assert.Equal(credential.DockerCredential{ServerURL: hub, Username: "other", Secret: "token3"}, c)
+
+	list, err := DockerCredentialList(cfg, key)
+	assert.NoError(err)
+	assert.Equal(map[string]string{hub: "other", "ghcr.io": "bot"}, list)
+
+	assert.NoError(DockerCredentialErase(cfg, hub, key))
+	assert.ErrorIs(DockerCredentialErase(cfg, hub, key), credential.ErrDockerNotFound)
+	_, err = DockerCredentialGet(cfg, hub, key)
+	assert.ErrorIs(err, credential.ErrDockerNotFound)
+
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	assert.NoError(err)
+	f.Close()
+	assert.Len(entries, 2)
+	assert.Equal(DOCKER_CREDENTIAL_FOLDER, entries[1].Folder)
+	assert.Equal([]string{"ghcr.io"}, entries[1].URLs)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// envCmd represents the env command
+var envCmd = &cobra.Command{
+	Use:   "env --env VAR=entry:field...",
+	Short: "Prints export lines for secrets from your vault",
+	Long: fmt.Sprintf(`%s
+
+'env' prints a shell 'export' line for each '--env', for 'eval'. The variables
+are given the same way as for 'run', as 'VAR=entry:field'. Prompts are written
+to stderr, so only the export lines are evaluated.
+
+Prefer 'run' where you can, as the secrets then never reach your shell.
+
+Ex.
+	$ eval "$(gopass env --env DB_PASS=prod-db:password --env API_KEY=stripe:api_key)"
+`, LongDescriptionText),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := EnvCmdHandler(cmd, args); err != nil {
+			// stdout is evaluated by the shell
+			fmt.Fprintf(os.Stderr, "Error with 'env' command: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(envCmd)
+
+	envCmd.Flags().StringArrayP("env", "e", nil, "Export VAR set to a field of an entry, as VAR=entry:field")
+	envCmd.MarkFlagRequired("env")
+}
+
+// EnvCmdHandler is the handler function that resolves the secrets and prints
+// the export lines.
+func EnvCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("no arguments needed for 'env', use '--env'. see 'help' for more guidance")
+	}
+
+	env, err := envFromFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	return WriteExports(os.Stdout, env)
+}
+
+// WriteExports writes an 'export' line for each 'VAR=value' pair, with the
+// value quoted for a POSIX shell
+func WriteExports(w io.Writer, env []string) error {
+	for _, pair := range env {
+		name, value, _ := strings.Cut(pair, "=")
+		if _, err := fmt.Fprintf(w, "export %s=%s\n", name, ShellQuote(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ShellQuote quotes 's' in single quotes, so a POSIX shell reads it as is
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
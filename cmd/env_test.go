@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"bytes"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteExports(t *testing.T) {
+	assert := assert.New(t)
+
+	var b bytes.Buffer
+	assert.NoError(WriteExports(&b, []string{"DB_PASS=it's $HOME", "EMPTY=", "EQ=a=b"}))
+	assert.Equal(
+		"export DB_PASS='it'\\''s $HOME'\nexport EMPTY=''\nexport EQ='a=b'\n",
+		b.String(),
+	)
+
+	// A shell reads the values back as they were
+	out, err := exec.Command("sh", "-c", b.String()+`printf '%s|%s|%s' "$DB_PASS" "$EMPTY" "$EQ"`).Output()
+	assert.NoError(err)
+	assert.Equal("it's $HOME||a=b", string(out))
+}
@@ -0,0 +1,267 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"go-pass/agent"
+	"go-pass/credential"
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+// GIT_CREDENTIAL_TAG is the tag of the entries 'git-credential store' adds.
+// Only these are removed by 'git-credential erase'.
+const GIT_CREDENTIAL_TAG = "git-credential"
+
+// gitCredentialCmd represents the git-credential command
+var gitCredentialCmd = &cobra.Command{
+	Use:   "git-credential <get|store|erase>",
+	Short: "A git credential helper backed by your vault",
+	Long: fmt.Sprintf(`%s
+
+'git-credential' lets git use your vault for HTTPS logins, as a credential
+helper. Set it up with:
+	$ git config --global credential.helper '!gopass git-credential'
+
+'get' looks for an entry with a URL for the same host, and the same repository
+if git sends its path (see 'credential.useHttpPath'), or an entry named after
+the host. Entries with a matching path come first, then the most recently
+updated ones.
+
+'store' saves a login git was told is good. A matching entry with a different
+password is updated, and otherwise a new entry, named after the host and
+tagged '%s', is added.
+
+'erase' removes a login git was told is bad, but only from the entries 'store'
+added, so an entry you added yourself is never deleted.
+
+The Master Password is asked for on the terminal, unless the agent is unlocked.
+`, LongDescriptionText, GIT_CREDENTIAL_TAG),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := GitCredentialCmdHandler(cmd, args); err != nil {
+			// stdout is read by git
+			fmt.Fprintf(os.Stderr, "Error with 'git-credential' command: %v\n", err)
+			return
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gitCredentialCmd)
+}
+
+// GitCredentialCmdHandler is the handler function that reads the request from
+// git and runs GitCredentialGet, GitCredentialStore or GitCredentialErase.
+func GitCredentialCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("exactly 1 argument, the action, is needed. see 'help' for correct usage")
+	}
+
+	// Helpers are to ignore actions they don't know
+	action := args[0]
+	if action != "get" && action != "store" && action != "erase" {
+		return nil
+	}
+
+	c, err := credential.ReadGit(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	keyring, err := helperKeyManager()
+	if err != nil {
+		return err
+	}
+	defer keyring.Close()
+
+	cfg, err := utils.CheckConfig("", keyring)
+	if err != nil {
+		return fmt.Errorf("error checking config: %v", err)
+	}
+
+	switch action {
+	case "get":
+		return GitCredentialGet(cfg, c, os.Stdout, keyring)
+	case "store":
+		return GitCredentialStore(cfg, c, time.Now().UnixMilli(), keyring)
+	default:
+		return GitCredentialErase(cfg, c, keyring)
+	}
+}
+
+// helperKeyManager returns the key manager for a credential helper, whose
+// stdin belongs to the tool running it. The Master Password is read from the
+// terminal instead, unless the agent is unlocked, and prompted for on stderr,
+// as the tool reads the answer from stdout.
+func helperKeyManager() (*model.MasterAESKeyManager, error) {
+	var tty io.Reader = os.Stdin
+	if f, err := os.Open("/dev/tty"); err == nil {
+		defer f.Close()
+		tty = f
+	}
+	return agent.PromptKeyManager(os.Stderr, tty)
+}
+
+// GitCredentialGet writes the username and password of the best match for
+// 'c' to 'w'. Nothing is written if there is no match, so git moves on to
+// its other helpers.
+func GitCredentialGet(
+	cfg *model.Config,
+	c credential.GitCredential,
+	w io.Writer,
+	key *model.MasterAESKeyManager,
+) error {
+	if c.Host == "" {
+		return nil
+	}
+
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	matches := credential.Match(entries, c.Query())
+	if len(matches) == 0 {
+		return nil
+	}
+
+	e := entries[matches[0]]
+	password, err := crypt.DecryptPassword(e.Password, key)
+	if err != nil {
+		return fmt.Errorf("decrypting password: %v", err)
+	}
+
+	return credential.WriteGit(w, credential.GitCredential{
+		Username: e.Username,
+		Password: password,
+	})
+}
+
+// GitCredentialStore saves the login in 'c'. The best match with the same
+// username has its password updated if it changed, and if there is no match a
+// new entry tagged GIT_CREDENTIAL_TAG is added.
+func GitCredentialStore(
+	cfg *model.Config,
+	c credential.GitCredential,
+	t int64,
+	key *model.MasterAESKeyManager,
+) error {
+	if c.Host == "" || c.Username == "" || c.Password == "" {
+		return nil
+	}
+
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	encryptedPass, err := crypt.EncryptPassword([]byte(c.Password), key)
+	if err != nil {
+		return err
+	}
+
+	q := c.Query()
+	if matches := credential.Match(entries, q); len(matches) > 0 {
+		e := &entries[matches[0]]
+		password, err := crypt.DecryptPassword(e.Password, key)
+		if err != nil {
+			return fmt.Errorf("decrypting password: %v", err)
+		}
+		if password == c.Password {
+			return nil
+		}
+
+		e.Password = []byte(encryptedPass)
+		e.UpdatedAt = t
+	} else {
+		id, err := model.NewEntryID()
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, model.VaultEntry{
+			ID:       id,
+			Name:     q.Host,
+			Username: c.Username,
+			Password: []byte(encryptedPass),
+			Metadata: model.Metadata{
+				URLs: []string{q.String()},
+				Tags: []string{GIT_CREDENTIAL_TAG},
+			},
+			CreatedAt: t,
+			UpdatedAt: t,
+		})
+	}
+
+	ciphertext, err := crypt.EncryptVault(entries, key)
+	if err != nil {
+		return fmt.Errorf("encrypting vault: %v", err)
+	}
+	return utils.WriteToFile(f.Name(), model.FileVault, ciphertext)
+}
+
+// GitCredentialErase removes the entries tagged GIT_CREDENTIAL_TAG that match
+// 'c'. If 'c' has a password, only entries with that password are removed.
+func GitCredentialErase(
+	cfg *model.Config,
+	c credential.GitCredential,
+	key *model.MasterAESKeyManager,
+) error {
+	if c.Host == "" {
+		return nil
+	}
+
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	erase := map[int]bool{}
+	for _, idx := range credential.Match(entries, c.Query()) {
+		e := entries[idx]
+		if !slices.Contains(e.Tags, GIT_CREDENTIAL_TAG) {
+			continue
+		}
+
+		if c.Password != "" {
+			password, err := crypt.DecryptPassword(e.Password, key)
+			if err != nil {
+				return fmt.Errorf("decrypting password: %v", err)
+			}
+			if password != c.Password {
+				continue
+			}
+		}
+		erase[idx] = true
+	}
+	if len(erase) == 0 {
+		return nil
+	}
+
+	kept := make([]model.VaultEntry, 0, len(entries)-len(erase))
+	for i, e := range entries {
+		if !erase[i] {
+			kept = append(kept, e)
+		}
+	}
+
+	ciphertext, err := crypt.EncryptVault(kept, key)
+	if err != nil {
+		return fmt.Errorf("encrypting vault: %v", err)
+	}
+	return utils.WriteToFile(f.Name(), model.FileVault, ciphertext)
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/cmd/vault"
+	"go-pass/credential"
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+// gitRequest parses canned git credential helper input
+func gitRequest(t *testing.T, input string) credential.GitCredential {
+	t.Helper()
+	c, err := credential.ReadGit(strings.NewReader(input))
+	assert.NoError(t, err)
+	return c
+}
+
+func TestGitCredential(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+	vaultFile, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	vaultFile.Close()
+	cfg := &model.Config{VaultName: testutils.TEST_VAULT_NAME}
+
+	// An entry added by hand
+	pass, err := crypt.EncryptPassword([]byte("handmade"), key)
+	assert.NoError(err)
+	assert.NoError(vault.AddToVault("GitHub", model.UserInput{
+		Username: "me",
+		Password: []byte(pass),
+		Metadata: model.Metadata{URLs: []string{"https://github.com"}},
+	}, cfg, 1, key))
+
+	get := func(input string) string {
+		var b bytes.Buffer
+		assert.NoError(GitCredentialGet(cfg, gitRequest(t, input), &b, key))
+		return b.String()
+	}
+
+	assert.Equal(
+		"username=me\npassword=handmade\n",
+		get("protocol=https\nhost=github.com\npath=org/repo.git\n\n"),
+	)
+	assert.Equal("", get("protocol=https\nhost=gitlab.com\n\n"))
+
+	// A new login is added, tagged, under the host
+	assert.NoError(GitCredentialStore(
+		cfg, gitRequest(t, "protocol=https\nhost=gitlab.com\nusername=bot\npassword=token1\n\n"), 2, key,
+	))
+	assert.Equal("username=bot\npassword=token1\n", get("protocol=https\nhost=gitlab.com\n\n"))
+
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	assert.NoError(err)
+	f.Close()
+	assert.Len(entries, 2)
+	assert.Equal("gitlab.com", entries[1].Name)
+	assert.Equal([]string{"https://gitlab.com"}, entries[1].URLs)
+	assert.Equal([]string{GIT_CREDENTIAL_TAG}, entries[1].Tags)
+
+	// Storing it again updates the password, rather than adding an entry
+	assert.NoError(GitCredentialStore(
+		cfg, gitRequest(t, "protocol=https\nhost=gitlab.com\nusername=bot\npassword=token2\n\n"), 3, key,
+	))
+	assert.Equal("username=bot\npassword=token2\n", get("url=https://gitlab.com/group/project\n\n"))
+
+	// Erasing with the wrong password, or an entry added by hand, does nothing
+	assert.NoError(GitCredentialErase(
+		cfg, gitRequest(t, "protocol=https\nhost=gitlab.com\nusername=bot\npassword=token1\n\n"), key,
+	))
+	assert.NoError(GitCredentialErase(
+		cfg, gitRequest(t, "protocol=https\nhost=github.com\nusername=me\npassword=handmade\n\n"), key,
+	))
+
+	f, entries, err = utils.ReadVault(cfg.VaultName, key)
+	assert.NoError(err)
+	f.Close()
+	assert.Len(entries, 2)
+	assert.Equal(int64(3), entries[1].UpdatedAt)
+
+	assert.NoError(GitCredentialErase(
+		cfg, gitRequest(t, "protocol=https\nhost=gitlab.com\nusername=bot\npassword=token2\n\n"), key,
+	))
+	assert.Equal("", get("protocol=https\nhost=gitlab.com\n\n"))
+	assert.Equal("username=me\npassword=handmade\n", get("protocol=https\nhost=github.com\n\n"))
+}
@@ -40,6 +40,15 @@ Ex.
 Ex 2.
 	$ gopass init --vault-name <random_name>.json
 	Master Password: <insert master password here>
+
+By default, the key that your vault is encrypted with is kept in your OS
+keyring. On machines without one, like headless servers, '--keystore file'
+keeps it in a file under your config directory instead, protected by your
+Master Password.
+
+Ex 3.
+	$ gopass init --keystore file
+	Master Password: <insert master password here>
 `, LongDescriptionText),
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := InitCmdHandler(cmd, args); err != nil {
@@ -54,6 +63,8 @@ func init() {
 
 	initCmd.Flags().
 		StringP("vault-name", "v", "", "The name of the vault file that's not the default")
+	initCmd.Flags().
+		String("keystore", model.KEYSTORE_KEYRING, "Where to keep the encryption key, 'keyring' or 'file'")
 }
 
 // InitCmdHandler is the handler funciton that encapsulates the logic for
@@ -78,14 +89,27 @@ func InitCmdHandler(cmd *cobra.Command, args []string) error {
 
 	vaultName = EnsureVaultName(vaultName)
 
+	keystore, err := cmd.Flags().GetString("keystore")
+	if err != nil {
+		return fmt.Errorf("init::failed to get flag: %v", err)
+	}
+
+	if err := utils.WriteSettings(utils.Settings{KeyStore: keystore}); err != nil {
+		return err
+	}
+
 	password, err := utils.GetPasswordFromUser(true, os.Stdin)
 	if err != nil {
 		return fmt.Errorf("failed to get password: %v", err)
 	}
 
-	km := model.NewMasterAESKeyManager(string(password))
+	km, err := utils.NewKeyManager(string(password))
+	if err != nil {
+		return err
+	}
+	defer km.Close()
 	if err := km.InitializeKeychain(); err != nil {
-		return fmt.Errorf("failed to initialize keychain")
+		return fmt.Errorf("failed to initialize keychain: %v", err)
 	}
 
 	bPassword, err := bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
@@ -0,0 +1,272 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+
+	"go-pass/agent"
+	"go-pass/cmd/vault"
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+// injectCmd represents the inject command
+var injectCmd = &cobra.Command{
+	Use:   "inject -i <template> [-o <file>]",
+	Short: "Fills secrets from your vault into a config file template",
+	Long: fmt.Sprintf(`%s
+
+'inject' renders a Go text/template, filling in secrets from your vault, and
+writes the result to the '--out' file, readable only by you, or to stdout. The
+template can use:
+	{{ secret "prod-db" }}               the password of an entry
+	{{ secret "prod-db" "username" }}    a field of an entry: %s,
+	                                     or a custom field
+	{{ field "stripe" "api_key" }}       a custom field of an entry
+
+Entries are found like 'vault get' finds them, by name or ID. A name that
+matches several entries is an error, so use the ID instead.
+
+With '--check', nothing is written. Every reference is resolved, and any that
+can't be are listed.
+
+Ex.
+	$ cat app.tmpl
+	database:
+	  user: {{ secret "prod-db" "username" }}
+	  password: {{ secret "prod-db" "password" }}
+	stripe_key: {{ field "stripe" "api_key" }}
+	$ gopass inject -i app.tmpl -o app.yaml
+	$ gopass inject -i app.tmpl --check
+`, LongDescriptionText, strings.Join(vault.SecretFieldNames(), ", ")),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := InjectCmdHandler(cmd, args); err != nil {
+			// stdout may be the rendered file
+			fmt.Fprintf(os.Stderr, "Error with 'inject' command: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(injectCmd)
+
+	injectCmd.Flags().StringP("in", "i", "", "The template to render")
+	injectCmd.Flags().StringP("out", "o", "", "The file to write, instead of stdout")
+	injectCmd.Flags().Bool("check", false, "Only check that every reference resolves")
+	injectCmd.MarkFlagRequired("in")
+	injectCmd.MarkFlagsMutuallyExclusive("out", "check")
+}
+
+// InjectCmdHandler is the handler function that renders the template, or
+// checks it with '--check'.
+func InjectCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("no arguments needed for 'inject', use '--in'. see 'help' for more guidance")
+	}
+
+	in, err := cmd.Flags().GetString("in")
+	if err != nil {
+		return fmt.Errorf("error getting in flag: %v", err)
+	}
+	outPath, err := cmd.Flags().GetString("out")
+	if err != nil {
+		return fmt.Errorf("error getting out flag: %v", err)
+	}
+	check, err := cmd.Flags().GetBool("check")
+	if err != nil {
+		return fmt.Errorf("error getting check flag: %v", err)
+	}
+
+	text, err := os.ReadFile(in)
+	if err != nil {
+		return fmt.Errorf("reading template: %v", err)
+	}
+
+	// Everything but the rendered file goes to stderr
+	keyring, err := agent.PromptKeyManager(os.Stderr, os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer keyring.Close()
+
+	cfg, err := utils.CheckConfig("", keyring)
+	if err != nil {
+		return fmt.Errorf("error checking config: %v", err)
+	}
+
+	if check {
+		n, err := CheckTemplate(cfg, in, string(text), keyring)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "All %d references in %s resolve.\n", n, in)
+		return nil
+	}
+
+	var b bytes.Buffer
+	if err := RenderTemplate(cfg, &b, in, string(text), keyring); err != nil {
+		return err
+	}
+
+	if outPath == "" {
+		_, err := os.Stdout.Write(b.Bytes())
+		return err
+	}
+	return WriteSecretFile(outPath, b.Bytes())
+}
+
+// RenderTemplate renders the template 'text' to 'w', filling in secrets from
+// the vault. Nothing is written if a reference can't be resolved.
+func RenderTemplate(
+	cfg *model.Config,
+	w io.Writer,
+	name, text string,
+	key *model.MasterAESKeyManager,
+) error {
+	r, err := newTemplateResolver(cfg, key, false)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	if err := r.execute(&b, name, text); err != nil {
+		return err
+	}
+
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
+// CheckTemplate resolves every reference the template 'text' makes, without
+// rendering it anywhere. It returns how many references there were, or an
+// error listing every one that can't be resolved.
+func CheckTemplate(
+	cfg *model.Config,
+	name, text string,
+	key *model.MasterAESKeyManager,
+) (int, error) {
+	r, err := newTemplateResolver(cfg, key, true)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := r.execute(io.Discard, name, text); err != nil {
+		return 0, err
+	}
+
+	if len(r.errs) > 0 {
+		msgs := make([]string, len(r.errs))
+		for i, err := range r.errs {
+			msgs[i] = "\t" + err.Error()
+		}
+		return r.count, fmt.Errorf(
+			"%d of %d references don't resolve:\n%s",
+			len(r.errs), r.count, strings.Join(msgs, "\n"),
+		)
+	}
+	return r.count, nil
+}
+
+// WriteSecretFile writes 'b' to 'path', readable only by the current user,
+// even if the file already existed with wider permissions
+func WriteSecretFile(path string, b []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := f.Chmod(0o600); err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	return err
+}
+
+// templateResolver provides the functions templates use to look up secrets.
+// When checking, failed lookups are recorded instead of stopping the
+// template.
+type templateResolver struct {
+	entries []model.VaultEntry
+	key     *model.MasterAESKeyManager
+	check   bool
+
+	count int
+	errs  []error
+}
+
+func newTemplateResolver(
+	cfg *model.Config,
+	key *model.MasterAESKeyManager,
+	check bool,
+) (*templateResolver, error) {
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	return &templateResolver{entries: entries, key: key, check: check}, nil
+}
+
+func (r *templateResolver) execute(w io.Writer, name, text string) error {
+	t, err := template.New(name).
+		Option("missingkey=error").
+		Funcs(template.FuncMap{
+			"secret": r.secret,
+			"field":  r.field,
+		}).
+		Parse(text)
+	if err != nil {
+		return fmt.Errorf("parsing template: %v", err)
+	}
+
+	if err := t.Execute(w, nil); err != nil {
+		return fmt.Errorf("rendering template: %v", err)
+	}
+	return nil
+}
+
+// secret is {{ secret "entry" ["field"] }}, see vault.ResolveSecret
+func (r *templateResolver) secret(entry string, field ...string) (string, error) {
+	ref := vault.SecretRef{Entry: entry, Field: vault.DEFAULT_SECRET_FIELD}
+	switch len(field) {
+	case 0:
+	case 1:
+		ref.Field = field[0]
+	default:
+		return "", errors.New("secret takes an entry and at most one field")
+	}
+
+	return r.result(vault.ResolveSecret(r.entries, ref, r.key))
+}
+
+// field is {{ field "entry" "name" }}, for a custom field only
+func (r *templateResolver) field(entry, name string) (string, error) {
+	idx, err := vault.ResolveEntry(r.entries, entry, nil)
+	if err != nil {
+		return r.result("", err)
+	}
+
+	return r.result(vault.EntryCustomField(r.entries[idx], name, r.key))
+}
+
+func (r *templateResolver) result(value string, err error) (string, error) {
+	r.count++
+	if err != nil && r.check {
+		r.errs = append(r.errs, err)
+		return "", nil
+	}
+	return value, err
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/cmd/vault"
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+func TestInjectTemplate(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+	vaultFile, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	vaultFile.Close()
+	cfg := &model.Config{VaultName: testutils.TEST_VAULT_NAME}
+
+	pass, err := crypt.EncryptPassword([]byte("hunter2"), key)
+	assert.NoError(err)
+	assert.NoError(vault.AddToVault("prod-db", model.UserInput{
+		Username: "admin",
+		Password: []byte(pass),
+	}, cfg, 1, key))
+
+	fields, err := crypt.EncryptFields([]model.CustomField{
+		{Name: "api_key", Value: "sk_live_123", Secret: true},
+		{Name: "username", Value: "custom"},
+	}, key)
+	assert.NoError(err)
+	assert.NoError(vault.AddToVault("stripe", model.UserInput{
+		Username: "billing",
+		Password: []byte(pass),
+		Metadata: model.Metadata{Fields: fields},
+	}, cfg, 2, key))
+
+	text := `database:
+  user: {{ secret "prod-db" "username" }}
+  password: {{ secret "prod-db" }}
+stripe_key: {{ field "stripe" "api_key" }}
+stripe_user: {{ field "stripe" "username" }}
+`
+	var b bytes.Buffer
+	assert.NoError(RenderTemplate(cfg, &b, "app.tmpl", text, key))
+	assert.Equal(`database:
+  user: admin
+  password: hunter2
+stripe_key: sk_live_123
+stripe_user: custom
+`, b.String())
+
+	n, err := CheckTemplate(cfg, "app.tmpl", text, key)
+	assert.NoError(err)
+	assert.Equal(4, n)
+
+	// Nothing is written when a reference doesn't resolve
+	bad := `ok: {{ secret "prod-db" }}
+{{ secret "missing" }} {{ field "prod-db" "api_key" }} {{ secret "stripe" "nope" }}
+`
+	b.Reset()
+	assert.Error(RenderTemplate(cfg, &b, "bad.tmpl", bad, key))
+	assert.Empty(b.String())
+
+	// Checking lists every reference that doesn't resolve
+	n, err = CheckTemplate(cfg, "bad.tmpl", bad, key)
+	assert.Equal(4, n)
+	assert.ErrorContains(err, "3 of 4 references don't resolve")
+	assert.ErrorContains(err, "'missing' not found in vault")
+	assert.ErrorContains(err, "'prod-db' has no field named 'api_key'")
+	assert.ErrorContains(err, "no field named 'nope'")
+
+	_, err = CheckTemplate(cfg, "broken.tmpl", `{{ secret "prod-db" `, key)
+	assert.ErrorContains(err, "parsing template")
+}
+
+func TestWriteSecretFile(t *testing.T) {
+	assert := assert.New(t)
+	p := path.Join(t.TempDir(), "app.yaml")
+
+	// An existing file loses its wider permissions
+	assert.NoError(os.WriteFile(p, []byte("old contents that are longer"), 0o644))
+	assert.NoError(WriteSecretFile(p, []byte("new")))
+
+	b, err := os.ReadFile(p)
+	assert.NoError(err)
+	assert.Equal("new", string(b))
+
+	info, err := os.Stat(p)
+	assert.NoError(err)
+	assert.Equal(os.FileMode(0o600), info.Mode().Perm())
+}
@@ -0,0 +1,52 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"go-pass/agent"
+)
+
+// lockCmd represents the lock command
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Wipes the key held by the agent",
+	Long: fmt.Sprintf(`%s
+
+'lock' tells the running agent to wipe the encryption key it holds. After this,
+the commands will prompt for your Master Password again until you 'unlock'.
+
+Ex.
+	$ gopass lock
+	Agent locked.
+`, LongDescriptionText),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := LockCmdHandler(cmd, args); err != nil {
+			fmt.Printf("Error with 'lock' command: %v\n", err)
+			return
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lockCmd)
+}
+
+// LockCmdHandler is the handler function for the 'lock' command
+func LockCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("no arguments needed for 'lock'. see 'help' for more guidance")
+	}
+
+	if _, err := agent.NewClient("").Lock(); err != nil {
+		return err
+	}
+
+	fmt.Println("Agent locked.")
+	return nil
+}
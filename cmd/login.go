@@ -52,7 +52,11 @@ func LoginCmdHandler(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	keyring := model.NewMasterAESKeyManager(string(passB))
+	keyring, err := utils.NewKeyManager(string(passB))
+	if err != nil {
+		return err
+	}
+	defer keyring.Close()
 
 	err = LoginUser("", os.Stdin, keyring, passB)
 	if err != nil {
@@ -71,7 +75,7 @@ func LoginUser(cfgName string, input io.Reader, key *model.MasterAESKeyManager,
 		return errors.New("a file is not found. need to 'init'")
 	}
 
-	cfg, err := crypt.DecryptConfig(cfgFile, key, false)
+	cfg, err := crypt.DecryptConfig(cfgFile, key)
 	if err != nil {
 		return errors.New("decrypting")
 	}
@@ -0,0 +1,189 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package cmd
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/bcrypt"
+
+	"go-pass/cmd/vault"
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+// MIGRATE_BACKUP_DIR is the directory, under the backup directory, that
+// 'migrate' copies the original files to
+const MIGRATE_BACKUP_DIR = "legacy__%v"
+
+// ErrNotLegacy is returned by Migrate when there is nothing to migrate
+var ErrNotLegacy = errors.New("the vault is not in the legacy format")
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate a vault from the original format",
+	Long: fmt.Sprintf(`%s
+
+'migrate' moves a vault from the original format, which is encrypted directly
+with SECRET_PASSWORD_KEY, to the current one, which is encrypted with a key
+kept in your keystore and derived with Argon2id. SECRET_PASSWORD_KEY has to be
+set to read the old files, and is no longer needed afterwards.
+
+The config, the vault and every backup are decrypted and encrypted again in
+memory, so nothing is ever written in plaintext, and every entry keeps its
+timestamps. Before anything is changed, the original config and vault are
+copied, as they are, to a 'legacy__YYYY-MM-DD_HH-MM-SS' directory next to your
+backups. If anything fails, every file is left as it was.
+
+Ex.
+	$ gopass migrate
+	Master Password: <insert master password here>
+	Copied the original files to '~/.local/gopass-backup/legacy__2025-01-02_15-04-05'.
+	Vault migrated to argon2id (time=3, memory=65536KiB, threads=4), random salt
+`, LongDescriptionText),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := MigrateCmdHandler(cmd, args); err != nil {
+			fmt.Printf("Error with 'migrate' command: %v\n", err)
+			return
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+}
+
+// MigrateCmdHandler is the handler function that encapsulates the Migrate
+// logic.
+func MigrateCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("no arguments needed for 'migrate'. see 'help' for more guidance")
+	}
+
+	passB, err := utils.GetPasswordFromUser(true, os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	keyring, err := utils.NewKeyManager(string(passB))
+	if err != nil {
+		return err
+	}
+	defer keyring.Close()
+
+	backupDir, err := Migrate("", model.DefaultKDF(), time.Now(), keyring)
+	if errors.Is(err, ErrNotLegacy) {
+		fmt.Println("Your vault is already in the current format, nothing to do.")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Copied the original files to '%s'.\n", backupDir)
+	fmt.Printf("Vault migrated to %s\n", keyring.KDF())
+	return nil
+}
+
+// Migrate re-encrypts a config, vault and backups in the original hex format
+// under a new random keyring key, stored in key.Store, and a key derived from
+// it with 'params' and a new random salt. The original config and vault are
+// copied to a new directory under the backup directory first, which is
+// returned. 'key' has to hold the Master Password.
+func Migrate(
+	cfgName string,
+	params model.KDFParams,
+	now time.Time,
+	key *model.MasterAESKeyManager,
+) (string, error) {
+	cfgPath := utils.CONFIG_FILE
+	if cfgName != "" {
+		cfgPath = path.Join(utils.CONFIG_PATH, cfgName)
+	}
+
+	cfgContents, err := os.ReadFile(cfgPath)
+	if err != nil {
+		return "", fmt.Errorf("reading config: %v", err)
+	}
+	header, err := crypt.ReadHeader(cfgContents)
+	if err != nil {
+		return "", err
+	}
+	if header.Version != crypt.VERSION_LEGACY_HEX {
+		return "", ErrNotLegacy
+	}
+
+	// SECRET_PASSWORD_KEY is no longer needed once migrated, so it isn't
+	// used as a pepper
+	params, err = params.WithNewSalt()
+	if err != nil {
+		return "", err
+	}
+	params.Pepper = false
+	if err := key.UseKDF(params); err != nil {
+		return "", err
+	}
+
+	cfg, err := utils.CheckConfig(cfgName, key)
+	if err != nil {
+		return "", err
+	}
+
+	err = bcrypt.CompareHashAndPassword(cfg.MasterPassword, []byte(key.Masterpassword))
+	if err != nil {
+		return "", errors.New("incorrect password")
+	}
+
+	vaultF, err := utils.OpenVault(cfg.VaultName)
+	if err != nil {
+		return "", err
+	}
+	vaultPath := vaultF.Name()
+	vaultF.Close()
+
+	vaultContents, err := os.ReadFile(vaultPath)
+	if err != nil {
+		return "", fmt.Errorf("reading vault: %v", err)
+	}
+	header, err = crypt.ReadHeader(vaultContents)
+	if err != nil {
+		return "", err
+	}
+	if header.Version != crypt.VERSION_LEGACY_HEX {
+		return "", errors.New("the config is in the legacy format, but the vault is not")
+	}
+
+	backupDir := path.Join(
+		utils.BACKUP_PATH,
+		fmt.Sprintf(MIGRATE_BACKUP_DIR, now.Format(vault.DATE_FORMAT_STRING)),
+	)
+	if err := os.MkdirAll(backupDir, 0o700); err != nil {
+		return "", fmt.Errorf("creating backup directory: %v", err)
+	}
+	if err := os.WriteFile(path.Join(backupDir, path.Base(cfgPath)), cfgContents, 0o600); err != nil {
+		return "", fmt.Errorf("backing up config: %v", err)
+	}
+	if err := os.WriteFile(path.Join(backupDir, path.Base(vaultPath)), vaultContents, 0o600); err != nil {
+		return "", fmt.Errorf("backing up vault: %v", err)
+	}
+
+	baseKey := make([]byte, model.KEY_SIZE)
+	if _, err := rand.Read(baseKey); err != nil {
+		return "", err
+	}
+
+	if err := utils.ReKeyFiles(cfgName, key, key.Masterpassword, baseKey, nil); err != nil {
+		return "", fmt.Errorf("re-encrypting: %v", err)
+	}
+
+	return backupDir, nil
+}
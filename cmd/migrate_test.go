@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+// legacyEncrypt encrypts b the way the original format did, directly with
+// SECRET_PASSWORD_KEY, and hex encodes it
+func legacyEncrypt(t *testing.T, b []byte) []byte {
+	t.Helper()
+
+	key, err := model.GetSalt()
+	assert.NoError(t, err)
+	block, err := aes.NewCipher(key)
+	assert.NoError(t, err)
+	aesgcm, err := cipher.NewGCM(block)
+	assert.NoError(t, err)
+	nonce, err := model.GenerateNonce()
+	assert.NoError(t, err)
+
+	return []byte(hex.EncodeToString(aesgcm.Seal(nonce, nonce, b, nil)))
+}
+
+// writeLegacyFiles writes a config and a vault in the original format
+func writeLegacyFiles(t *testing.T, entries map[string]string, updatedAt int64) {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword(testutils.TEST_MASTER_PASSWORD, bcrypt.MinCost)
+	assert.NoError(t, err)
+	cfg, err := json.Marshal(model.Config{
+		MasterPassword: hash,
+		VaultName:      testutils.TEST_VAULT_NAME,
+		Timeout:        utils.THIRTY_MINUTES,
+	})
+	assert.NoError(t, err)
+
+	var ve []model.VaultEntry
+	for name, pass := range entries {
+		ve = append(ve, model.VaultEntry{
+			Name:      name,
+			Username:  name,
+			Password:  legacyEncrypt(t, []byte(hex.EncodeToString([]byte(pass)))),
+			UpdatedAt: updatedAt,
+		})
+	}
+	vault, err := json.Marshal(ve)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.MkdirAll(utils.CONFIG_PATH, 0o700))
+	assert.NoError(t, os.MkdirAll(utils.VAULT_PATH, 0o700))
+	assert.NoError(t, os.WriteFile(
+		path.Join(utils.CONFIG_PATH, testutils.TEST_CONFIG_NAME), legacyEncrypt(t, cfg), 0o600,
+	))
+	assert.NoError(t, os.WriteFile(
+		path.Join(utils.VAULT_PATH, testutils.TEST_VAULT_NAME), legacyEncrypt(t, vault), 0o600,
+	))
+}
+
+func TestMigrate(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	t.Setenv(model.SECRET_PASSWORD_KEY, strings.Repeat("k", model.KEY_SIZE))
+	oldBackupPath := utils.BACKUP_PATH
+	utils.BACKUP_PATH = t.TempDir()
+	defer func() { utils.BACKUP_PATH = oldBackupPath }()
+	assert := assert.New(t)
+
+	writeLegacyFiles(t, map[string]string{"test1": "password1", "test2": "password2"}, 1704164645123)
+	originalVault, err := os.ReadFile(path.Join(utils.VAULT_PATH, testutils.TEST_VAULT_NAME))
+	assert.NoError(err)
+
+	// The Master Password is checked before anything is written
+	wrong := model.NewTestMasterAESKeyManager("wrong")
+	_, err = Migrate(testutils.TEST_CONFIG_NAME, model.DefaultKDF(), time.Now(), wrong)
+	assert.Error(err)
+
+	params := model.KDFParams{ID: model.KDFArgon2id, Time: 1, Memory: 2 * 1024, Threads: 1}
+	key := model.NewTestMasterAESKeyManager(string(testutils.TEST_MASTER_PASSWORD))
+	now := time.Date(2025, 1, 2, 15, 4, 5, 0, time.Local)
+	backupDir, err := Migrate(testutils.TEST_CONFIG_NAME, params, now, key)
+	assert.NoError(err)
+	assert.Equal(path.Join(utils.BACKUP_PATH, "legacy__2025-01-02_15-04-05"), backupDir)
+
+	// The original files are backed up as they were
+	backup, err := os.ReadFile(path.Join(backupDir, testutils.TEST_VAULT_NAME))
+	assert.NoError(err)
+	assert.Equal(originalVault, backup)
+	_, err = os.Stat(path.Join(backupDir, testutils.TEST_CONFIG_NAME))
+	assert.NoError(err)
+
+	// Everything can be read without SECRET_PASSWORD_KEY
+	t.Setenv(model.SECRET_PASSWORD_KEY, "")
+	key = model.NewTestMasterAESKeyManager(string(testutils.TEST_MASTER_PASSWORD))
+	defer key.Close()
+
+	cfg, err := utils.CheckConfig(testutils.TEST_CONFIG_NAME, key)
+	assert.NoError(err)
+	assert.Equal(testutils.TEST_VAULT_NAME, cfg.VaultName)
+	assert.Equal(params, key.KDF().Cost())
+	assert.NotEmpty(key.KDF().Salt)
+	assert.False(key.KDF().Pepper)
+
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	assert.NoError(err)
+	f.Close()
+	decrypted, err := crypt.DecryptEntries(entries, key)
+	assert.NoError(err)
+	assert.Len(decrypted, 2)
+	for _, d := range decrypted {
+		assert.Equal(strings.Replace(d.Name, "test", "password", 1), d.Password)
+		assert.Equal(int64(1704164645123), d.UpdatedAt)
+	}
+
+	// There is nothing left to migrate
+	_, err = Migrate(testutils.TEST_CONFIG_NAME, params, now, key)
+	assert.ErrorIs(err, ErrNotLegacy)
+}
@@ -0,0 +1,156 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"go-pass/cmd/nativehost"
+	"go-pass/utils"
+)
+
+// NATIVE_HOST_WRAPPER is the file name of the script browsers run, inside of
+// the gopass config directory
+const NATIVE_HOST_WRAPPER = "native-host.sh"
+
+// nativeHostCmd represents the native-host command
+var nativeHostCmd = &cobra.Command{
+	Use:   "native-host",
+	Short: "Fills in and saves logins for a browser extension",
+	Long: fmt.Sprintf(`%s
+
+'native-host' is run by Chrome, Chromium, Brave, Edge or Firefox for the gopass
+browser extension, and talks to it over native messaging on stdin and stdout.
+Set it up with 'native-host install'; it isn't meant to be run by hand.
+
+The extension can look up the logins for the page it is on, by matching the
+page against the URLs of your entries like 'git-credential' does, and fill one
+in. It can only get the logins that match the page. The protocol has to match
+too, and a URL without one is only for https, so a page over plain http is
+never given an https login. New logins are saved to an
+entry named after the host and tagged '%s', and changed passwords update the
+entry they came from. A new login isn't saved over an entry for another site
+with the same name and username.
+
+The vault has to be unlocked first, with the Master Password the extension
+asks for, unless the agent is unlocked. The key is held until the browser
+closes the extension's connection, or the timeout in your config passes
+without it being used.
+`, LongDescriptionText, nativehost.TAG),
+	// Browsers pass the extension's origin, or the manifest and extension ID
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := NativeHostCmdHandler(cmd, args); err != nil {
+			// stdout is read by the browser
+			fmt.Fprintf(os.Stderr, "Error with 'native-host' command: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// nativeHostInstallCmd represents the native-host install command
+var nativeHostInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Registers 'native-host' with your browsers",
+	Long: fmt.Sprintf(`%s
+
+'install' writes the native messaging manifest that lets the gopass extension
+run 'native-host', into the directory each browser reads them from, and the
+script it points them at into the gopass config directory.
+
+Chromium-based browsers need the extension's ID with '--chrome-extension-id',
+and Firefox with '--firefox-extension-id'. Every browser with a config
+directory, and an ID given for it, is installed for, unless some are picked
+with '--browser': %s.
+
+Ex.
+	$ gopass native-host install --chrome-extension-id abcdefghijklmnopabcdefghijklmnop
+	$ gopass native-host install --browser firefox --firefox-extension-id gopass@example.com
+`, LongDescriptionText, strings.Join(nativehost.BrowserNames(), ", ")),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := NativeHostInstallCmdHandler(cmd, args); err != nil {
+			fmt.Printf("Error with 'native-host install' command: %v\n", err)
+			return
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(nativeHostCmd)
+
+	nativeHostCmd.AddCommand(nativeHostInstallCmd)
+
+	nativeHostInstallCmd.Flags().
+		StringArray("chrome-extension-id", nil, "The ID of the extension in Chromium-based browsers")
+	nativeHostInstallCmd.Flags().
+		StringArray("firefox-extension-id", nil, "The ID of the extension in Firefox")
+	nativeHostInstallCmd.Flags().
+		StringSlice("browser", nil, "The browsers to install for, instead of every one found")
+}
+
+// NativeHostCmdHandler is the handler function that answers the extension
+// until the browser closes the connection.
+func NativeHostCmdHandler(cmd *cobra.Command, args []string) error {
+	// Only messages may go to stdout, which the host only writes to through
+	// Serve
+	return nativehost.NewHost().Serve(os.Stdin, os.Stdout)
+}
+
+// NativeHostInstallCmdHandler is the handler function that writes the wrapper
+// script and the browsers' manifests.
+func NativeHostInstallCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("no arguments needed for 'native-host install'. see 'help' for more guidance")
+	}
+
+	chromeIDs, err := cmd.Flags().GetStringArray("chrome-extension-id")
+	if err != nil {
+		return fmt.Errorf("native-host::getting string array from flag: %v", err)
+	}
+	firefoxIDs, err := cmd.Flags().GetStringArray("firefox-extension-id")
+	if err != nil {
+		return fmt.Errorf("native-host::getting string array from flag: %v", err)
+	}
+	browsers, err := cmd.Flags().GetStringSlice("browser")
+	if err != nil {
+		return fmt.Errorf("native-host::getting string slice from flag: %v", err)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("finding the gopass executable: %v", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return fmt.Errorf("finding the gopass executable: %v", err)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+
+	wrapper := path.Join(utils.CONFIG_PATH, NATIVE_HOST_WRAPPER)
+	if err := nativehost.WriteWrapper(wrapper, exe); err != nil {
+		return fmt.Errorf("writing %s: %v", wrapper, err)
+	}
+
+	written, err := nativehost.InstallManifests(nativehost.InstallOptions{
+		Home:       home,
+		HostPath:   wrapper,
+		ChromeIDs:  chromeIDs,
+		FirefoxIDs: firefoxIDs,
+		Browsers:   browsers,
+	})
+	for _, p := range written {
+		fmt.Printf("Wrote %s\n", p)
+	}
+	return err
+}
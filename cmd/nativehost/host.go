@@ -0,0 +1,375 @@
+package nativehost
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go-pass/agent"
+	"go-pass/cmd/vault"
+	"go-pass/credential"
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+// TAG is the tag of the entries 'save' adds
+const TAG = "native-host"
+
+// errLocked is answered with Locked set, so the extension asks for the Master
+// Password and sends 'unlock'
+var errLocked = errors.New("the vault is locked")
+
+// Host answers the extension's requests. The key from an 'unlock' is held
+// until 'lock', the browser closing the pipe, or the timeout in the config
+// passing without it being used. Without it, the key of an unlocked agent is
+// used.
+type Host struct {
+	unlock   agent.UnlockFunc
+	agentKey func() ([]byte, model.KDFParams, error)
+	config   func(key *model.MasterAESKeyManager) (*model.Config, error)
+	now      func() time.Time
+
+	key       *model.MasterAESKeyManager
+	timeout   time.Duration
+	expiresAt time.Time
+}
+
+// NewHost returns a Host that checks the Master Password against the config
+// and uses the agent at its default socket
+func NewHost() *Host {
+	return &Host{
+		unlock:   agent.VerifyMasterPassword,
+		agentKey: agent.NewClient("").Key,
+		config: func(key *model.MasterAESKeyManager) (*model.Config, error) {
+			return utils.CheckConfig("", key)
+		},
+		now: time.Now,
+	}
+}
+
+// Serve answers the requests read from 'r' on 'w' until 'r' is closed
+func (h *Host) Serve(r io.Reader, w io.Writer) error {
+	defer h.Lock()
+
+	for {
+		b, err := ReadMessage(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		var resp Response
+		if err := json.Unmarshal(b, &req); err != nil {
+			resp = Response{Error: fmt.Sprintf("invalid request: %v", err)}
+		} else {
+			resp = h.Do(req)
+		}
+
+		err = WriteMessage(w, resp)
+		if errors.Is(err, ErrMessageTooLarge) {
+			err = WriteMessage(w, Response{RequestID: req.RequestID, Error: err.Error()})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Lock wipes the key from 'unlock', if there is one
+func (h *Host) Lock() {
+	if h.key != nil {
+		h.key.Close()
+		h.key = nil
+	}
+	h.expiresAt = time.Time{}
+}
+
+// Do answers one request
+func (h *Host) Do(req Request) Response {
+	var resp Response
+	var err error
+
+	switch req.Action {
+	case "status":
+		resp, err = h.status()
+	case "unlock":
+		resp, err = h.unlockVault(req)
+	case "lock":
+		h.Lock()
+		resp.OK = true
+	case "lookup":
+		resp, err = h.lookup(req)
+	case "get":
+		resp, err = h.get(req)
+	case "save":
+		resp, err = h.save(req)
+	default:
+		err = fmt.Errorf("unknown action '%s'", req.Action)
+	}
+
+	if err != nil {
+		resp = Response{Error: err.Error(), Locked: errors.Is(err, errLocked)}
+	}
+	resp.RequestID = req.RequestID
+	return resp
+}
+
+func (h *Host) status() (Response, error) {
+	key, err := h.keyManager()
+	if errors.Is(err, errLocked) {
+		return Response{OK: true, Locked: true}, nil
+	}
+	if err != nil {
+		return Response{}, err
+	}
+	h.release(key)
+
+	return Response{OK: true}, nil
+}
+
+func (h *Host) unlockVault(req Request) (Response, error) {
+	if req.Password == "" {
+		return Response{}, errors.New("no password")
+	}
+
+	key, kdf, timeout, err := h.unlock([]byte(req.Password))
+	if err != nil {
+		return Response{}, err
+	}
+	if timeout <= 0 {
+		timeout = time.Duration(utils.THIRTY_MINUTES) * time.Millisecond
+	}
+
+	h.Lock()
+	h.key = model.NewMasterAESKeyManagerFromKey(key, kdf)
+	h.timeout = timeout
+	h.expiresAt = h.now().Add(timeout)
+
+	return Response{OK: true}, nil
+}
+
+// lookup lists the logins for the origin, without their passwords
+func (h *Host) lookup(req Request) (Response, error) {
+	q, err := originQuery(req.Origin)
+	if err != nil {
+		return Response{}, err
+	}
+
+	key, err := h.keyManager()
+	if err != nil {
+		return Response{}, err
+	}
+	defer h.release(key)
+
+	entries, err := h.readEntries(key)
+	if err != nil {
+		return Response{}, err
+	}
+
+	matches := credential.MatchOrigin(entries, q)
+	logins := make([]Login, len(matches))
+	for i, idx := range matches {
+		e := entries[idx]
+		logins[i] = Login{ID: e.ID, Name: e.Name, Username: e.Username}
+	}
+	return Response{OK: true, Logins: logins}, nil
+}
+
+// get returns the login of an entry, which has to match the origin, so a page
+// only ever gets its own logins
+func (h *Host) get(req Request) (Response, error) {
+	q, err := originQuery(req.Origin)
+	if err != nil {
+		return Response{}, err
+	}
+	if req.ID == "" {
+		return Response{}, errors.New("no id")
+	}
+
+	key, err := h.keyManager()
+	if err != nil {
+		return Response{}, err
+	}
+	defer h.release(key)
+
+	entries, err := h.readEntries(key)
+	if err != nil {
+		return Response{}, err
+	}
+
+	for _, idx := range credential.MatchOrigin(entries, q) {
+		e := entries[idx]
+		if e.ID != req.ID {
+			continue
+		}
+
+		password, err := crypt.DecryptPassword(e.Password, key)
+		if err != nil {
+			return Response{}, fmt.Errorf("decrypting password: %v", err)
+		}
+
+		login := Login{ID: e.ID, Name: e.Name, Username: e.Username, Password: password}
+		if len(e.TOTP) > 0 {
+			login.OTP, _, err = vault.EntryOTP(e, h.now(), key)
+			if err != nil {
+				return Response{}, err
+			}
+		}
+		return Response{OK: true, Login: &login}, nil
+	}
+
+	return Response{}, fmt.Errorf("no login with id '%s' for %s", req.ID, q)
+}
+
+// save saves a login the user entered on a page. The best match with the same
+// username has its password updated if it changed, and if there is no match a
+// new entry tagged TAG is added.
+func (h *Host) save(req Request) (Response, error) {
+	q, err := originQuery(req.Origin)
+	if err != nil {
+		return Response{}, err
+	}
+	if req.Username == "" || req.Password == "" {
+		return Response{}, errors.New("a username and password are needed")
+	}
+	q.Username = req.Username
+
+	key, err := h.keyManager()
+	if err != nil {
+		return Response{}, err
+	}
+	defer h.release(key)
+
+	cfg, err := h.config(key)
+	if err != nil {
+		return Response{}, fmt.Errorf("checking config: %v", err)
+	}
+
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	if err != nil {
+		return Response{}, err
+	}
+	defer f.Close()
+
+	encryptedPass, err := crypt.EncryptPassword([]byte(req.Password), key)
+	if err != nil {
+		return Response{}, err
+	}
+
+	t := h.now().UnixMilli()
+	saved := "added"
+	if matches := credential.MatchOrigin(entries, q); len(matches) > 0 {
+		e := &entries[matches[0]]
+		password, err := crypt.DecryptPassword(e.Password, key)
+		if err != nil {
+			return Response{}, fmt.Errorf("decrypting password: %v", err)
+		}
+		if password == req.Password {
+			return Response{OK: true, Saved: "unchanged"}, nil
+		}
+
+		e.Password = []byte(encryptedPass)
+		e.UpdatedAt = t
+		saved = "updated"
+	} else {
+		id, err := model.NewEntryID()
+		if err != nil {
+			return Response{}, err
+		}
+
+		name := req.Name
+		if name == "" {
+			name = q.Host
+		}
+		// The entry is for another site, so its password isn't replaced
+		if vault.FindDuplicate(entries, name, req.Username) >= 0 {
+			return Response{}, fmt.Errorf("'%s': %w", name, vault.ErrDuplicateEntry)
+		}
+
+		// Only the origin is kept, so the entry matches every page of the site
+		origin := credential.Query{Protocol: q.Protocol, Host: q.Host}
+		entries = append(entries, model.VaultEntry{
+			ID:       id,
+			Name:     name,
+			Username: req.Username,
+			Password: []byte(encryptedPass),
+			Metadata: model.Metadata{
+				URLs: []string{origin.String()},
+				Tags: []string{TAG},
+			},
+			CreatedAt: t,
+			UpdatedAt: t,
+		})
+	}
+
+	ciphertext, err := crypt.EncryptVault(entries, key)
+	if err != nil {
+		return Response{}, fmt.Errorf("encrypting vault: %v", err)
+	}
+	if err := utils.WriteToFile(f.Name(), model.FileVault, ciphertext); err != nil {
+		return Response{}, err
+	}
+	return Response{OK: true, Saved: saved}, nil
+}
+
+// keyManager returns the key from 'unlock' if it hasn't expired, pushing the
+// expiry back, or else the key of an unlocked agent. It returns errLocked
+// without either. The key is to be given back with release.
+func (h *Host) keyManager() (*model.MasterAESKeyManager, error) {
+	if h.key != nil {
+		if h.now().Before(h.expiresAt) {
+			h.expiresAt = h.now().Add(h.timeout)
+			return h.key, nil
+		}
+		h.Lock()
+	}
+
+	key, kdf, err := h.agentKey()
+	if err != nil {
+		return nil, errLocked
+	}
+	return model.NewMasterAESKeyManagerFromKey(key, kdf), nil
+}
+
+// release closes a key from keyManager, unless it is the one 'unlock' holds
+func (h *Host) release(key *model.MasterAESKeyManager) {
+	if key != h.key {
+		key.Close()
+	}
+}
+
+func (h *Host) readEntries(key *model.MasterAESKeyManager) ([]model.VaultEntry, error) {
+	cfg, err := h.config(key)
+	if err != nil {
+		return nil, fmt.Errorf("checking config: %v", err)
+	}
+
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return entries, nil
+}
+
+// originQuery parses the page's origin. Only web pages have logins.
+func originQuery(origin string) (credential.Query, error) {
+	if origin == "" {
+		return credential.Query{}, errors.New("no origin")
+	}
+
+	q, err := credential.ParseURL(origin)
+	if err != nil {
+		return credential.Query{}, err
+	}
+	if q.Protocol != "http" && q.Protocol != "https" {
+		return credential.Query{}, fmt.Errorf("'%s' is not a web page", origin)
+	}
+	return q, nil
+}
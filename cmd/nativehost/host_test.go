@@ -0,0 +1,232 @@
+package nativehost
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/agent"
+	"go-pass/cmd/vault"
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+// newTestHost returns a Host over a new test vault holding a GitHub login,
+// that unlocks with the test Master Password and has no agent. The clock is
+// moved with the returned pointer.
+func newTestHost(t *testing.T) (*Host, *time.Time, *model.MasterAESKeyManager) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	t.Cleanup(func() { testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD)) })
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(t, err)
+	vaultFile, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(t, err)
+	vaultFile.Close()
+	cfg := &model.Config{VaultName: testutils.TEST_VAULT_NAME}
+
+	pass, err := crypt.EncryptPassword([]byte("hunter2"), key)
+	assert.NoError(t, err)
+	assert.NoError(t, vault.AddToVault("GitHub", model.UserInput{
+		Username: "me",
+		Password: []byte(pass),
+		Metadata: model.Metadata{URLs: []string{"https://github.com"}},
+	}, cfg, 1, key))
+
+	now := time.UnixMilli(10_000)
+	h := &Host{
+		unlock: func(password []byte) ([]byte, model.KDFParams, time.Duration, error) {
+			if !bytes.Equal(password, testutils.TEST_MASTER_PASSWORD) {
+				return nil, model.KDFParams{}, 0, errors.New("incorrect master password")
+			}
+			cached, err := key.GetEncryptionKey()
+			if err != nil {
+				return nil, model.KDFParams{}, 0, err
+			}
+			return bytes.Clone(cached), key.KDF(), time.Minute, nil
+		},
+		agentKey: func() ([]byte, model.KDFParams, error) {
+			return nil, model.KDFParams{}, agent.ErrLocked
+		},
+		config: func(*model.MasterAESKeyManager) (*model.Config, error) {
+			return cfg, nil
+		},
+		now: func() time.Time { return now },
+	}
+	return h, &now, key
+}
+
+func TestHostLocked(t *testing.T) {
+	assert := assert.New(t)
+	h, now, _ := newTestHost(t)
+
+	assert.Equal(Response{OK: true, Locked: true, RequestID: "1"}, h.Do(Request{RequestID: "1", Action: "status"}))
+
+	resp := h.Do(Request{Action: "lookup", Origin: "https://github.com"})
+	assert.False(resp.OK)
+	assert.True(resp.Locked)
+
+	resp = h.Do(Request{Action: "unlock", Password: "wrong"})
+	assert.False(resp.OK)
+	assert.Contains(resp.Error, "incorrect")
+
+	assert.True(h.Do(Request{Action: "unlock", Password: string(testutils.TEST_MASTER_PASSWORD)}).OK)
+	assert.Equal(Response{OK: true}, h.Do(Request{Action: "status"}))
+
+	// Using the key pushes the expiry back
+	*now = now.Add(50 * time.Second)
+	assert.True(h.Do(Request{Action: "lookup", Origin: "https://github.com"}).OK)
+	*now = now.Add(50 * time.Second)
+	assert.False(h.Do(Request{Action: "status"}).Locked)
+
+	*now = now.Add(time.Minute)
+	assert.True(h.Do(Request{Action: "status"}).Locked)
+
+	assert.True(h.Do(Request{Action: "unlock", Password: string(testutils.TEST_MASTER_PASSWORD)}).OK)
+	assert.True(h.Do(Request{Action: "lock"}).OK)
+	assert.True(h.Do(Request{Action: "status"}).Locked)
+}
+
+func TestHostAgentKey(t *testing.T) {
+	h, _, key := newTestHost(t)
+
+	// An unlocked agent's key is used without an 'unlock'
+	h.agentKey = func() ([]byte, model.KDFParams, error) {
+		cached, err := key.GetEncryptionKey()
+		return bytes.Clone(cached), key.KDF(), err
+	}
+
+	assert.Equal(t, Response{OK: true}, h.Do(Request{Action: "status"}))
+	assert.Len(t, h.Do(Request{Action: "lookup", Origin: "https://github.com"}).Logins, 1)
+}
+
+func TestHostLookupAndGet(t *testing.T) {
+	assert := assert.New(t)
+	h, _, _ := newTestHost(t)
+	assert.True(h.Do(Request{Action: "unlock", Password: string(testutils.TEST_MASTER_PASSWORD)}).OK)
+
+	resp := h.Do(Request{Action: "lookup", Origin: "https://github.com/login"})
+	assert.True(resp.OK)
+	assert.Len(resp.Logins, 1)
+	login := resp.Logins[0]
+	assert.Equal("GitHub", login.Name)
+	assert.Equal("me", login.Username)
+	assert.Empty(login.Password)
+
+	assert.Empty(h.Do(Request{Action: "lookup", Origin: "https://gitlab.com"}).Logins)
+
+	resp = h.Do(Request{Action: "get", Origin: "https://github.com/login", ID: login.ID})
+	assert.True(resp.OK)
+	assert.Equal("hunter2", resp.Login.Password)
+
+	// Another page can't get the login, even with its ID
+	resp = h.Do(Request{Action: "get", Origin: "https://evil.example.com", ID: login.ID})
+	assert.False(resp.OK)
+	assert.Nil(resp.Login)
+
+	// Only web pages have logins
+	resp = h.Do(Request{Action: "lookup", Origin: "ftp://github.com"})
+	assert.False(resp.OK)
+	assert.Contains(resp.Error, "not a web page")
+
+	resp = h.Do(Request{Action: "fill"})
+	assert.Equal("unknown action 'fill'", resp.Error)
+}
+
+func TestHostSave(t *testing.T) {
+	assert := assert.New(t)
+	h, _, _ := newTestHost(t)
+	assert.True(h.Do(Request{Action: "unlock", Password: string(testutils.TEST_MASTER_PASSWORD)}).OK)
+
+	save := func(origin, username, password string) string {
+		resp := h.Do(Request{Action: "save", Origin: origin, Username: username, Password: password})
+		assert.True(resp.OK, resp.Error)
+		return resp.Saved
+	}
+
+	assert.Equal("unchanged", save("https://github.com/login", "me", "hunter2"))
+	assert.Equal("updated", save("https://github.com/login", "me", "hunter3"))
+	assert.Equal("added", save("https://gitlab.com/users/sign_in", "me", "pw"))
+	// A different username on the same site is a new login
+	assert.Equal("added", save("https://github.com/login", "bot", "token"))
+
+	cfg, err := h.config(nil)
+	assert.NoError(err)
+	key, err := h.keyManager()
+	assert.NoError(err)
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	assert.NoError(err)
+	f.Close()
+
+	assert.Len(entries, 3)
+	password, err := crypt.DecryptPassword(entries[0].Password, key)
+	assert.NoError(err)
+	assert.Equal("hunter3", password)
+
+	assert.Equal("gitlab.com", entries[1].Name)
+	assert.Equal([]string{"https://gitlab.com"}, entries[1].URLs)
+	assert.Equal([]string{TAG}, entries[1].Tags)
+	assert.Equal("bot", entries[2].Username)
+
+	resp := h.Do(Request{Action: "save", Origin: "https://github.com"})
+	assert.False(resp.OK)
+
+	// An entry of the same name and username on another site isn't replaced
+	resp = h.Do(Request{Action: "save", Origin: "https://github.io", Name: "GitHub", Username: "me", Password: "pw"})
+	assert.False(resp.OK)
+	assert.Contains(resp.Error, "already exists")
+
+	// A page over plain http never gets or replaces the https login
+	resp = h.Do(Request{Action: "lookup", Origin: "http://github.com"})
+	assert.True(resp.OK, resp.Error)
+	assert.Empty(resp.Logins)
+	assert.Equal("added", save("http://github.com/login", "me", "pw"))
+	f, entries, err = utils.ReadVault(cfg.VaultName, key)
+	assert.NoError(err)
+	f.Close()
+	password, err = crypt.DecryptPassword(entries[0].Password, key)
+	assert.NoError(err)
+	assert.Equal("hunter3", password)
+}
+
+func TestHostServe(t *testing.T) {
+	assert := assert.New(t)
+	h, _, _ := newTestHost(t)
+
+	var in bytes.Buffer
+	assert.NoError(WriteMessage(&in, Request{
+		RequestID: "a",
+		Action:    "unlock",
+		Password:  string(testutils.TEST_MASTER_PASSWORD),
+	}))
+	assert.NoError(WriteMessage(&in, Request{RequestID: "b", Action: "lookup", Origin: "https://github.com"}))
+	assert.NoError(WriteMessage(&in, "not a request"))
+
+	var out bytes.Buffer
+	assert.NoError(h.Serve(&in, &out))
+
+	var resps []Response
+	for out.Len() > 0 {
+		msg, err := ReadMessage(&out)
+		assert.NoError(err)
+		var resp Response
+		assert.NoError(json.Unmarshal(msg, &resp))
+		resps = append(resps, resp)
+	}
+
+	assert.Len(resps, 3)
+	assert.Equal(Response{RequestID: "a", OK: true}, resps[0])
+	assert.Equal("b", resps[1].RequestID)
+	assert.Len(resps[1].Logins, 1)
+	assert.True(strings.HasPrefix(resps[2].Error, "invalid request"))
+
+	// The key is wiped once the browser is gone
+	assert.Nil(h.key)
+}
@@ -0,0 +1,214 @@
+package nativehost
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// HOST_NAME is the name the extension connects to. Browsers look for the
+// manifest in '<HOST_NAME>.json'.
+const HOST_NAME = "com.github.dkagan07.gopass"
+
+// Browser is a browser the manifest can be installed for
+type Browser struct {
+	// Name is what '--browser' selects it by
+	Name string
+	// ConfigDir is the browser's config directory, relative to the home
+	// directory
+	ConfigDir string
+	// ManifestDir is where the browser looks for manifests, relative to the
+	// home directory
+	ManifestDir string
+	// Firefox is true for browsers that allow extensions by ID, rather than
+	// by origin
+	Firefox bool
+}
+
+// Browsers are the browsers the manifest can be installed for on Linux
+var Browsers = []Browser{
+	{
+		Name:        "chrome",
+		ConfigDir:   ".config/google-chrome",
+		ManifestDir: ".config/google-chrome/NativeMessagingHosts",
+	},
+	{
+		Name:        "chromium",
+		ConfigDir:   ".config/chromium",
+		ManifestDir: ".config/chromium/NativeMessagingHosts",
+	},
+	{
+		Name:        "brave",
+		ConfigDir:   ".config/BraveSoftware/Brave-Browser",
+		ManifestDir: ".config/BraveSoftware/Brave-Browser/NativeMessagingHosts",
+	},
+	{
+		Name:        "edge",
+		ConfigDir:   ".config/microsoft-edge",
+		ManifestDir: ".config/microsoft-edge/NativeMessagingHosts",
+	},
+	{
+		Name:        "firefox",
+		ConfigDir:   ".mozilla",
+		ManifestDir: ".mozilla/native-messaging-hosts",
+		Firefox:     true,
+	},
+}
+
+// BrowserNames returns the names of Browsers
+func BrowserNames() []string {
+	names := make([]string, len(Browsers))
+	for i, b := range Browsers {
+		names[i] = b.Name
+	}
+	return names
+}
+
+// Manifest is the native messaging host manifest
+type Manifest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Path        string `json:"path"`
+	Type        string `json:"type"`
+	// AllowedOrigins are the Chrome extensions allowed to connect, as
+	// 'chrome-extension://<id>/'
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+	// AllowedExtensions are the Firefox extensions allowed to connect, by ID
+	AllowedExtensions []string `json:"allowed_extensions,omitempty"`
+}
+
+// InstallOptions are what InstallManifests installs
+type InstallOptions struct {
+	// Home is the home directory the browsers' directories are in
+	Home string
+	// HostPath is the program the browsers run, which has to be an absolute
+	// path
+	HostPath string
+	// ChromeIDs are the IDs of the extension in the Chrome Web Store, or of an
+	// unpacked copy
+	ChromeIDs []string
+	// FirefoxIDs are the IDs of the extension for Firefox
+	FirefoxIDs []string
+	// Browsers are the names of the browsers to install for. If it is empty,
+	// every browser with a config directory, and an extension ID for its
+	// kind, is installed for.
+	Browsers []string
+}
+
+// InstallManifests writes the manifest for each browser, and returns the paths
+// it wrote
+func InstallManifests(opts InstallOptions) ([]string, error) {
+	if !path.IsAbs(opts.HostPath) {
+		return nil, fmt.Errorf("the host path must be absolute, not '%s'", opts.HostPath)
+	}
+
+	browsers, err := selectBrowsers(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, b := range browsers {
+		m := Manifest{
+			Name:        HOST_NAME,
+			Description: "gopass, a local password manager",
+			Path:        opts.HostPath,
+			Type:        "stdio",
+		}
+		if b.Firefox {
+			if len(opts.FirefoxIDs) == 0 {
+				return written, fmt.Errorf("a Firefox extension ID is needed for %s", b.Name)
+			}
+			m.AllowedExtensions = opts.FirefoxIDs
+		} else {
+			if len(opts.ChromeIDs) == 0 {
+				return written, fmt.Errorf("a Chrome extension ID is needed for %s", b.Name)
+			}
+			for _, id := range opts.ChromeIDs {
+				m.AllowedOrigins = append(m.AllowedOrigins, "chrome-extension://"+id+"/")
+			}
+		}
+
+		p, err := writeManifest(path.Join(opts.Home, b.ManifestDir), m)
+		if err != nil {
+			return written, fmt.Errorf("installing for %s: %v", b.Name, err)
+		}
+		written = append(written, p)
+	}
+	return written, nil
+}
+
+// selectBrowsers returns the browsers named, or if none are, every browser
+// with a config directory that there is an extension ID for
+func selectBrowsers(opts InstallOptions) ([]Browser, error) {
+	var browsers []Browser
+	if len(opts.Browsers) == 0 {
+		for _, b := range Browsers {
+			if b.Firefox && len(opts.FirefoxIDs) == 0 || !b.Firefox && len(opts.ChromeIDs) == 0 {
+				continue
+			}
+			if _, err := os.Stat(path.Join(opts.Home, b.ConfigDir)); err == nil {
+				browsers = append(browsers, b)
+			}
+		}
+		if len(browsers) == 0 {
+			return nil, errors.New("no browsers found for the extension IDs given, pick them with '--browser'")
+		}
+		return browsers, nil
+	}
+
+	for _, name := range opts.Browsers {
+		i := slices.IndexFunc(Browsers, func(b Browser) bool {
+			return strings.EqualFold(b.Name, name)
+		})
+		if i < 0 {
+			return nil, fmt.Errorf(
+				"unknown browser '%s', pick from: %s",
+				name, strings.Join(BrowserNames(), ", "),
+			)
+		}
+		browsers = append(browsers, Browsers[i])
+	}
+	return browsers, nil
+}
+
+func writeManifest(dir string, m Manifest) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	p := path.Join(dir, HOST_NAME+".json")
+	if err := os.WriteFile(p, append(b, '\n'), 0o644); err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+// WriteWrapper writes the script browsers run as the host, to 'p'. Browsers
+// run the host with arguments of their own, so the script runs 'exe
+// native-host' with them.
+func WriteWrapper(p, exe string) error {
+	if err := os.MkdirAll(path.Dir(p), 0o700); err != nil {
+		return err
+	}
+
+	script := fmt.Sprintf("#!/bin/sh\nexec %s native-host \"$@\"\n", shellQuote(exe))
+	if err := os.WriteFile(p, []byte(script), 0o700); err != nil {
+		return err
+	}
+	return os.Chmod(p, 0o700)
+}
+
+// shellQuote quotes 's' for sh, in single quotes
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,87 @@
+package nativehost
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readManifest(t *testing.T, p string) Manifest {
+	t.Helper()
+	b, err := os.ReadFile(p)
+	assert.NoError(t, err)
+
+	var m Manifest
+	assert.NoError(t, json.Unmarshal(b, &m))
+	return m
+}
+
+func TestInstallManifests(t *testing.T) {
+	assert := assert.New(t)
+	home := t.TempDir()
+
+	// Only browsers that are there, and have an ID, are found
+	assert.NoError(os.MkdirAll(path.Join(home, ".config", "chromium"), 0o700))
+	assert.NoError(os.MkdirAll(path.Join(home, ".mozilla"), 0o700))
+
+	written, err := InstallManifests(InstallOptions{
+		Home:      home,
+		HostPath:  "/opt/gopass/native-host.sh",
+		ChromeIDs: []string{"abc"},
+	})
+	assert.NoError(err)
+	assert.Equal(
+		[]string{path.Join(home, ".config/chromium/NativeMessagingHosts", HOST_NAME+".json")},
+		written,
+	)
+	assert.Equal(Manifest{
+		Name:           HOST_NAME,
+		Description:    "gopass, a local password manager",
+		Path:           "/opt/gopass/native-host.sh",
+		Type:           "stdio",
+		AllowedOrigins: []string{"chrome-extension://abc/"},
+	}, readManifest(t, written[0]))
+
+	// Browsers picked by name are installed for, even if they aren't there
+	written, err = InstallManifests(InstallOptions{
+		Home:       home,
+		HostPath:   "/opt/gopass/native-host.sh",
+		FirefoxIDs: []string{"gopass@example.com"},
+		Browsers:   []string{"Firefox"},
+	})
+	assert.NoError(err)
+	assert.Len(written, 1)
+	m := readManifest(t, path.Join(home, ".mozilla/native-messaging-hosts", HOST_NAME+".json"))
+	assert.Equal([]string{"gopass@example.com"}, m.AllowedExtensions)
+	assert.Empty(m.AllowedOrigins)
+
+	_, err = InstallManifests(InstallOptions{Home: home, HostPath: "/x", Browsers: []string{"chrome"}})
+	assert.ErrorContains(err, "Chrome extension ID is needed")
+
+	_, err = InstallManifests(InstallOptions{Home: home, HostPath: "/x", Browsers: []string{"netscape"}})
+	assert.ErrorContains(err, "unknown browser 'netscape'")
+
+	_, err = InstallManifests(InstallOptions{Home: t.TempDir(), HostPath: "/x", ChromeIDs: []string{"abc"}})
+	assert.ErrorContains(err, "no browsers found")
+
+	_, err = InstallManifests(InstallOptions{Home: home, HostPath: "gopass"})
+	assert.ErrorContains(err, "must be absolute")
+}
+
+func TestWriteWrapper(t *testing.T) {
+	assert := assert.New(t)
+	p := path.Join(t.TempDir(), "gopass", "native-host.sh")
+
+	assert.NoError(WriteWrapper(p, "/home/me/it's/gopass"))
+
+	b, err := os.ReadFile(p)
+	assert.NoError(err)
+	assert.Equal("#!/bin/sh\nexec '/home/me/it'\\''s/gopass' native-host \"$@\"\n", string(b))
+
+	info, err := os.Stat(p)
+	assert.NoError(err)
+	assert.Equal(os.FileMode(0o700), info.Mode().Perm())
+}
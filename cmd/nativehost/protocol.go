@@ -0,0 +1,102 @@
+// Package nativehost answers a browser extension over the native messaging
+// protocol of Chrome and Firefox, so it can fill in and save logins from the
+// vault.
+package nativehost
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MAX_MESSAGE_BYTES is the largest message a host may send the browser. The
+// same limit is used for the messages it reads, as requests are small.
+const MAX_MESSAGE_BYTES = 1024 * 1024
+
+// ErrMessageTooLarge is returned for a message over MAX_MESSAGE_BYTES
+var ErrMessageTooLarge = errors.New("message is too large")
+
+// ReadMessage reads one message, a JSON value prefixed by its length as a
+// 32-bit integer in native byte order. It returns io.EOF once the browser
+// closes the pipe between messages.
+func ReadMessage(r io.Reader) ([]byte, error) {
+	var size uint32
+	if err := binary.Read(r, binary.NativeEndian, &size); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("reading message length: %v", err)
+	}
+	if size > MAX_MESSAGE_BYTES {
+		return nil, ErrMessageTooLarge
+	}
+
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, fmt.Errorf("reading message: %v", err)
+	}
+	return b, nil
+}
+
+// WriteMessage writes 'v' as one message, see ReadMessage
+func WriteMessage(w io.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding message: %v", err)
+	}
+	if len(b) > MAX_MESSAGE_BYTES {
+		return ErrMessageTooLarge
+	}
+
+	msg := binary.NativeEndian.AppendUint32(make([]byte, 0, 4+len(b)), uint32(len(b)))
+	if _, err := w.Write(append(msg, b...)); err != nil {
+		return fmt.Errorf("writing message: %v", err)
+	}
+	return nil
+}
+
+// Request is a message from the extension
+type Request struct {
+	// RequestID is sent back in the response, so the extension can match them
+	RequestID string `json:"request_id,omitempty"`
+	// Action is one of 'status', 'unlock', 'lock', 'lookup', 'get' and 'save'
+	Action string `json:"action"`
+	// Origin is the page the login is for, like 'https://github.com/login'
+	Origin string `json:"origin,omitempty"`
+	// ID is the entry to 'get'
+	ID string `json:"id,omitempty"`
+	// Name is the name of the entry 'save' adds. It defaults to the host.
+	Name string `json:"name,omitempty"`
+	// Username is the username to 'save'
+	Username string `json:"username,omitempty"`
+	// Password is the Master Password for 'unlock', or the password to 'save'
+	Password string `json:"password,omitempty"`
+}
+
+// Response is the answer to a Request
+type Response struct {
+	RequestID string `json:"request_id,omitempty"`
+	OK        bool   `json:"ok"`
+	Error     string `json:"error,omitempty"`
+	// Locked is true when the vault has to be unlocked first
+	Locked bool `json:"locked,omitempty"`
+	// Logins are the matches of a 'lookup', best match first, without their
+	// passwords
+	Logins []Login `json:"logins,omitempty"`
+	// Login is the result of a 'get'
+	Login *Login `json:"login,omitempty"`
+	// Saved is what 'save' did: 'added', 'updated' or 'unchanged'
+	Saved string `json:"saved,omitempty"`
+}
+
+// Login is an entry's login
+type Login struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+	// OTP is the current TOTP code, if the entry has a TOTP secret
+	OTP string `json:"otp,omitempty"`
+}
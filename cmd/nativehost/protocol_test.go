@@ -0,0 +1,53 @@
+package nativehost
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	var b bytes.Buffer
+	assert.NoError(WriteMessage(&b, Request{Action: "lookup", Origin: "https://github.com"}))
+	assert.NoError(WriteMessage(&b, Response{OK: true}))
+
+	msg, err := ReadMessage(&b)
+	assert.NoError(err)
+	assert.JSONEq(`{"action":"lookup","origin":"https://github.com"}`, string(msg))
+
+	msg, err = ReadMessage(&b)
+	assert.NoError(err)
+	assert.Equal(`{"ok":true}`, string(msg))
+
+	_, err = ReadMessage(&b)
+	assert.ErrorIs(err, io.EOF)
+}
+
+func TestWriteMessage(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, WriteMessage(&b, Response{OK: true}))
+	assert.Equal(t, append(binary.NativeEndian.AppendUint32(nil, 11), `{"ok":true}`...), b.Bytes())
+}
+
+func TestReadMessageErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	large := binary.NativeEndian.AppendUint32(nil, MAX_MESSAGE_BYTES+1)
+	_, err := ReadMessage(bytes.NewReader(large))
+	assert.ErrorIs(err, ErrMessageTooLarge)
+
+	// A message cut short isn't the end of the stream
+	short := append(binary.NativeEndian.AppendUint32(nil, 10), `{"a"`...)
+	_, err = ReadMessage(bytes.NewReader(short))
+	assert.Error(err)
+	assert.NotErrorIs(err, io.EOF)
+
+	err = WriteMessage(io.Discard, strings.Repeat("a", MAX_MESSAGE_BYTES))
+	assert.ErrorIs(err, ErrMessageTooLarge)
+}
@@ -4,11 +4,16 @@ Copyright © 2025 DKagan07
 package cmd
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"go-pass/cmd/tui"
+	"go-pass/utils"
 )
 
 var LongDescriptionText = `GoPass is a CLI tool that help stores your passwords with security in mind.
@@ -20,6 +25,17 @@ var rootCmd = &cobra.Command{
 	Use:   "gopass",
 	Short: "Stores and encrypts all of your sensitive passwords",
 	Long:  LongDescriptionText,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		password, err := MasterPasswordFromFlags(cmd, os.Stdin)
+		if err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		if password != nil {
+			utils.SetMasterPassword(password)
+		}
+		return nil
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if isOther {
 			tui.TviewRun()
@@ -39,10 +55,52 @@ func Execute() {
 	rootCmd.Flags().
 		BoolVarP(&isOther, "isOther", "o", false, "Use other framework instead of the default")
 
+	// Docker runs its credential helper as DOCKER_CREDENTIAL_BINARY
+	if filepath.Base(os.Args[0]) == DOCKER_CREDENTIAL_BINARY {
+		rootCmd.SetArgs(append([]string{dockerCredentialCmd.Name()}, os.Args[1:]...))
+	}
+
 	err := rootCmd.Execute()
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
-func init() {}
+func init() {
+	addPasswordFlags(rootCmd.PersistentFlags())
+	rootCmd.MarkFlagsMutuallyExclusive("password-stdin", "password-fd")
+}
+
+// addPasswordFlags adds the flags MasterPasswordFromFlags reads
+func addPasswordFlags(flags *pflag.FlagSet) {
+	flags.Bool("password-stdin", false, "Read the Master Password from the first line of stdin")
+	flags.Int("password-fd", -1, "Read the Master Password from this file descriptor")
+}
+
+// MasterPasswordFromFlags reads the Master Password for scripts, from the
+// first line of 'stdin' with '--password-stdin', from the file descriptor
+// given with '--password-fd', or from the file named by GOPASS_PASSWORD_FILE,
+// in that order. It returns nil if none of them is given, so the Master
+// Password is prompted for.
+func MasterPasswordFromFlags(cmd *cobra.Command, stdin io.Reader) ([]byte, error) {
+	fromStdin, err := cmd.Flags().GetBool("password-stdin")
+	if err != nil {
+		return nil, fmt.Errorf("getting password-stdin flag: %v", err)
+	}
+	fd, err := cmd.Flags().GetInt("password-fd")
+	if err != nil {
+		return nil, fmt.Errorf("getting password-fd flag: %v", err)
+	}
+
+	switch {
+	case fromStdin:
+		return utils.ReadPassword(stdin)
+	case cmd.Flags().Changed("password-fd"):
+		return utils.ReadPasswordFD(fd)
+	}
+
+	if p := os.Getenv(utils.PASSWORD_FILE_ENV); p != "" {
+		return utils.ReadPasswordFile(p)
+	}
+	return nil, nil
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/utils"
+)
+
+func TestMasterPasswordFromFlags(t *testing.T) {
+	assert := assert.New(t)
+
+	newCmd := func(args ...string) *cobra.Command {
+		c := &cobra.Command{Use: "test"}
+		addPasswordFlags(c.Flags())
+		assert.NoError(c.Flags().Parse(args))
+		return c
+	}
+
+	file := path.Join(t.TempDir(), "password")
+	assert.NoError(os.WriteFile(file, []byte("from-file\n"), 0o600))
+
+	t.Setenv(utils.PASSWORD_FILE_ENV, "")
+	pass, err := MasterPasswordFromFlags(newCmd(), strings.NewReader("ignored\n"))
+	assert.NoError(err)
+	assert.Nil(pass)
+
+	pass, err = MasterPasswordFromFlags(newCmd("--password-stdin"), strings.NewReader("from-stdin\n"))
+	assert.NoError(err)
+	assert.Equal([]byte("from-stdin"), pass)
+
+	t.Setenv(utils.PASSWORD_FILE_ENV, file)
+	pass, err = MasterPasswordFromFlags(newCmd(), strings.NewReader(""))
+	assert.NoError(err)
+	assert.Equal([]byte("from-file"), pass)
+
+	// The flags come before the file
+	pass, err = MasterPasswordFromFlags(newCmd("--password-stdin"), strings.NewReader("from-stdin\n"))
+	assert.NoError(err)
+	assert.Equal([]byte("from-stdin"), pass)
+
+	_, err = MasterPasswordFromFlags(newCmd("--password-fd", "-1"), strings.NewReader(""))
+	assert.Error(err)
+}
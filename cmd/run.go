@@ -0,0 +1,213 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"go-pass/agent"
+	"go-pass/cmd/vault"
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+// envNameRegex matches the names 'run' and 'env' accept for environment
+// variables
+var envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run --env VAR=entry:field... -- <command> [args...]",
+	Short: "Runs a command with secrets from your vault in its environment",
+	Long: fmt.Sprintf(`%s
+
+'run' runs a command with environment variables set to secrets from your vault,
+so they never have to be exported in your shell. Each '--env' is written
+'VAR=entry:field'. The entry is found like 'vault get' finds it, by name or ID,
+and the field is one of %s, or the name of a custom field.
+Without a field, the password is used.
+
+The variables are only set for the command. Signals gopass receives are passed
+on to it, and gopass exits with its exit code.
+
+Ex.
+	$ gopass run --env DB_PASS=prod-db:password --env API_KEY=stripe:api_key -- ./server
+`, LongDescriptionText, strings.Join(vault.SecretFieldNames(), ", ")),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := RunCmdHandler(cmd, args); err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				os.Exit(ExitCode(exitErr))
+			}
+
+			// stdout belongs to the command
+			fmt.Fprintf(os.Stderr, "Error with 'run' command: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	runCmd.Flags().StringArrayP("env", "e", nil, "Set VAR to a field of an entry, as VAR=entry:field")
+	runCmd.MarkFlagRequired("env")
+	// Flags after the command are the command's
+	runCmd.Flags().SetInterspersed(false)
+}
+
+// RunCmdHandler is the handler function that resolves the secrets and runs
+// the command with them.
+func RunCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("a command to run is needed. see 'help' for correct usage")
+	}
+
+	env, err := envFromFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	return RunWithEnv(args, env)
+}
+
+// envFromFlag resolves the secrets given with '--env' to 'VAR=value' pairs.
+// The Master Password is prompted for on stderr, as stdout belongs to the
+// exports or the command. The key manager is closed before returning, so it
+// isn't held while the command runs.
+func envFromFlag(cmd *cobra.Command) ([]string, error) {
+	specs, err := cmd.Flags().GetStringArray("env")
+	if err != nil {
+		return nil, fmt.Errorf("error getting env flag: %v", err)
+	}
+
+	secrets, err := ParseEnvSecrets(specs)
+	if err != nil {
+		return nil, err
+	}
+
+	keyring, err := agent.PromptKeyManager(os.Stderr, os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	defer keyring.Close()
+
+	cfg, err := utils.CheckConfig("", keyring)
+	if err != nil {
+		return nil, fmt.Errorf("error checking config: %v", err)
+	}
+
+	return ResolveEnvSecrets(cfg, secrets, keyring)
+}
+
+// EnvSecret is an environment variable to set to a secret from the vault
+type EnvSecret struct {
+	Name string
+	Ref  vault.SecretRef
+}
+
+// ParseEnvSecrets parses the 'VAR=entry:field' pairs given with '--env'
+func ParseEnvSecrets(specs []string) ([]EnvSecret, error) {
+	if len(specs) == 0 {
+		return nil, errors.New("no variables given with '--env'")
+	}
+
+	secrets := make([]EnvSecret, len(specs))
+	for i, spec := range specs {
+		name, ref, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("'%s' must be written VAR=entry:field", spec)
+		}
+		if !envNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("'%s' is not a valid variable name", name)
+		}
+
+		r, err := vault.ParseSecretRef(ref)
+		if err != nil {
+			return nil, err
+		}
+		secrets[i] = EnvSecret{Name: name, Ref: r}
+	}
+	return secrets, nil
+}
+
+// ResolveEnvSecrets returns every secret as a 'VAR=value' pair, in order. It
+// fails if any of them can't be resolved.
+func ResolveEnvSecrets(
+	cfg *model.Config,
+	secrets []EnvSecret,
+	key *model.MasterAESKeyManager,
+) ([]string, error) {
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	env := make([]string, len(secrets))
+	for i, s := range secrets {
+		value, err := vault.ResolveSecret(entries, s.Ref, key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", s.Name, err)
+		}
+		env[i] = s.Name + "=" + value
+	}
+	return env, nil
+}
+
+// RunWithEnv runs 'command' with 'env' added to the environment, passing on
+// the signals gopass receives until it exits. A command that fails returns an
+// *exec.ExitError.
+func RunWithEnv(command []string, env []string) error {
+	c := exec.Command(command[0], command[1:]...)
+	c.Env = append(os.Environ(), env...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(
+		sigs,
+		os.Interrupt, syscall.SIGTERM, syscall.SIGHUP,
+		syscall.SIGQUIT, syscall.SIGUSR1, syscall.SIGUSR2,
+	)
+	defer signal.Stop(sigs)
+
+	if err := c.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				c.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return c.Wait()
+}
+
+// ExitCode returns the exit code a shell would give for the command, which is
+// 128 plus the signal if it was killed by one
+func ExitCode(err *exec.ExitError) int {
+	if status, ok := err.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return err.ExitCode()
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/cmd/vault"
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+func TestParseEnvSecrets(t *testing.T) {
+	assert := assert.New(t)
+
+	secrets, err := ParseEnvSecrets([]string{"DB_PASS=prod-db:password", "API_KEY=stripe"})
+	assert.NoError(err)
+	assert.Equal([]EnvSecret{
+		{Name: "DB_PASS", Ref: vault.SecretRef{Entry: "prod-db", Field: "password"}},
+		{Name: "API_KEY", Ref: vault.SecretRef{Entry: "stripe", Field: "password"}},
+	}, secrets)
+
+	for _, specs := range [][]string{
+		nil,
+		{"DB_PASS"},
+		{"1DB=prod-db"},
+		{"DB-PASS=prod-db"},
+		{"DB_PASS="},
+	} {
+		_, err := ParseEnvSecrets(specs)
+		assert.Error(err, specs)
+	}
+}
+
+func TestResolveEnvSecrets(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+	vaultFile, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	vaultFile.Close()
+	cfg := &model.Config{VaultName: testutils.TEST_VAULT_NAME}
+
+	pass, err := crypt.EncryptPassword([]byte("hunter2"), key)
+	assert.NoError(err)
+	assert.NoError(vault.AddToVault("prod-db", model.UserInput{
+		Username: "admin",
+		Password: []byte(pass),
+	}, cfg, 1, key))
+
+	secrets, err := ParseEnvSecrets([]string{"DB_USER=prod-db:username", "DB_PASS=prod-db"})
+	assert.NoError(err)
+	env, err := ResolveEnvSecrets(cfg, secrets, key)
+	assert.NoError(err)
+	assert.Equal([]string{"DB_USER=admin", "DB_PASS=hunter2"}, env)
+
+	secrets, err = ParseEnvSecrets([]string{"DB_PASS=prod-db", "API_KEY=stripe"})
+	assert.NoError(err)
+	_, err = ResolveEnvSecrets(cfg, secrets, key)
+	assert.ErrorContains(err, "API_KEY")
+}
+
+func TestRunWithEnv(t *testing.T) {
+	assert := assert.New(t)
+
+	// The variables reach the command
+	assert.NoError(RunWithEnv(
+		[]string{"sh", "-c", `test "$DB_PASS" = "it's a secret"`},
+		[]string{"DB_PASS=it's a secret"},
+	))
+
+	// Its exit code is kept
+	err := RunWithEnv([]string{"sh", "-c", "exit 3"}, nil)
+	var exitErr *exec.ExitError
+	assert.True(errors.As(err, &exitErr))
+	assert.Equal(3, ExitCode(exitErr))
+
+	err = RunWithEnv([]string{"sh", "-c", "kill -TERM $$"}, nil)
+	assert.True(errors.As(err, &exitErr))
+	assert.Equal(143, ExitCode(exitErr))
+
+	assert.Error(RunWithEnv([]string{"/nonexistent/command"}, nil))
+}
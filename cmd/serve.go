@@ -0,0 +1,137 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"go-pass/agent"
+	"go-pass/cmd/api"
+	"go-pass/utils"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serves a JSON API over your vault, for scripts and other tools",
+	Long: fmt.Sprintf(`%s
+
+'serve' serves a small JSON API over your vault on a unix socket that only your
+user can access, so scripts don't have to scrape 'vault get'. The path can be
+set with '--socket' or the GOPASS_API_SOCK environment variable.
+
+Every request needs the token that is made when 'serve' starts, sent as
+'Authorization: Bearer <token>'. It is printed, and written next to the socket
+in '<socket>.token'. The token only reads the vault, unless '--write' is given.
+
+	GET    /v1/entries                        list entries, without secrets
+	GET    /v1/entries/{entry}                get an entry, by name or ID
+	GET    /v1/entries/{entry}/fields/{field} get one field of an entry
+	POST   /v1/entries                        add an entry (write)
+	PATCH  /v1/entries/{entry}                update an entry (write)
+	DELETE /v1/entries/{entry}                delete an entry (write)
+	POST   /v1/generate                       generate a password, and add
+	                                          it with "add" (write)
+
+Ex.
+	$ gopass serve --socket ~/.gopass.sock
+	$ curl --unix-socket ~/.gopass.sock \
+		-H "Authorization: Bearer $(cat ~/.gopass.sock.token)" \
+		http://gopass/v1/entries/github/fields/password
+`, LongDescriptionText),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ServeCmdHandler(cmd, args); err != nil {
+			fmt.Printf("Error with 'serve' command: %v\n", err)
+			return
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().String("socket", "", "The unix socket to listen on")
+	serveCmd.Flags().Bool("write", false, "Let the token add, update and delete entries")
+}
+
+// ServeCmdHandler is the handler function that serves the API until the
+// process is interrupted.
+func ServeCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("no arguments needed for 'serve'. see 'help' for more guidance")
+	}
+
+	socketPath, err := cmd.Flags().GetString("socket")
+	if err != nil {
+		return fmt.Errorf("serve::getting string from flag: %v", err)
+	}
+	if socketPath == "" {
+		socketPath = api.SocketPath()
+	}
+
+	write, err := cmd.Flags().GetBool("write")
+	if err != nil {
+		return fmt.Errorf("serve::getting bool from flag: %v", err)
+	}
+	scope := api.ScopeRead
+	if write {
+		scope = api.ScopeWrite
+	}
+
+	keyring, err := agent.GetKeyManager(os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer keyring.Close()
+
+	cfg, err := utils.CheckConfig("", keyring)
+	if err != nil {
+		return fmt.Errorf("error checking config: %v", err)
+	}
+
+	token, err := api.NewToken(scope)
+	if err != nil {
+		return err
+	}
+
+	l, err := utils.ListenUnix(socketPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(socketPath)
+
+	tokenPath := socketPath + ".token"
+	if err := WriteSecretFile(tokenPath, []byte(token.Value+"\n")); err != nil {
+		l.Close()
+		return fmt.Errorf("writing token: %v", err)
+	}
+	defer os.Remove(tokenPath)
+
+	server := &http.Server{Handler: api.NewServer(cfg, keyring, token)}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	go func() {
+		<-sigs
+		server.Shutdown(context.Background())
+	}()
+
+	fmt.Printf("Serving the API on %s\n", socketPath)
+	fmt.Printf("Token (%s): %s\n", token.Scope, token.Value)
+	fmt.Printf("The token is also in %s\n", tokenPath)
+
+	if err := server.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
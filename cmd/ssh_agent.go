@@ -0,0 +1,209 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+
+	"go-pass/agent"
+	"go-pass/cmd/vault"
+	"go-pass/model"
+	"go-pass/sshagent"
+	"go-pass/utils"
+)
+
+// sshAgentCmd represents the ssh-agent command
+var sshAgentCmd = &cobra.Command{
+	Use:   "ssh-agent",
+	Short: "Serves the SSH keys in your vault over the ssh-agent protocol",
+	Long: fmt.Sprintf(`%s
+
+'ssh-agent' serves the SSH keys in your vault, added with 'vault add --ssh-key',
+to ssh, git and anything else that talks to an ssh-agent. The keys are only
+decrypted while it runs, so no private key needs to sit in ~/.ssh.
+
+It listens on a unix socket that only your user can access, and prints the
+SSH_AUTH_SOCK line to point ssh at it. The path can be overridden with the
+GOPASS_SSH_AGENT_SOCK environment variable.
+
+The keys are removed, and the agent stops, once the timeout in your config has
+passed (see 'config update_timeout'), or after '--lifetime' instead. With
+'--confirm', every use of a key has to be allowed first, in a dialog shown by
+the program in SSH_ASKPASS, or 'ssh-askpass'.
+
+Clients can remove keys with 'ssh-add -d' or 'ssh-add -D', and lock the agent
+with 'ssh-add -x', but keys are never added through it.
+
+Ex.
+	$ gopass ssh-agent --confirm
+	Master Password: <insert master password here>
+	Serving 2 SSH keys for 30m0s.
+	SSH_AUTH_SOCK=/run/user/1000/gopass/ssh-agent.sock; export SSH_AUTH_SOCK;
+
+	$ SSH_AUTH_SOCK=/run/user/1000/gopass/ssh-agent.sock ssh-add -l
+`, LongDescriptionText),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := SSHAgentCmdHandler(cmd, args); err != nil {
+			fmt.Printf("Error with 'ssh-agent' command: %v\n", err)
+			return
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sshAgentCmd)
+
+	sshAgentCmd.Flags().
+		Duration("lifetime", 0, "How long to serve the keys for, instead of the timeout in your config")
+	sshAgentCmd.Flags().Bool("confirm", false, "Ask before every use of a key")
+}
+
+// SSHAgentCmdHandler is the handler function that decrypts the SSH keys in the
+// vault and serves them until the lifetime is up or the process is
+// interrupted.
+func SSHAgentCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("no arguments needed for 'ssh-agent'. see 'help' for more guidance")
+	}
+
+	lifetime, err := cmd.Flags().GetDuration("lifetime")
+	if err != nil {
+		return fmt.Errorf("ssh-agent::getting duration from flag: %v", err)
+	}
+	if lifetime < 0 {
+		return errors.New("'--lifetime' cannot be negative")
+	}
+
+	confirmFlag, err := cmd.Flags().GetBool("confirm")
+	if err != nil {
+		return fmt.Errorf("ssh-agent::getting bool from flag: %v", err)
+	}
+
+	var confirm sshagent.ConfirmFunc
+	if confirmFlag {
+		confirm, err = AskpassConfirm()
+		if err != nil {
+			return err
+		}
+	}
+
+	keyring, err := agent.GetKeyManager(os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer keyring.Close()
+
+	cfg, err := utils.CheckConfig("", keyring)
+	if err != nil {
+		return fmt.Errorf("error checking config: %v", err)
+	}
+
+	if lifetime == 0 {
+		lifetime = time.Duration(cfg.Timeout) * time.Millisecond
+	}
+
+	keys, err := LoadSSHKeys(cfg, keyring)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return errors.New("no SSH keys in the vault, add one with 'vault add --ssh-key'")
+	}
+
+	return RunSSHAgent(sshagent.SocketPath(), keys, lifetime, confirm)
+}
+
+// LoadSSHKeys decrypts every SSH key in the vault
+func LoadSSHKeys(cfg *model.Config, key *model.MasterAESKeyManager) ([]sshagent.Key, error) {
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	var keys []sshagent.Key
+	for _, e := range entries {
+		if len(e.SSHKey) == 0 {
+			continue
+		}
+
+		k, err := vault.DecryptSSHKey(e, key)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// RunSSHAgent listens on socketPath and serves 'keys' until the lifetime is
+// up or the process is interrupted. The keys are removed on the way out.
+func RunSSHAgent(
+	socketPath string,
+	keys []sshagent.Key,
+	lifetime time.Duration,
+	confirm sshagent.ConfirmFunc,
+) error {
+	a, err := sshagent.NewAgent(keys, lifetime, confirm)
+	if err != nil {
+		return err
+	}
+	defer a.Stop()
+
+	l, err := utils.ListenUnix(socketPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(socketPath)
+
+	signal.Ignore(syscall.SIGHUP)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	go func() {
+		select {
+		case <-sigs:
+			a.Stop()
+		case <-a.Done():
+		}
+	}()
+
+	fmt.Printf("Serving %d SSH keys for %s.\n", len(keys), lifetime)
+	fmt.Printf("SSH_AUTH_SOCK=%s; export SSH_AUTH_SOCK;\n", socketPath)
+	return a.Serve(l)
+}
+
+// AskpassConfirm returns a ConfirmFunc that asks with the program in
+// SSH_ASKPASS, or 'ssh-askpass', the same way OpenSSH's agent does for keys
+// added with 'ssh-add -c'.
+func AskpassConfirm() (sshagent.ConfirmFunc, error) {
+	program := os.Getenv("SSH_ASKPASS")
+	if program == "" {
+		program = "ssh-askpass"
+	}
+
+	program, err := exec.LookPath(program)
+	if err != nil {
+		return nil, fmt.Errorf("'--confirm' needs SSH_ASKPASS or 'ssh-askpass': %v", err)
+	}
+
+	return func(comment string, key ssh.PublicKey) bool {
+		prompt := fmt.Sprintf(
+			"Allow use of key %s?\nKey fingerprint %s.",
+			comment, ssh.FingerprintSHA256(key),
+		)
+		c := exec.Command(program, prompt)
+		c.Env = append(os.Environ(), "SSH_ASKPASS_PROMPT=confirm")
+		return c.Run() == nil
+	}, nil
+}
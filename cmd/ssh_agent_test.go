@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+
+	"go-pass/cmd/vault"
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+func TestLoadSSHKeys(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+	vaultFile, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	vaultFile.Close()
+	cfg := &model.Config{VaultName: testutils.TEST_VAULT_NAME}
+
+	keys, err := LoadSSHKeys(cfg, key)
+	assert.NoError(err)
+	assert.Empty(keys)
+
+	pass, err := crypt.EncryptPassword([]byte("pass"), key)
+	assert.NoError(err)
+	assert.NoError(vault.AddToVault("github", model.UserInput{
+		Username: "me",
+		Password: []byte(pass),
+	}, cfg, 1, key))
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(err)
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	assert.NoError(err)
+	sshKey, _, err := vault.EncryptSSHKey("github-ssh", pem.EncodeToMemory(block), nil, key)
+	assert.NoError(err)
+	assert.NoError(vault.AddToVault("github-ssh", model.UserInput{
+		Username: "git",
+		Password: []byte(pass),
+		SSHKey:   sshKey,
+	}, cfg, 2, key))
+
+	keys, err = LoadSSHKeys(cfg, key)
+	assert.NoError(err)
+	assert.Len(keys, 1)
+	assert.Equal("github-ssh", keys[0].Comment)
+	assert.Equal(priv, keys[0].PrivateKey)
+}
+
+func TestAskpassConfirm(t *testing.T) {
+	assert := assert.New(t)
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(err)
+	signer, err := ssh.NewSignerFromKey(priv)
+	assert.NoError(err)
+
+	t.Setenv("SSH_ASKPASS", "true")
+	confirm, err := AskpassConfirm()
+	assert.NoError(err)
+	assert.True(confirm("github-ssh", signer.PublicKey()))
+
+	t.Setenv("SSH_ASKPASS", "false")
+	confirm, err = AskpassConfirm()
+	assert.NoError(err)
+	assert.False(confirm("github-ssh", signer.PublicKey()))
+
+	t.Setenv("SSH_ASKPASS", "/nonexistent/askpass")
+	_, err = AskpassConfirm()
+	assert.Error(err)
+}
@@ -71,4 +71,9 @@ func TviewRun() {
 		modal := app.ExitErrorModal(err.Error())
 		app.App.SetRoot(modal, true)
 	}
+
+	// The key is shared by the whole session, so it is only wiped on exit
+	if app.Keyring != nil {
+		app.Keyring.Close()
+	}
 }
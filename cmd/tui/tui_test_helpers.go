@@ -33,11 +33,11 @@ func NewTestApp(t *testing.T) (*App, func()) {
 	assert.NoError(t, err)
 
 	// Load config
-	cfg, err := crypt.DecryptConfig(cfgFile, keyManager, false)
+	cfg, err := crypt.DecryptConfig(cfgFile, keyManager)
 	assert.NoError(t, err)
 
 	// Load vault
-	vault, err := crypt.DecryptVault(vaultFile, keyManager, false)
+	vault, err := crypt.DecryptVault(vaultFile, keyManager)
 	assert.NoError(t, err)
 
 	// Create App instance
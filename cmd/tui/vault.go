@@ -152,6 +152,13 @@ func FilterVaultEntries(vault []model.VaultEntry, searchText string) []model.Vau
 // findFilteredVaultIndex finds the actual vault entry after search
 func (a *App) findFilteredVaultIndex(entry model.VaultEntry) int {
 	for i, v := range a.Vault {
+		if entry.ID != "" {
+			if v.ID == entry.ID {
+				return i
+			}
+			continue
+		}
+
 		if v.UpdatedAt == entry.UpdatedAt {
 			return i
 		}
@@ -1,12 +1,14 @@
 package tui
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 
+	"go-pass/cmd/vault"
 	"go-pass/crypt"
 	"go-pass/model"
 )
@@ -14,11 +16,14 @@ import (
 // ModalAddVault returns a modal in a Flex primitive in which shows the
 // information needed to create a new model.VaultEntry
 func (a *App) ModalAddVault() *tview.Flex {
+	var flex *tview.Flex
+
 	inputForm := tview.NewForm().
 		AddInputField("Name", "", 0, nil, nil).
 		AddInputField("Username", "", 0, nil, nil).
 		AddPasswordField("Password", "", 0, '*', nil).
 		AddInputField("Notes", "", 0, nil, nil)
+	AddMetadataFields(inputForm, model.Metadata{})
 
 	inputForm.AddButton("Save", func() {
 		formName := inputForm.GetFormItem(0).(*tview.InputField).GetText()
@@ -34,12 +39,28 @@ func (a *App) ModalAddVault() *tview.Flex {
 			return
 		}
 
-		a.AddToVault(formName, formNotes, formUsername, formPassword)
+		metadata, err := a.GetMetadataFields(inputForm)
+		if err != nil {
+			eModal := a.ErrorModal(err.Error(), a.Root)
+			a.App.SetRoot(eModal, true)
+			return
+		}
+
+		add := func() {
+			a.AddToVaultWithMetadata(formName, formNotes, formUsername, formPassword, metadata)
+
+			a.PopulateVaultList()
+			a.RefreshRoot()
+			a.App.SetRoot(a.Root, true)
+			a.App.SetFocus(a.VaultList)
+		}
+
+		if vault.FindDuplicate(a.Vault, formName, formUsername) >= 0 {
+			a.App.SetRoot(a.ConfirmDuplicateModal(formName, formUsername, add, flex), true)
+			return
+		}
 
-		a.PopulateVaultList()
-		a.RefreshRoot()
-		a.App.SetRoot(a.Root, true)
-		a.App.SetFocus(a.VaultList)
+		add()
 	})
 	inputForm.SetTitle(" Add Vault ")
 	inputForm.SetBorder(true)
@@ -55,7 +76,7 @@ func (a *App) ModalAddVault() *tview.Flex {
 		return event
 	})
 
-	flex := tview.NewFlex().
+	flex = tview.NewFlex().
 		SetDirection(tview.FlexRow).
 		AddItem(nil, 0, 1, false).
 		AddItem(inputForm, 0, 1, true).
@@ -68,19 +89,38 @@ func (a *App) ModalAddVault() *tview.Flex {
 // adding it to the vault. AddToVault also calls SaveVault() to save the new
 // entry to disk
 func (a *App) AddToVault(name, notes, username, password string) {
+	a.AddToVaultWithMetadata(name, notes, username, password, model.Metadata{})
+}
+
+// AddToVaultWithMetadata is AddToVault for an entry with metadata. The values
+// of secret fields in 'metadata' must already be encrypted.
+func (a *App) AddToVaultWithMetadata(
+	name, notes, username, password string,
+	metadata model.Metadata,
+) {
 	passwordBytes := []byte(password)
 	encryptedPassword, _ := crypt.EncryptPassword(passwordBytes, a.Keyring)
 	now := time.Now().UnixMilli()
 
-	vault := model.VaultEntry{
+	id, err := model.NewEntryID()
+	if err != nil {
+		modal := a.ErrorModal(err.Error(), a.Root)
+		a.App.SetRoot(modal, true)
+		return
+	}
+
+	entry := model.VaultEntry{
+		ID:        id,
 		Name:      name,
 		Username:  username,
 		Notes:     notes,
 		Password:  []byte(encryptedPassword),
+		Metadata:  metadata,
+		CreatedAt: now,
 		UpdatedAt: now,
 	}
 
-	a.Vault = append(a.Vault, vault)
+	a.Vault = append(a.Vault, entry)
 	a.SaveVault()
 }
 
@@ -100,3 +140,32 @@ func ValidateAddInput(name, username, password string) error {
 
 	return nil
 }
+
+// ConfirmDuplicateModal asks whether to add an entry with the same name and
+// username as an existing one. 'add' is called if the user confirms, otherwise
+// they are returned to 'form'.
+func (a *App) ConfirmDuplicateModal(
+	name, username string,
+	add func(),
+	form tview.Primitive,
+) *tview.Modal {
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf(
+			"An entry named '%s' with username '%s' already exists. Add another one?",
+			name, username,
+		)).
+		AddButtons([]string{"Add", "Cancel"}).
+		SetBackgroundColor(tcell.ColorBlack).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel == "Add" {
+				add()
+				return
+			}
+			a.App.SetRoot(form, true)
+		})
+
+	modal.SetTitle(" Duplicate Entry ")
+	modal.SetBorder(true)
+	modal.SetBorderStyle(tcell.StyleDefault.Background(tcell.ColorBlack))
+	return modal
+}
@@ -3,6 +3,7 @@ package tui
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/atotto/clipboard"
 	"github.com/gdamore/tcell/v2"
@@ -15,7 +16,7 @@ import (
 // ModalVaultInfoByVault returns the Modal primitive to display the specific
 // vault entry information
 func (a *App) ModalVaultInfoByVault(ve model.VaultEntry) *tview.Modal {
-	decryptedPassword, err := crypt.DecryptPassword(ve.Password, a.Keyring, false)
+	decryptedPassword, err := crypt.DecryptPassword(ve.Password, a.Keyring)
 	if err != nil {
 		modal := a.ErrorModal(err.Error(), a.Root)
 		a.App.SetRoot(modal, true)
@@ -26,15 +27,27 @@ func (a *App) ModalVaultInfoByVault(ve model.VaultEntry) *tview.Modal {
 	Password: %s
 	Notes: %s
 	`, ve.Name, ve.Username, decryptedPassword, ve.Notes)
+
+	metadataText, err := a.MetadataText(ve.Metadata)
+	if err != nil {
+		modal := a.ErrorModal(err.Error(), a.Root)
+		a.App.SetRoot(modal, true)
+	}
+	render := func() string {
+		return text + a.OTPText(ve, time.Now()) + metadataText
+	}
+
 	modal := tview.NewModal().
 		AddButtons([]string{"OK", "Copy"}).
 		SetBackgroundColor(tcell.ColorBlack)
 
 	modal.SetTitle(" Vault Info ")
-	modal.SetText(text)
+	modal.SetText(render())
 	modal.SetBorder(true)
 	modal.SetBorderStyle(tcell.StyleDefault.Background(tcell.ColorBlack))
+	stopOTP := a.RefreshModalText(modal, render, len(ve.TOTP) > 0)
 	modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+		stopOTP()
 		if strings.EqualFold(buttonLabel, "Copy") {
 			err := clipboard.WriteAll(decryptedPassword)
 			if err != nil {
@@ -52,7 +65,7 @@ func (a *App) ModalVaultInfoByVault(ve model.VaultEntry) *tview.Modal {
 // vault to display the information
 func (a *App) ModalVaultInfoByIdx(idx int) *tview.Modal {
 	entry := a.Vault[idx]
-	decryptedPassword, err := crypt.DecryptPassword(entry.Password, a.Keyring, false)
+	decryptedPassword, err := crypt.DecryptPassword(entry.Password, a.Keyring)
 	if err != nil {
 		modal := a.ErrorModal(err.Error(), a.Root)
 		a.App.SetRoot(modal, true)
@@ -63,15 +76,27 @@ func (a *App) ModalVaultInfoByIdx(idx int) *tview.Modal {
 	Password: %s
 	Notes: %s
 	`, entry.Name, entry.Username, decryptedPassword, entry.Notes)
+
+	metadataText, err := a.MetadataText(entry.Metadata)
+	if err != nil {
+		modal := a.ErrorModal(err.Error(), a.Root)
+		a.App.SetRoot(modal, true)
+	}
+	render := func() string {
+		return text + a.OTPText(entry, time.Now()) + metadataText
+	}
+
 	modal := tview.NewModal().
 		AddButtons([]string{"OK", "Copy"}).
 		SetBackgroundColor(tcell.ColorBlack)
 
 	modal.SetTitle(" Vault Info ")
-	modal.SetText(text)
+	modal.SetText(render())
 	modal.SetBorder(true)
 	modal.SetBorderStyle(tcell.StyleDefault.Background(tcell.ColorBlack))
+	stopOTP := a.RefreshModalText(modal, render, len(entry.TOTP) > 0)
 	modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+		stopOTP()
 		if strings.EqualFold(buttonLabel, "Copy") {
 			err := clipboard.WriteAll(decryptedPassword)
 			if err != nil {
@@ -90,7 +115,7 @@ func (a *App) ModalVaultInfoByIdx(idx int) *tview.Modal {
 // password
 func (a *App) CopyDirectlyToClipboard(idx int) *tview.Modal {
 	entry := a.Vault[idx]
-	decryptedPassword, err := crypt.DecryptPassword(entry.Password, a.Keyring, false)
+	decryptedPassword, err := crypt.DecryptPassword(entry.Password, a.Keyring)
 	if err != nil {
 		modal := a.ErrorModal(err.Error(), a.Root)
 		a.App.SetRoot(modal, true)
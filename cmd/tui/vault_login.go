@@ -29,7 +29,15 @@ func (a *App) LoginPage() *tview.Flex {
 	loginForm.AddButton("Login", func() {
 		masterPassword := loginForm.GetFormItem(0).(*tview.InputField).GetText()
 
-		keyring := model.NewMasterAESKeyManager(masterPassword)
+		// A previous failed attempt may have left a manager behind
+		if a.Keyring != nil {
+			a.Keyring.Close()
+		}
+		keyring, err := utils.NewKeyManager(masterPassword)
+		if err != nil {
+			a.App.SetRoot(a.ExitErrorModal(err.Error()), true)
+			return
+		}
 		a.Keyring = keyring
 
 		cfgFile, ok, err := utils.OpenConfig("")
@@ -38,7 +46,7 @@ func (a *App) LoginPage() *tview.Flex {
 			a.App.SetRoot(modal, true)
 		}
 
-		cfg, err := crypt.DecryptConfig(cfgFile, a.Keyring, false)
+		cfg, err := crypt.DecryptConfig(cfgFile, a.Keyring)
 		if err != nil {
 			errMsg := err.Error()
 			if strings.Contains(errMsg, "decrypting contents") {
@@ -59,19 +67,14 @@ func (a *App) LoginPage() *tview.Flex {
 
 		a.Cfg = cfg
 
-		vaultF, err := utils.OpenVault(cfg.VaultName)
+		vaultF, vault, err := utils.ReadVault(cfg.VaultName, a.Keyring)
 		if err != nil {
-			modal := a.ErrorModal(err.Error(), loginPage)
+			modal := a.ExitErrorModal(err.Error())
 			a.App.SetRoot(modal, true)
 			return
 		}
 
 		a.VaultFile = vaultF
-		vault, err := crypt.DecryptVault(vaultF, a.Keyring, false)
-		if err != nil {
-			modal := a.ExitErrorModal(err.Error())
-			a.App.SetRoot(modal, true)
-		}
 
 		a.Vault = vault
 		a.FilteredVault = vault
@@ -0,0 +1,124 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivo/tview"
+
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+// The form labels for the metadata of an entry. URLs and tags are comma
+// separated, and custom fields are 'name=value' pairs separated by semicolons.
+const (
+	urlsLabel         = "URLs"
+	tagsLabel         = "Tags"
+	folderLabel       = "Folder"
+	fieldsLabel       = "Fields"
+	secretFieldsLabel = "Secret Fields"
+)
+
+// AddMetadataFields adds the metadata inputs to the form, filled in with 'm'.
+// The values of secret fields in 'm' must already be decrypted.
+func AddMetadataFields(form *tview.Form, m model.Metadata) *tview.Form {
+	var fields, secretFields []string
+	for _, f := range m.Fields {
+		if f.Secret {
+			secretFields = append(secretFields, f.Name+"="+f.Value)
+		} else {
+			fields = append(fields, f.Name+"="+f.Value)
+		}
+	}
+
+	return form.
+		AddInputField(urlsLabel, strings.Join(m.URLs, ", "), 0, nil, nil).
+		AddInputField(tagsLabel, strings.Join(m.Tags, ", "), 0, nil, nil).
+		AddInputField(folderLabel, m.Folder, 0, nil, nil).
+		AddInputField(fieldsLabel, strings.Join(fields, "; "), 0, nil, nil).
+		AddPasswordField(secretFieldsLabel, strings.Join(secretFields, "; "), 0, '*', nil)
+}
+
+// GetMetadataFields reads the metadata inputs added by AddMetadataFields and
+// parses them with ParseMetadata
+func (a *App) GetMetadataFields(form *tview.Form) (model.Metadata, error) {
+	text := func(label string) string {
+		return form.GetFormItemByLabel(label).(*tview.InputField).GetText()
+	}
+
+	return a.ParseMetadata(
+		text(urlsLabel),
+		text(tagsLabel),
+		text(folderLabel),
+		text(fieldsLabel),
+		text(secretFieldsLabel),
+	)
+}
+
+// ParseMetadata parses the metadata as typed into the add and update forms, and
+// encrypts the values of the secret fields
+func (a *App) ParseMetadata(urls, tags, folder, fields, secretFields string) (model.Metadata, error) {
+	m := model.Metadata{
+		URLs:   utils.SplitList(urls, ","),
+		Tags:   utils.SplitList(tags, ","),
+		Folder: strings.TrimSpace(folder),
+	}
+
+	for _, f := range utils.SplitList(fields, ";") {
+		name, value, err := utils.ParseField(f)
+		if err != nil {
+			return model.Metadata{}, &ValidationError{Field: fieldsLabel, Message: err.Error()}
+		}
+		m.SetField(model.CustomField{Name: name, Value: value})
+	}
+
+	for _, f := range utils.SplitList(secretFields, ";") {
+		name, value, err := utils.ParseField(f)
+		if err != nil {
+			// Don't echo the secret value back
+			return model.Metadata{}, &ValidationError{
+				Field:   secretFieldsLabel,
+				Message: "Secret fields must be 'name=value'",
+			}
+		}
+
+		encrypted, err := crypt.EncryptPassword([]byte(value), a.Keyring)
+		if err != nil {
+			return model.Metadata{}, &ValidationError{
+				Field:   secretFieldsLabel,
+				Message: "Failed to encrypt secret field",
+			}
+		}
+		m.SetField(model.CustomField{Name: name, Value: encrypted, Secret: true})
+	}
+
+	return m, nil
+}
+
+// MetadataText returns the metadata of an entry for the info modal, with the
+// values of secret fields decrypted. Each line ends with the tab that indents
+// the next, like the rest of the modal text.
+func (a *App) MetadataText(m model.Metadata) (string, error) {
+	fields, err := crypt.DecryptFields(m.Fields, a.Keyring)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if len(m.URLs) > 0 {
+		fmt.Fprintf(&sb, "URLs: %s\n\t", strings.Join(m.URLs, ", "))
+	}
+	if len(m.Tags) > 0 {
+		fmt.Fprintf(&sb, "Tags: %s\n\t", strings.Join(m.Tags, ", "))
+	}
+	if m.Folder != "" {
+		fmt.Fprintf(&sb, "Folder: %s\n\t", m.Folder)
+	}
+	for _, f := range fields {
+		fmt.Fprintf(&sb, "%s: %s\n\t", f.Name, f.Value)
+	}
+
+	return sb.String(), nil
+}
@@ -0,0 +1,97 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/crypt"
+	"go-pass/model"
+)
+
+func TestParseMetadata(t *testing.T) {
+	assert := assert.New(t)
+	app, cleanup := NewTestApp(t)
+	defer cleanup()
+
+	m, err := app.ParseMetadata(
+		"https://a.com, https://b.com",
+		"work,",
+		" work/cloud ",
+		"recovery email=me@example.com; q=a=b",
+		"pin=1234",
+	)
+	assert.NoError(err)
+	assert.Equal([]string{"https://a.com", "https://b.com"}, m.URLs)
+	assert.Equal([]string{"work"}, m.Tags)
+	assert.Equal("work/cloud", m.Folder)
+
+	fields, err := crypt.DecryptFields(m.Fields, app.Keyring)
+	assert.NoError(err)
+	assert.Equal([]model.CustomField{
+		{Name: "recovery email", Value: "me@example.com"},
+		{Name: "q", Value: "a=b"},
+		{Name: "pin", Value: "1234", Secret: true},
+	}, fields)
+	assert.NotEqual("1234", m.Fields[2].Value)
+
+	_, err = app.ParseMetadata("", "", "", "novalue", "")
+	assert.Error(err)
+	assert.Equal(fieldsLabel, err.(*ValidationError).Field)
+
+	_, err = app.ParseMetadata("", "", "", "", "secret")
+	assert.Error(err)
+	assert.NotContains(err.Error(), "secret'")
+}
+
+func TestMetadataFormRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	app, cleanup := NewTestApp(t)
+	defer cleanup()
+
+	m := model.Metadata{
+		URLs:   []string{"https://a.com"},
+		Tags:   []string{"a", "b"},
+		Folder: "work",
+		Fields: []model.CustomField{
+			{Name: "q", Value: "a"},
+			{Name: "pin", Value: "1234", Secret: true},
+		},
+	}
+
+	form := AddMetadataFields(tview.NewForm(), m)
+	got, err := app.GetMetadataFields(form)
+	assert.NoError(err)
+
+	got.Fields, err = crypt.DecryptFields(got.Fields, app.Keyring)
+	assert.NoError(err)
+	assert.Equal(m, got)
+}
+
+func TestAddToVaultWithMetadata(t *testing.T) {
+	assert := assert.New(t)
+	app, cleanup := NewTestApp(t)
+	defer cleanup()
+
+	m, err := app.ParseMetadata("https://github.com", "code", "", "", "pin=1234")
+	assert.NoError(err)
+
+	app.AddToVaultWithMetadata("GitHub", "", "user", "pass", m)
+	assert.Len(app.Vault, 1)
+	assert.Equal(m, app.Vault[0].Metadata)
+	assert.NotZero(app.Vault[0].CreatedAt)
+
+	text, err := app.MetadataText(app.Vault[0].Metadata)
+	assert.NoError(err)
+	assert.Contains(text, "URLs: https://github.com")
+	assert.Contains(text, "Tags: code")
+	assert.Contains(text, "pin: 1234")
+
+	// Updating keeps the creation time
+	createdAt := app.Vault[0].CreatedAt
+	newEntry, err := app.ValidateUpdateInputs("GitHub", "user2", "pass", "")
+	assert.NoError(err)
+	app.UpdateVaultEntry(0, *newEntry)
+	assert.Equal(createdAt, app.Vault[0].CreatedAt)
+}
@@ -0,0 +1,59 @@
+package tui
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rivo/tview"
+
+	"go-pass/cmd/vault"
+	"go-pass/model"
+)
+
+// OTPText returns the TOTP line of the info modal for the entry at time 't',
+// with the seconds left before the code changes. Nothing is returned if the
+// entry has no TOTP secret.
+func (a *App) OTPText(ve model.VaultEntry, t time.Time) string {
+	if len(ve.TOTP) == 0 {
+		return ""
+	}
+
+	code, remaining, err := vault.EntryOTP(ve, t, a.Keyring)
+	if err != nil {
+		return fmt.Sprintf("TOTP: %v\n\t", err)
+	}
+
+	return fmt.Sprintf("TOTP: %s (%ds)\n\t", code, int(remaining.Seconds()))
+}
+
+// RefreshModalText re-renders the modal's text every second, so the TOTP code
+// and countdown stay current, until the returned function is called. Nothing
+// is refreshed if 'live' is false.
+func (a *App) RefreshModalText(modal *tview.Modal, render func() string, live bool) func() {
+	if !live {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				a.App.QueueUpdateDraw(func() {
+					modal.SetText(render())
+				})
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
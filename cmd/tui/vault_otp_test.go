@@ -0,0 +1,49 @@
+package tui
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/cmd/vault"
+	"go-pass/model"
+)
+
+func TestOTPText(t *testing.T) {
+	assert := assert.New(t)
+	app, cleanup := NewTestApp(t)
+	defer cleanup()
+
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	totp, err := vault.EncryptTOTP("otpauth://totp/a?digits=8&secret="+secret, app.Keyring)
+	assert.NoError(err)
+
+	ve := model.VaultEntry{Name: "a", TOTP: totp}
+	assert.Equal("TOTP: 94287082 (1s)\n\t", app.OTPText(ve, time.Unix(59, 0)))
+	assert.Equal("", app.OTPText(model.VaultEntry{Name: "b"}, time.Unix(59, 0)))
+
+	// Nothing to stop when the text isn't live
+	app.RefreshModalText(nil, nil, false)()
+}
+
+func TestUpdateVaultEntryKeepsTOTP(t *testing.T) {
+	assert := assert.New(t)
+	app, cleanup := NewTestApp(t)
+	defer cleanup()
+
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	totp, err := vault.EncryptTOTP(secret, app.Keyring)
+	assert.NoError(err)
+
+	app.AddToVault("Entry1", "", "user1", "pass1")
+	app.Vault[0].TOTP = totp
+
+	newEntry, err := app.ValidateUpdateInputs("Entry1", "user2", "pass2", "")
+	assert.NoError(err)
+	app.UpdateVaultEntry(0, *newEntry)
+
+	assert.Equal("user2", app.Vault[0].Username)
+	assert.Equal(totp, app.Vault[0].TOTP)
+}
@@ -33,7 +33,7 @@ func TestFilterVaultBySearch(t *testing.T) {
 	assert.Equal("GitHub", filteredEntries[0].Name)
 	assert.Equal("notes2", filteredEntries[0].Notes)
 	assert.Equal("user2", filteredEntries[0].Username)
-	decryptedPass2, err := crypt.DecryptPassword(filteredEntries[0].Password, app.Keyring, false)
+	decryptedPass2, err := crypt.DecryptPassword(filteredEntries[0].Password, app.Keyring)
 	assert.NoError(err)
 	assert.Equal("pass2", decryptedPass2)
 
@@ -41,7 +41,7 @@ func TestFilterVaultBySearch(t *testing.T) {
 	assert.Equal("notes1", filteredEntries[1].Notes)
 	assert.Equal("user1", filteredEntries[1].Username)
 
-	decryptedPass1, err := crypt.DecryptPassword(filteredEntries[1].Password, app.Keyring, false)
+	decryptedPass1, err := crypt.DecryptPassword(filteredEntries[1].Password, app.Keyring)
 	assert.NoError(err)
 	assert.Equal("pass1", decryptedPass1)
 }
@@ -65,3 +65,18 @@ func TestFindFilteredVaultIndex_EmptyVault(t *testing.T) {
 	idx := emptyApp.findFilteredVaultIndex(entries[0])
 	assert.Equal(-1, idx)
 }
+
+func TestFindFilteredVaultIndex_ByID(t *testing.T) {
+	assert := assert.New(t)
+	app, cleanup := NewTestApp(t)
+	defer cleanup()
+
+	// Entries with the same name and timestamp are told apart by ID
+	app.Vault = []model.VaultEntry{
+		{ID: "a", Name: "Entry", UpdatedAt: 1},
+		{ID: "b", Name: "Entry", UpdatedAt: 1},
+	}
+
+	assert.Equal(1, app.findFilteredVaultIndex(app.Vault[1]))
+	assert.Equal(-1, app.findFilteredVaultIndex(model.VaultEntry{ID: "c", UpdatedAt: 1}))
+}
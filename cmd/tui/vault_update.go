@@ -1,12 +1,14 @@
 package tui
 
 import (
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 
+	"go-pass/cmd/vault"
 	"go-pass/crypt"
 	"go-pass/model"
 )
@@ -16,7 +18,14 @@ import (
 func (a *App) UpdateVaultModal(currIdx int) *tview.Flex {
 	entry := a.Vault[currIdx]
 
-	decryptedPass, err := crypt.DecryptPassword(entry.Password, a.Keyring, false)
+	decryptedPass, err := crypt.DecryptPassword(entry.Password, a.Keyring)
+	if err != nil {
+		modal := a.ErrorModal(err.Error(), a.Root)
+		a.App.SetRoot(modal, true)
+	}
+
+	metadata := entry.Metadata
+	metadata.Fields, err = crypt.DecryptFields(entry.Fields, a.Keyring)
 	if err != nil {
 		modal := a.ErrorModal(err.Error(), a.Root)
 		a.App.SetRoot(modal, true)
@@ -27,6 +36,7 @@ func (a *App) UpdateVaultModal(currIdx int) *tview.Flex {
 		AddInputField("Username", entry.Username, 0, nil, nil).
 		AddInputField("Password", decryptedPass, 0, nil, nil).
 		AddInputField("Notes", entry.Notes, 0, nil, nil)
+	AddMetadataFields(form, metadata)
 	form.AddButton("Save", func() {
 		formName := form.GetFormItem(0).(*tview.InputField).GetText()
 		formUsername := form.GetFormItem(1).(*tview.InputField).GetText()
@@ -45,6 +55,21 @@ func (a *App) UpdateVaultModal(currIdx int) *tview.Flex {
 			return
 		}
 
+		newEntry.Metadata, err = a.GetMetadataFields(form)
+		if err != nil {
+			modal := a.ErrorModal(err.Error(), a.Root)
+			a.App.SetRoot(modal, false)
+			return
+		}
+
+		renamed := formName != entry.Name || formUsername != entry.Username
+		others := slices.Delete(slices.Clone(a.Vault), currIdx, currIdx+1)
+		if renamed && vault.FindDuplicate(others, formName, formUsername) >= 0 {
+			modal := a.ErrorModal(vault.ErrDuplicateEntry.Error(), a.Root)
+			a.App.SetRoot(modal, false)
+			return
+		}
+
 		a.UpdateVaultEntry(currIdx, *newEntry)
 		a.PopulateVaultList()
 		a.RefreshRoot()
@@ -67,8 +92,22 @@ func (a *App) UpdateVaultModal(currIdx int) *tview.Flex {
 	return flex
 }
 
-// UpdateVaultEntry contains the business logic of updating the vault on disk
+// UpdateVaultEntry contains the business logic of updating the vault on disk.
+// The entry keeps its ID, original creation time, TOTP secret and SSH key,
+// which the update form doesn't edit.
 func (a *App) UpdateVaultEntry(currIdx int, newEntry model.VaultEntry) {
+	if newEntry.ID == "" {
+		newEntry.ID = a.Vault[currIdx].ID
+	}
+	if newEntry.CreatedAt == 0 {
+		newEntry.CreatedAt = a.Vault[currIdx].CreatedAt
+	}
+	if len(newEntry.TOTP) == 0 {
+		newEntry.TOTP = a.Vault[currIdx].TOTP
+	}
+	if len(newEntry.SSHKey) == 0 {
+		newEntry.SSHKey = a.Vault[currIdx].SSHKey
+	}
 	a.Vault[currIdx] = newEntry
 	a.SaveVault()
 }
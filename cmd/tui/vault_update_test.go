@@ -44,13 +44,29 @@ func TestUpdateVaultEntry(t *testing.T) {
 	assert.NotEqual("Entry1", app.Vault[0].Name)
 	assert.Equal("NewUser1", app.Vault[0].Username)
 	assert.NotEqual("user1", app.Vault[0].Username)
-	decryptedPass1, err := crypt.DecryptPassword(app.Vault[0].Password, app.Keyring, false)
+	decryptedPass1, err := crypt.DecryptPassword(app.Vault[0].Password, app.Keyring)
 	assert.NoError(err)
 	assert.Equal("newPass1", decryptedPass1)
 
-	decryptedPass2, err := crypt.DecryptPassword(app.Vault[0].Password, app.Keyring, false)
+	decryptedPass2, err := crypt.DecryptPassword(app.Vault[0].Password, app.Keyring)
 	assert.NoError(err)
 	assert.NotEqual("pass1", decryptedPass2)
 	assert.Equal("newNotes1", app.Vault[0].Notes)
 	assert.NotEqual("notes1", app.Vault[0].Notes)
 }
+
+func TestUpdateVaultEntryKeepsID(t *testing.T) {
+	assert := assert.New(t)
+	app, cleanup := NewTestApp(t)
+	defer cleanup()
+
+	app.AddToVault("Entry1", "notes1", "user1", "pass1")
+	id := app.Vault[0].ID
+	assert.NotEmpty(id)
+
+	newVaultEntry, err := app.ValidateUpdateInputs("NewEntry1", "NewUser1", "newPass1", "newNotes1")
+	assert.NoError(err)
+
+	app.UpdateVaultEntry(0, *newVaultEntry)
+	assert.Equal(id, app.Vault[0].ID)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"go-pass/agent"
+	"go-pass/utils"
+)
+
+// unlockCmd represents the unlock command
+var unlockCmd = &cobra.Command{
+	Use:   "unlock",
+	Short: "Unlocks the agent with your Master Password",
+	Long: fmt.Sprintf(`%s
+
+'unlock' gives your Master Password to the running agent. The agent verifies it,
+derives your encryption key, and keeps the key in memory until it has not been
+used for your configured timeout. Start the agent first with 'gopass agent'.
+
+Ex.
+	$ gopass unlock
+	Master Password: <insert master password here>
+	Agent unlocked.
+`, LongDescriptionText),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := UnlockCmdHandler(cmd, args); err != nil {
+			fmt.Printf("Error with 'unlock' command: %v\n", err)
+			return
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(unlockCmd)
+}
+
+// UnlockCmdHandler is the handler function for the 'unlock' command
+func UnlockCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("no arguments needed for 'unlock'. see 'help' for more guidance")
+	}
+
+	client := agent.NewClient("")
+	if _, err := client.Status(); err != nil {
+		return errors.New("agent is not running. start it with 'gopass agent'")
+	}
+
+	passB, err := utils.GetPasswordFromUser(true, os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Unlock(passB)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Agent unlocked.")
+	PrintAgentStatus(resp, time.Now())
+	return nil
+}
@@ -5,10 +5,13 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"go-pass/cmd/vault"
+	"go-pass/importer"
+	"go-pass/model"
 )
 
 // vaultCmd represents the vault command
@@ -30,12 +33,17 @@ func init() {
 	vaultCmd.AddCommand(vault.AddCmd)
 	vaultCmd.AddCommand(vault.BackupCmd)
 	vaultCmd.AddCommand(vault.DeleteCmd)
+	vaultCmd.AddCommand(vault.ExportCmd)
 	vaultCmd.AddCommand(vault.GenerateCmd)
 	vaultCmd.AddCommand(vault.GetCmd)
+	vaultCmd.AddCommand(vault.ImportCmd)
 	vaultCmd.AddCommand(vault.ListCmd)
+	vaultCmd.AddCommand(vault.OTPCmd)
+	vaultCmd.AddCommand(vault.RenderCmd)
 	vaultCmd.AddCommand(vault.RestoreCmd)
 	vaultCmd.AddCommand(vault.SearchCmd)
 	vaultCmd.AddCommand(vault.UpdateCmd)
+	vaultCmd.AddCommand(vault.UpgradeKDFCmd)
 
 	initVaultFlags()
 }
@@ -44,6 +52,26 @@ func initVaultFlags() {
 	// Get Command
 	vault.GetCmd.Flags().BoolP("copy", "y", false, "Add password to clipboard, does not display information")
 
+	// OTP Command
+	vault.OTPCmd.Flags().BoolP("copy", "y", false, "Add the code to clipboard, does not display it")
+
+	// Add Command
+	vault.AddCmd.Flags().Bool("totp", false, "Prompt for a TOTP secret or otpauth:// URI")
+	vault.AddCmd.Flags().String("ssh-key", "", "Add the SSH private key in this file, for 'ssh-agent'")
+	vault.AddCmd.Flags().
+		BoolP("force", "f", false, "Add the entry even if one with the same name and username exists")
+	initMetadataFlags(vault.AddCmd)
+
+	// Export Command
+	vault.ExportCmd.Flags().
+		String("format", "", "Format to export to: "+strings.Join(vault.ExportFormats, ", "))
+	vault.ExportCmd.Flags().
+		String("fields", vault.DEFAULT_EXPORT_FIELDS, "Comma-separated fields to write to a csv, json or yaml export: "+strings.Join(vault.ExportFieldNames(), ", "))
+	vault.ExportCmd.Flags().Bool("include-passwords", false, "Write passwords to a csv, json or yaml export, after confirming")
+	vault.ExportCmd.Flags().Bool("bundle", false, "Export an encrypted bundle for importing on another machine")
+	vault.ExportCmd.MarkFlagsOneRequired("format", "bundle")
+	vault.ExportCmd.MarkFlagsMutuallyExclusive("format", "bundle")
+
 	// Generate Command
 	specialCharsStr := "List the special characters you want to add to your password generation. If adjustment is necessary, list all the special characters you want. IMPORTANT: BE SURE TO USE SINGLE QUOTES."
 	vault.GenerateCmd.Flags().IntP("length", "l", 24, "Decides length of new password")
@@ -51,13 +79,53 @@ func initVaultFlags() {
 		StringP("add", "a", "", "Add a newly generated password to your vault")
 	vault.GenerateCmd.Flags().StringP("specialChars", "c", vault.DefaultChars, specialCharsStr)
 
+	// Import Command
+	vault.ImportCmd.Flags().
+		String("format", "", "Format of the export: "+strings.Join(importer.Formats(), ", "))
+	vault.ImportCmd.Flags().
+		String("on-conflict", string(vault.CONFLICT_SKIP), "What to do with an entry that is already in the vault: skip, rename or overwrite")
+	vault.ImportCmd.Flags().Bool("dry-run", false, "Print what would be imported without writing anything")
+	vault.ImportCmd.Flags().Bool("bundle", false, "Import a bundle written by 'export --bundle'")
+	vault.ImportCmd.MarkFlagsOneRequired("format", "bundle")
+	vault.ImportCmd.MarkFlagsMutuallyExclusive("format", "bundle")
+
 	// List Command
 	vault.ListCmd.Flags().StringP("name", "n", "", "Searches your list for the specific source")
 	vault.ListCmd.Flags().BoolP("backups", "b", false, "Lists your backups")
 
+	// Render Command
+	vault.RenderCmd.Flags().String("tag", "", "Only write the entries with this tag")
+	vault.RenderCmd.Flags().StringP("out", "o", "", "The file to write, instead of stdout")
+	vault.RenderCmd.Flags().
+		BoolP("watch", "w", false, "Write the file again whenever the vault changes")
+
 	// Update Command
 	vault.UpdateCmd.Flags().BoolP("source", "s", false, "Update the source name")
 	vault.UpdateCmd.Flags().BoolP("username", "u", false, "Update the login username")
 	vault.UpdateCmd.Flags().BoolP("password", "p", false, "Update the password")
 	vault.UpdateCmd.Flags().BoolP("notes", "t", false, "Update the notes")
+	vault.UpdateCmd.Flags().Bool("totp", false, "Update the TOTP secret")
+	vault.UpdateCmd.Flags().Bool("remove-totp", false, "Remove the TOTP secret")
+	initMetadataFlags(vault.UpdateCmd)
+	vault.UpdateCmd.Flags().StringArray("remove-field", nil, "Remove the custom field with this name")
+
+	// Upgrade KDF Command
+	vault.UpgradeKDFCmd.Flags().
+		String("kdf", string(model.KDFArgon2id), "Key derivation function to use, 'argon2id' or 'pbkdf2-sha256'")
+	vault.UpgradeKDFCmd.Flags().Uint32("time", model.ARGON2_TIME, "Argon2id time cost (passes)")
+	vault.UpgradeKDFCmd.Flags().Uint32("memory", model.ARGON2_MEMORY, "Argon2id memory cost in KiB")
+	vault.UpgradeKDFCmd.Flags().Uint8("threads", model.ARGON2_THREADS, "Argon2id parallelism")
+	vault.UpgradeKDFCmd.Flags().Uint32("iterations", model.NUM_ITERATIONS, "PBKDF2 iterations")
+	vault.UpgradeKDFCmd.Flags().
+		Bool("pepper", false, "Keep mixing SECRET_PASSWORD_KEY into the key as a pepper")
+}
+
+// initMetadataFlags adds the entry metadata flags shared by 'add' and 'update'
+func initMetadataFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("url", nil, "URLs the login is used at, comma separated or repeated")
+	cmd.Flags().StringSlice("tag", nil, "Tags for the entry, comma separated or repeated")
+	cmd.Flags().String("folder", "", "Folder for the entry, like 'work/cloud'")
+	cmd.Flags().StringArray("field", nil, "Custom field as 'name=value', can be repeated")
+	cmd.Flags().
+		StringArray("secret-field", nil, "Name of a secret custom field to prompt for, can be repeated")
 }
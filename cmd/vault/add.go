@@ -13,6 +13,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"go-pass/agent"
 	"go-pass/crypt"
 	"go-pass/model"
 	"go-pass/utils"
@@ -25,9 +26,24 @@ var AddCmd = &cobra.Command{
 	Long: `'add' adds a new password to the vault. The passwords are encrypted and
 stored securely. 'add' takes a source, and then you are prompted to add a
 username and password, and some notes. This notes section is for extra
-information needed for any login. If multiple pieces of information are needed,
-the info should be separated by semicolons, as pressing <Enter> will submit the
-information.
+information needed for any login.
+
+Anything else about the login can be added with flags: the URLs it is used at,
+tags, a folder, and custom fields. A custom field added with '--secret-field' is
+prompted for without echoing, and is encrypted like the password.
+
+With '--totp', you are also prompted for a TOTP secret, either base32 or an
+otpauth:// URI, so 'otp' can generate two-factor codes for the entry.
+
+With '--ssh-key <file>', the entry is an SSH private key for 'ssh-agent' to
+serve, rather than a password. You are prompted for the key's passphrase, if it
+has one, then a username and notes. The key is kept encrypted by the vault
+alone, so the file can be deleted afterwards. Its public key is kept in the
+'public key' field.
+
+Every entry is given an ID that never changes, shown by 'get', which can be used
+in place of the name. Adding an entry with the same name and username as an
+existing one is refused, unless '--force' is given.
 
 NOTE: Entries are case sensitive in order to retreive. When you use the list
 cmd, that is NOT case sensitive.
@@ -36,6 +52,18 @@ Ex.
 	Username: me@example.com
 	Password: ********
 	Notes: <any extra notes, can be empty>
+
+	$ gopass vault add github --url https://github.com --tag work,code \
+		--folder work --field "recovery email=me@example.com" --secret-field pin
+	Username: me@example.com
+	Password: ********
+	Notes:
+	pin (hidden): ********
+
+	$ gopass vault add github-ssh --ssh-key ~/.ssh/id_ed25519
+	Passphrase for /home/me/.ssh/id_ed25519 (hidden): ********
+	Username: git
+	Notes:
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := AddCmdHandler(cmd, args); err != nil {
@@ -56,23 +84,64 @@ func AddCmdHandler(cmd *cobra.Command, args []string) error {
 
 	totalStr := strings.Join(args, " ")
 
-	pBytes, err := utils.GetPasswordFromUser(true, os.Stdin)
+	mf, err := GetMetadataFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	totpFlag, err := cmd.Flags().GetBool("totp")
+	if err != nil {
+		return err
+	}
+
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return err
+	}
+
+	sshKeyPath, err := cmd.Flags().GetString("ssh-key")
 	if err != nil {
 		return err
 	}
 
-	keyring := model.NewMasterAESKeyManager(string(pBytes))
+	keyring, err := agent.GetKeyManager(os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer keyring.Close()
 
 	cfg, err := utils.CheckConfig("", keyring)
 	if err != nil {
 		return err
 	}
 
-	userInput, err := GetInput(os.Stdin, os.Stdin, os.Stdin, keyring)
+	var userInput model.UserInput
+	if sshKeyPath != "" {
+		userInput, err = GetSSHKeyInput(totalStr, sshKeyPath, os.Stdin, os.Stdin, os.Stdin, keyring)
+	} else {
+		userInput, err = GetInput(os.Stdin, os.Stdin, os.Stdin, keyring)
+	}
 	if err != nil {
 		return err
 	}
 
+	if totpFlag {
+		userInput.TOTP, err = PromptTOTP(os.Stdin, keyring)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := PromptSecretFields(&mf, os.Stdin); err != nil {
+		return err
+	}
+	if err := ApplyMetadata(&userInput.Metadata, mf, keyring); err != nil {
+		return err
+	}
+
+	if force {
+		return ForceAddToVault(totalStr, userInput, cfg, time.Now().UnixMilli(), keyring)
+	}
 	return AddToVault(totalStr, userInput, cfg, time.Now().UnixMilli(), keyring)
 }
 
@@ -103,7 +172,8 @@ func GetInput(us, pw, no io.Reader, key *model.MasterAESKeyManager) (model.UserI
 }
 
 // AddToVault holds the logic that adds encrypts the input from the user, and
-// stores it into the vault.
+// stores it into the vault. It returns ErrDuplicateEntry if an entry with the
+// same name and username already exists.
 func AddToVault(
 	source string,
 	ui model.UserInput,
@@ -111,11 +181,44 @@ func AddToVault(
 	t int64,
 	key *model.MasterAESKeyManager,
 ) error {
+	return addToVault(source, ui, cfg, t, key, false)
+}
+
+// ForceAddToVault is AddToVault, but adds the entry even if one with the same
+// name and username already exists.
+func ForceAddToVault(
+	source string,
+	ui model.UserInput,
+	cfg *model.Config,
+	t int64,
+	key *model.MasterAESKeyManager,
+) error {
+	return addToVault(source, ui, cfg, t, key, true)
+}
+
+func addToVault(
+	source string,
+	ui model.UserInput,
+	cfg *model.Config,
+	t int64,
+	key *model.MasterAESKeyManager,
+	force bool,
+) error {
+	id, err := model.NewEntryID()
+	if err != nil {
+		return err
+	}
+
 	ve := model.VaultEntry{
+		ID:        id,
 		Name:      source,
 		Username:  ui.Username,
 		Password:  ui.Password,
 		Notes:     ui.Notes,
+		TOTP:      ui.TOTP,
+		SSHKey:    ui.SSHKey,
+		Metadata:  ui.Metadata,
+		CreatedAt: t,
 		UpdatedAt: t,
 	}
 
@@ -132,12 +235,25 @@ func AddToVault(
 
 	var entries []model.VaultEntry
 	if fStat.Size() != 2 {
-		entries, err = crypt.DecryptVault(f, key, false)
+		entries, err = crypt.DecryptVault(f, key)
 		if err != nil {
 			return fmt.Errorf("decrypting vault: %v", err)
 		}
 	}
 
+	if !force && FindDuplicate(entries, ve.Name, ve.Username) >= 0 {
+		return fmt.Errorf(
+			"'%s' (%s): %w, use '--force' to add it anyway",
+			ve.Name, ve.Username, ErrDuplicateEntry,
+		)
+	}
+
+	// Entries from before IDs existed get theirs now, as the vault is being
+	// written anyway
+	if _, err := model.AssignIDs(entries); err != nil {
+		return err
+	}
+
 	entries = append(entries, ve)
 
 	encryptedCipherText, err := crypt.EncryptVault(entries, key)
@@ -3,11 +3,13 @@ package vault
 import (
 	"os"
 	"path"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"go-pass/crypt"
 	"go-pass/model"
 	"go-pass/testutils"
 	"go-pass/utils"
@@ -119,3 +121,19 @@ func TestAddToVault(t *testing.T) {
 	fStat, _ := f.Stat()
 	assert.Greater(t, fStat.Size(), int64(2), "Vault should contain encrypted data")
 }
+
+// The prompts of 'add' can be answered by piped input, one line each
+func TestGetInputFromPipe(t *testing.T) {
+	assert := assert.New(t)
+	key := model.NewMasterAESKeyManagerFromKey(make([]byte, model.KEY_SIZE), model.DefaultKDF())
+
+	r := strings.NewReader("me\nhunter2\nsome notes\n")
+	ui, err := GetInput(r, r, r, key)
+	assert.NoError(err)
+	assert.Equal("me", ui.Username)
+	assert.Equal("some notes", ui.Notes)
+
+	password, err := crypt.DecryptPassword(ui.Password, key)
+	assert.NoError(err)
+	assert.Equal("hunter2", password)
+}
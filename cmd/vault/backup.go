@@ -12,6 +12,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"go-pass/agent"
 	"go-pass/crypt"
 	"go-pass/model"
 	"go-pass/utils"
@@ -54,12 +55,11 @@ func BackupCmdHandler(cmd *cobra.Command, args []string) error {
 		)
 	}
 
-	passB, err := utils.GetPasswordFromUser(true, os.Stdin)
+	keyring, err := agent.GetKeyManager(os.Stdin)
 	if err != nil {
 		return err
 	}
-
-	keyring := model.NewMasterAESKeyManager(string(passB))
+	defer keyring.Close()
 
 	cfg, err := utils.CheckConfig("", keyring)
 	if err != nil {
@@ -106,7 +106,7 @@ func BackupVault(
 	}
 	defer currentVault.Close()
 
-	entries, err := crypt.DecryptVault(currentVault, key, false)
+	entries, err := crypt.DecryptVault(currentVault, key)
 	if err != nil {
 		return "", err
 	}
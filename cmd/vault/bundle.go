@@ -0,0 +1,78 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+// bundleContents is what a bundle holds once it is decrypted
+type bundleContents struct {
+	// ExportedAt is the time in UnixMilli the bundle was written
+	ExportedAt int64                  `json:"exported_at"`
+	Entries    []model.DecryptedEntry `json:"entries"`
+}
+
+// ExportBundle writes every entry in the vault to 'w' as a bundle encrypted
+// with the passphrase, and returns how many entries it wrote. Unlike the vault,
+// the bundle doesn't need this machine's keyring to open.
+func ExportBundle(
+	cfg *model.Config,
+	w io.Writer,
+	passphrase []byte,
+	t int64,
+	key *model.MasterAESKeyManager,
+) (int, error) {
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	if err != nil {
+		return 0, err
+	}
+	f.Close()
+
+	decrypted, err := crypt.DecryptEntries(entries, key)
+	if err != nil {
+		return 0, err
+	}
+
+	b, err := json.Marshal(bundleContents{ExportedAt: t, Entries: decrypted})
+	if err != nil {
+		return 0, fmt.Errorf("marshaling bundle: %v", err)
+	}
+
+	sealed, err := crypt.SealBundle(b, passphrase, model.DefaultKDF())
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := w.Write(sealed); err != nil {
+		return 0, fmt.Errorf("writing bundle: %v", err)
+	}
+
+	return len(decrypted), nil
+}
+
+// ReadBundle decrypts a bundle written by ExportBundle and returns its entries
+func ReadBundle(r io.Reader, passphrase []byte) ([]model.DecryptedEntry, error) {
+	contents, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading bundle: %v", err)
+	}
+
+	b, err := crypt.OpenBundle(contents, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	var bc bundleContents
+	if err := json.Unmarshal(b, &bc); err != nil {
+		return nil, fmt.Errorf("reading bundle entries: %v", err)
+	}
+	return bc.Entries, nil
+}
@@ -0,0 +1,68 @@
+package vault
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+	cfg := setupImportVault(t, key)
+
+	ui := model.UserInput{Username: vaultEntry2, Notes: "notes"}
+	pw, err := crypt.EncryptPassword([]byte("password2"), key)
+	assert.NoError(err)
+	ui.Password = []byte(pw)
+	ui.TOTP, err = EncryptTOTP("JBSWY3DPEHPK3PXP", key)
+	assert.NoError(err)
+	assert.NoError(ApplyMetadata(&ui.Metadata, MetadataFlags{
+		Folder:    "work",
+		SetFolder: true,
+		Fields:    []model.CustomField{{Name: "pin", Value: "1234", Secret: true}},
+	}, key))
+	assert.NoError(AddToVault(vaultEntry2, ui, cfg, 1704164645123, key))
+
+	before, err := crypt.DecryptEntries(readImportVault(t, key), key)
+	assert.NoError(err)
+
+	var b bytes.Buffer
+	n, err := ExportBundle(cfg, &b, []byte("passphrase"), 1704164645123, key)
+	assert.NoError(err)
+	assert.Equal(2, n)
+	assert.NotContains(b.String(), "password2")
+
+	_, err = ReadBundle(bytes.NewReader(b.Bytes()), []byte("wrong"))
+	assert.ErrorIs(err, crypt.ErrBundlePassphrase)
+
+	// Into an empty vault with a new keyring key, as on another machine
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	key, err = testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+	vF, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	vF.Close()
+
+	imported, err := ReadBundle(&b, []byte("passphrase"))
+	assert.NoError(err)
+
+	summary, err := ImportEntries(cfg, imported, CONFLICT_SKIP, false, 0, key)
+	assert.NoError(err)
+	assert.Len(summary.Added, 2)
+	assert.Empty(summary.Warnings)
+
+	after, err := crypt.DecryptEntries(readImportVault(t, key), key)
+	assert.NoError(err)
+	assert.Equal(before, after)
+}
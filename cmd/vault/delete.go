@@ -4,6 +4,7 @@ Copyright © 2025 DKagan07
 package vault
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
 	"io"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"go-pass/agent"
 	"go-pass/crypt"
 	"go-pass/model"
 	"go-pass/utils"
@@ -23,7 +25,8 @@ var DeleteCmd = &cobra.Command{
 	Use:   "delete",
 	Short: "Delete a specific item from your vault",
 	Long: `'delete' deletes a specific source name from your vault. This HAS to be case
-sensitive.
+sensitive. The entry's ID can be given instead of the name, and if several
+entries have the name, you are asked which one you mean.
 Ex.
 	$ gopass vault delete google
 `,
@@ -45,12 +48,11 @@ func DeleteCmdHandler(cmd *cobra.Command, args []string) error {
 
 	itemToDelete := strings.Join(args, " ")
 
-	passB, err := utils.GetPasswordFromUser(true, os.Stdin)
+	keyring, err := agent.GetKeyManager(os.Stdin)
 	if err != nil {
 		return err
 	}
-
-	keyring := model.NewMasterAESKeyManager(string(passB))
+	defer keyring.Close()
 
 	cfg, err := utils.CheckConfig("", keyring)
 	if err != nil {
@@ -65,47 +67,45 @@ func DeleteCmdHandler(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// DeleteItemInVault encapsulates the logic for deleting 'name' from the vault
-// if it exists. If not, it will error and print a message out to user.
+// DeleteItemInVault encapsulates the logic for deleting 'name', or the entry
+// with that ID, from the vault if it exists. If not, it will error and print a
+// message out to user.
 func DeleteItemInVault(
 	cfg *model.Config,
 	name string,
 	r io.Reader,
 	key *model.MasterAESKeyManager,
 ) error {
-	f, err := utils.OpenVault(cfg.VaultName)
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
 	if err != nil {
-		return fmt.Errorf("opening vault: %v", err)
+		return err
 	}
 	defer f.Close()
 
-	entries, err := crypt.DecryptVault(f, key, false)
-	if err != nil {
-		return fmt.Errorf("decrypting vault: %v", err)
-	}
-
 	if len(entries) == 0 {
 		return fmt.Errorf("nothing in your vault")
 	}
 
-	confirm, err := utils.ConfirmPrompt(utils.DeletePrompt, name, r)
+	// The choice of entry and the confirmation are read from the same
+	// buffer, so neither reads ahead into the other
+	br := bufio.NewReader(r)
+
+	idx, err := ResolveEntry(entries, name, br)
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%s not found in vault", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	confirm, err := utils.ConfirmPrompt(utils.DeletePrompt, entries[idx].Name, br)
 	if !confirm && err != nil {
 		return fmt.Errorf("failed to confirm deletion: %v", err)
 	}
 
 	if confirm {
-		found := false
-		for i, v := range entries {
-			if name == v.Name {
-				entries = slices.Delete(entries, i, i+1)
-				fmt.Printf("Deleted %s from your vault\n", name)
-				found = true
-			}
-		}
-
-		if !found {
-			return fmt.Errorf("%s not found in vault", name)
-		}
+		fmt.Printf("Deleted %s from your vault\n", entries[idx].Name)
+		entries = slices.Delete(entries, idx, idx+1)
 	}
 
 	b, err := crypt.EncryptVault(entries, key)
@@ -0,0 +1,270 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package vault
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"go-pass/agent"
+	"go-pass/crypt"
+	"go-pass/importer"
+	"go-pass/kdbx"
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+// ExportFormats are the formats 'export' can write
+var ExportFormats = append([]string{importer.FORMAT_KDBX}, PlaintextFormats...)
+
+// exportCmd represents the export command
+var ExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the vault for another password manager, another machine or review",
+	Long: fmt.Sprintf(`'export' writes every entry in the vault to a file another password manager can
+open. The format is given with '--format', one of:
+	%s
+
+'kdbx' is a KeePass 4 database, for KeePassXC and KeePass, protected by a
+passphrase you are prompted for. Folders become groups, and custom fields, the
+TOTP secret and SSH keys are kept as strings. 'import --format kdbx' reads it
+back.
+
+'csv', 'json' and 'yaml' are unencrypted, for reviewing which accounts exist.
+'--fields' picks the fields written, in order, from:
+	%s
+Passwords are left out unless '--include-passwords' is given, which you have
+to confirm. The file is only readable by you, and without a file the export
+is written to stdout. A csv value starting with '=', '+', '-' or '@' has a '
+put before it, so spreadsheets don't run it as a formula.
+
+An existing file is never overwritten.
+
+With '--bundle' instead of '--format', the vault is written as a gopass bundle
+for moving it to another machine. The bundle is encrypted with AES-256-GCM,
+with a key derived with Argon2id from a passphrase you are prompted for alone,
+so it doesn't need this machine's keyring or SECRET_PASSWORD_KEY to open.
+'import --bundle' reads it back, keeping every entry's ID and timestamps.
+
+Ex.
+	$ gopass vault export --format kdbx gopass.kdbx
+	KDBX passphrase (hidden): ********
+	KDBX passphrase again (hidden): ********
+	Exported 12 entries to 'gopass.kdbx'.
+
+	$ gopass vault export --format csv --fields name,username,url,updated_at accounts.csv
+	Exported 12 entries to 'accounts.csv'.
+
+	$ gopass vault export --format json --include-passwords | jq .
+	Are you sure you want to write your passwords unencrypted to stdout? (y/n) y
+
+	$ gopass vault export --bundle vault.gpb
+	Bundle passphrase (hidden): ********
+	Bundle passphrase again (hidden): ********
+	Exported 12 entries to 'vault.gpb'.
+`, strings.Join(ExportFormats, "\n\t"), strings.Join(ExportFieldNames(), ", ")),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ExportCmdHandler(cmd, args); err != nil {
+			// stdout may be the export
+			fmt.Fprintf(os.Stderr, "Error with 'export' command: %v\n", err)
+			return
+		}
+	},
+}
+
+// ExportCmdHandler is the handler function that encapsulates the ExportKDBX,
+// ExportPlaintext and ExportBundle logic and runs some checks beforehand.
+func ExportCmdHandler(cmd *cobra.Command, args []string) error {
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return fmt.Errorf("error getting format flag: %v", err)
+	}
+	bundle, err := cmd.Flags().GetBool("bundle")
+	if err != nil {
+		return fmt.Errorf("error getting bundle flag: %v", err)
+	}
+	if !bundle && !slices.Contains(ExportFormats, format) {
+		return fmt.Errorf(
+			"unknown format '%s', must be one of: %s",
+			format, strings.Join(ExportFormats, ", "),
+		)
+	}
+	plaintext := !bundle && slices.Contains(PlaintextFormats, format)
+
+	fieldsFlag, err := cmd.Flags().GetString("fields")
+	if err != nil {
+		return fmt.Errorf("error getting fields flag: %v", err)
+	}
+	includePasswords, err := cmd.Flags().GetBool("include-passwords")
+	if err != nil {
+		return fmt.Errorf("error getting include-passwords flag: %v", err)
+	}
+
+	var fields []string
+	if plaintext {
+		if len(args) > 1 {
+			return errors.New(
+				"at most 1 argument, the file to export to, is needed. see 'help' for correct usage",
+			)
+		}
+
+		fields, err = ParseExportFields(fieldsFlag, includePasswords)
+		if err != nil {
+			return err
+		}
+	} else {
+		if len(args) != 1 {
+			return errors.New(
+				"exactly 1 argument, the file to export to, is needed. see 'help' for correct usage",
+			)
+		}
+		if cmd.Flags().Changed("fields") || includePasswords {
+			return fmt.Errorf(
+				"'--fields' and '--include-passwords' only apply to %s",
+				strings.Join(PlaintextFormats, ", "),
+			)
+		}
+	}
+
+	toStdout := len(args) == 0 || args[0] == "-"
+	dest := "stdout"
+	if !toStdout {
+		dest = fmt.Sprintf("'%s'", args[0])
+	}
+
+	// Everything but the export goes to stderr
+	stdin, stdout, stderr := cmd.InOrStdin(), cmd.OutOrStdout(), cmd.ErrOrStderr()
+
+	if includePasswords {
+		confirm, err := utils.ConfirmPrompt(utils.ExportPrompt, dest, stdin)
+		if err != nil {
+			return fmt.Errorf("failed to confirm export: %v", err)
+		}
+		if !confirm {
+			fmt.Fprintln(stderr, "Export cancelled.")
+			return nil
+		}
+	}
+
+	keyring, err := agent.PromptKeyManager(stderr, stdin)
+	if err != nil {
+		return err
+	}
+	defer keyring.Close()
+
+	cfg, err := utils.CheckConfig("", keyring)
+	if err != nil {
+		return fmt.Errorf("error checking config: %v", err)
+	}
+
+	if plaintext && toStdout {
+		_, err := ExportPlaintext(cfg, stdout, format, fields, keyring)
+		return err
+	}
+
+	// The file is only ever created, so the file removed when the export
+	// fails is always the one this command made
+	out, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("'%s' already exists, remove it or export to another file", args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("creating export: %v", err)
+	}
+
+	n, err := exportToFile(cfg, out, stdin, stderr, format, fields, bundle, keyring)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(args[0])
+		return err
+	}
+
+	fmt.Fprintf(stderr, "Exported %d entries to '%s'.\n", n, args[0])
+	return nil
+}
+
+// exportToFile writes the export to 'out', prompting on 'w' for the
+// passphrase of a KDBX database or bundle
+func exportToFile(
+	cfg *model.Config,
+	out io.Writer,
+	r io.Reader,
+	w io.Writer,
+	format string,
+	fields []string,
+	bundle bool,
+	key *model.MasterAESKeyManager,
+) (int, error) {
+	switch {
+	case bundle:
+		passphrase, err := PromptNewPassphrase(w, r, "Bundle passphrase")
+		if err != nil {
+			return 0, err
+		}
+		return ExportBundle(cfg, out, passphrase, time.Now().UnixMilli(), key)
+	case slices.Contains(PlaintextFormats, format):
+		return ExportPlaintext(cfg, out, format, fields, key)
+	default:
+		passphrase, err := PromptNewPassphrase(w, r, "KDBX passphrase")
+		if err != nil {
+			return 0, err
+		}
+		return ExportKDBX(cfg, out, passphrase, key)
+	}
+}
+
+// ExportKDBX writes every entry in the vault to 'w' as a KDBX database
+// protected by the passphrase, and returns how many entries it wrote
+func ExportKDBX(
+	cfg *model.Config,
+	w io.Writer,
+	passphrase []byte,
+	key *model.MasterAESKeyManager,
+) (int, error) {
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	if err != nil {
+		return 0, err
+	}
+	f.Close()
+
+	decrypted, err := crypt.DecryptEntries(entries, key)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := kdbx.Write(w, decrypted, passphrase); err != nil {
+		return 0, fmt.Errorf("writing kdbx: %v", err)
+	}
+
+	return len(decrypted), nil
+}
+
+// PromptNewPassphrase prompts on 'w' twice for a new passphrase, without
+// echoing it, and returns it if both match
+func PromptNewPassphrase(w io.Writer, r io.Reader, field string) ([]byte, error) {
+	passphrase, err := utils.PromptSecret(w, r, field)
+	if err != nil {
+		return nil, err
+	}
+
+	again, err := utils.PromptSecret(w, r, field+" again")
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare(passphrase, again) != 1 {
+		return nil, errors.New("passphrases do not match")
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,256 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package vault
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+const (
+	EXPORT_CSV  = "csv"
+	EXPORT_JSON = "json"
+	EXPORT_YAML = "yaml"
+
+	// DEFAULT_EXPORT_FIELDS are the fields of a plaintext export when
+	// '--fields' isn't given
+	DEFAULT_EXPORT_FIELDS = "name,username,url,updated_at"
+	// EXPORT_FIELD_PASSWORD can only be exported with '--include-passwords'
+	EXPORT_FIELD_PASSWORD = "password"
+)
+
+// PlaintextFormats are the formats ExportPlaintext can write
+var PlaintextFormats = []string{EXPORT_CSV, EXPORT_JSON, EXPORT_YAML}
+
+// exportFields are the fields a plaintext export can have, other than the
+// password, and how each is read from an entry. Lists are []string, and
+// everything else is a string.
+var exportFields = map[string]func(model.VaultEntry) any{
+	"id":         func(e model.VaultEntry) any { return e.ID },
+	"name":       func(e model.VaultEntry) any { return e.Name },
+	"username":   func(e model.VaultEntry) any { return e.Username },
+	"url":        func(e model.VaultEntry) any { return exportList(e.URLs) },
+	"notes":      func(e model.VaultEntry) any { return e.Notes },
+	"tags":       func(e model.VaultEntry) any { return exportList(e.Tags) },
+	"folder":     func(e model.VaultEntry) any { return e.Folder },
+	"created_at": func(e model.VaultEntry) any { return exportTime(e.CreatedAt) },
+	"updated_at": func(e model.VaultEntry) any { return exportTime(e.UpdatedAt) },
+}
+
+// ExportFieldNames returns the names of every field a plaintext export can
+// have, sorted
+func ExportFieldNames() []string {
+	names := []string{EXPORT_FIELD_PASSWORD}
+	for name := range exportFields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ParseExportFields parses the comma-separated list of fields given with
+// '--fields'. The password is only allowed with 'includePasswords', and is
+// added to the end if it isn't listed.
+func ParseExportFields(s string, includePasswords bool) ([]string, error) {
+	var fields []string
+	for _, f := range utils.SplitList(s, ",") {
+		f = strings.ToLower(f)
+
+		_, ok := exportFields[f]
+		if !ok && f != EXPORT_FIELD_PASSWORD {
+			return nil, fmt.Errorf(
+				"unknown field '%s', must be one of: %s",
+				f, strings.Join(ExportFieldNames(), ", "),
+			)
+		}
+		if f == EXPORT_FIELD_PASSWORD && !includePasswords {
+			return nil, fmt.Errorf("the '%s' field needs '--include-passwords'", EXPORT_FIELD_PASSWORD)
+		}
+		if slices.Contains(fields, f) {
+			return nil, fmt.Errorf("field '%s' is listed more than once", f)
+		}
+		fields = append(fields, f)
+	}
+
+	if includePasswords && !slices.Contains(fields, EXPORT_FIELD_PASSWORD) {
+		fields = append(fields, EXPORT_FIELD_PASSWORD)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields to export")
+	}
+	return fields, nil
+}
+
+// ExportPlaintext writes the given fields of every entry in the vault to 'w'
+// as CSV, JSON or YAML, and returns how many entries it wrote. Passwords are
+// only decrypted if 'fields' has the password.
+func ExportPlaintext(
+	cfg *model.Config,
+	w io.Writer,
+	format string,
+	fields []string,
+	key *model.MasterAESKeyManager,
+) (int, error) {
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	if err != nil {
+		return 0, err
+	}
+	f.Close()
+
+	records := make([]exportRecord, len(entries))
+	for i, e := range entries {
+		record := make(exportRecord, len(fields))
+		for j, field := range fields {
+			var value any
+			if field == EXPORT_FIELD_PASSWORD {
+				value, err = crypt.DecryptPassword(e.Password, key)
+				if err != nil {
+					return 0, fmt.Errorf("decrypting password for '%s': %v", e.Name, err)
+				}
+			} else {
+				value = exportFields[field](e)
+			}
+			record[j] = exportValue{Name: field, Value: value}
+		}
+		records[i] = record
+	}
+
+	switch format {
+	case EXPORT_CSV:
+		err = writeExportCSV(w, fields, records)
+	case EXPORT_JSON:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(records)
+	case EXPORT_YAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err = enc.Encode(records); err == nil {
+			err = enc.Close()
+		}
+	default:
+		return 0, fmt.Errorf("unknown format '%s'", format)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("writing %s: %v", format, err)
+	}
+
+	return len(records), nil
+}
+
+// writeExportCSV writes the records as CSV with a header row. Lists are
+// joined with commas, the way 'import --format generic-csv' reads them.
+func writeExportCSV(w io.Writer, fields []string, records []exportRecord) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(fields); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := make([]string, len(record))
+		for i, v := range record {
+			switch value := v.Value.(type) {
+			case []string:
+				row[i] = csvCell(strings.Join(value, ", "))
+			case string:
+				row[i] = csvCell(value)
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvCell returns 's' with a ' before it if it starts like a formula, so a
+// spreadsheet opening the export shows it instead of running it
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// exportValue is one field of an exported entry
+type exportValue struct {
+	Name  string
+	Value any
+}
+
+// exportRecord is an exported entry. It is written as an object whose keys
+// are in the order the fields were given, rather than sorted.
+type exportRecord []exportValue
+
+// MarshalJSON writes the record as a JSON object
+func (r exportRecord) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, v := range r {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		name, err := json.Marshal(v.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(v.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// MarshalYAML writes the record as a YAML mapping
+func (r exportRecord) MarshalYAML() (any, error) {
+	n := &yaml.Node{Kind: yaml.MappingNode}
+	for _, v := range r {
+		var value yaml.Node
+		if err := value.Encode(v.Value); err != nil {
+			return nil, err
+		}
+		n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: v.Name}, &value)
+	}
+	return n, nil
+}
+
+// exportList returns the list, or an empty one instead of nil, so JSON and
+// YAML get '[]' rather than null
+func exportList(l []string) []string {
+	if l == nil {
+		return []string{}
+	}
+	return l
+}
+
+// exportTime formats a time in milliseconds as RFC 3339, in UTC. Times that
+// were never recorded are empty.
+func exportTime(ms int64) string {
+	if ms == 0 {
+		return ""
+	}
+	return time.UnixMilli(ms).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,314 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/agent"
+	"go-pass/crypt"
+	"go-pass/importer"
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+func TestKDBXRoundTrip(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+	cfg := setupImportVault(t, key)
+
+	// An entry with every field set
+	ui := model.UserInput{Username: vaultEntry2, Notes: "line one\nline two"}
+	pw, err := crypt.EncryptPassword([]byte("p&ss <word>"), key)
+	assert.NoError(err)
+	ui.Password = []byte(pw)
+	ui.TOTP, err = EncryptTOTP("JBSWY3DPEHPK3PXP", key)
+	assert.NoError(err)
+	assert.NoError(ApplyMetadata(&ui.Metadata, MetadataFlags{
+		URLs:      []string{"https://example.com", "https://example.org"},
+		SetURLs:   true,
+		Tags:      []string{"work", "code"},
+		SetTags:   true,
+		Folder:    "work/cloud",
+		SetFolder: true,
+		Fields: []model.CustomField{
+			{Name: "recovery email", Value: "me@example.com"},
+			{Name: "pin", Value: "1234", Secret: true},
+		},
+	}, key))
+	assert.NoError(AddToVault(vaultEntry2, ui, cfg, 1704164645123, key))
+
+	before, err := crypt.DecryptEntries(readImportVault(t, key), key)
+	assert.NoError(err)
+
+	var b bytes.Buffer
+	n, err := ExportKDBX(cfg, &b, []byte("passphrase"), key)
+	assert.NoError(err)
+	assert.Equal(2, n)
+
+	// Into an empty vault
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	key, err = testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+	vF, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	vF.Close()
+
+	imported, err := importer.ParseKDBX(&b, []byte("passphrase"))
+	assert.NoError(err)
+
+	summary, err := ImportEntries(cfg, imported, CONFLICT_SKIP, false, 0, key)
+	assert.NoError(err)
+	assert.Len(summary.Added, 2)
+	assert.Empty(summary.Warnings)
+
+	after, err := crypt.DecryptEntries(readImportVault(t, key), key)
+	assert.NoError(err)
+	assert.ElementsMatch(before, after)
+}
+
+func TestExportPlaintext(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+	cfg := setupImportVault(t, key)
+
+	ui := model.UserInput{Username: vaultEntry2}
+	pw, err := crypt.EncryptPassword([]byte("p&ss, \"word\""), key)
+	assert.NoError(err)
+	ui.Password = []byte(pw)
+	assert.NoError(ApplyMetadata(&ui.Metadata, MetadataFlags{
+		URLs:    []string{"https://example.com", "https://example.org"},
+		SetURLs: true,
+	}, key))
+	assert.NoError(AddToVault(vaultEntry2, ui, cfg, 1704164645123, key))
+
+	fields, err := ParseExportFields("name,username,url,updated_at", false)
+	assert.NoError(err)
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: EXPORT_CSV,
+			want: `name,username,url,updated_at
+test1,test1,,1970-01-01T00:00:01Z
+test2,test2,"https://example.com, https://example.org",2024-01-02T03:04:05Z
+`,
+		},
+		{
+			format: EXPORT_JSON,
+			want: `[
+  {
+    "name": "test1",
+    "username": "test1",
+    "url": [],
+    "updated_at": "1970-01-01T00:00:01Z"
+  },
+  {
+    "name": "test2",
+    "username": "test2",
+    "url": [
+      "https://example.com",
+      "https://example.org"
+    ],
+    "updated_at": "2024-01-02T03:04:05Z"
+  }
+]
+`,
+		},
+		{
+			format: EXPORT_YAML,
+			want: `- name: test1
+  username: test1
+  url: []
+  updated_at: "1970-01-01T00:00:01Z"
+- name: test2
+  username: test2
+  url:
+    - https://example.com
+    - https://example.org
+  updated_at: "2024-01-02T03:04:05Z"
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var b bytes.Buffer
+			n, err := ExportPlaintext(cfg, &b, tt.format, fields, key)
+			assert.NoError(err)
+			assert.Equal(2, n)
+			assert.Equal(tt.want, b.String())
+		})
+	}
+
+	// Passwords are only written when asked for
+	fields, err = ParseExportFields("name", true)
+	assert.NoError(err)
+	var b bytes.Buffer
+	_, err = ExportPlaintext(cfg, &b, EXPORT_CSV, fields, key)
+	assert.NoError(err)
+	assert.Equal("name,password\ntest1,test1\ntest2,\"p&ss, \"\"word\"\"\"\n", b.String())
+}
+
+func TestParseExportFields(t *testing.T) {
+	tests := []struct {
+		name             string
+		fields           string
+		includePasswords bool
+		want             []string
+		shouldError      bool
+	}{
+		{name: "default", fields: DEFAULT_EXPORT_FIELDS, want: []string{"name", "username", "url", "updated_at"}},
+		{name: "spaces and case", fields: " Name , TAGS,", want: []string{"name", "tags"}},
+		{name: "password added", fields: "name", includePasswords: true, want: []string{"name", "password"}},
+		{name: "password kept in place", fields: "password,name", includePasswords: true, want: []string{"password", "name"}},
+		{name: "password not allowed", fields: "name,password", shouldError: true},
+		{name: "unknown", fields: "name,secret", shouldError: true},
+		{name: "duplicate", fields: "name,name", shouldError: true},
+		{name: "empty", fields: "", shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			got, err := ParseExportFields(tt.fields, tt.includePasswords)
+			if tt.shouldError {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tt.want, got)
+		})
+	}
+}
+
+func TestWriteExportCSV(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, writeExportCSV(&b, []string{"name", "notes", "url"}, []exportRecord{{
+		{Name: "name", Value: "=HYPERLINK(\"https://evil.example\")"},
+		{Name: "notes", Value: "-1+2"},
+		{Name: "url", Value: []string{"@SUM(A1)", "https://example.com"}},
+	}, {
+		{Name: "name", Value: "plain"},
+		{Name: "notes", Value: ""},
+		{Name: "url", Value: []string{}},
+	}}))
+
+	assert.Equal(t, `name,notes,url
+"'=HYPERLINK(""https://evil.example"")",'-1+2,"'@SUM(A1), https://example.com"
+plain,,
+`, b.String())
+}
+
+// useTempInstall points the config, settings, keystore and vault at a
+// temporary directory, with the file keystore and no agent, and creates the
+// default config and vault for the master password
+func useTempInstall(t *testing.T, masterPassword string) *model.MasterAESKeyManager {
+	dir := t.TempDir()
+	old := []string{
+		utils.CONFIG_PATH, utils.CONFIG_FILE, utils.SETTINGS_FILE, utils.KEY_FILE, utils.VAULT_PATH,
+	}
+	utils.CONFIG_PATH = path.Join(dir, "config")
+	utils.CONFIG_FILE = path.Join(utils.CONFIG_PATH, "gopass-cfg.json")
+	utils.SETTINGS_FILE = path.Join(utils.CONFIG_PATH, "settings.json")
+	utils.KEY_FILE = path.Join(utils.CONFIG_PATH, "key.enc")
+	utils.VAULT_PATH = path.Join(dir, "vault")
+	t.Cleanup(func() {
+		utils.CONFIG_PATH, utils.CONFIG_FILE, utils.SETTINGS_FILE = old[0], old[1], old[2]
+		utils.KEY_FILE, utils.VAULT_PATH = old[3], old[4]
+	})
+	t.Setenv(agent.SocketEnv, path.Join(dir, "agent.sock"))
+
+	assert.NoError(t, utils.WriteSettings(utils.Settings{KeyStore: model.KEYSTORE_FILE}))
+	key, err := utils.NewKeyManager(masterPassword)
+	assert.NoError(t, err)
+	t.Cleanup(func() { key.Close() })
+	assert.NoError(t, key.InitializeKeychain())
+
+	cF, err := utils.CreateConfig(testutils.TEST_VAULT_NAME, []byte(masterPassword), "", key)
+	assert.NoError(t, err)
+	cF.Close()
+	vF, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(t, err)
+	vF.Close()
+	return key
+}
+
+func TestExportToStdout(t *testing.T) {
+	assert := assert.New(t)
+	const masterPassword = "mastahpass"
+	key := useTempInstall(t, masterPassword)
+
+	cfg := &model.Config{VaultName: testutils.TEST_VAULT_NAME}
+	pw, err := crypt.EncryptPassword([]byte("hunter2"), key)
+	assert.NoError(err)
+	assert.NoError(AddToVault(vaultEntry1, model.UserInput{
+		Username: vaultEntry1,
+		Password: []byte(pw),
+	}, cfg, 1000, key))
+
+	cmd := newExportCmd(EXPORT_JSON, true)
+	// The confirmation and the Master Password are typed in, so both prompts
+	// are written
+	cmd.SetIn(strings.NewReader("y\n" + masterPassword + "\n"))
+	var stdout, stderr bytes.Buffer
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+
+	assert.NoError(ExportCmdHandler(cmd, []string{"-"}))
+
+	var exported []map[string]any
+	assert.NoError(json.Unmarshal(stdout.Bytes(), &exported), stdout.String())
+	if assert.Len(exported, 1) {
+		assert.Equal("hunter2", exported[0]["password"])
+	}
+	assert.Contains(stderr.String(), "Master Password: ")
+}
+
+func TestExportKeepsExistingFile(t *testing.T) {
+	assert := assert.New(t)
+	const masterPassword = "mastahpass"
+	useTempInstall(t, masterPassword)
+
+	file := path.Join(t.TempDir(), "accounts.csv")
+	assert.NoError(os.WriteFile(file, []byte("keep me"), 0o644))
+
+	cmd := newExportCmd(EXPORT_CSV, false)
+	cmd.SetIn(strings.NewReader(masterPassword + "\n"))
+	cmd.SetErr(io.Discard)
+
+	err := ExportCmdHandler(cmd, []string{file})
+	assert.ErrorContains(err, "already exists")
+
+	b, err := os.ReadFile(file)
+	assert.NoError(err)
+	assert.Equal("keep me", string(b))
+}
+
+// newExportCmd returns a command with the flags of 'export'
+func newExportCmd(format string, includePasswords bool) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("format", format, "")
+	cmd.Flags().String("fields", DEFAULT_EXPORT_FIELDS, "")
+	cmd.Flags().Bool("include-passwords", includePasswords, "")
+	cmd.Flags().Bool("bundle", false, "")
+	return cmd
+}
@@ -4,6 +4,7 @@ Copyright © 2025 DKagan07
 package vault
 
 import (
+	"bytes"
 	"crypto/rand"
 	"fmt"
 	"math/big"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"go-pass/agent"
 	"go-pass/crypt"
 	"go-pass/model"
 	"go-pass/utils"
@@ -52,12 +54,11 @@ func GenerateCmdHandler(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no arguments needed for 'generate'. see 'help' for more guidance")
 	}
 
-	passB, err := utils.GetPasswordFromUser(true, os.Stdin)
+	keyring, err := agent.GetKeyManager(os.Stdin)
 	if err != nil {
 		return err
 	}
-
-	keyring := model.NewMasterAESKeyManager(string(passB))
+	defer keyring.Close()
 
 	cfg, err := utils.CheckConfig("", keyring)
 	if err != nil {
@@ -127,6 +128,9 @@ func AddGeneratedPasswordToVault(
 // of lower case, uppercase, numbers, and special characters using the
 // crypto/rand package for cryptographically secure RNG.
 //
+// If special characters are given, the password always has at least one; a
+// password without any is thrown away and generated again.
+//
 // Note: There is always a chance that these passwords will not satisfy password
 // inputs, so double check that it does.
 func GeneratePassword(l int, special string) ([]byte, error) {
@@ -135,13 +139,18 @@ func GeneratePassword(l int, special string) ([]byte, error) {
 	setLength := big.NewInt(int64(len(byteSet)))
 
 	b := make([]byte, l)
-	for i := range b {
-		idx, err := rand.Int(rand.Reader, setLength)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get random number: %v", err)
+	for {
+		for i := range b {
+			idx, err := rand.Int(rand.Reader, setLength)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get random number: %v", err)
+			}
+
+			b[i] = byteSet[idx.Int64()]
 		}
 
-		b[i] = byteSet[idx.Int64()]
+		if l == 0 || special == "" || bytes.ContainsAny(b, special) {
+			return b, nil
+		}
 	}
-	return b, nil
 }
@@ -4,13 +4,16 @@ Copyright © 2025 DKagan07
 package vault
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/atotto/clipboard"
 	"github.com/spf13/cobra"
 
+	"go-pass/agent"
 	"go-pass/crypt"
 	"go-pass/model"
 	"go-pass/utils"
@@ -24,6 +27,10 @@ var GetCmd = &cobra.Command{
 credentials from the name of the source. If the source name has a <Space> in it,
 you have to surround the source name with double quotes.
 
+The entry's ID, or the first 8 or more characters of it, can be given instead
+of the name. If several entries have the name, you are asked which one you
+mean.
+
 If you want to see if a specific source is in the vault, you can use the:
 	'gopass list -n <name-of-source>'
 command.
@@ -31,9 +38,11 @@ command.
 Ex.
 $ gopass vault get Google
 Name: Google
+	ID: <the entry's ID>
 	Username: <username>
 	Password: <human-readable password>
 	Notes: <will show if any notes are present>
+	URLs: <will show any URLs, tags, folder and custom fields that are present>
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := GetCmdHandler(cmd, args); err != nil {
@@ -59,11 +68,11 @@ func GetCmdHandler(cmd *cobra.Command, args []string) error {
 
 	name := strings.Join(args, " ")
 
-	passB, err := utils.GetPasswordFromUser(true, os.Stdin)
+	keyring, err := agent.GetKeyManager(os.Stdin)
 	if err != nil {
 		return err
 	}
-	keyring := model.NewMasterAESKeyManager(string(passB))
+	defer keyring.Close()
 
 	cfg, err := utils.CheckConfig("", keyring)
 	if err != nil {
@@ -78,68 +87,86 @@ func GetCmdHandler(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// GetItemFromVault retreies the 'name' from the vault. If it doesn't exist, an
-// error gets returned.
+// GetItemFromVault retreies the 'name' from the vault, which can also be an ID.
+// If it doesn't exist, an error gets returned.
 func GetItemFromVault(
 	cfg *model.Config,
 	name string,
 	copyFlag bool,
 	keyring *model.MasterAESKeyManager,
 ) error {
-	f, err := utils.OpenVault(cfg.VaultName)
+	f, entries, err := utils.ReadVault(cfg.VaultName, keyring)
 	if err != nil {
-		return fmt.Errorf("opening vault: %v", err)
+		return err
 	}
 	defer f.Close()
 
-	entries, err := crypt.DecryptVault(f, keyring, false)
+	if len(entries) == 0 {
+		return fmt.Errorf("nothing in vault")
+	}
+
+	idx, err := ResolveEntry(entries, name, os.Stdin)
+	if errors.Is(err, ErrNotFound) {
+		fmt.Printf("'%s' not found.\n", name)
+
+		encryptedCipherText, err := crypt.EncryptVault(entries, keyring)
+		if err != nil {
+			return fmt.Errorf("add::obtaining ciphertext: %v", err)
+		}
+
+		if err := utils.WriteToFile(f.Name(), model.FileVault, encryptedCipherText); err != nil {
+			return err
+		}
+
+		return fmt.Errorf("'%s' not found in vault", name)
+	}
 	if err != nil {
-		return fmt.Errorf("decrypting vault: %v", err)
+		return err
 	}
 
-	if len(entries) == 0 {
-		return fmt.Errorf("nothing in vault")
+	e := entries[idx]
+	decryptedPass, err := crypt.DecryptPassword(e.Password, keyring)
+	if err != nil {
+		return fmt.Errorf("decrypting password: %v", err)
 	}
 
-	for _, e := range entries {
-		if e.Name == name {
-			decryptedPass, err := crypt.DecryptPassword(e.Password, keyring, false)
-			if err != nil {
-				return fmt.Errorf("decrypting password: %v", err)
-			}
-
-			if copyFlag {
-				clipboard.WriteAll(decryptedPass)
-				fmt.Println("Copied password to clipboard!")
-			} else {
-				// The \t's are for aligning the text in the terminal
-				fmt.Println("From vault:")
-				fmt.Println("Name: ", e.Name)
-				fmt.Println("\tUsername: \t", e.Username)
-				fmt.Println(
-					"\tPassword: \t",
-					decryptedPass,
-				)
-
-				if len(e.Notes) > 0 {
-					fmt.Println("\tNotes: \t\t", e.Notes)
-				}
-			}
-
-			return nil
+	if copyFlag {
+		if err := clipboard.WriteAll(decryptedPass); err != nil {
+			return fmt.Errorf("copying password to clipboard: %v", err)
 		}
+		fmt.Println("Copied password to clipboard!")
+		return nil
 	}
 
-	fmt.Printf("'%s' not found.\n", name)
+	// The \t's are for aligning the text in the terminal
+	fmt.Println("From vault:")
+	fmt.Println("Name: ", e.Name)
+	fmt.Println("\tID: \t\t", e.ID)
+	fmt.Println("\tUsername: \t", e.Username)
+	fmt.Println(
+		"\tPassword: \t",
+		decryptedPass,
+	)
+
+	if len(e.Notes) > 0 {
+		fmt.Println("\tNotes: \t\t", e.Notes)
+	}
 
-	encryptedCipherText, err := crypt.EncryptVault(entries, keyring)
-	if err != nil {
-		return fmt.Errorf("add::obtaining ciphertext: %v", err)
+	if len(e.TOTP) > 0 {
+		code, remaining, err := EntryOTP(e, time.Now(), keyring)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\tTOTP: \t\t %s (expires in %s)\n", code, remaining)
 	}
 
-	if err := utils.WriteToFile(f.Name(), model.FileVault, encryptedCipherText); err != nil {
-		return err
+	if len(e.SSHKey) > 0 {
+		fingerprint, err := SSHKeyFingerprint(e, keyring)
+		if err != nil {
+			return err
+		}
+		fmt.Println("\tSSH key: \t", fingerprint)
 	}
 
-	return fmt.Errorf("'%s' not found in vault", name)
+	return PrintMetadata(e.Metadata, keyring)
 }
@@ -0,0 +1,422 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"go-pass/agent"
+	"go-pass/crypt"
+	"go-pass/importer"
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+// ConflictPolicy decides what happens to an imported entry with the same name
+// and username as one already in the vault
+type ConflictPolicy string
+
+const (
+	// CONFLICT_SKIP leaves the vault's entry as it is
+	CONFLICT_SKIP ConflictPolicy = "skip"
+	// CONFLICT_RENAME adds the imported entry under a free name, like 'name (2)'
+	CONFLICT_RENAME ConflictPolicy = "rename"
+	// CONFLICT_OVERWRITE replaces the vault's entry, keeping its ID
+	CONFLICT_OVERWRITE ConflictPolicy = "overwrite"
+)
+
+// importCmd represents the import command
+var ImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import entries exported from another password manager",
+	Long: fmt.Sprintf(`'import' adds the entries from another password manager's export to the vault.
+The format of the export is given with '--format', one of:
+	%s
+
+Usernames, passwords, notes, URLs, TOTP secrets, folders, tags and custom fields
+are imported where the format has them. 'generic-csv' takes any CSV with a
+header row, recognising columns by common names like 'title', 'login' or
+'website'; any other column becomes a custom field.
+
+'kdbx' is a KeePass database, which you are prompted for the passphrase of. Its
+groups become folders, and entries keep their IDs, so a vault exported with
+'export --format kdbx' imports as it was.
+
+'password-store' is the directory kept by 'pass', usually ~/.password-store.
+Each file is decrypted with gpg, and its path gives the entry's folder and name.
+The first line is the password, 'login:' and 'url:' lines give the username and
+URLs, an otpauth:// line gives the TOTP secret, other 'key: value' lines become
+custom fields, and the rest are the notes.
+
+'--bundle' instead of '--format' imports a bundle written by 'export --bundle',
+usually on another machine. You are prompted for the bundle's passphrase, and
+every entry keeps its ID and timestamps.
+
+An imported entry with the same name and username as one in the vault is a
+conflict, and '--on-conflict' decides what happens to it: 'skip' (the default)
+leaves the vault's entry alone, 'rename' imports it as 'name (2)', and
+'overwrite' replaces the vault's entry, keeping its ID.
+
+A summary of what was imported, renamed, overwritten and skipped is printed.
+With '--dry-run', the summary is printed but nothing is written.
+
+NOTE: Exports other than 'kdbx' and bundles hold your passwords unencrypted, so delete them
+once imported.
+
+Ex.
+	$ gopass vault import --format bitwarden-json bitwarden_export.json --dry-run
+	$ gopass vault import --format chrome-csv "Chrome Passwords.csv" --on-conflict rename
+	$ gopass vault import --format kdbx Passwords.kdbx
+	KDBX passphrase (hidden): ********
+	$ gopass vault import --format password-store ~/.password-store
+	$ gopass vault import --bundle vault.gpb
+	Bundle passphrase (hidden): ********
+`, strings.Join(importer.Formats(), "\n\t")),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ImportCmdHandler(cmd, args); err != nil {
+			fmt.Printf("Error with 'import' command: %v\n", err)
+			return
+		}
+	},
+}
+
+// ImportSummary records what an import did, or would do, to each entry
+type ImportSummary struct {
+	Added       []string
+	Renamed     []string
+	Overwritten []string
+	Skipped     []string
+	// Warnings are about entries that were imported, but not entirely as-is
+	Warnings []string
+}
+
+// Total returns the number of entries that are added or overwritten
+func (s ImportSummary) Total() int {
+	return len(s.Added) + len(s.Renamed) + len(s.Overwritten)
+}
+
+// ImportCmdHandler is the handler function that encapsulates the ImportEntries
+// logic and runs some checks beforehand.
+func ImportCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New(
+			"exactly 1 argument, the file or directory to import, is needed. see 'help' for correct usage",
+		)
+	}
+
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return fmt.Errorf("error getting format flag: %v", err)
+	}
+
+	bundle, err := cmd.Flags().GetBool("bundle")
+	if err != nil {
+		return fmt.Errorf("error getting bundle flag: %v", err)
+	}
+
+	onConflict, err := cmd.Flags().GetString("on-conflict")
+	if err != nil {
+		return fmt.Errorf("error getting on-conflict flag: %v", err)
+	}
+	policy, err := ParseConflictPolicy(onConflict)
+	if err != nil {
+		return err
+	}
+
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return fmt.Errorf("error getting dry-run flag: %v", err)
+	}
+
+	var imported []model.DecryptedEntry
+	if bundle {
+		imported, err = parseBundle(args[0])
+	} else {
+		imported, err = parseExport(format, args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	keyring, err := agent.GetKeyManager(os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer keyring.Close()
+
+	cfg, err := utils.CheckConfig("", keyring)
+	if err != nil {
+		return fmt.Errorf("error checking config: %v", err)
+	}
+
+	summary, err := ImportEntries(cfg, imported, policy, dryRun, time.Now().UnixMilli(), keyring)
+	if err != nil {
+		return err
+	}
+
+	PrintImportSummary(summary)
+	if dryRun {
+		fmt.Println("Dry run, nothing was written.")
+		return nil
+	}
+
+	fmt.Printf("Imported %d entries.\n", summary.Total())
+	if !bundle && format != importer.FORMAT_KDBX && format != importer.FORMAT_PASSWORD_STORE {
+		fmt.Printf("Remember to delete '%s', it holds your passwords unencrypted.\n", args[0])
+	}
+	return nil
+}
+
+// parseExport parses the export at 'path', prompting for a passphrase if the
+// format needs one
+func parseExport(format, path string) ([]model.DecryptedEntry, error) {
+	if format == importer.FORMAT_PASSWORD_STORE {
+		return importer.ParsePasswordStore(path, importer.GPGDecryptor{})
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening export: %v", err)
+	}
+	defer f.Close()
+
+	if format == importer.FORMAT_KDBX {
+		passphrase, err := utils.GetSecretFromUser(os.Stdin, "KDBX passphrase")
+		if err != nil {
+			return nil, err
+		}
+		return importer.ParseKDBX(f, passphrase)
+	}
+
+	return importer.Parse(format, f)
+}
+
+// parseBundle decrypts the bundle at 'path', prompting for its passphrase
+func parseBundle(path string) ([]model.DecryptedEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening bundle: %v", err)
+	}
+	defer f.Close()
+
+	passphrase, err := utils.GetSecretFromUser(os.Stdin, "Bundle passphrase")
+	if err != nil {
+		return nil, err
+	}
+	return ReadBundle(f, passphrase)
+}
+
+// ParseConflictPolicy parses the value of '--on-conflict'
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case CONFLICT_SKIP, CONFLICT_RENAME, CONFLICT_OVERWRITE:
+		return p, nil
+	}
+	return "", fmt.Errorf(
+		"invalid conflict policy '%s', must be '%s', '%s' or '%s'",
+		s, CONFLICT_SKIP, CONFLICT_RENAME, CONFLICT_OVERWRITE,
+	)
+}
+
+// ImportEntries encrypts the imported entries and adds them to the vault,
+// resolving conflicts with 'policy'. Imported entries without a creation time
+// are given 't', and keep their ID if they have one no other entry has. With
+// 'dryRun', the vault is not written, but the summary is the same.
+func ImportEntries(
+	cfg *model.Config,
+	imported []model.DecryptedEntry,
+	policy ConflictPolicy,
+	dryRun bool,
+	t int64,
+	key *model.MasterAESKeyManager,
+) (ImportSummary, error) {
+	var summary ImportSummary
+
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	if err != nil {
+		return summary, err
+	}
+	defer f.Close()
+
+	for _, d := range imported {
+		label := entryLabel(d.Name, d.Username)
+
+		idx := FindDuplicate(entries, d.Name, d.Username)
+		if idx >= 0 {
+			switch policy {
+			case CONFLICT_SKIP:
+				summary.Skipped = append(summary.Skipped, label)
+				continue
+			case CONFLICT_RENAME:
+				d.Name = freeName(entries, d.Name)
+				summary.Renamed = append(
+					summary.Renamed,
+					fmt.Sprintf("%s -> %s", label, d.Name),
+				)
+			case CONFLICT_OVERWRITE:
+				summary.Overwritten = append(summary.Overwritten, label)
+			}
+		} else {
+			summary.Added = append(summary.Added, label)
+		}
+
+		ve, warning, err := encryptImported(d, t, key)
+		if err != nil {
+			return summary, fmt.Errorf("%s: %v", label, err)
+		}
+		if warning != "" {
+			summary.Warnings = append(summary.Warnings, fmt.Sprintf("%s: %s", label, warning))
+		}
+
+		if idx >= 0 && policy == CONFLICT_OVERWRITE {
+			ve.ID = entries[idx].ID
+			ve.CreatedAt = entries[idx].CreatedAt
+			entries[idx] = ve
+			continue
+		}
+
+		if ve.ID == "" || hasID(entries, ve.ID) {
+			ve.ID, err = model.NewEntryID()
+			if err != nil {
+				return summary, err
+			}
+		}
+		entries = append(entries, ve)
+	}
+
+	if dryRun || summary.Total() == 0 {
+		return summary, nil
+	}
+
+	ciphertext, err := crypt.EncryptVault(entries, key)
+	if err != nil {
+		return summary, fmt.Errorf("import::obtaining ciphertext: %v", err)
+	}
+
+	return summary, utils.WriteToFile(f.Name(), model.FileVault, ciphertext)
+}
+
+// encryptImported returns the imported entry as a vault entry, with its
+// secrets encrypted. A TOTP secret that can't be parsed is kept as a secret
+// custom field instead, and a warning saying so is returned.
+func encryptImported(
+	d model.DecryptedEntry,
+	t int64,
+	key *model.MasterAESKeyManager,
+) (model.VaultEntry, string, error) {
+	var warning string
+
+	ve := model.VaultEntry{
+		ID:        d.ID,
+		Name:      d.Name,
+		Username:  d.Username,
+		Notes:     d.Notes,
+		Metadata:  d.Metadata,
+		CreatedAt: d.CreatedAt,
+		UpdatedAt: d.UpdatedAt,
+	}
+	if ve.CreatedAt == 0 {
+		ve.CreatedAt = t
+	}
+	if ve.UpdatedAt == 0 {
+		ve.UpdatedAt = ve.CreatedAt
+	}
+
+	pw, err := crypt.EncryptPassword([]byte(d.Password), key)
+	if err != nil {
+		return ve, "", fmt.Errorf("encrypting password: %v", err)
+	}
+	ve.Password = []byte(pw)
+
+	if d.TOTP != "" {
+		ve.TOTP, err = EncryptTOTP(d.TOTP, key)
+		if err != nil {
+			ve.TOTP = nil
+			ve.Fields = append([]model.CustomField(nil), ve.Fields...)
+			ve.SetField(model.CustomField{Name: "totp", Value: d.TOTP, Secret: true})
+			warning = fmt.Sprintf("TOTP secret kept as the secret field 'totp': %v", err)
+		}
+	}
+
+	if d.SSHKey != "" {
+		sshKey, err := crypt.EncryptPassword([]byte(d.SSHKey), key)
+		if err != nil {
+			return ve, "", fmt.Errorf("encrypting SSH key: %v", err)
+		}
+		ve.SSHKey = []byte(sshKey)
+	}
+
+	ve.Fields, err = crypt.EncryptFields(ve.Fields, key)
+	if err != nil {
+		return ve, "", fmt.Errorf("encrypting fields: %v", err)
+	}
+
+	return ve, warning, nil
+}
+
+// freeName returns 'name (n)' with the smallest n from 2 that no entry has
+func freeName(entries []model.VaultEntry, name string) string {
+	taken := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		taken[e.Name] = true
+	}
+
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)", name, n)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// hasID returns true if an entry has the ID
+func hasID(entries []model.VaultEntry, id string) bool {
+	for _, e := range entries {
+		if e.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// entryLabel returns how an entry is shown in the summary
+func entryLabel(name, username string) string {
+	if username == "" {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", name, username)
+}
+
+// PrintImportSummary prints what an import did, or would do, to each entry
+func PrintImportSummary(s ImportSummary) {
+	fmt.Printf(
+		"%d new, %d renamed, %d overwritten, %d skipped\n",
+		len(s.Added), len(s.Renamed), len(s.Overwritten), len(s.Skipped),
+	)
+
+	for _, group := range []struct {
+		title string
+		names []string
+	}{
+		{"New", s.Added},
+		{"Renamed", s.Renamed},
+		{"Overwritten", s.Overwritten},
+		{"Skipped", s.Skipped},
+		{"Warnings", s.Warnings},
+	} {
+		if len(group.names) == 0 {
+			continue
+		}
+		fmt.Printf("%s:\n", group.title)
+		for _, n := range group.names {
+			fmt.Printf("\t%s\n", n)
+		}
+	}
+}
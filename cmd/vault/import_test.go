@@ -0,0 +1,196 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+func setupImportVault(t *testing.T, key *model.MasterAESKeyManager) *model.Config {
+	cF, err := utils.CreateConfig(
+		testutils.TEST_VAULT_NAME,
+		testutils.TEST_MASTER_PASSWORD,
+		testutils.TEST_CONFIG_NAME,
+		key,
+	)
+	assert.NoError(t, err)
+	cF.Close()
+
+	vF, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(t, err)
+	vF.Close()
+
+	cfg := &model.Config{
+		VaultName:      testutils.TEST_VAULT_NAME,
+		MasterPassword: testutils.TEST_MASTER_PASSWORD,
+		LastVisited:    time.Now().UnixMilli(),
+	}
+
+	pw, err := crypt.EncryptPassword([]byte(vaultEntry1), key)
+	assert.NoError(t, err)
+	assert.NoError(t, AddToVault(vaultEntry1, model.UserInput{
+		Username: vaultEntry1,
+		Password: []byte(pw),
+	}, cfg, 1000, key))
+
+	return cfg
+}
+
+func readImportVault(t *testing.T, key *model.MasterAESKeyManager) []model.VaultEntry {
+	f, entries, err := utils.ReadVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(t, err)
+	f.Close()
+	return entries
+}
+
+func TestImportEntries(t *testing.T) {
+	imported := []model.DecryptedEntry{
+		{
+			Name:     vaultEntry1,
+			Username: vaultEntry1,
+			Password: "imported",
+			Notes:    "from the export",
+		},
+		{
+			Name:     vaultEntry2,
+			Username: vaultEntry2,
+			Password: vaultEntry2,
+			TOTP:     "JBSWY3DPEHPK3PXP",
+			Metadata: model.Metadata{
+				URLs:   []string{"https://example.com"},
+				Fields: []model.CustomField{{Name: "pin", Value: "1234", Secret: true}},
+			},
+			CreatedAt: 500,
+		},
+	}
+
+	tests := []struct {
+		policy   ConflictPolicy
+		summary  ImportSummary
+		names    []string
+		password string
+	}{
+		{
+			policy:   CONFLICT_SKIP,
+			summary:  ImportSummary{Added: []string{"test2 (test2)"}, Skipped: []string{"test1 (test1)"}},
+			names:    []string{vaultEntry1, vaultEntry2},
+			password: vaultEntry1,
+		},
+		{
+			policy: CONFLICT_RENAME,
+			summary: ImportSummary{
+				Added:   []string{"test2 (test2)"},
+				Renamed: []string{"test1 (test1) -> test1 (2)"},
+			},
+			names:    []string{vaultEntry1, "test1 (2)", vaultEntry2},
+			password: vaultEntry1,
+		},
+		{
+			policy: CONFLICT_OVERWRITE,
+			summary: ImportSummary{
+				Added:       []string{"test2 (test2)"},
+				Overwritten: []string{"test1 (test1)"},
+			},
+			names:    []string{vaultEntry1, vaultEntry2},
+			password: "imported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+			defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+			assert := assert.New(t)
+
+			key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+			assert.NoError(err)
+			cfg := setupImportVault(t, key)
+			before := readImportVault(t, key)
+
+			// A dry run gives the same summary without writing anything
+			summary, err := ImportEntries(cfg, imported, tt.policy, true, 2000, key)
+			assert.NoError(err)
+			assert.Equal(tt.summary, summary)
+			assert.Equal(before, readImportVault(t, key))
+
+			summary, err = ImportEntries(cfg, imported, tt.policy, false, 2000, key)
+			assert.NoError(err)
+			assert.Equal(tt.summary, summary)
+
+			entries := readImportVault(t, key)
+			names := make([]string, len(entries))
+			for i, e := range entries {
+				names[i] = e.Name
+				assert.NotEmpty(e.ID)
+			}
+			assert.Equal(tt.names, names)
+
+			// The original entry keeps its ID and creation time
+			assert.Equal(before[0].ID, entries[0].ID)
+			assert.Equal(int64(1000), entries[0].CreatedAt)
+			pw, err := crypt.DecryptPassword(entries[0].Password, key)
+			assert.NoError(err)
+			assert.Equal(tt.password, pw)
+
+			e := entries[len(entries)-1]
+			assert.Equal(int64(500), e.CreatedAt)
+			assert.Equal([]string{"https://example.com"}, e.URLs)
+			assert.NotEqual("1234", e.Fields[0].Value)
+			fields, err := crypt.DecryptFields(e.Fields, key)
+			assert.NoError(err)
+			assert.Equal("1234", fields[0].Value)
+
+			_, _, err = EntryOTP(e, time.Now(), key)
+			assert.NoError(err)
+		})
+	}
+}
+
+func TestImportEntriesBadTOTP(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+	cfg := setupImportVault(t, key)
+
+	summary, err := ImportEntries(cfg, []model.DecryptedEntry{
+		{Name: vaultEntry2, Password: vaultEntry2, TOTP: "not a secret!"},
+	}, CONFLICT_SKIP, false, 2000, key)
+	assert.NoError(err)
+	assert.Len(summary.Warnings, 1)
+
+	entries := readImportVault(t, key)
+	e := entries[len(entries)-1]
+	assert.Empty(e.TOTP)
+	assert.Equal(int64(2000), e.CreatedAt)
+	assert.Equal(int64(2000), e.UpdatedAt)
+
+	fields, err := crypt.DecryptFields(e.Fields, key)
+	assert.NoError(err)
+	assert.Equal([]model.CustomField{{Name: "totp", Value: "not a secret!", Secret: true}}, fields)
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	for _, s := range []string{"skip", "rename", "overwrite"} {
+		p, err := ParseConflictPolicy(s)
+		assert.NoError(t, err)
+		assert.Equal(t, ConflictPolicy(s), p)
+	}
+
+	_, err := ParseConflictPolicy("merge")
+	assert.Error(t, err)
+}
+
+func TestFreeName(t *testing.T) {
+	entries := []model.VaultEntry{{Name: "a"}, {Name: "a (2)"}, {Name: "b"}}
+	assert.Equal(t, "a (3)", freeName(entries, "a"))
+	assert.Equal(t, "b (2)", freeName(entries, "b"))
+}
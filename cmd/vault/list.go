@@ -11,6 +11,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"go-pass/agent"
 	"go-pass/crypt"
 	"go-pass/model"
 	"go-pass/utils"
@@ -43,12 +44,11 @@ func ListCmdHandler(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no arguments needed for 'list'. see 'help' for more guidance")
 	}
 
-	bp, err := utils.GetPasswordFromUser(true, os.Stdin)
+	keyring, err := agent.GetKeyManager(os.Stdin)
 	if err != nil {
 		return err
 	}
-
-	keyring := model.NewMasterAESKeyManager(string(bp))
+	defer keyring.Close()
 
 	cfg, err := utils.CheckConfig("", keyring)
 	if err != nil {
@@ -89,7 +89,7 @@ func PrintList(sourceName string, cfg *model.Config, key *model.MasterAESKeyMana
 	}
 	defer f.Close()
 
-	entries, err := crypt.DecryptVault(f, key, false)
+	entries, err := crypt.DecryptVault(f, key)
 	if err != nil {
 		return fmt.Errorf("decrypting vault: %v", err)
 	}
@@ -127,7 +127,7 @@ func PrintList(sourceName string, cfg *model.Config, key *model.MasterAESKeyMana
 }
 
 func PrintBackups() error {
-	dirEntries, err := os.ReadDir(utils.BACKUP_PATH)
+	dirEntries, err := utils.ReadBackups()
 	if err != nil {
 		return err
 	}
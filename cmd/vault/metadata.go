@@ -0,0 +1,189 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package vault
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+// MetadataFlags holds the metadata flags shared by 'add' and 'update'. The
+// Set* fields record whether a flag was given, so 'update' only changes what
+// was asked for.
+type MetadataFlags struct {
+	URLs      []string
+	SetURLs   bool
+	Tags      []string
+	SetTags   bool
+	Folder    string
+	SetFolder bool
+	// Fields are the custom fields to add or replace. Secret values are in
+	// plain text until ApplyMetadata encrypts them.
+	Fields []model.CustomField
+	// SecretFields are the names of secret fields whose values still need to
+	// be prompted for, see PromptSecretFields
+	SecretFields []string
+	// RemoveFields are the names of custom fields to remove
+	RemoveFields []string
+}
+
+// Any returns true if any metadata flag was given
+func (mf MetadataFlags) Any() bool {
+	return mf.SetURLs || mf.SetTags || mf.SetFolder ||
+		len(mf.Fields) > 0 || len(mf.SecretFields) > 0 || len(mf.RemoveFields) > 0
+}
+
+// GetMetadataFlags reads the metadata flags from the command. Flags that the
+// command doesn't have are skipped.
+func GetMetadataFlags(cmd *cobra.Command) (MetadataFlags, error) {
+	var mf MetadataFlags
+	var err error
+	flags := cmd.Flags()
+
+	if flags.Lookup("url") != nil {
+		if mf.URLs, err = flags.GetStringSlice("url"); err != nil {
+			return MetadataFlags{}, err
+		}
+		mf.SetURLs = flags.Changed("url")
+	}
+
+	if flags.Lookup("tag") != nil {
+		if mf.Tags, err = flags.GetStringSlice("tag"); err != nil {
+			return MetadataFlags{}, err
+		}
+		mf.SetTags = flags.Changed("tag")
+	}
+
+	if flags.Lookup("folder") != nil {
+		if mf.Folder, err = flags.GetString("folder"); err != nil {
+			return MetadataFlags{}, err
+		}
+		mf.SetFolder = flags.Changed("folder")
+	}
+
+	if flags.Lookup("field") != nil {
+		fields, err := flags.GetStringArray("field")
+		if err != nil {
+			return MetadataFlags{}, err
+		}
+		for _, f := range fields {
+			name, value, err := utils.ParseField(f)
+			if err != nil {
+				return MetadataFlags{}, err
+			}
+			mf.Fields = append(mf.Fields, model.CustomField{Name: name, Value: value})
+		}
+	}
+
+	if flags.Lookup("secret-field") != nil {
+		if mf.SecretFields, err = flags.GetStringArray("secret-field"); err != nil {
+			return MetadataFlags{}, err
+		}
+	}
+
+	if flags.Lookup("remove-field") != nil {
+		if mf.RemoveFields, err = flags.GetStringArray("remove-field"); err != nil {
+			return MetadataFlags{}, err
+		}
+	}
+
+	return mf, nil
+}
+
+// PromptSecretFields prompts for the value of every field named with
+// '--secret-field', without echoing it, and adds it to mf.Fields
+func PromptSecretFields(mf *MetadataFlags, r io.Reader) error {
+	for _, name := range mf.SecretFields {
+		value, err := utils.GetSecretFromUser(r, name)
+		if err != nil {
+			return err
+		}
+		mf.Fields = append(mf.Fields, model.CustomField{
+			Name:   name,
+			Value:  string(value),
+			Secret: true,
+		})
+	}
+	mf.SecretFields = nil
+
+	return nil
+}
+
+// ApplyMetadata applies the metadata flags to 'm', encrypting the values of any
+// secret fields
+func ApplyMetadata(m *model.Metadata, mf MetadataFlags, key *model.MasterAESKeyManager) error {
+	if len(mf.SecretFields) > 0 {
+		return fmt.Errorf("no value given for secret field '%s'", mf.SecretFields[0])
+	}
+
+	if mf.SetURLs {
+		m.URLs = cleanList(mf.URLs)
+	}
+	if mf.SetTags {
+		m.Tags = cleanList(mf.Tags)
+	}
+	if mf.SetFolder {
+		m.Folder = mf.Folder
+	}
+
+	for _, name := range mf.RemoveFields {
+		if !m.RemoveField(name) {
+			return fmt.Errorf("no field named '%s'", name)
+		}
+	}
+
+	fields, err := crypt.EncryptFields(mf.Fields, key)
+	if err != nil {
+		return fmt.Errorf("encrypting fields: %v", err)
+	}
+	for _, f := range fields {
+		m.SetField(f)
+	}
+
+	return nil
+}
+
+// PrintMetadata prints the metadata of an entry for 'get', with the values of
+// secret fields decrypted
+func PrintMetadata(m model.Metadata, key *model.MasterAESKeyManager) error {
+	fields, err := crypt.DecryptFields(m.Fields, key)
+	if err != nil {
+		return fmt.Errorf("decrypting fields: %v", err)
+	}
+
+	// The \t's are for aligning the text in the terminal
+	if len(m.URLs) > 0 {
+		fmt.Println("\tURLs: \t\t", strings.Join(m.URLs, ", "))
+	}
+	if len(m.Tags) > 0 {
+		fmt.Println("\tTags: \t\t", strings.Join(m.Tags, ", "))
+	}
+	if m.Folder != "" {
+		fmt.Println("\tFolder: \t", m.Folder)
+	}
+	for _, f := range fields {
+		fmt.Printf("\t%s: \t %s\n", f.Name, f.Value)
+	}
+
+	return nil
+}
+
+// cleanList trims the items of a list given on the command line and drops
+// empty ones, so '--url ""' clears the list
+func cleanList(items []string) []string {
+	var cleaned []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			cleaned = append(cleaned, item)
+		}
+	}
+	return cleaned
+}
@@ -0,0 +1,173 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+func newMetadataCmd() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().StringSlice("url", nil, "")
+	cmd.Flags().StringSlice("tag", nil, "")
+	cmd.Flags().String("folder", "", "")
+	cmd.Flags().StringArray("field", nil, "")
+	cmd.Flags().StringArray("secret-field", nil, "")
+	cmd.Flags().StringArray("remove-field", nil, "")
+	return cmd
+}
+
+func TestGetMetadataFlags(t *testing.T) {
+	assert := assert.New(t)
+
+	cmd := newMetadataCmd()
+	mf, err := GetMetadataFlags(cmd)
+	assert.NoError(err)
+	assert.False(mf.Any())
+
+	assert.NoError(cmd.ParseFlags([]string{
+		"--url", "https://a.com,https://b.com",
+		"--tag", "work", "--tag", "code",
+		"--field", "recovery email=me@example.com",
+		"--secret-field", "pin",
+		"--remove-field", "old",
+	}))
+
+	mf, err = GetMetadataFlags(cmd)
+	assert.NoError(err)
+	assert.True(mf.Any())
+	assert.Equal(MetadataFlags{
+		URLs:         []string{"https://a.com", "https://b.com"},
+		SetURLs:      true,
+		Tags:         []string{"work", "code"},
+		SetTags:      true,
+		Fields:       []model.CustomField{{Name: "recovery email", Value: "me@example.com"}},
+		SecretFields: []string{"pin"},
+		RemoveFields: []string{"old"},
+	}, mf)
+
+	cmd = newMetadataCmd()
+	assert.NoError(cmd.ParseFlags([]string{"--field", "novalue"}))
+	_, err = GetMetadataFlags(cmd)
+	assert.Error(err)
+}
+
+func TestApplyMetadata(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+
+	m := model.Metadata{
+		URLs:   []string{"https://old.com"},
+		Tags:   []string{"old"},
+		Folder: "old",
+		Fields: []model.CustomField{{Name: "keep", Value: "1"}, {Name: "old", Value: "2"}},
+	}
+
+	err = ApplyMetadata(&m, MetadataFlags{
+		Tags:         []string{" new ", ""},
+		SetTags:      true,
+		Fields:       []model.CustomField{{Name: "pin", Value: "1234", Secret: true}},
+		RemoveFields: []string{"old"},
+	}, key)
+	assert.NoError(err)
+
+	// Only what was given is changed
+	assert.Equal([]string{"https://old.com"}, m.URLs)
+	assert.Equal([]string{"new"}, m.Tags)
+	assert.Equal("old", m.Folder)
+
+	assert.Len(m.Fields, 2)
+	assert.Equal(model.CustomField{Name: "keep", Value: "1"}, m.Fields[0])
+	assert.NotEqual("1234", m.Fields[1].Value)
+
+	fields, err := crypt.DecryptFields(m.Fields, key)
+	assert.NoError(err)
+	assert.Equal(model.CustomField{Name: "pin", Value: "1234", Secret: true}, fields[1])
+
+	// Clearing a list
+	err = ApplyMetadata(&m, MetadataFlags{URLs: []string{""}, SetURLs: true}, key)
+	assert.NoError(err)
+	assert.Nil(m.URLs)
+
+	err = ApplyMetadata(&m, MetadataFlags{RemoveFields: []string{"missing"}}, key)
+	assert.Error(err)
+
+	err = ApplyMetadata(&m, MetadataFlags{SecretFields: []string{"unprompted"}}, key)
+	assert.Error(err)
+}
+
+func TestAddToVaultMetadata(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+
+	cF, err := utils.CreateConfig(
+		testutils.TEST_VAULT_NAME,
+		testutils.TEST_MASTER_PASSWORD,
+		testutils.TEST_CONFIG_NAME,
+		key,
+	)
+	assert.NoError(err)
+	cF.Close()
+
+	vF, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	vF.Close()
+
+	cfg := &model.Config{
+		VaultName:      testutils.TEST_VAULT_NAME,
+		MasterPassword: testutils.TEST_MASTER_PASSWORD,
+		LastVisited:    time.Now().UnixMilli(),
+	}
+
+	ui := model.UserInput{Username: vaultEntry1, Password: []byte(vaultEntry1)}
+	err = ApplyMetadata(&ui.Metadata, MetadataFlags{
+		URLs:      []string{"https://github.com"},
+		SetURLs:   true,
+		Folder:    "work",
+		SetFolder: true,
+		Fields:    []model.CustomField{{Name: "pin", Value: "1234", Secret: true}},
+	}, key)
+	assert.NoError(err)
+
+	now := time.Now().UnixMilli()
+	assert.NoError(AddToVault(vaultEntry1, ui, cfg, now, key))
+
+	i := Inputs{Metadata: MetadataFlags{Tags: []string{"code"}, SetTags: true}}
+	assert.NoError(UpdateEntry(i, cfg, vaultEntry1, InputSources{}, key))
+
+	f, err := utils.OpenVault(testutils.TEST_VAULT_NAME)
+	assert.NoError(err)
+	defer f.Close()
+
+	entries, err := crypt.DecryptVault(f, key)
+	assert.NoError(err)
+	assert.Len(entries, 1)
+
+	e := entries[0]
+	assert.Equal(now, e.CreatedAt)
+	assert.GreaterOrEqual(e.UpdatedAt, now)
+	assert.Equal([]string{"https://github.com"}, e.URLs)
+	assert.Equal([]string{"code"}, e.Tags)
+	assert.Equal("work", e.Folder)
+
+	fields, err := crypt.DecryptFields(e.Fields, key)
+	assert.NoError(err)
+	assert.Equal([]model.CustomField{{Name: "pin", Value: "1234", Secret: true}}, fields)
+
+	assert.NoError(PrintMetadata(e.Metadata, key))
+}
@@ -0,0 +1,172 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/spf13/cobra"
+
+	"go-pass/agent"
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/otp"
+	"go-pass/utils"
+)
+
+// otpCmd represents the otp command
+var OTPCmd = &cobra.Command{
+	Use:   "otp",
+	Short: "Get the current TOTP code for an entry",
+	Long: `'otp' prints the current two-factor code for an entry that has a TOTP secret,
+and how long until it changes. Like 'get', the source name is case SENSITIVE,
+and the entry's ID can be given instead.
+
+A TOTP secret is added to an entry with the '--totp' flag on 'add' or 'update',
+which prompts for either the base32 secret or the otpauth:// URI that a site
+shows as a QR code.
+
+Ex.
+	$ gopass vault otp github
+	Code: 492039 (expires in 17s)
+
+	$ gopass vault otp github -y
+	Copied code to clipboard! (expires in 17s)
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := OTPCmdHandler(cmd, args); err != nil {
+			fmt.Printf("Error with 'otp' command: %v\n", err)
+			return
+		}
+	},
+}
+
+// OTPCmdHandler is the handler function that encapsulates the GetOTPFromVault
+// logic and runs some checks beforehand.
+func OTPCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return errors.New(
+			"at least 1 argument needed for the otp command. see 'help' for correct usage",
+		)
+	}
+
+	copyFlag, err := cmd.Flags().GetBool("copy")
+	if err != nil {
+		return fmt.Errorf("error getting copy flag: %v", err)
+	}
+
+	name := strings.Join(args, " ")
+
+	keyring, err := agent.GetKeyManager(os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer keyring.Close()
+
+	cfg, err := utils.CheckConfig("", keyring)
+	if err != nil {
+		return fmt.Errorf("error checking config: %v", err)
+	}
+
+	return GetOTPFromVault(cfg, name, copyFlag, time.Now(), keyring)
+}
+
+// GetOTPFromVault prints, or copies to the clipboard, the TOTP code at time
+// 't' for the entry 'name'.
+func GetOTPFromVault(
+	cfg *model.Config,
+	name string,
+	copyFlag bool,
+	t time.Time,
+	keyring *model.MasterAESKeyManager,
+) error {
+	f, entries, err := utils.ReadVault(cfg.VaultName, keyring)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	idx, err := ResolveEntry(entries, name, os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	code, remaining, err := EntryOTP(entries[idx], t, keyring)
+	if err != nil {
+		return err
+	}
+
+	if copyFlag {
+		if err := clipboard.WriteAll(code); err != nil {
+			return fmt.Errorf("copying to clipboard: %v", err)
+		}
+		fmt.Printf("Copied code to clipboard! (expires in %s)\n", remaining)
+	} else {
+		fmt.Printf("Code: %s (expires in %s)\n", code, remaining)
+	}
+
+	return nil
+}
+
+// EntryOTP returns the TOTP code at time 't' for the entry, and how long it is
+// valid for.
+func EntryOTP(
+	e model.VaultEntry,
+	t time.Time,
+	key *model.MasterAESKeyManager,
+) (string, time.Duration, error) {
+	if len(e.TOTP) == 0 {
+		return "", 0, fmt.Errorf("'%s' has no TOTP secret", e.Name)
+	}
+
+	uri, err := crypt.DecryptPassword(e.TOTP, key)
+	if err != nil {
+		return "", 0, fmt.Errorf("decrypting TOTP secret: %v", err)
+	}
+
+	k, err := otp.Parse(uri)
+	if err != nil {
+		return "", 0, err
+	}
+
+	code, err := k.Code(t)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return code, k.Remaining(t), nil
+}
+
+// PromptTOTP prompts for a TOTP secret, without echoing it, and returns it
+// encrypted
+func PromptTOTP(r io.Reader, key *model.MasterAESKeyManager) ([]byte, error) {
+	secret, err := utils.GetSecretFromUser(r, "TOTP secret or otpauth:// URI")
+	if err != nil {
+		return nil, err
+	}
+
+	return EncryptTOTP(string(secret), key)
+}
+
+// EncryptTOTP parses a base32 TOTP secret or otpauth:// URI and returns it
+// encrypted, as an otpauth:// URI so its parameters are kept.
+func EncryptTOTP(secret string, key *model.MasterAESKeyManager) ([]byte, error) {
+	k, err := otp.Parse(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := crypt.EncryptPassword([]byte(k.URI()), key)
+	if err != nil {
+		return nil, fmt.Errorf("encrypting TOTP secret: %v", err)
+	}
+
+	return []byte(encrypted), nil
+}
@@ -0,0 +1,98 @@
+package vault
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+// rfcTOTP is the SHA1 seed from RFC 6238 appendix B, as a URI for 8 digit codes
+var rfcTOTP = "otpauth://totp/Example:alice?digits=8&secret=" +
+	base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestEntryOTP(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+
+	totp, err := EncryptTOTP(rfcTOTP, key)
+	assert.NoError(err)
+
+	// Stored encrypted, as a normalized URI
+	uri, err := crypt.DecryptPassword(totp, key)
+	assert.NoError(err)
+	assert.Contains(uri, "otpauth://totp/Example:alice?")
+
+	ve := model.VaultEntry{Name: vaultEntry1, TOTP: totp}
+	code, remaining, err := EntryOTP(ve, time.Unix(1111111109, 0), key)
+	assert.NoError(err)
+	assert.Equal("07081804", code)
+	assert.Equal(time.Second, remaining)
+
+	_, _, err = EntryOTP(model.VaultEntry{Name: vaultEntry2}, time.Now(), key)
+	assert.Error(err)
+
+	_, err = EncryptTOTP("not a secret!", key)
+	assert.Error(err)
+}
+
+func TestGetOTPFromVault(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+
+	cF, err := utils.CreateConfig(
+		testutils.TEST_VAULT_NAME,
+		testutils.TEST_MASTER_PASSWORD,
+		testutils.TEST_CONFIG_NAME,
+		key,
+	)
+	assert.NoError(err)
+	cF.Close()
+
+	vF, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	vF.Close()
+
+	cfg := &model.Config{
+		VaultName:      testutils.TEST_VAULT_NAME,
+		MasterPassword: testutils.TEST_MASTER_PASSWORD,
+		LastVisited:    time.Now().UnixMilli(),
+	}
+
+	totp, err := EncryptTOTP(rfcTOTP, key)
+	assert.NoError(err)
+
+	now := time.Now().UnixMilli()
+	assert.NoError(AddToVault(vaultEntry1, model.UserInput{
+		Username: vaultEntry1,
+		Password: []byte(vaultEntry1),
+		TOTP:     totp,
+	}, cfg, now, key))
+	assert.NoError(AddToVault(vaultEntry2, model.UserInput{
+		Username: vaultEntry2,
+		Password: []byte(vaultEntry2),
+	}, cfg, now, key))
+
+	assert.NoError(GetOTPFromVault(cfg, vaultEntry1, false, time.Unix(59, 0), key))
+	assert.Error(GetOTPFromVault(cfg, vaultEntry2, false, time.Now(), key))
+	assert.Error(GetOTPFromVault(cfg, "notExist", false, time.Now(), key))
+
+	// Removing the secret
+	assert.NoError(UpdateEntry(Inputs{RemoveTOTP: true}, cfg, vaultEntry1, InputSources{}, key))
+	assert.Error(GetOTPFromVault(cfg, vaultEntry1, false, time.Now(), key))
+	assert.Error(UpdateEntry(Inputs{RemoveTOTP: true}, cfg, vaultEntry1, InputSources{}, key))
+}
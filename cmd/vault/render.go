@@ -0,0 +1,399 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package vault
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"go-pass/agent"
+	"go-pass/credential"
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+const (
+	RENDER_NETRC  = "netrc"
+	RENDER_PGPASS = "pgpass"
+
+	// The custom fields an entry can set, instead of its URL and username,
+	// for the files 'render' writes
+	HOST_FIELD     = "host"
+	PORT_FIELD     = "port"
+	LOGIN_FIELD    = "login"
+	DATABASE_FIELD = "database"
+
+	// DEFAULT_WATCH_INTERVAL is how often 'render --watch' checks the vault
+	DEFAULT_WATCH_INTERVAL = 2 * time.Second
+)
+
+// RenderFormats are the files 'render' can write
+var RenderFormats = []string{RENDER_NETRC, RENDER_PGPASS}
+
+// renderCmd represents the render command
+var RenderCmd = &cobra.Command{
+	Use:   "render <netrc|pgpass>",
+	Short: "Writes a .netrc or .pgpass file from the entries in your vault",
+	Long: fmt.Sprintf(`'render' writes the logins in your vault as a .netrc file, for curl, ftp and
+git, or a .pgpass file, for psql and libpq, to the '--out' file, readable only
+by you, or to stdout. With '--tag', only entries with the tag are written.
+
+Each entry with a host is written. The host, port and login come from the
+entry's custom fields '%s', '%s' and '%s' if it has them, or else from its
+URL and username:
+	netrc    the host of the first URL
+	pgpass   the host, port and database of the first postgres:// URL. The
+	         database can also be set with the '%s' field, and the port
+	         and database are '*', any, if neither sets them.
+
+With '--watch', the file is written again whenever the vault changes, until
+'render' is interrupted.
+
+Ex.
+	$ gopass vault add prod-db --url postgres://db.example.com:5432/app --tag pg
+	$ gopass vault render pgpass --tag pg --out ~/.pgpass
+	Wrote 1 entries to '/home/me/.pgpass'.
+
+	$ gopass vault render netrc --out ~/.netrc --watch
+`, HOST_FIELD, PORT_FIELD, LOGIN_FIELD, DATABASE_FIELD),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := RenderCmdHandler(cmd, args); err != nil {
+			// stdout may be the rendered file
+			fmt.Fprintf(os.Stderr, "Error with 'render' command: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// RenderCmdHandler is the handler function that encapsulates RenderFile and
+// WatchRender.
+func RenderCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("exactly 1 argument, the file to render, is needed. see 'help' for correct usage")
+	}
+	format := args[0]
+	if !slices.Contains(RenderFormats, format) {
+		return fmt.Errorf(
+			"unknown file '%s', must be one of: %s",
+			format, strings.Join(RenderFormats, ", "),
+		)
+	}
+
+	tag, err := cmd.Flags().GetString("tag")
+	if err != nil {
+		return fmt.Errorf("render::getting string from flag: %v", err)
+	}
+	outPath, err := cmd.Flags().GetString("out")
+	if err != nil {
+		return fmt.Errorf("render::getting string from flag: %v", err)
+	}
+	watch, err := cmd.Flags().GetBool("watch")
+	if err != nil {
+		return fmt.Errorf("render::getting bool from flag: %v", err)
+	}
+	if watch && outPath == "" {
+		return errors.New("'--watch' needs a file to write to with '--out'")
+	}
+
+	// Everything but the rendered file goes to stderr
+	keyring, err := agent.PromptKeyManager(os.Stderr, os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer keyring.Close()
+
+	cfg, err := utils.CheckConfig("", keyring)
+	if err != nil {
+		return fmt.Errorf("error checking config: %v", err)
+	}
+
+	if watch {
+		stop := make(chan struct{})
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sigs)
+		go func() {
+			<-sigs
+			close(stop)
+		}()
+
+		fmt.Fprintf(os.Stderr, "Watching the vault, writing '%s' when it changes.\n", outPath)
+		return WatchRender(cfg, format, tag, outPath, DEFAULT_WATCH_INTERVAL, stop, keyring)
+	}
+
+	var b bytes.Buffer
+	n, err := RenderFile(cfg, &b, format, tag, keyring)
+	if err != nil {
+		return err
+	}
+
+	if outPath == "" {
+		_, err := os.Stdout.Write(b.Bytes())
+		return err
+	}
+	if err := WriteRenderedFile(outPath, b.Bytes()); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d entries to '%s'.\n", n, outPath)
+	return nil
+}
+
+// RenderFile writes the entries of the vault with 'tag', or every entry if it
+// is empty, to 'w' in the format of the file. It returns how many entries
+// were written.
+func RenderFile(
+	cfg *model.Config,
+	w io.Writer,
+	format, tag string,
+	key *model.MasterAESKeyManager,
+) (int, error) {
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	if err != nil {
+		return 0, err
+	}
+	f.Close()
+
+	line := netrcLine
+	if format == RENDER_PGPASS {
+		line = pgpassLine
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, e := range entries {
+		if tag != "" && !slices.Contains(e.Tags, tag) {
+			continue
+		}
+
+		l, err := line(e, key)
+		if err != nil {
+			return 0, fmt.Errorf("'%s': %v", e.Name, err)
+		}
+		if l == "" {
+			continue
+		}
+		b.WriteString(l + "\n")
+		n++
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return n, err
+}
+
+// WatchRender writes the file to 'outPath' with RenderFile, and again each
+// time the vault changes, checking every 'interval' until 'stop' is closed.
+// The file is only rewritten if what would be in it changed.
+func WatchRender(
+	cfg *model.Config,
+	format, tag, outPath string,
+	interval time.Duration,
+	stop <-chan struct{},
+	key *model.MasterAESKeyManager,
+) error {
+	var last os.FileInfo
+	var written []byte
+	wrote := false
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		info, err := vaultFileInfo(cfg.VaultName)
+		if err != nil {
+			return err
+		}
+
+		if last == nil || !info.ModTime().Equal(last.ModTime()) || info.Size() != last.Size() {
+			last = info
+
+			var b bytes.Buffer
+			n, err := RenderFile(cfg, &b, format, tag, key)
+			if err != nil {
+				return err
+			}
+			if !wrote || !bytes.Equal(b.Bytes(), written) {
+				if err := WriteRenderedFile(outPath, b.Bytes()); err != nil {
+					return err
+				}
+				written, wrote = b.Bytes(), true
+				fmt.Fprintf(os.Stderr, "Wrote %d entries to '%s'.\n", n, outPath)
+			}
+		}
+
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func vaultFileInfo(name string) (os.FileInfo, error) {
+	f, err := utils.OpenVault(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+// WriteRenderedFile writes 'b' to 'path', readable only by the current user.
+// It is written to a temporary file that replaces 'path', so programs reading
+// it never see half of it.
+func WriteRenderedFile(path string, b []byte) error {
+	// The temporary file is created readable only by the current user
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"_*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// netrcLine returns the 'machine' line of an entry, or "" if it has no host
+func netrcLine(e model.VaultEntry, key *model.MasterAESKeyManager) (string, error) {
+	host, err := optionalField(e, HOST_FIELD, key)
+	if err != nil {
+		return "", err
+	}
+	if host == "" && len(e.URLs) > 0 {
+		if q, err := credential.ParseURL(e.URLs[0]); err == nil {
+			host = q.Host
+			if h, _, err := net.SplitHostPort(q.Host); err == nil {
+				host = h
+			}
+		}
+	}
+	if host == "" {
+		return "", nil
+	}
+
+	login, password, err := renderLogin(e, key)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(
+		"machine %s login %s password %s",
+		netrcQuote(host), netrcQuote(login), netrcQuote(password),
+	), nil
+}
+
+// netrcQuote quotes a token that is empty or has spaces, quotes or
+// backslashes, the way curl and Python's netrc read them
+func netrcQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\r\n\"\\") {
+		return s
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// pgpassLine returns the 'host:port:database:user:password' line of an
+// entry, or "" if it has no host
+func pgpassLine(e model.VaultEntry, key *model.MasterAESKeyManager) (string, error) {
+	var host, port, database string
+	for _, raw := range e.URLs {
+		q, err := credential.ParseURL(raw)
+		if err != nil || (q.Protocol != "postgres" && q.Protocol != "postgresql") {
+			continue
+		}
+
+		host = q.Host
+		if h, p, err := net.SplitHostPort(q.Host); err == nil {
+			host, port = h, p
+		}
+		database = q.Path
+		break
+	}
+
+	for field, value := range map[string]*string{
+		HOST_FIELD:     &host,
+		PORT_FIELD:     &port,
+		DATABASE_FIELD: &database,
+	} {
+		v, err := optionalField(e, field, key)
+		if err != nil {
+			return "", err
+		}
+		if v != "" {
+			*value = v
+		}
+	}
+	if host == "" {
+		return "", nil
+	}
+
+	login, password, err := renderLogin(e, key)
+	if err != nil {
+		return "", err
+	}
+
+	values := []string{host, cmp.Or(port, "*"), cmp.Or(database, "*"), login, password}
+	for i, v := range values {
+		if strings.ContainsAny(v, "\r\n") {
+			return "", errors.New("a .pgpass line can't have a line break in it")
+		}
+		// '*' is kept as is, to match anything
+		if (i == 1 || i == 2) && v == "*" {
+			continue
+		}
+		values[i] = pgpassEscape(v)
+	}
+	return strings.Join(values, ":"), nil
+}
+
+// pgpassEscape escapes the colons and backslashes in a .pgpass value
+func pgpassEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `:`, `\:`).Replace(s)
+}
+
+// renderLogin returns the login, the LOGIN_FIELD field or else the username,
+// and the password of an entry
+func renderLogin(e model.VaultEntry, key *model.MasterAESKeyManager) (string, string, error) {
+	login, err := optionalField(e, LOGIN_FIELD, key)
+	if err != nil {
+		return "", "", err
+	}
+	if login == "" {
+		login = e.Username
+	}
+
+	password, err := EntryField(e, DEFAULT_SECRET_FIELD, key)
+	if err != nil {
+		return "", "", err
+	}
+	return login, password, nil
+}
+
+// optionalField returns the value of a custom field, or "" if the entry
+// doesn't have it
+func optionalField(e model.VaultEntry, name string, key *model.MasterAESKeyManager) (string, error) {
+	v, err := EntryCustomField(e, name, key)
+	if errors.Is(err, ErrNoField) {
+		return "", nil
+	}
+	return v, err
+}
@@ -0,0 +1,198 @@
+package vault
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+func TestRenderLines(t *testing.T) {
+	key := model.NewMasterAESKeyManagerFromKey(make([]byte, model.KEY_SIZE), model.DefaultKDF())
+
+	entry := func(username, password string, urls []string, fields ...model.CustomField) model.VaultEntry {
+		t.Helper()
+		pass, err := crypt.EncryptPassword([]byte(password), key)
+		assert.NoError(t, err)
+		fields, err = crypt.EncryptFields(fields, key)
+		assert.NoError(t, err)
+		return model.VaultEntry{
+			Name:     "entry",
+			Username: username,
+			Password: []byte(pass),
+			Metadata: model.Metadata{URLs: urls, Fields: fields},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		entry  model.VaultEntry
+		netrc  string
+		pgpass string
+	}{
+		{
+			name:   "web login",
+			entry:  entry("me", "hunter2", []string{"https://api.github.com:443/v3"}),
+			netrc:  "machine api.github.com login me password hunter2",
+			pgpass: "",
+		},
+		{
+			name:   "postgres url",
+			entry:  entry("app", "pw", []string{"https://admin.example.com", "postgres://db.example.com:5433/orders"}),
+			netrc:  "machine admin.example.com login app password pw",
+			pgpass: "db.example.com:5433:orders:app:pw",
+		},
+		{
+			name:   "no port or database",
+			entry:  entry("app", "pw", []string{"postgresql://db.example.com"}),
+			netrc:  "machine db.example.com login app password pw",
+			pgpass: "db.example.com:*:*:app:pw",
+		},
+		{
+			name: "fields override the url and username",
+			entry: entry("me", "pw", []string{"postgres://db.example.com/orders"},
+				model.CustomField{Name: HOST_FIELD, Value: "replica.example.com"},
+				model.CustomField{Name: PORT_FIELD, Value: "6432"},
+				model.CustomField{Name: LOGIN_FIELD, Value: "reader", Secret: true},
+			),
+			netrc:  "machine replica.example.com login reader password pw",
+			pgpass: "replica.example.com:6432:orders:reader:pw",
+		},
+		{
+			name:   "escaping",
+			entry:  entry("a b", `p:a\s"s`, []string{"postgres://db.example.com"}),
+			netrc:  `machine db.example.com login "a b" password "p:a\\s\"s"`,
+			pgpass: `db.example.com:*:*:a b:p\:a\\s"s`,
+		},
+		{
+			name:   "empty password",
+			entry:  entry("me", "", []string{"ftp://files.example.com"}),
+			netrc:  `machine files.example.com login me password ""`,
+			pgpass: "",
+		},
+		{
+			name:   "no host",
+			entry:  entry("me", "pw", nil),
+			netrc:  "",
+			pgpass: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			netrc, err := netrcLine(tt.entry, key)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.netrc, netrc)
+
+			pgpass, err := pgpassLine(tt.entry, key)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.pgpass, pgpass)
+		})
+	}
+
+	_, err := pgpassLine(entry("me", "line\nbreak", []string{"postgres://db"}), key)
+	assert.Error(t, err)
+}
+
+func TestRenderFile(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+	vaultFile, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	vaultFile.Close()
+	cfg := &model.Config{VaultName: testutils.TEST_VAULT_NAME}
+
+	add := func(name, password string, m model.Metadata) {
+		pass, err := crypt.EncryptPassword([]byte(password), key)
+		assert.NoError(err)
+		assert.NoError(AddToVault(name, model.UserInput{
+			Username: "me",
+			Password: []byte(pass),
+			Metadata: m,
+		}, cfg, 1, key))
+	}
+	add("github", "gh", model.Metadata{URLs: []string{"https://github.com"}, Tags: []string{"dev"}})
+	add("gitlab", "gl", model.Metadata{URLs: []string{"https://gitlab.com"}})
+	add("notes", "x", model.Metadata{Tags: []string{"dev"}})
+
+	var b bytes.Buffer
+	n, err := RenderFile(cfg, &b, RENDER_NETRC, "", key)
+	assert.NoError(err)
+	assert.Equal(2, n)
+	assert.Equal(
+		"machine github.com login me password gh\nmachine gitlab.com login me password gl\n",
+		b.String(),
+	)
+
+	b.Reset()
+	n, err = RenderFile(cfg, &b, RENDER_NETRC, "dev", key)
+	assert.NoError(err)
+	assert.Equal(1, n)
+	assert.Equal("machine github.com login me password gh\n", b.String())
+
+	// The file replaces one that was readable by anyone
+	out := path.Join(t.TempDir(), ".netrc")
+	assert.NoError(os.WriteFile(out, []byte("old"), 0o644))
+	assert.NoError(WriteRenderedFile(out, b.Bytes()))
+	info, err := os.Stat(out)
+	assert.NoError(err)
+	assert.Equal(os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestWatchRender(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+	vaultFile, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	vaultFile.Close()
+	cfg := &model.Config{VaultName: testutils.TEST_VAULT_NAME}
+
+	out := path.Join(t.TempDir(), ".pgpass")
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- WatchRender(cfg, RENDER_PGPASS, "", out, 10*time.Millisecond, stop, key)
+	}()
+
+	read := func() string {
+		b, _ := os.ReadFile(out)
+		return string(b)
+	}
+	assert.Eventually(func() bool { return read() == "" && fileExists(out) }, time.Second, 10*time.Millisecond)
+
+	pass, err := crypt.EncryptPassword([]byte("pw"), key)
+	assert.NoError(err)
+	assert.NoError(AddToVault("db", model.UserInput{
+		Username: "app",
+		Password: []byte(pass),
+		Metadata: model.Metadata{URLs: []string{"postgres://db.example.com/app"}},
+	}, cfg, 2, key))
+
+	assert.Eventually(func() bool {
+		return read() == "db.example.com:*:app:app:pw\n"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	close(stop)
+	assert.NoError(<-done)
+}
+
+func fileExists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
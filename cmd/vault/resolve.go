@@ -0,0 +1,104 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+// MIN_ID_PREFIX is the shortest ID prefix that is accepted in place of a full
+// ID
+const MIN_ID_PREFIX = 8
+
+var (
+	// ErrNotFound is returned when no entry matches a name or ID
+	ErrNotFound = errors.New("not found in vault")
+	// ErrDuplicateEntry is returned by AddToVault when an entry with the same
+	// name and username already exists
+	ErrDuplicateEntry = errors.New("an entry with this name and username already exists")
+)
+
+// MatchEntries returns the indexes of the entries that 'query' refers to. An ID,
+// or a prefix of one at least MIN_ID_PREFIX long, takes precedence over names.
+func MatchEntries(entries []model.VaultEntry, query string) []int {
+	var byID, byName []int
+	for i, e := range entries {
+		if e.ID != "" && e.ID == query {
+			return []int{i}
+		}
+		if e.ID != "" && len(query) >= MIN_ID_PREFIX && strings.HasPrefix(e.ID, query) {
+			byID = append(byID, i)
+		}
+		if e.Name == query {
+			byName = append(byName, i)
+		}
+	}
+
+	if len(byID) > 0 {
+		return byID
+	}
+	return byName
+}
+
+// ResolveEntry returns the index of the entry that 'query' refers to, see
+// MatchEntries. If several entries match, the user picks one from a list read
+// from 'r'. If 'r' is nil, an error listing the matching IDs is returned
+// instead.
+func ResolveEntry(entries []model.VaultEntry, query string, r io.Reader) (int, error) {
+	matches := MatchEntries(entries, query)
+
+	switch len(matches) {
+	case 0:
+		return -1, fmt.Errorf("'%s' %w", query, ErrNotFound)
+	case 1:
+		return matches[0], nil
+	}
+
+	if r == nil {
+		ids := make([]string, len(matches))
+		for i, idx := range matches {
+			ids[i] = entries[idx].ID
+		}
+		return -1, fmt.Errorf(
+			"'%s' matches %d entries, use one of their IDs: %s",
+			query, len(matches), strings.Join(ids, ", "),
+		)
+	}
+
+	fmt.Printf("'%s' matches %d entries:\n", query, len(matches))
+	for i, idx := range matches {
+		e := entries[idx]
+		fmt.Printf("\t%d) %s\t%s\t%s\n", i+1, e.Name, e.Username, e.ID)
+	}
+
+	choice, err := utils.GetInputFromUser(r, fmt.Sprintf("Choose an entry (1-%d)", len(matches)))
+	if err != nil {
+		return -1, fmt.Errorf("reading choice: %v", err)
+	}
+
+	n, err := strconv.Atoi(choice)
+	if err != nil || n < 1 || n > len(matches) {
+		return -1, fmt.Errorf("invalid choice '%s'", choice)
+	}
+
+	return matches[n-1], nil
+}
+
+// FindDuplicate returns the index of the entry with the same name and username,
+// or -1 if there is none
+func FindDuplicate(entries []model.VaultEntry, name, username string) int {
+	for i, e := range entries {
+		if e.Name == name && e.Username == username {
+			return i
+		}
+	}
+	return -1
+}
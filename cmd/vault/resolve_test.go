@@ -0,0 +1,145 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+func TestMatchEntries(t *testing.T) {
+	entries := []model.VaultEntry{
+		{ID: "11111111-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "github", Username: "me"},
+		{ID: "11111111-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "github", Username: "work"},
+		{ID: "22222222-cccc-4ccc-8ccc-cccccccccccc", Name: "gitlab", Username: "me"},
+		{Name: "old"},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []int
+	}{
+		{name: "unique name", query: "gitlab", want: []int{2}},
+		{name: "duplicate name", query: "github", want: []int{0, 1}},
+		{name: "full ID", query: "11111111-bbbb-4bbb-8bbb-bbbbbbbbbbbb", want: []int{1}},
+		{name: "ID prefix", query: "22222222", want: []int{2}},
+		{name: "ambiguous ID prefix", query: "11111111-", want: []int{0, 1}},
+		{name: "too short a prefix", query: "2222", want: nil},
+		{name: "entry without an ID", query: "old", want: []int{3}},
+		{name: "not found", query: "nothing", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MatchEntries(entries, tt.query))
+		})
+	}
+}
+
+func TestResolveEntry(t *testing.T) {
+	assert := assert.New(t)
+	entries := []model.VaultEntry{
+		{ID: "11111111-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "github", Username: "me"},
+		{ID: "11111111-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "github", Username: "work"},
+	}
+
+	idx, err := ResolveEntry(entries, "11111111-bbbb", nil)
+	assert.NoError(err)
+	assert.Equal(1, idx)
+
+	idx, err = ResolveEntry(entries, "github", strings.NewReader("2\n"))
+	assert.NoError(err)
+	assert.Equal(1, idx)
+
+	_, err = ResolveEntry(entries, "github", strings.NewReader("3\n"))
+	assert.Error(err)
+
+	// Without a reader, the IDs are listed instead
+	_, err = ResolveEntry(entries, "github", nil)
+	assert.ErrorContains(err, entries[0].ID)
+	assert.ErrorContains(err, entries[1].ID)
+
+	_, err = ResolveEntry(entries, "nothing", nil)
+	assert.ErrorIs(err, ErrNotFound)
+}
+
+func TestDuplicateEntries(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+
+	cF, err := utils.CreateConfig(
+		testutils.TEST_VAULT_NAME,
+		testutils.TEST_MASTER_PASSWORD,
+		testutils.TEST_CONFIG_NAME,
+		key,
+	)
+	assert.NoError(err)
+	cF.Close()
+
+	vF, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	vF.Close()
+
+	cfg := &model.Config{
+		VaultName:      testutils.TEST_VAULT_NAME,
+		MasterPassword: testutils.TEST_MASTER_PASSWORD,
+		LastVisited:    time.Now().UnixMilli(),
+	}
+
+	now := time.Now().UnixMilli()
+	ui := model.UserInput{Username: vaultEntry1, Password: []byte(vaultEntry1)}
+	assert.NoError(AddToVault(vaultEntry1, ui, cfg, now, key))
+
+	// The same name and username is refused, unless forced
+	err = AddToVault(vaultEntry1, ui, cfg, now, key)
+	assert.ErrorIs(err, ErrDuplicateEntry)
+	assert.NoError(ForceAddToVault(vaultEntry1, ui, cfg, now, key))
+
+	// The same name with another username is fine
+	assert.NoError(AddToVault(vaultEntry1, model.UserInput{
+		Username: vaultEntry2,
+		Password: []byte(vaultEntry2),
+	}, cfg, now, key))
+
+	f, entries, err := utils.ReadVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	f.Close()
+	assert.Len(entries, 3)
+	assert.NotEqual(entries[0].ID, entries[1].ID)
+
+	// An ambiguous name is refused when there is no one to ask
+	err = UpdateEntry(
+		Inputs{Metadata: MetadataFlags{Folder: "a", SetFolder: true}},
+		cfg, vaultEntry1, InputSources{}, key,
+	)
+	assert.Error(err)
+
+	// The ID always works
+	err = UpdateEntry(
+		Inputs{Metadata: MetadataFlags{Folder: "a", SetFolder: true}},
+		cfg, entries[1].ID, InputSources{}, key,
+	)
+	assert.NoError(err)
+
+	// Choosing the third match, then confirming
+	err = DeleteItemInVault(cfg, vaultEntry1, strings.NewReader("3\ny\n"), key)
+	assert.NoError(err)
+
+	f, remaining, err := utils.ReadVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	f.Close()
+	assert.Len(remaining, 2)
+	assert.Equal(entries[0].ID, remaining[0].ID)
+	assert.Equal(entries[1].ID, remaining[1].ID)
+	assert.Equal("a", remaining[1].Folder)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/charmbracelet/huh"
 	"github.com/spf13/cobra"
 
+	"go-pass/agent"
 	"go-pass/crypt"
 	"go-pass/model"
 	"go-pass/utils"
@@ -55,12 +56,11 @@ func RestoreCmdHandler(cmd *cobra.Command, args []string) error {
 		)
 	}
 
-	passB, err := utils.GetPasswordFromUser(true, os.Stdin)
+	keyring, err := agent.GetKeyManager(os.Stdin)
 	if err != nil {
 		return err
 	}
-
-	keyring := model.NewMasterAESKeyManager(string(passB))
+	defer keyring.Close()
 
 	cfg, err := utils.CheckConfig("", keyring)
 	if err != nil {
@@ -80,7 +80,7 @@ func RestoreVault(vaultName string, test bool, key *model.MasterAESKeyManager) e
 		return fmt.Errorf("vault already exists")
 	}
 
-	entries, err := os.ReadDir(utils.BACKUP_PATH)
+	entries, err := utils.ReadBackups()
 	if err != nil {
 		return err
 	}
@@ -115,7 +115,7 @@ func RestoreVault(vaultName string, test bool, key *model.MasterAESKeyManager) e
 	defer restoreFp.Close()
 
 	// Get plaintext from file
-	backupEntries, err := crypt.DecryptVault(restoreFp, key, false)
+	backupEntries, err := crypt.DecryptVault(restoreFp, key)
 	if err != nil {
 		return err
 	}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"go-pass/agent"
 	"go-pass/crypt"
 	"go-pass/model"
 	"go-pass/utils"
@@ -49,12 +50,11 @@ func SearchCmdHandler(cmd *cobra.Command, args []string) error {
 		)
 	}
 
-	passB, err := utils.GetPasswordFromUser(true, os.Stdin)
+	keyring, err := agent.GetKeyManager(os.Stdin)
 	if err != nil {
 		return err
 	}
-
-	keyring := model.NewMasterAESKeyManager(string(passB))
+	defer keyring.Close()
 
 	cfg, err := utils.CheckConfig("", keyring)
 	if err != nil {
@@ -79,7 +79,7 @@ func SearchVault(searchTerm string, cfg *model.Config, key *model.MasterAESKeyMa
 	}
 	defer f.Close()
 
-	entries, err := crypt.DecryptVault(f, key, false)
+	entries, err := crypt.DecryptVault(f, key)
 	if err != nil {
 		return fmt.Errorf("decrypting vault: %v", err)
 	}
@@ -0,0 +1,147 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go-pass/crypt"
+	"go-pass/model"
+)
+
+// DEFAULT_SECRET_FIELD is the field a secret reference without one refers to
+const DEFAULT_SECRET_FIELD = "password"
+
+// ErrNoField is returned when an entry has no custom field with a name
+var ErrNoField = errors.New("has no field named")
+
+// secretFields are the fields of an entry a secret reference can name, other
+// than its custom fields, and how each is read
+var secretFields = map[string]func(model.VaultEntry, *model.MasterAESKeyManager) (string, error){
+	"id":       func(e model.VaultEntry, _ *model.MasterAESKeyManager) (string, error) { return e.ID, nil },
+	"name":     func(e model.VaultEntry, _ *model.MasterAESKeyManager) (string, error) { return e.Name, nil },
+	"username": func(e model.VaultEntry, _ *model.MasterAESKeyManager) (string, error) { return e.Username, nil },
+	"notes":    func(e model.VaultEntry, _ *model.MasterAESKeyManager) (string, error) { return e.Notes, nil },
+	"folder":   func(e model.VaultEntry, _ *model.MasterAESKeyManager) (string, error) { return e.Folder, nil },
+	"url": func(e model.VaultEntry, _ *model.MasterAESKeyManager) (string, error) {
+		if len(e.URLs) == 0 {
+			return "", fmt.Errorf("'%s' has no URL", e.Name)
+		}
+		return e.URLs[0], nil
+	},
+	"password": func(e model.VaultEntry, key *model.MasterAESKeyManager) (string, error) {
+		password, err := crypt.DecryptPassword(e.Password, key)
+		if err != nil {
+			return "", fmt.Errorf("decrypting password: %v", err)
+		}
+		return password, nil
+	},
+	"otp": func(e model.VaultEntry, key *model.MasterAESKeyManager) (string, error) {
+		code, _, err := EntryOTP(e, time.Now(), key)
+		return code, err
+	},
+}
+
+// SecretFieldNames returns the names of the fields every entry has for a
+// secret reference, sorted
+func SecretFieldNames() []string {
+	names := make([]string, 0, len(secretFields))
+	for name := range secretFields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SecretRef refers to one field of an entry, written 'entry:field'
+type SecretRef struct {
+	// Entry is the name or ID of the entry, as given to 'get'
+	Entry string
+	// Field is one of SecretFieldNames, or the name of a custom field
+	Field string
+}
+
+// ParseSecretRef parses a reference written 'entry:field', or just 'entry'
+// for its password. The entry is everything before the last colon, so names
+// with colons in them still work.
+func ParseSecretRef(s string) (SecretRef, error) {
+	ref := SecretRef{Entry: s, Field: DEFAULT_SECRET_FIELD}
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		ref.Entry, ref.Field = s[:i], s[i+1:]
+	}
+
+	if ref.Entry == "" {
+		return SecretRef{}, fmt.Errorf("'%s' has no entry", s)
+	}
+	if ref.Field == "" {
+		return SecretRef{}, fmt.Errorf("'%s' has no field", s)
+	}
+	return ref, nil
+}
+
+func (r SecretRef) String() string {
+	return r.Entry + ":" + r.Field
+}
+
+// ResolveSecret returns the value of the field 'ref' refers to, decrypted.
+// The entry is found the same way 'get' finds it, see ResolveEntry, except an
+// ambiguous name is an error rather than a prompt.
+func ResolveSecret(
+	entries []model.VaultEntry,
+	ref SecretRef,
+	key *model.MasterAESKeyManager,
+) (string, error) {
+	idx, err := ResolveEntry(entries, ref.Entry, nil)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := EntryField(entries[idx], ref.Field, key)
+	if err != nil {
+		return "", fmt.Errorf("'%s': %v", ref, err)
+	}
+	return value, nil
+}
+
+// EntryField returns the value of the named field of 'e', decrypted. The
+// fields in SecretFieldNames come first, then the entry's custom fields.
+func EntryField(e model.VaultEntry, field string, key *model.MasterAESKeyManager) (string, error) {
+	if get, ok := secretFields[field]; ok {
+		return get(e, key)
+	}
+
+	value, err := EntryCustomField(e, field, key)
+	if errors.Is(err, ErrNoField) {
+		return "", fmt.Errorf(
+			"no field named '%s', must be a custom field or one of: %s",
+			field, strings.Join(SecretFieldNames(), ", "),
+		)
+	}
+	return value, err
+}
+
+// EntryCustomField returns the value of the custom field of 'e' named
+// 'name', decrypted. It returns ErrNoField if 'e' has no such field.
+func EntryCustomField(e model.VaultEntry, name string, key *model.MasterAESKeyManager) (string, error) {
+	for _, f := range e.Fields {
+		if f.Name != name {
+			continue
+		}
+		if !f.Secret {
+			return f.Value, nil
+		}
+
+		value, err := crypt.DecryptPassword([]byte(f.Value), key)
+		if err != nil {
+			return "", fmt.Errorf("decrypting field '%s': %v", name, err)
+		}
+		return value, nil
+	}
+
+	return "", fmt.Errorf("'%s' %w '%s'", e.Name, ErrNoField, name)
+}
@@ -0,0 +1,94 @@
+package vault
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/crypt"
+	"go-pass/model"
+)
+
+func TestParseSecretRef(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    SecretRef
+		wantErr bool
+	}{
+		{name: "entry and field", input: "prod-db:username", want: SecretRef{"prod-db", "username"}},
+		{name: "password by default", input: "prod-db", want: SecretRef{"prod-db", DEFAULT_SECRET_FIELD}},
+		{name: "colon in name", input: "host:5432:password", want: SecretRef{"host:5432", "password"}},
+		{name: "no entry", input: ":password", wantErr: true},
+		{name: "no field", input: "prod-db:", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := ParseSecretRef(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, ref)
+		})
+	}
+}
+
+func TestResolveSecret(t *testing.T) {
+	assert := assert.New(t)
+	key := model.NewMasterAESKeyManagerFromKey(make([]byte, model.KEY_SIZE), model.DefaultKDF())
+
+	pass, err := crypt.EncryptPassword([]byte("s3cret"), key)
+	assert.NoError(err)
+	fields, err := crypt.EncryptFields([]model.CustomField{
+		{Name: "api_key", Value: "sk_live_123", Secret: true},
+		{Name: "region", Value: "eu"},
+	}, key)
+	assert.NoError(err)
+
+	entries := []model.VaultEntry{
+		{
+			ID:       "11111111-aaaa",
+			Name:     "stripe",
+			Username: "billing",
+			Password: []byte(pass),
+			Metadata: model.Metadata{URLs: []string{"https://stripe.com"}, Fields: fields},
+		},
+		{ID: "22222222-bbbb", Name: "dup", Password: []byte(pass)},
+		{ID: "33333333-cccc", Name: "dup", Password: []byte(pass)},
+	}
+
+	resolve := func(s string) (string, error) {
+		ref, err := ParseSecretRef(s)
+		assert.NoError(err)
+		return ResolveSecret(entries, ref, key)
+	}
+
+	for ref, want := range map[string]string{
+		"stripe":             "s3cret",
+		"stripe:username":    "billing",
+		"stripe:url":         "https://stripe.com",
+		"stripe:api_key":     "sk_live_123",
+		"stripe:region":      "eu",
+		"11111111-aaaa:id":   "11111111-aaaa",
+		"22222222:password":  "s3cret",
+		"33333333-cccc:name": "dup",
+	} {
+		value, err := resolve(ref)
+		assert.NoError(err, ref)
+		assert.Equal(want, value, ref)
+	}
+
+	_, err = resolve("stripe:missing")
+	assert.ErrorContains(err, "no field named 'missing'")
+	_, err = resolve("stripe:otp")
+	assert.Error(err)
+	_, err = resolve("missing")
+	assert.ErrorIs(err, ErrNotFound)
+
+	// An ambiguous name is refused, rather than prompted for
+	_, err = resolve("dup")
+	assert.ErrorContains(err, "matches 2 entries")
+}
@@ -0,0 +1,139 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package vault
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/sshagent"
+	"go-pass/utils"
+)
+
+// SSH_PUBLIC_KEY_FIELD is the custom field an SSH key entry keeps its public
+// key in, as a line for authorized_keys
+const SSH_PUBLIC_KEY_FIELD = "public key"
+
+// GetSSHKeyInput reads the private key at 'keyPath' and returns it, encrypted,
+// as the input for an SSH key entry. The key's passphrase, if it has one, is
+// prompted for on 'pp', and the username and notes on 'us' and 'no'. The
+// entry's password is left empty.
+func GetSSHKeyInput(
+	name, keyPath string,
+	us, pp, no io.Reader,
+	key *model.MasterAESKeyManager,
+) (model.UserInput, error) {
+	pemBytes, err := os.ReadFile(keyPath)
+	if err != nil {
+		return model.UserInput{}, fmt.Errorf("reading SSH key: %v", err)
+	}
+
+	sshKey, pub, err := EncryptSSHKey(name, pemBytes, func() ([]byte, error) {
+		return utils.GetSecretFromUser(pp, fmt.Sprintf("Passphrase for %s", keyPath))
+	}, key)
+	if err != nil {
+		return model.UserInput{}, err
+	}
+
+	username, err := utils.GetInputFromUser(us, "Username")
+	if err != nil {
+		return model.UserInput{}, err
+	}
+	notes, err := utils.GetInputFromUser(no, "Notes")
+	if err != nil {
+		return model.UserInput{}, err
+	}
+
+	pass, err := crypt.EncryptPassword([]byte(""), key)
+	if err != nil {
+		return model.UserInput{}, err
+	}
+
+	ui := model.UserInput{
+		Username: username,
+		Password: []byte(pass),
+		Notes:    notes,
+		SSHKey:   sshKey,
+	}
+	ui.SetField(model.CustomField{
+		Name:  SSH_PUBLIC_KEY_FIELD,
+		Value: strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub))),
+	})
+	return ui, nil
+}
+
+// EncryptSSHKey parses a PEM encoded private key, asking 'passphrase' for its
+// passphrase if it has one, and returns it encrypted along with its public
+// key. The passphrase is dropped, as the vault's encryption takes its place,
+// and 'name' becomes the key's comment.
+func EncryptSSHKey(
+	name string,
+	pemBytes []byte,
+	passphrase sshagent.PassphraseFunc,
+	key *model.MasterAESKeyManager,
+) ([]byte, ssh.PublicKey, error) {
+	privateKey, err := sshagent.ParsePrivateKey(pemBytes, passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pub, err := sshagent.PublicKey(privateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unsupported SSH key: %v", err)
+	}
+
+	unencrypted, err := sshagent.MarshalPrivateKey(privateKey, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encrypted, err := crypt.EncryptPassword(unencrypted, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encrypting SSH key: %v", err)
+	}
+
+	return []byte(encrypted), pub, nil
+}
+
+// SSHKeyFingerprint returns the type and SHA256 fingerprint of an entry's SSH
+// key, like 'ssh-add -l' shows them
+func SSHKeyFingerprint(e model.VaultEntry, key *model.MasterAESKeyManager) (string, error) {
+	k, err := DecryptSSHKey(e, key)
+	if err != nil {
+		return "", err
+	}
+
+	pub, err := sshagent.PublicKey(k.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s", pub.Type(), ssh.FingerprintSHA256(pub)), nil
+}
+
+// DecryptSSHKey returns the entry's SSH key, commented with the entry's name
+func DecryptSSHKey(e model.VaultEntry, key *model.MasterAESKeyManager) (sshagent.Key, error) {
+	if len(e.SSHKey) == 0 {
+		return sshagent.Key{}, fmt.Errorf("'%s' has no SSH key", e.Name)
+	}
+
+	pemStr, err := crypt.DecryptPassword(e.SSHKey, key)
+	if err != nil {
+		return sshagent.Key{}, fmt.Errorf("decrypting SSH key: %v", err)
+	}
+
+	privateKey, err := sshagent.ParsePrivateKey([]byte(pemStr), func() ([]byte, error) {
+		return nil, fmt.Errorf("SSH key of '%s' is encrypted", e.Name)
+	})
+	if err != nil {
+		return sshagent.Key{}, fmt.Errorf("'%s': %v", e.Name, err)
+	}
+
+	return sshagent.Key{PrivateKey: privateKey, Comment: e.Name}, nil
+}
@@ -0,0 +1,81 @@
+package vault
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+func TestAddSSHKey(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+	vaultFile, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	vaultFile.Close()
+	cfg := &model.Config{VaultName: testutils.TEST_VAULT_NAME}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(err)
+	block, err := ssh.MarshalPrivateKey(priv, "me@laptop")
+	assert.NoError(err)
+	keyPath := path.Join(t.TempDir(), "id_ed25519")
+	assert.NoError(os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600))
+
+	ui, err := GetSSHKeyInput(
+		"github-ssh", keyPath,
+		strings.NewReader("git\n"), nil, strings.NewReader("work laptop\n"),
+		key,
+	)
+	assert.NoError(err)
+	assert.NoError(AddToVault("github-ssh", ui, cfg, 1, key))
+
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
+	assert.NoError(err)
+	f.Close()
+	assert.Len(entries, 1)
+	e := entries[0]
+	assert.Equal("git", e.Username)
+	assert.Equal("work laptop", e.Notes)
+
+	// The key is only ever stored encrypted
+	assert.NotContains(string(e.SSHKey), "PRIVATE KEY")
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	assert.NoError(err)
+	assert.Equal([]model.CustomField{{
+		Name:  SSH_PUBLIC_KEY_FIELD,
+		Value: strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub))),
+	}}, e.Fields)
+
+	fingerprint, err := SSHKeyFingerprint(e, key)
+	assert.NoError(err)
+	assert.Equal("ssh-ed25519 "+ssh.FingerprintSHA256(sshPub), fingerprint)
+
+	k, err := DecryptSSHKey(e, key)
+	assert.NoError(err)
+	assert.Equal("github-ssh", k.Comment)
+	assert.Equal(priv, k.PrivateKey)
+
+	password, err := crypt.DecryptPassword(e.Password, key)
+	assert.NoError(err)
+	assert.Empty(password)
+
+	_, err = DecryptSSHKey(model.VaultEntry{Name: "github"}, key)
+	assert.Error(err)
+}
@@ -8,11 +8,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"go-pass/agent"
 	"go-pass/crypt"
 	"go-pass/model"
 	"go-pass/utils"
@@ -24,8 +26,15 @@ var UpdateCmd = &cobra.Command{
 	Short: "Updates an entry in your vault with specific flags",
 	Long: `'update' updates a current entry in your vault. The command takes in the name
 of your entry. To update the entry, at least 1 flag is required. There are 4
-flags, each of them to update part of the entry. Minimum of 1, but can have
-multiple if multiple fields needs updating.
+flags that prompt for part of the entry: source, username, password and notes.
+Minimum of 1, but can have multiple if multiple fields needs updating. '--totp'
+prompts for a new TOTP secret, and '--remove-totp' removes it.
+
+The metadata flags set the URLs, tags or folder, replacing what was there, and
+add or replace custom fields. '--remove-field' removes a custom field.
+
+The entry's ID can be given instead of the name, and if several entries have
+the name, you are asked which one you mean.
 
 If you want to update the source name with a name with a <Space>, be careful
 that if you want to 'get' this source name, you need to add double quotes around
@@ -37,6 +46,8 @@ Ex.
 	$ gopass update github -u -s
 	Source name: <updated name for entry>
 	Username: <update username>
+
+	$ gopass update github --tag personal --remove-field pin
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := UpdateCmdHandler(cmd, args); err != nil {
@@ -51,6 +62,11 @@ type Inputs struct {
 	Username bool
 	Password bool
 	Notes    bool
+	TOTP     bool
+	// RemoveTOTP removes the TOTP secret
+	RemoveTOTP bool
+	// Metadata holds the metadata flags, which are applied without prompting
+	Metadata MetadataFlags
 }
 
 type InputSources struct {
@@ -58,6 +74,10 @@ type InputSources struct {
 	Username io.Reader
 	Password io.Reader
 	Notes    io.Reader
+	TOTP     io.Reader
+	// Entry is where the choice is read from when the name matches several
+	// entries. If it is nil, the entry has to be given by ID instead.
+	Entry io.Reader
 }
 
 // UpdateCmdHandler is the handler function that encapsulates the update logic
@@ -73,12 +93,15 @@ func UpdateCmdHandler(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	passB, err := utils.GetPasswordFromUser(true, os.Stdin)
-	if err != nil {
+	if err := PromptSecretFields(&i.Metadata, os.Stdin); err != nil {
 		return err
 	}
 
-	keyring := model.NewMasterAESKeyManager(string(passB))
+	keyring, err := agent.GetKeyManager(os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer keyring.Close()
 
 	cfgFile, ok, err := utils.OpenConfig("")
 	if ok && err == nil {
@@ -86,7 +109,7 @@ func UpdateCmdHandler(cmd *cobra.Command, args []string) error {
 	}
 	defer cfgFile.Close()
 
-	cfg, err := crypt.DecryptConfig(cfgFile, keyring, false)
+	cfg, err := crypt.DecryptConfig(cfgFile, keyring)
 	if err != nil {
 		return errors.New("decrypting config")
 	}
@@ -95,7 +118,7 @@ func UpdateCmdHandler(cmd *cobra.Command, args []string) error {
 		i,
 		cfg,
 		totalStr,
-		InputSources{os.Stdin, os.Stdin, os.Stdin, os.Stdin},
+		InputSources{os.Stdin, os.Stdin, os.Stdin, os.Stdin, os.Stdin, os.Stdin},
 		keyring,
 	)
 	if err != nil {
@@ -128,16 +151,39 @@ func UpdateFlags(cmd *cobra.Command) (Inputs, error) {
 		return Inputs{}, err
 	}
 
-	if !sourceBool && !usernameBool && !passwordBool && !notesBool {
+	totpBool, err := cmd.Flags().GetBool("totp")
+	if err != nil {
+		return Inputs{}, err
+	}
+
+	removeTOTPBool, err := cmd.Flags().GetBool("remove-totp")
+	if err != nil {
+		return Inputs{}, err
+	}
+
+	if totpBool && removeTOTPBool {
+		return Inputs{}, errors.New("'--totp' and '--remove-totp' can't be used together")
+	}
+
+	mf, err := GetMetadataFlags(cmd)
+	if err != nil {
+		return Inputs{}, err
+	}
+
+	if !sourceBool && !usernameBool && !passwordBool && !notesBool &&
+		!totpBool && !removeTOTPBool && !mf.Any() {
 		fmt.Println("Need at least one flag. See help for more information")
 		return Inputs{}, errors.New("need at last 1 flag")
 	}
 
 	return Inputs{
-		Source:   sourceBool,
-		Username: usernameBool,
-		Password: passwordBool,
-		Notes:    notesBool,
+		Source:     sourceBool,
+		Username:   usernameBool,
+		Password:   passwordBool,
+		Notes:      notesBool,
+		TOTP:       totpBool,
+		RemoveTOTP: removeTOTPBool,
+		Metadata:   mf,
 	}, nil
 }
 
@@ -150,38 +196,33 @@ func UpdateEntry(
 	is InputSources,
 	key *model.MasterAESKeyManager,
 ) error {
-	f, err := utils.OpenVault(cfg.VaultName)
+	f, entries, err := utils.ReadVault(cfg.VaultName, key)
 	if err != nil {
-		return fmt.Errorf("opening vault: %v", err)
+		return err
 	}
 	defer f.Close()
 
-	entries, err := crypt.DecryptVault(f, key, false)
-	if err != nil {
-		return fmt.Errorf("decryping vault: %v", err)
-	}
-
-	var ve model.VaultEntry
-
-	var idx int
-	for i, e := range entries {
-		if sourceName == e.Name {
-			ve = e
-			idx = i
-			break
-		}
-	}
-
-	// Not found
-	if ve.Name == "" {
+	idx, err := ResolveEntry(entries, sourceName, is.Entry)
+	if errors.Is(err, ErrNotFound) {
 		return fmt.Errorf("'%s' not found", sourceName)
 	}
+	if err != nil {
+		return err
+	}
 
-	ve, err = UpdateVaultEntry(ve, inputs, is, key)
+	ve, err := UpdateVaultEntry(entries[idx], inputs, is, key)
 	if err != nil {
 		return err
 	}
 
+	// Entries already forced in as duplicates can still be changed, as long
+	// as they aren't renamed
+	renamed := ve.Name != entries[idx].Name || ve.Username != entries[idx].Username
+	others := slices.Delete(slices.Clone(entries), idx, idx+1)
+	if renamed && FindDuplicate(others, ve.Name, ve.Username) >= 0 {
+		return fmt.Errorf("'%s' (%s): %w", ve.Name, ve.Username, ErrDuplicateEntry)
+	}
+
 	entries[idx] = ve
 
 	encryptedCipherText, err := crypt.EncryptVault(entries, key)
@@ -236,6 +277,21 @@ func UpdateVaultEntry(
 		}
 		ve.Notes = updatedNotes
 	}
+	if inputs.TOTP {
+		ve.TOTP, err = PromptTOTP(updateSources.TOTP, key)
+		if err != nil {
+			return model.VaultEntry{}, err
+		}
+	}
+	if inputs.RemoveTOTP {
+		if len(ve.TOTP) == 0 {
+			return model.VaultEntry{}, fmt.Errorf("'%s' has no TOTP secret", ve.Name)
+		}
+		ve.TOTP = nil
+	}
+	if err := ApplyMetadata(&ve.Metadata, inputs.Metadata, key); err != nil {
+		return model.VaultEntry{}, err
+	}
 
 	now := time.Now().UnixMilli()
 	ve.UpdatedAt = now
@@ -65,6 +65,21 @@ func TestUpdateEntry(t *testing.T) {
 
 	err = PrintList(vaultEntry1, cfg, key)
 	assert.Error(err)
+
+	err = AddToVault("other", model.UserInput{
+		Username: vaultEntry1,
+		Password: []byte("other"),
+	}, cfg, time.Now().UnixMilli(), key)
+	assert.NoError(err)
+
+	// Renaming 'other' to 'newSource' would make two entries with the same
+	// name and username
+	is = InputSources{Source: strings.NewReader("newSource\n")}
+	err = UpdateEntry(i, cfg, "other", is, key)
+	assert.ErrorIs(err, ErrDuplicateEntry)
+
+	err = PrintList("other", cfg, key)
+	assert.NoError(err)
 }
 
 func TestUpdateVaultEntry(t *testing.T) {
@@ -0,0 +1,189 @@
+/*
+Copyright © 2025 DKagan07
+*/
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/utils"
+)
+
+// upgradeKDFCmd represents the upgrade-kdf command
+var UpgradeKDFCmd = &cobra.Command{
+	Use:   "upgrade-kdf",
+	Short: "Re-encrypts your vault with a new key derivation function",
+	Long: `'upgrade-kdf' re-encrypts your vault, your config and all of your backups with a
+key derived by a different key derivation function (KDF). New vaults use
+Argon2id. Vaults created before that use PBKDF2, and this command moves them
+over to Argon2id in place.
+
+The KDF and its parameters are stored in the header of each file, so the
+defaults can be tuned with the flags below. The Master Password is always
+prompted for, as the new key has to be derived from it.
+
+A new random salt is generated and stored in the header as well. Vaults
+created before salts were stored are salted with SECRET_PASSWORD_KEY, so it
+has to be set to upgrade them. After upgrading, SECRET_PASSWORD_KEY is no
+longer needed, unless '--pepper' is passed to keep mixing it into the key.
+
+Ex.
+	$ gopass vault upgrade-kdf
+	Master Password: <insert master password here>
+	Current KDF: pbkdf2-sha256 (iterations=100000), salted with SECRET_PASSWORD_KEY
+	Vault upgraded to argon2id (time=3, memory=65536KiB, threads=4), random salt
+
+Ex 2.
+	$ gopass vault upgrade-kdf --memory 262144 --time 4
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := UpgradeKDFCmdHandler(cmd, args); err != nil {
+			fmt.Println("Error with 'upgrade-kdf' command: ", err)
+			return
+		}
+	},
+}
+
+// UpgradeKDFCmdHandler is the handler function that encapsulates the
+// UpgradeKDF logic
+func UpgradeKDFCmdHandler(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return errors.New("no arguments needed for 'upgrade-kdf'. see 'help' for more guidance")
+	}
+
+	params, err := KDFFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	passB, err := utils.GetPasswordFromUser(true, os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	keyring, err := utils.NewKeyManager(string(passB))
+	if err != nil {
+		return err
+	}
+	defer keyring.Close()
+
+	return UpgradeKDF("", params, keyring)
+}
+
+// KDFFlags builds the model.KDFParams from the flags, starting from
+// model.DefaultKDF
+func KDFFlags(cmd *cobra.Command) (model.KDFParams, error) {
+	kdf, err := cmd.Flags().GetString("kdf")
+	if err != nil {
+		return model.KDFParams{}, err
+	}
+
+	var params model.KDFParams
+	switch model.KDFID(kdf) {
+	case model.KDFArgon2id:
+		params = model.DefaultKDF()
+
+		if cmd.Flags().Changed("time") {
+			params.Time, err = cmd.Flags().GetUint32("time")
+			if err != nil {
+				return model.KDFParams{}, err
+			}
+		}
+		if cmd.Flags().Changed("memory") {
+			params.Memory, err = cmd.Flags().GetUint32("memory")
+			if err != nil {
+				return model.KDFParams{}, err
+			}
+		}
+		if cmd.Flags().Changed("threads") {
+			params.Threads, err = cmd.Flags().GetUint8("threads")
+			if err != nil {
+				return model.KDFParams{}, err
+			}
+		}
+	case model.KDFPBKDF2:
+		params = model.LegacyKDF()
+
+		if cmd.Flags().Changed("iterations") {
+			params.Iterations, err = cmd.Flags().GetUint32("iterations")
+			if err != nil {
+				return model.KDFParams{}, err
+			}
+		}
+	default:
+		return model.KDFParams{}, fmt.Errorf(
+			"unknown kdf '%s', must be '%s' or '%s'", kdf, model.KDFArgon2id, model.KDFPBKDF2,
+		)
+	}
+
+	params.Pepper, err = cmd.Flags().GetBool("pepper")
+	if err != nil {
+		return model.KDFParams{}, err
+	}
+
+	return params, params.Validate()
+}
+
+// UpgradeKDF re-encrypts the config, vault and backups with a key derived with
+// 'params' and a new random salt. The files are left alone if they already
+// have a salt and use the same parameters and pepper. 'from' has
+// to be derived from the Master Password, not handed out by the agent, as the
+// new key is derived from it as well.
+func UpgradeKDF(cfgName string, params model.KDFParams, from *model.MasterAESKeyManager) error {
+	cfg, err := utils.CheckConfig(cfgName, from)
+	if err != nil {
+		return err
+	}
+
+	vaultF, err := utils.OpenVault(cfg.VaultName)
+	if err != nil {
+		return fmt.Errorf("opening vault: %v", err)
+	}
+	contents, err := os.ReadFile(vaultF.Name())
+	vaultF.Close()
+	if err != nil {
+		return fmt.Errorf("reading vault: %v", err)
+	}
+
+	header, err := crypt.ReadHeader(contents)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Current KDF: %s\n", header.KDF)
+	if header.Version == crypt.VERSION_ENVELOPE && header.KDF.Salt != "" &&
+		header.KDF.Cost() == params.Cost() && header.KDF.Pepper == params.Pepper {
+		fmt.Println("Vault already uses this KDF, nothing to do.")
+		return nil
+	}
+
+	if params.Pepper && !model.HasPepper() {
+		return fmt.Errorf("--pepper needs %s to be set", model.SECRET_PASSWORD_KEY)
+	}
+
+	pepper := params.Pepper
+	params, err = params.WithNewSalt()
+	if err != nil {
+		return err
+	}
+	params.Pepper = pepper
+
+	to, err := from.WithKDF(params)
+	if err != nil {
+		return err
+	}
+	defer to.Close()
+
+	if err := utils.ReEncryptFiles(cfgName, from, to, nil); err != nil {
+		return fmt.Errorf("re-encrypting: %v", err)
+	}
+
+	fmt.Printf("Vault upgraded to %s\n", params)
+	return nil
+}
@@ -0,0 +1,148 @@
+package vault
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/testutils"
+	"go-pass/utils"
+)
+
+// useTempBackups points utils.BACKUP_PATH at a temporary directory for the
+// length of the test, so backups left over elsewhere aren't re-encrypted
+func useTempBackups(t *testing.T) {
+	oldBackupPath := utils.BACKUP_PATH
+	utils.BACKUP_PATH = t.TempDir()
+	t.Cleanup(func() { utils.BACKUP_PATH = oldBackupPath })
+}
+
+func TestUpgradeKDF(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	useTempBackups(t)
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+
+	cfgFile, err := utils.CreateConfig(
+		testutils.TEST_VAULT_NAME,
+		testutils.TEST_MASTER_PASSWORD,
+		testutils.TEST_CONFIG_NAME,
+		key,
+	)
+	assert.NoError(err)
+	defer cfgFile.Close()
+
+	vaultFile, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	defer vaultFile.Close()
+
+	now := time.Now().UnixMilli()
+	cfg := &model.Config{
+		VaultName:      testutils.TEST_VAULT_NAME,
+		MasterPassword: testutils.TEST_MASTER_PASSWORD,
+		LastVisited:    now,
+	}
+
+	pass, err := crypt.EncryptPassword([]byte(vaultEntry1), key)
+	assert.NoError(err)
+	err = AddToVault(vaultEntry1, model.UserInput{
+		Username: vaultEntry1,
+		Password: []byte(pass),
+	}, cfg, now, key)
+	assert.NoError(err)
+
+	// Cheap parameters, so the test stays fast
+	params := model.KDFParams{ID: model.KDFArgon2id, Time: 1, Memory: 2 * 1024, Threads: 1}
+	assert.NoError(UpgradeKDF(testutils.TEST_CONFIG_NAME, params, key))
+
+	vaultPath := path.Join(utils.VAULT_PATH, testutils.TEST_VAULT_NAME)
+	contents, err := os.ReadFile(vaultPath)
+	assert.NoError(err)
+	header, err := crypt.ReadHeader(contents)
+	assert.NoError(err)
+	assert.Equal(params, header.KDF.Cost())
+	assert.NotEmpty(header.KDF.Salt)
+	salt := header.KDF.Salt
+
+	cfgContents, err := os.ReadFile(path.Join(utils.CONFIG_PATH, testutils.TEST_CONFIG_NAME))
+	assert.NoError(err)
+	header, err = crypt.ReadHeader(cfgContents)
+	assert.NoError(err)
+	assert.Equal(params, header.KDF.Cost())
+	assert.Equal(salt, header.KDF.Salt)
+
+	// A fresh manager picks the new KDF up from the header and can still read
+	// the passwords
+	fresh := model.NewTestMasterAESKeyManager(string(testutils.TEST_MASTER_PASSWORD))
+	defer fresh.Close()
+
+	f, err := os.Open(vaultPath)
+	assert.NoError(err)
+	defer f.Close()
+
+	entries, err := crypt.DecryptVault(f, fresh)
+	assert.NoError(err)
+	assert.Len(entries, 1)
+
+	decrypted, err := crypt.DecryptPassword(entries[0].Password, fresh)
+	assert.NoError(err)
+	assert.Equal(vaultEntry1, decrypted)
+
+	// Running it again is a no-op
+	assert.NoError(UpgradeKDF(testutils.TEST_CONFIG_NAME, params, fresh))
+	contents, err = os.ReadFile(vaultPath)
+	assert.NoError(err)
+	header, err = crypt.ReadHeader(contents)
+	assert.NoError(err)
+	assert.Equal(salt, header.KDF.Salt)
+}
+
+func TestUpgradeKDFFromEnvSalt(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	useTempBackups(t)
+	assert := assert.New(t)
+
+	// A vault from before salts were stored is salted with the env var
+	t.Setenv(model.SECRET_PASSWORD_KEY, "abcdefghijklmnopqrstuvwxyz012345")
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+	legacy := key.KDF()
+	legacy.Salt = ""
+	legacy.Pepper = false
+	assert.NoError(key.UseKDF(legacy))
+
+	cfgFile, err := utils.CreateConfig(
+		testutils.TEST_VAULT_NAME,
+		testutils.TEST_MASTER_PASSWORD,
+		testutils.TEST_CONFIG_NAME,
+		key,
+	)
+	assert.NoError(err)
+	defer cfgFile.Close()
+
+	vaultFile, err := utils.CreateVault(testutils.TEST_VAULT_NAME, key)
+	assert.NoError(err)
+	defer vaultFile.Close()
+
+	params := model.KDFParams{ID: model.KDFArgon2id, Time: 1, Memory: 2 * 1024, Threads: 1}
+	assert.NoError(UpgradeKDF(testutils.TEST_CONFIG_NAME, params, key))
+
+	// The env var is no longer needed
+	t.Setenv(model.SECRET_PASSWORD_KEY, "")
+	fresh := model.NewTestMasterAESKeyManager(string(testutils.TEST_MASTER_PASSWORD))
+	defer fresh.Close()
+
+	_, err = utils.CheckConfig(testutils.TEST_CONFIG_NAME, fresh)
+	assert.NoError(err)
+	assert.NotEmpty(fresh.KDF().Salt)
+	assert.False(fresh.KDF().Pepper)
+}
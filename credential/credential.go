@@ -0,0 +1,151 @@
+// Package credential finds the vault entries for the credentials that other
+// tools, like git, ask for by URL.
+package credential
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"go-pass/model"
+)
+
+// Query describes the credential a tool asks for
+type Query struct {
+	// Protocol is the URL scheme, like 'https'. It may be empty.
+	Protocol string
+	// Host is the host name, with the port if there is one
+	Host string
+	// Path is the path on the host, without the slashes around it or a '.git'
+	// suffix. It may be empty.
+	Path string
+	// Username, if it isn't empty, only matches entries with that username
+	Username string
+}
+
+// ParseURL parses a URL, like 'https://user@github.com/org/repo.git', into a
+// Query. A bare host, like 'registry.example.com', is accepted too.
+func ParseURL(raw string) (Query, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "//" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Query{}, fmt.Errorf("parsing url: %v", err)
+	}
+	if u.Host == "" {
+		return Query{}, errors.New("url has no host")
+	}
+
+	q := Query{
+		Protocol: strings.ToLower(u.Scheme),
+		Host:     strings.ToLower(u.Host),
+		Path:     cleanPath(u.Path),
+	}
+	if u.User != nil {
+		q.Username = u.User.Username()
+	}
+	return q, nil
+}
+
+// String returns the URL the query is for, without the username
+func (q Query) String() string {
+	u := url.URL{Scheme: q.Protocol, Host: q.Host}
+	if q.Path != "" {
+		u.Path = "/" + q.Path
+	}
+	if u.Scheme == "" {
+		return strings.TrimPrefix(u.String(), "//")
+	}
+	return u.String()
+}
+
+const (
+	// matchName is an entry named after the host
+	matchName = iota + 1
+	// matchHost is an entry with a URL for the host
+	matchHost
+	// matchPath is an entry with a URL for the host and the path asked for
+	matchPath
+)
+
+// Match returns the indexes of the entries for the credential 'q' asks for,
+// best match first. An entry matches if one of its URLs is for the same host,
+// with the same protocol and path if the URL has them, or if it is named
+// after the host. URLs with a matching path come before URLs for just the
+// host, which come before names, and otherwise the most recently updated
+// entries come first.
+func Match(entries []model.VaultEntry, q Query) []int {
+	return match(entries, q, false)
+}
+
+// MatchOrigin is Match for a web page's origin, which is stricter, as a login
+// must never be handed to another site, or over plain http when it is for
+// https. Only URLs match, not names, and a URL's protocol must be the same as
+// the page's. A URL without a protocol only matches https pages.
+func MatchOrigin(entries []model.VaultEntry, q Query) []int {
+	return match(entries, q, true)
+}
+
+func match(entries []model.VaultEntry, q Query, strict bool) []int {
+	host := strings.ToLower(q.Host)
+	path := cleanPath(q.Path)
+	protocol := strings.ToLower(q.Protocol)
+
+	scores := make(map[int]int)
+	var matches []int
+	for i, e := range entries {
+		if q.Username != "" && e.Username != q.Username {
+			continue
+		}
+
+		score := 0
+		for _, raw := range e.URLs {
+			u, err := ParseURL(raw)
+			if err != nil || u.Host != host {
+				continue
+			}
+			if strict && cmp.Or(u.Protocol, "https") != protocol {
+				continue
+			}
+			if u.Protocol != "" && protocol != "" && u.Protocol != protocol {
+				continue
+			}
+
+			switch {
+			case u.Path == "" || path == "":
+				score = max(score, matchHost)
+			case path == u.Path || strings.HasPrefix(path, u.Path+"/"):
+				score = max(score, matchPath)
+			}
+		}
+		if score == 0 && !strict && strings.EqualFold(e.Name, host) {
+			score = matchName
+		}
+
+		if score > 0 {
+			scores[i] = score
+			matches = append(matches, i)
+		}
+	}
+
+	slices.SortStableFunc(matches, func(a, b int) int {
+		if c := cmp.Compare(scores[b], scores[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(entries[b].UpdatedAt, entries[a].UpdatedAt)
+	})
+	return matches
+}
+
+// cleanPath trims the slashes around a path and a '.git' suffix, so
+// 'org/repo', '/org/repo/' and 'org/repo.git' are all the same
+func cleanPath(p string) string {
+	p = strings.Trim(p, "/")
+	return strings.TrimSuffix(p, ".git")
+}
@@ -0,0 +1,159 @@
+package credential
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/model"
+)
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		raw         string
+		want        Query
+		wantString  string
+		shouldError bool
+	}{
+		{
+			raw:        "https://me@GitHub.com/org/repo.git",
+			want:       Query{Protocol: "https", Host: "github.com", Path: "org/repo", Username: "me"},
+			wantString: "https://github.com/org/repo",
+		},
+		{
+			raw:        "http://localhost:8080/",
+			want:       Query{Protocol: "http", Host: "localhost:8080"},
+			wantString: "http://localhost:8080",
+		},
+		{
+			raw:        "registry.example.com",
+			want:       Query{Host: "registry.example.com"},
+			wantString: "registry.example.com",
+		},
+		{raw: "https://", shouldError: true},
+		{raw: "", shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			assert := assert.New(t)
+			got, err := ParseURL(tt.raw)
+			if tt.shouldError {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tt.want, got)
+			assert.Equal(tt.wantString, got.String())
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	entries := []model.VaultEntry{
+		{Name: "github.com", Username: "named", UpdatedAt: 5},
+		{Name: "GitHub", Username: "host", Metadata: model.Metadata{URLs: []string{"https://github.com"}}, UpdatedAt: 1},
+		{Name: "repo", Username: "repo", Metadata: model.Metadata{URLs: []string{"https://github.com/org/repo"}}},
+		{Name: "other repo", Username: "other", Metadata: model.Metadata{URLs: []string{"https://github.com/org/other"}}},
+		{Name: "newer", Username: "newer", Metadata: model.Metadata{URLs: []string{"github.com"}}, UpdatedAt: 2},
+		{Name: "plain http", Username: "http", Metadata: model.Metadata{URLs: []string{"http://github.com"}}},
+		{Name: "gitlab", Username: "gitlab", Metadata: model.Metadata{URLs: []string{"https://gitlab.com"}}},
+	}
+
+	tests := []struct {
+		name string
+		q    Query
+		want []int
+	}{
+		{
+			name: "host",
+			q:    Query{Protocol: "https", Host: "github.com"},
+			want: []int{4, 1, 2, 3, 0},
+		},
+		{
+			name: "path",
+			q:    Query{Protocol: "https", Host: "github.com", Path: "org/repo"},
+			want: []int{2, 4, 1, 0},
+		},
+		{
+			name: "sub path",
+			q:    Query{Protocol: "https", Host: "github.com", Path: "org/repo/info/lfs"},
+			want: []int{2, 4, 1, 0},
+		},
+		{
+			name: "username",
+			q:    Query{Protocol: "https", Host: "github.com", Username: "named"},
+			want: []int{0},
+		},
+		{
+			name: "no protocol",
+			q:    Query{Host: "GitHub.com", Path: "org/other"},
+			want: []int{3, 4, 1, 5, 0},
+		},
+		{
+			name: "no match",
+			q:    Query{Protocol: "https", Host: "example.com"},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Match(entries, tt.q))
+		})
+	}
+}
+
+func TestMatchOrigin(t *testing.T) {
+	entries := []model.VaultEntry{
+		{Name: "github.com", Username: "named"},
+		{Name: "GitHub", Username: "https", Metadata: model.Metadata{URLs: []string{"https://github.com"}}},
+		{Name: "no protocol", Username: "bare", Metadata: model.Metadata{URLs: []string{"github.com"}}},
+		{Name: "plain http", Username: "http", Metadata: model.Metadata{URLs: []string{"http://github.com"}}},
+	}
+
+	assert.Equal(t, []int{1, 2}, MatchOrigin(entries, Query{Protocol: "https", Host: "github.com"}))
+	// A page over plain http is only given the logins for http
+	assert.Equal(t, []int{3}, MatchOrigin(entries, Query{Protocol: "http", Host: "github.com"}))
+	assert.Empty(t, MatchOrigin(entries, Query{Host: "github.com"}))
+}
+
+func TestReadGit(t *testing.T) {
+	assert := assert.New(t)
+
+	c, err := ReadGit(strings.NewReader(
+		"protocol=https\nhost=github.com\npath=org/repo.git\nusername=me\npassword=a=b\ncapability[]=authtype\n\nignored=after blank line\n",
+	))
+	assert.NoError(err)
+	assert.Equal(GitCredential{
+		Protocol: "https",
+		Host:     "github.com",
+		Path:     "org/repo.git",
+		Username: "me",
+		Password: "a=b",
+	}, c)
+	assert.Equal(Query{Protocol: "https", Host: "github.com", Path: "org/repo", Username: "me"}, c.Query())
+
+	c, err = ReadGit(strings.NewReader("url=https://me@example.com:8443/x\r\n"))
+	assert.NoError(err)
+	assert.Equal(GitCredential{Protocol: "https", Host: "example.com:8443", Path: "x", Username: "me"}, c)
+
+	_, err = ReadGit(strings.NewReader("protocol\n"))
+	assert.Error(err)
+}
+
+func TestWriteGit(t *testing.T) {
+	assert := assert.New(t)
+
+	var b bytes.Buffer
+	assert.NoError(WriteGit(&b, GitCredential{Username: "me", Password: "p=w"}))
+	assert.Equal("username=me\npassword=p=w\n", b.String())
+
+	b.Reset()
+	assert.NoError(WriteGit(&b, GitCredential{Password: "pw"}))
+	assert.Equal("password=pw\n", b.String())
+
+	assert.Error(WriteGit(&b, GitCredential{Password: "p\nw"}))
+}
@@ -0,0 +1,51 @@
+package credential
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrDockerNotFound is the error docker expects from a credential helper that
+// has no credentials for a server. Docker checks for the message itself.
+var ErrDockerNotFound = errors.New("credentials not found in native keychain")
+
+// DockerCredential is a credential in docker's credential helper protocol, see
+// https://github.com/docker/docker-credential-helpers
+type DockerCredential struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+// ReadDockerServerURL reads the server URL docker writes to a credential
+// helper for 'get' and 'erase'
+func ReadDockerServerURL(r io.Reader) (string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("reading server URL: %v", err)
+	}
+
+	serverURL := strings.TrimSpace(string(b))
+	if serverURL == "" {
+		return "", errors.New("no server URL")
+	}
+	return serverURL, nil
+}
+
+// ReadDocker reads the credential docker writes to a credential helper for
+// 'store'
+func ReadDocker(r io.Reader) (DockerCredential, error) {
+	var c DockerCredential
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return DockerCredential{}, fmt.Errorf("reading credential: %v", err)
+	}
+
+	c.ServerURL = strings.TrimSpace(c.ServerURL)
+	if c.ServerURL == "" {
+		return DockerCredential{}, errors.New("no server URL")
+	}
+	return c, nil
+}
@@ -0,0 +1,30 @@
+package credential
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadDocker(t *testing.T) {
+	assert := assert.New(t)
+
+	serverURL, err := ReadDockerServerURL(strings.NewReader("https://index.docker.io/v1/\n"))
+	assert.NoError(err)
+	assert.Equal("https://index.docker.io/v1/", serverURL)
+
+	_, err = ReadDockerServerURL(strings.NewReader("  \n"))
+	assert.Error(err)
+
+	c, err := ReadDocker(strings.NewReader(
+		`{"ServerURL":"ghcr.io","Username":"me","Secret":"token"}`,
+	))
+	assert.NoError(err)
+	assert.Equal(DockerCredential{ServerURL: "ghcr.io", Username: "me", Secret: "token"}, c)
+
+	_, err = ReadDocker(strings.NewReader(`{"Username":"me"}`))
+	assert.Error(err)
+	_, err = ReadDocker(strings.NewReader(`ghcr.io`))
+	assert.Error(err)
+}
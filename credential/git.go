@@ -0,0 +1,92 @@
+package credential
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GitCredential is a credential in git's credential helper protocol, see
+// git-credential(1)
+type GitCredential struct {
+	Protocol string
+	Host     string
+	Path     string
+	Username string
+	Password string
+}
+
+// ReadGit reads the 'key=value' lines git writes to a credential helper, up to
+// a blank line or the end of the input. A 'url' sets every part of the URL,
+// and unknown keys are ignored.
+func ReadGit(r io.Reader) (GitCredential, error) {
+	var c GitCredential
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			break
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return GitCredential{}, fmt.Errorf("invalid credential line '%s'", line)
+		}
+
+		switch key {
+		case "protocol":
+			c.Protocol = value
+		case "host":
+			c.Host = value
+		case "path":
+			c.Path = value
+		case "username":
+			c.Username = value
+		case "password":
+			c.Password = value
+		case "url":
+			q, err := ParseURL(value)
+			if err != nil {
+				return GitCredential{}, err
+			}
+			c.Protocol, c.Host, c.Path = q.Protocol, q.Host, q.Path
+			if q.Username != "" {
+				c.Username = q.Username
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return GitCredential{}, fmt.Errorf("reading credential: %v", err)
+	}
+
+	return c, nil
+}
+
+// WriteGit writes the username and password for git to read, skipping
+// whichever is empty
+func WriteGit(w io.Writer, c GitCredential) error {
+	for _, kv := range [][2]string{{"username", c.Username}, {"password", c.Password}} {
+		if kv[1] == "" {
+			continue
+		}
+		if strings.ContainsAny(kv[1], "\n\x00") {
+			return fmt.Errorf("the %s can't be sent to git, it has a newline or NUL", kv[0])
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Query returns the Query for the credential
+func (c GitCredential) Query() Query {
+	return Query{
+		Protocol: strings.ToLower(c.Protocol),
+		Host:     strings.ToLower(c.Host),
+		Path:     cleanPath(c.Path),
+		Username: c.Username,
+	}
+}
@@ -0,0 +1,148 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go-pass/model"
+)
+
+const (
+	// BUNDLE_MAGIC identifies a portable bundle
+	BUNDLE_MAGIC = "GOPASS-BUNDLE"
+	// VERSION_BUNDLE is the current bundle format version
+	VERSION_BUNDLE = 1
+)
+
+var (
+	// ErrNotBundle is returned by OpenBundle for anything that isn't a bundle
+	ErrNotBundle = errors.New("not a gopass bundle")
+	// ErrBundlePassphrase is returned by OpenBundle when the passphrase is
+	// wrong, or the bundle has been tampered with
+	ErrBundlePassphrase = errors.New("wrong passphrase, or the bundle is corrupt")
+)
+
+// Bundle is a file encrypted with a key derived from a passphrase alone,
+// unlike an Envelope, whose key also depends on the keyring. It can be opened
+// on any machine that has the passphrase. The header is authenticated along
+// with the data, the same as an Envelope's.
+type Bundle struct {
+	Header
+	// Data is the base64 encoded nonce and ciphertext
+	Data string `json:"data"`
+}
+
+// SealBundle encrypts plaintext with a key derived from the passphrase, with
+// Argon2id at the costs of 'kdf' and a fresh random salt.
+func SealBundle(plaintext, passphrase []byte, kdf model.KDFParams) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("the passphrase can't be empty")
+	}
+	if kdf.ID != model.KDFArgon2id {
+		return nil, fmt.Errorf("bundles must use %s", model.KDFArgon2id)
+	}
+
+	params, err := kdf.WithNewSalt()
+	if err != nil {
+		return nil, err
+	}
+	// SECRET_PASSWORD_KEY is specific to this machine
+	params.Pepper = false
+
+	header := Header{
+		Magic:   BUNDLE_MAGIC,
+		Version: VERSION_BUNDLE,
+		KDF:     params,
+		Cipher:  CIPHER_AES_256_GCM,
+	}
+
+	aad, err := json.Marshal(header)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling header: %v", err)
+	}
+
+	keychain, err := bundleKeychain(passphrase, params)
+	if err != nil {
+		return nil, err
+	}
+	defer keychain.Close()
+
+	sealed, err := keychain.Seal(plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(Bundle{
+		Header: header,
+		Data:   base64.StdEncoding.EncodeToString(sealed),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling bundle: %v", err)
+	}
+
+	return b, nil
+}
+
+// OpenBundle decrypts a bundle written by SealBundle with its passphrase
+func OpenBundle(contents, passphrase []byte) ([]byte, error) {
+	contents = bytes.TrimSpace(contents)
+	if len(contents) == 0 || contents[0] != '{' {
+		return nil, ErrNotBundle
+	}
+
+	var b Bundle
+	if err := json.Unmarshal(contents, &b); err != nil {
+		return nil, ErrNotBundle
+	}
+	if b.Magic != BUNDLE_MAGIC {
+		return nil, ErrNotBundle
+	}
+	if b.Version != VERSION_BUNDLE {
+		return nil, fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+	if b.Cipher != CIPHER_AES_256_GCM {
+		return nil, fmt.Errorf("unsupported cipher '%s'", b.Cipher)
+	}
+	if b.KDF.ID != model.KDFArgon2id || b.KDF.Salt == "" || b.KDF.Pepper {
+		return nil, fmt.Errorf("unsupported bundle key derivation: %s", b.KDF)
+	}
+	// The costs come from the file, so they are capped before deriving a key
+	if b.KDF.Time > model.ARGON2_MAX_TIME || b.KDF.Memory > model.ARGON2_MAX_MEMORY {
+		return nil, fmt.Errorf("the bundle's key derivation costs too much: %s", b.KDF)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(b.Data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding data: %v", err)
+	}
+
+	aad, err := json.Marshal(b.Header)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling header: %v", err)
+	}
+
+	keychain, err := bundleKeychain(passphrase, b.KDF)
+	if err != nil {
+		return nil, err
+	}
+	defer keychain.Close()
+
+	plaintext, err := keychain.Open(sealed, aad)
+	if err != nil {
+		return nil, ErrBundlePassphrase
+	}
+	return plaintext, nil
+}
+
+// bundleKeychain returns a keychain with the key derived from the passphrase
+// alone, with no keyring key
+func bundleKeychain(passphrase []byte, params model.KDFParams) (*model.MasterAESKeyManager, error) {
+	key, err := model.DeriveKey(nil, passphrase, params)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %v", err)
+	}
+	return model.NewMasterAESKeyManagerFromKey(key, params), nil
+}
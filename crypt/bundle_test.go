@@ -0,0 +1,92 @@
+package crypt
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/model"
+)
+
+// bundleKDF keeps these tests fast
+var bundleKDF = model.KDFParams{ID: model.KDFArgon2id, Time: 1, Memory: 1024, Threads: 1}
+
+func TestSealOpenBundle(t *testing.T) {
+	assert := assert.New(t)
+	// The pepper is specific to this machine, so it must not be used
+	t.Setenv(model.SECRET_PASSWORD_KEY, "pepper")
+
+	sealed, err := SealBundle([]byte("plaintext"), []byte("passphrase"), bundleKDF)
+	assert.NoError(err)
+
+	var b Bundle
+	assert.NoError(json.Unmarshal(sealed, &b))
+	assert.Equal(BUNDLE_MAGIC, b.Magic)
+	assert.Equal(VERSION_BUNDLE, b.Version)
+	assert.Equal(CIPHER_AES_256_GCM, b.Cipher)
+	assert.NotEmpty(b.KDF.Salt)
+	assert.False(b.KDF.Pepper)
+	assert.Equal(bundleKDF, b.KDF.Cost())
+
+	t.Setenv(model.SECRET_PASSWORD_KEY, "")
+	opened, err := OpenBundle(sealed, []byte("passphrase"))
+	assert.NoError(err)
+	assert.Equal("plaintext", string(opened))
+
+	// Every bundle gets its own salt
+	again, err := SealBundle([]byte("plaintext"), []byte("passphrase"), bundleKDF)
+	assert.NoError(err)
+	assert.NotEqual(sealed, again)
+}
+
+func TestOpenBundleErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	sealed, err := SealBundle([]byte("plaintext"), []byte("passphrase"), bundleKDF)
+	assert.NoError(err)
+
+	_, err = OpenBundle(sealed, []byte("wrong"))
+	assert.ErrorIs(err, ErrBundlePassphrase)
+
+	// The header is authenticated
+	var b Bundle
+	assert.NoError(json.Unmarshal(sealed, &b))
+	b.KDF.Time = 2
+	tampered, err := json.Marshal(b)
+	assert.NoError(err)
+	_, err = OpenBundle(tampered, []byte("passphrase"))
+	assert.ErrorIs(err, ErrBundlePassphrase)
+
+	// The costs are capped before the key is derived
+	assert.NoError(json.Unmarshal(sealed, &b))
+	b.KDF.Memory = model.ARGON2_MAX_MEMORY + 1
+	tampered, err = json.Marshal(b)
+	assert.NoError(err)
+	_, err = OpenBundle(tampered, []byte("passphrase"))
+	assert.ErrorContains(err, "costs too much")
+
+	// So is the data
+	assert.NoError(json.Unmarshal(sealed, &b))
+	data := []byte(b.Data)
+	data[len(data)/2] ^= 1
+	b.Data = string(data)
+	tampered, err = json.Marshal(b)
+	assert.NoError(err)
+	_, err = OpenBundle(tampered, []byte("passphrase"))
+	assert.Error(err)
+
+	// A vault file isn't a bundle
+	vault, err := Seal([]byte("plaintext"), keyFor(model.DefaultKDF()))
+	assert.NoError(err)
+	_, err = OpenBundle([]byte(vault), []byte("passphrase"))
+	assert.ErrorIs(err, ErrNotBundle)
+
+	_, err = OpenBundle([]byte("not a bundle"), []byte("passphrase"))
+	assert.ErrorIs(err, ErrNotBundle)
+
+	_, err = SealBundle([]byte("plaintext"), nil, bundleKDF)
+	assert.Error(err)
+	_, err = SealBundle([]byte("plaintext"), []byte("passphrase"), model.LegacyKDF())
+	assert.Error(err)
+}
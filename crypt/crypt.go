@@ -4,4 +4,18 @@ const (
 	NONCE_SIZE     = 12
 	KEY_SIZE       = 32
 	NUM_ITERATIONS = 100000
+
+	// MAGIC identifies a gopass file
+	MAGIC = "GOPASS"
+
+	// VERSION_LEGACY_HEX is the original format: hex encoded and keyed
+	// directly off of SECRET_PASSWORD_KEY.
+	VERSION_LEGACY_HEX = 0
+	// VERSION_BASE64 is a bare base64 blob keyed off of the keyring with
+	// PBKDF2.
+	VERSION_BASE64 = 1
+	// VERSION_ENVELOPE is the self-describing Envelope.
+	VERSION_ENVELOPE = 2
+
+	CIPHER_AES_256_GCM = "aes-256-gcm"
 )
@@ -1,11 +1,16 @@
 package crypt
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"go-pass/model"
 	"go-pass/testutils"
 )
 
@@ -30,7 +35,7 @@ func TestEncryptDecryptRoundTrip(t *testing.T) {
 		encrypted, err := EncryptPassword(p, key)
 		assert.NoError(t, err)
 
-		decrypted, err := DecryptPassword([]byte(encrypted), key, false)
+		decrypted, err := DecryptPassword([]byte(encrypted), key)
 		assert.NoError(t, err)
 
 		if decrypted != pwd {
@@ -38,3 +43,91 @@ func TestEncryptDecryptRoundTrip(t *testing.T) {
 		}
 	}
 }
+
+// benchmarkEntries is the size of the vault used in the benchmarks. Deriving a
+// key per call makes each op cost benchmarkEntries PBKDF2 runs, so this is
+// kept small.
+const benchmarkEntries = 20
+
+// newBenchmarkKey derives a key the same way MasterAESKeyManager does, without
+// touching the OS keyring.
+func newBenchmarkKey(b *testing.B) *model.MasterAESKeyManager {
+	baseKey := bytes.Repeat([]byte{1}, model.KEY_SIZE)
+	params := model.LegacyKDF()
+	params.Salt = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, model.SALT_SIZE))
+
+	key, err := model.DeriveKey(baseKey, testutils.TEST_MASTER_PASSWORD, params)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return model.NewMasterAESKeyManagerFromKey(key, params)
+}
+
+// newBenchmarkVault returns an encrypted vault of benchmarkEntries entries
+func newBenchmarkVault(b *testing.B) string {
+	key := newBenchmarkKey(b)
+	defer key.Close()
+
+	entries := make([]model.VaultEntry, benchmarkEntries)
+	for i := range entries {
+		pass, err := EncryptPassword([]byte(fmt.Sprintf("password-%d", i)), key)
+		if err != nil {
+			b.Fatal(err)
+		}
+		entries[i] = model.VaultEntry{Name: fmt.Sprintf("entry-%d", i), Password: []byte(pass)}
+	}
+
+	vault, err := EncryptVault(entries, key)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return vault
+}
+
+// BenchmarkDecryptVaultAndPasswords decrypts a vault and then every password
+// in it. 'derive-per-call' is how Encrypt/Decrypt used to behave, running
+// PBKDF2 on every call. 'cached' derives once per session.
+func BenchmarkDecryptVaultAndPasswords(b *testing.B) {
+	vault := newBenchmarkVault(b)
+
+	decryptAll := func(b *testing.B, newKey func() *model.MasterAESKeyManager) {
+		plaintext, err := Open([]byte(vault), newKey())
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		var entries []model.VaultEntry
+		if err := json.Unmarshal(plaintext, &entries); err != nil {
+			b.Fatal(err)
+		}
+
+		for _, e := range entries {
+			if _, err := DecryptPassword(e.Password, newKey()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.Run("derive-per-call", func(b *testing.B) {
+		for b.Loop() {
+			var keys []*model.MasterAESKeyManager
+			decryptAll(b, func() *model.MasterAESKeyManager {
+				key := newBenchmarkKey(b)
+				keys = append(keys, key)
+				return key
+			})
+			for _, key := range keys {
+				key.Close()
+			}
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		for b.Loop() {
+			key := newBenchmarkKey(b)
+			decryptAll(b, func() *model.MasterAESKeyManager { return key })
+			key.Close()
+		}
+	})
+}
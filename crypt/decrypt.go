@@ -13,41 +13,38 @@ import (
 )
 
 // DecryptPassword takes a []byte that we initially stored in the file, decrypt
-// it, and return the string form of that password.
+// it, and return the string form of that password. Passwords from the original
+// hex format are recognised and decrypted with the legacy key.
 func DecryptPassword(
 	passBytes []byte,
 	keychain *model.MasterAESKeyManager,
-	isOld bool,
 ) (string, error) {
-	var ciphertext []byte
-	var err error
-	if !isOld {
-		ciphertext, err = keychain.Decrypt(string(passBytes))
-		if err != nil {
-			return "", err
-		}
-		return string(ciphertext), nil
-	} else {
-		ciphertext, err = Decrypt(passBytes)
+	if !isLegacyHex(passBytes) {
+		plaintext, err := keychain.Decrypt(string(passBytes))
 		if err != nil {
 			return "", err
 		}
+		return string(plaintext), nil
+	}
 
-		pass := make([]byte, hex.DecodedLen(len(ciphertext)))
-		if _, err := hex.Decode(pass, ciphertext); err != nil {
-			return "", fmt.Errorf("hex decode password")
-		}
-		return string(pass), nil
+	plaintext, err := Decrypt(passBytes)
+	if err != nil {
+		return "", err
+	}
+
+	pass := make([]byte, hex.DecodedLen(len(plaintext)))
+	if _, err := hex.Decode(pass, plaintext); err != nil {
+		return "", fmt.Errorf("hex decode password")
 	}
+	return string(pass), nil
 }
 
 // DecryptVault takes a *os.File (the vault file) and returns a
 // []model.VaultEntry. The purpose of this is is to read the contents of the
-// file.
+// file. The format is read from the file's header.
 func DecryptVault(
 	f *os.File,
 	keychain *model.MasterAESKeyManager,
-	isOld bool,
 ) ([]model.VaultEntry, error) {
 	if _, err := f.Seek(0, io.SeekStart); err != nil {
 		return nil, fmt.Errorf("seeking for vault: %w", err)
@@ -59,22 +56,14 @@ func DecryptVault(
 		return nil, fmt.Errorf("reading vault contents: %v", err)
 	}
 
-	var ciphertext []byte
-	if !isOld {
-		ciphertext, err = keychain.Decrypt(string(contents))
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		ciphertext, err = Decrypt(contents)
-		if err != nil {
-			return nil, fmt.Errorf("decryping vault: %v", err)
-		}
+	plaintext, err := Open(contents, keychain)
+	if err != nil {
+		return nil, fmt.Errorf("decryping vault: %v", err)
 	}
 
 	var entries []model.VaultEntry
 
-	if err = json.Unmarshal(ciphertext, &entries); err != nil {
+	if err = json.Unmarshal(plaintext, &entries); err != nil {
 		return nil, fmt.Errorf("unmarshaling: %v", err)
 	}
 
@@ -82,11 +71,10 @@ func DecryptVault(
 }
 
 // DecryptConfig take the *os.File of the config file. It decrypts it, and
-// returns the model.Config.
+// returns the model.Config. The format is read from the file's header.
 func DecryptConfig(
 	f *os.File,
 	keychain *model.MasterAESKeyManager,
-	isOld bool,
 ) (*model.Config, error) {
 	if _, err := f.Seek(0, io.SeekStart); err != nil {
 		return nil, fmt.Errorf("seeking for config: %w", err)
@@ -98,17 +86,9 @@ func DecryptConfig(
 		return nil, fmt.Errorf("reading config file: %w", err)
 	}
 
-	var b []byte
-	if isOld {
-		b, err = Decrypt(contents)
-		if err != nil {
-			return nil, fmt.Errorf("decrypting file: %w", err)
-		}
-	} else {
-		b, err = keychain.Decrypt(string(contents))
-		if err != nil {
-			return nil, fmt.Errorf("decrypting contents: %w", err)
-		}
+	b, err := Open(contents, keychain)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting contents: %w", err)
 	}
 
 	var cfg model.Config
@@ -123,11 +103,14 @@ func DecryptConfig(
 // return the decrypted bytes. It is up to the caller function to then Marshal
 // that into the correct struct.
 func Decrypt(contents []byte) ([]byte, error) {
-	key := model.GetSalt()
+	key, err := model.GetSalt()
+	if err != nil {
+		return nil, err
+	}
 
 	// Hex decode first
 	hexBuf := make([]byte, hex.DecodedLen(len(contents)))
-	_, err := hex.Decode(hexBuf, contents)
+	_, err = hex.Decode(hexBuf, contents)
 	if err != nil {
 		return nil, fmt.Errorf("decoding hex: %v", err)
 	}
@@ -57,7 +57,7 @@ func TestDecryptVault(t *testing.T) {
 		defer file.Close()
 
 		// Decrypt vault from file
-		decryptedVault, err := DecryptVault(file, key, false)
+		decryptedVault, err := DecryptVault(file, key)
 		assert.NoError(err)
 
 		// Verify the decrypted vault matches the original
@@ -107,7 +107,7 @@ func TestDecryptConfig(t *testing.T) {
 		defer file.Close()
 
 		// Decrypt config from file
-		decryptedConfig, err := DecryptConfig(file, key, false)
+		decryptedConfig, err := DecryptConfig(file, key)
 
 		// Verify the decrypted config matches the original
 		assert.Nil(err)
@@ -148,7 +148,7 @@ func TestDecryptVault_Empty(t *testing.T) {
 		defer file.Close()
 
 		// Decrypt vault from file
-		decryptedVault, err := DecryptVault(file, key, false)
+		decryptedVault, err := DecryptVault(file, key)
 		assert.NoError(err)
 
 		// Verify the decrypted vault is empty
@@ -195,7 +195,7 @@ func TestDecryptVault_VaultEntryLargeData(t *testing.T) {
 		defer file.Close()
 
 		// Decrypt vault from file
-		decryptedVault, err := DecryptVault(file, key, false)
+		decryptedVault, err := DecryptVault(file, key)
 		assert.NoError(err)
 
 		// Verify the decrypted vault matches the original
@@ -245,7 +245,7 @@ func TestDecryptPasswordEdgeCases(t *testing.T) {
 			assert.NotNil(encrypted)
 
 			// Decrypt password
-			decrypted, err := DecryptPassword([]byte(encrypted), key, false)
+			decrypted, err := DecryptPassword([]byte(encrypted), key)
 			assert.NoError(err)
 			assert.Equal(tt.password, decrypted)
 		})
@@ -14,7 +14,7 @@ func EncryptPassword(pw []byte, keychain *model.MasterAESKeyManager) (string, er
 }
 
 // EncryptVault encrypts the whole model.VaultEntry struct to be stored locally
-// on disc. This returns the base64 encoded AES-GCM encrypted vault.
+// on disc. This returns the Envelope holding the AES-GCM encrypted vault.
 // For the most part, after this function is called, utils.WriteToFile() gets
 // called
 func EncryptVault(vault []model.VaultEntry, keychain *model.MasterAESKeyManager) (string, error) {
@@ -23,7 +23,7 @@ func EncryptVault(vault []model.VaultEntry, keychain *model.MasterAESKeyManager)
 		return "", fmt.Errorf("marshaling vault json: %v", err)
 	}
 
-	return keychain.Encrypt(b)
+	return Seal(b, keychain)
 }
 
 // EncryptConfig encrypts the config with AES-256 GCM
@@ -33,5 +33,5 @@ func EncryptConfig(cfg *model.Config, keychain *model.MasterAESKeyManager) (stri
 		return "", fmt.Errorf("marshaling cfg json: %v", err)
 	}
 
-	return keychain.Encrypt(b)
+	return Seal(b, keychain)
 }
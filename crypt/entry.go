@@ -0,0 +1,70 @@
+package crypt
+
+import (
+	"fmt"
+
+	"go-pass/model"
+)
+
+// DecryptEntry returns the entry with its password, TOTP secret, SSH key and
+// secret fields decrypted
+func DecryptEntry(
+	ve model.VaultEntry,
+	keychain *model.MasterAESKeyManager,
+) (model.DecryptedEntry, error) {
+	password, err := DecryptPassword(ve.Password, keychain)
+	if err != nil {
+		return model.DecryptedEntry{}, fmt.Errorf("decrypting password: %v", err)
+	}
+
+	var totp string
+	if len(ve.TOTP) > 0 {
+		totp, err = DecryptPassword(ve.TOTP, keychain)
+		if err != nil {
+			return model.DecryptedEntry{}, fmt.Errorf("decrypting TOTP secret: %v", err)
+		}
+	}
+
+	var sshKey string
+	if len(ve.SSHKey) > 0 {
+		sshKey, err = DecryptPassword(ve.SSHKey, keychain)
+		if err != nil {
+			return model.DecryptedEntry{}, fmt.Errorf("decrypting SSH key: %v", err)
+		}
+	}
+
+	m := ve.Metadata
+	m.Fields, err = DecryptFields(ve.Fields, keychain)
+	if err != nil {
+		return model.DecryptedEntry{}, fmt.Errorf("decrypting fields: %v", err)
+	}
+
+	return model.DecryptedEntry{
+		ID:        ve.ID,
+		Name:      ve.Name,
+		Username:  ve.Username,
+		Password:  password,
+		Notes:     ve.Notes,
+		TOTP:      totp,
+		SSHKey:    sshKey,
+		Metadata:  m,
+		CreatedAt: ve.CreatedAt,
+		UpdatedAt: ve.UpdatedAt,
+	}, nil
+}
+
+// DecryptEntries decrypts every entry, see DecryptEntry
+func DecryptEntries(
+	entries []model.VaultEntry,
+	keychain *model.MasterAESKeyManager,
+) ([]model.DecryptedEntry, error) {
+	decrypted := make([]model.DecryptedEntry, len(entries))
+	for i, ve := range entries {
+		d, err := DecryptEntry(ve, keychain)
+		if err != nil {
+			return nil, fmt.Errorf("'%s': %v", ve.Name, err)
+		}
+		decrypted[i] = d
+	}
+	return decrypted, nil
+}
@@ -0,0 +1,61 @@
+package crypt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/model"
+)
+
+func TestDecryptEntries(t *testing.T) {
+	assert := assert.New(t)
+	key := keyFor(model.DefaultKDF())
+
+	pass, err := EncryptPassword([]byte("pass"), key)
+	assert.NoError(err)
+	totp, err := EncryptPassword([]byte("otpauth://totp/a?secret=JBSWY3DPEHPK3PXP"), key)
+	assert.NoError(err)
+	fields, err := EncryptFields([]model.CustomField{{Name: "pin", Value: "1234", Secret: true}}, key)
+	assert.NoError(err)
+
+	entries := []model.VaultEntry{
+		{
+			ID:        "id",
+			Name:      "github",
+			Username:  "octocat",
+			Password:  []byte(pass),
+			Notes:     "notes",
+			TOTP:      []byte(totp),
+			Metadata:  model.Metadata{URLs: []string{"https://github.com"}, Fields: fields},
+			CreatedAt: 1,
+			UpdatedAt: 2,
+		},
+		{Name: "wifi", Password: []byte(pass)},
+	}
+
+	decrypted, err := DecryptEntries(entries, key)
+	assert.NoError(err)
+	assert.Equal([]model.DecryptedEntry{
+		{
+			ID:       "id",
+			Name:     "github",
+			Username: "octocat",
+			Password: "pass",
+			Notes:    "notes",
+			TOTP:     "otpauth://totp/a?secret=JBSWY3DPEHPK3PXP",
+			Metadata: model.Metadata{
+				URLs:   []string{"https://github.com"},
+				Fields: []model.CustomField{{Name: "pin", Value: "1234", Secret: true}},
+			},
+			CreatedAt: 1,
+			UpdatedAt: 2,
+		},
+		{Name: "wifi", Password: "pass"},
+	}, decrypted)
+	// The vault entry's fields are left encrypted
+	assert.Equal(fields, entries[0].Fields)
+
+	_, err = DecryptEntries([]model.VaultEntry{{Name: "bad", Password: []byte("garbage")}}, key)
+	assert.Error(err)
+}
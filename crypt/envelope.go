@@ -0,0 +1,157 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go-pass/model"
+)
+
+// Header describes how an encrypted file was written. For files written in
+// the current format it is stored in plaintext in front of the ciphertext and
+// is authenticated along with it, so it can't be changed without the
+// decryption failing.
+type Header struct {
+	// Magic is always MAGIC for gopass files
+	Magic string `json:"magic"`
+	// Version is the format version, see the VERSION_ constants
+	Version int `json:"version"`
+	// KDF is the key derivation function that the key was derived with
+	KDF model.KDFParams `json:"kdf"`
+	// Cipher is the cipher the data is encrypted with
+	Cipher string `json:"cipher"`
+}
+
+// Envelope is the on-disk format of the vault, config and backups.
+type Envelope struct {
+	Header
+	// Data is the base64 encoded nonce and ciphertext
+	Data string `json:"data"`
+}
+
+// ReadHeader returns the header of an encrypted file. Files written before the
+// header existed get a synthesized header describing how they were written.
+func ReadHeader(contents []byte) (Header, error) {
+	contents = bytes.TrimSpace(contents)
+
+	switch {
+	case len(contents) > 0 && contents[0] == '{':
+		var env Envelope
+		if err := json.Unmarshal(contents, &env); err != nil {
+			return Header{}, fmt.Errorf("reading header: %v", err)
+		}
+		if env.Magic != MAGIC {
+			return Header{}, errors.New("not a gopass file")
+		}
+		if env.Version != VERSION_ENVELOPE {
+			return Header{}, fmt.Errorf("unsupported format version %d", env.Version)
+		}
+		if env.Cipher != CIPHER_AES_256_GCM {
+			return Header{}, fmt.Errorf("unsupported cipher '%s'", env.Cipher)
+		}
+		if err := env.KDF.Validate(); err != nil {
+			return Header{}, err
+		}
+		return env.Header, nil
+	case isLegacyHex(contents):
+		return Header{Magic: MAGIC, Version: VERSION_LEGACY_HEX, Cipher: CIPHER_AES_256_GCM}, nil
+	default:
+		return Header{
+			Magic:   MAGIC,
+			Version: VERSION_BASE64,
+			KDF:     model.LegacyKDF(),
+			Cipher:  CIPHER_AES_256_GCM,
+		}, nil
+	}
+}
+
+// Seal encrypts plaintext with the keychain's current KDF and returns the
+// envelope, ready to be written to disk.
+func Seal(plaintext []byte, keychain *model.MasterAESKeyManager) (string, error) {
+	header := Header{
+		Magic:   MAGIC,
+		Version: VERSION_ENVELOPE,
+		KDF:     keychain.KDF(),
+		Cipher:  CIPHER_AES_256_GCM,
+	}
+
+	aad, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("marshaling header: %v", err)
+	}
+
+	sealed, err := keychain.Seal(plaintext, aad)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(Envelope{
+		Header: header,
+		Data:   base64.StdEncoding.EncodeToString(sealed),
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshaling envelope: %v", err)
+	}
+
+	return string(b), nil
+}
+
+// Open decrypts the contents of a file written by Seal, or by any of the
+// older formats. The keychain is switched to the KDF that the file was written
+// with.
+func Open(contents []byte, keychain *model.MasterAESKeyManager) ([]byte, error) {
+	header, err := ReadHeader(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	switch header.Version {
+	case VERSION_LEGACY_HEX:
+		return Decrypt(bytes.TrimSpace(contents))
+	case VERSION_BASE64:
+		if err := keychain.UseKDF(header.KDF); err != nil {
+			return nil, err
+		}
+		return keychain.Decrypt(string(bytes.TrimSpace(contents)))
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(contents, &env); err != nil {
+		return nil, fmt.Errorf("reading envelope: %v", err)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(env.Data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding data: %v", err)
+	}
+
+	aad, err := json.Marshal(header)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling header: %v", err)
+	}
+
+	if err := keychain.UseKDF(header.KDF); err != nil {
+		return nil, err
+	}
+
+	return keychain.Open(sealed, aad)
+}
+
+// isLegacyHex reports whether b is in the original format, which hex encoded
+// the ciphertext. Valid base64 can in theory be all hex digits, but not for
+// anything as long as a nonce and a GCM tag.
+func isLegacyHex(b []byte) bool {
+	if len(b) == 0 || len(b)%2 != 0 {
+		return false
+	}
+
+	for _, c := range b {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
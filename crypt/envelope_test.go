@@ -0,0 +1,106 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/model"
+)
+
+// keyFor returns a key manager with a fixed key for the given KDF, so these
+// tests don't need the OS keyring.
+func keyFor(params model.KDFParams) *model.MasterAESKeyManager {
+	return model.NewMasterAESKeyManagerFromKey(bytes.Repeat([]byte{3}, model.KEY_SIZE), params)
+}
+
+func TestSealOpen(t *testing.T) {
+	assert := assert.New(t)
+	key := keyFor(model.DefaultKDF())
+
+	sealed, err := Seal([]byte("plaintext"), key)
+	assert.NoError(err)
+
+	header, err := ReadHeader([]byte(sealed))
+	assert.NoError(err)
+	assert.Equal(Header{
+		Magic:   MAGIC,
+		Version: VERSION_ENVELOPE,
+		KDF:     model.DefaultKDF(),
+		Cipher:  CIPHER_AES_256_GCM,
+	}, header)
+
+	opened, err := Open([]byte(sealed), key)
+	assert.NoError(err)
+	assert.Equal("plaintext", string(opened))
+}
+
+func TestOpenSwitchesKDF(t *testing.T) {
+	assert := assert.New(t)
+
+	sealed, err := Seal([]byte("plaintext"), keyFor(model.LegacyKDF()))
+	assert.NoError(err)
+
+	// The manager only has a key for the legacy KDF, and starts out on it
+	key := keyFor(model.LegacyKDF())
+	assert.NoError(key.UseKDF(model.DefaultKDF()))
+
+	opened, err := Open([]byte(sealed), key)
+	assert.NoError(err)
+	assert.Equal("plaintext", string(opened))
+	assert.Equal(model.LegacyKDF(), key.KDF())
+}
+
+func TestOpenRejectsTamperedHeader(t *testing.T) {
+	assert := assert.New(t)
+	key := keyFor(model.LegacyKDF())
+
+	sealed, err := Seal([]byte("plaintext"), key)
+	assert.NoError(err)
+
+	var env Envelope
+	assert.NoError(json.Unmarshal([]byte(sealed), &env))
+
+	// Same key, but the header no longer matches what was authenticated
+	env.Cipher = CIPHER_AES_256_GCM + " "
+	b, err := json.Marshal(env)
+	assert.NoError(err)
+	_, err = Open(b, key)
+	assert.Error(err)
+
+	env.Cipher = CIPHER_AES_256_GCM
+	env.Magic = "NOTGOPASS"
+	b, err = json.Marshal(env)
+	assert.NoError(err)
+	_, err = Open(b, key)
+	assert.Error(err)
+}
+
+func TestReadHeaderLegacyFormats(t *testing.T) {
+	assert := assert.New(t)
+
+	// Bare base64, as written before the envelope existed
+	key := keyFor(model.LegacyKDF())
+	bare, err := key.Encrypt([]byte("plaintext"))
+	assert.NoError(err)
+
+	header, err := ReadHeader([]byte(bare))
+	assert.NoError(err)
+	assert.Equal(VERSION_BASE64, header.Version)
+	assert.Equal(model.LegacyKDF(), header.KDF)
+
+	opened, err := Open([]byte(bare), keyFor(model.DefaultKDF()))
+	assert.Error(err, "a key for the wrong KDF can't open it")
+	opened, err = Open([]byte(bare), key)
+	assert.NoError(err)
+	assert.Equal("plaintext", string(opened))
+
+	// Hex, the original format
+	header, err = ReadHeader([]byte(hex.EncodeToString([]byte(strings.Repeat("x", 40)))))
+	assert.NoError(err)
+	assert.Equal(VERSION_LEGACY_HEX, header.Version)
+}
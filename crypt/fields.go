@@ -0,0 +1,54 @@
+package crypt
+
+import (
+	"fmt"
+
+	"go-pass/model"
+)
+
+// EncryptFields returns a copy of the fields with the value of every secret
+// field encrypted, the same way as a password.
+func EncryptFields(
+	fields []model.CustomField,
+	keychain *model.MasterAESKeyManager,
+) ([]model.CustomField, error) {
+	return mapSecretFields(fields, func(f model.CustomField) (string, error) {
+		return EncryptPassword([]byte(f.Value), keychain)
+	})
+}
+
+// DecryptFields returns a copy of the fields with the value of every secret
+// field decrypted.
+func DecryptFields(
+	fields []model.CustomField,
+	keychain *model.MasterAESKeyManager,
+) ([]model.CustomField, error) {
+	return mapSecretFields(fields, func(f model.CustomField) (string, error) {
+		return DecryptPassword([]byte(f.Value), keychain)
+	})
+}
+
+// mapSecretFields returns a copy of the fields with fn applied to the value of
+// every secret field
+func mapSecretFields(
+	fields []model.CustomField,
+	fn func(model.CustomField) (string, error),
+) ([]model.CustomField, error) {
+	if fields == nil {
+		return nil, nil
+	}
+
+	mapped := make([]model.CustomField, len(fields))
+	for i, f := range fields {
+		if f.Secret {
+			value, err := fn(f)
+			if err != nil {
+				return nil, fmt.Errorf("field '%s': %v", f.Name, err)
+			}
+			f.Value = value
+		}
+		mapped[i] = f
+	}
+
+	return mapped, nil
+}
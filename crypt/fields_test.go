@@ -0,0 +1,69 @@
+package crypt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/model"
+)
+
+func TestEncryptDecryptFields(t *testing.T) {
+	assert := assert.New(t)
+	key := keyFor(model.DefaultKDF())
+
+	fields := []model.CustomField{
+		{Name: "recovery email", Value: "me@example.com"},
+		{Name: "pin", Value: "1234", Secret: true},
+	}
+
+	encrypted, err := EncryptFields(fields, key)
+	assert.NoError(err)
+	assert.Equal("me@example.com", encrypted[0].Value)
+	assert.NotEqual("1234", encrypted[1].Value)
+	assert.True(encrypted[1].Secret)
+	// The input isn't modified
+	assert.Equal("1234", fields[1].Value)
+
+	decrypted, err := DecryptFields(encrypted, key)
+	assert.NoError(err)
+	assert.Equal(fields, decrypted)
+
+	none, err := EncryptFields(nil, key)
+	assert.NoError(err)
+	assert.Nil(none)
+}
+
+func TestReEncryptEntriesFields(t *testing.T) {
+	assert := assert.New(t)
+	from := keyFor(model.DefaultKDF())
+	to := model.NewMasterAESKeyManagerFromKey(bytes.Repeat([]byte{4}, model.KEY_SIZE), model.DefaultKDF())
+
+	pass, err := EncryptPassword([]byte("pass"), from)
+	assert.NoError(err)
+	fields, err := EncryptFields([]model.CustomField{
+		{Name: "note", Value: "plain"},
+		{Name: "pin", Value: "1234", Secret: true},
+	}, from)
+	assert.NoError(err)
+
+	entries := []model.VaultEntry{{
+		Name:     "github",
+		Password: []byte(pass),
+		Metadata: model.Metadata{Fields: fields},
+	}}
+
+	reEncrypted, err := ReEncryptEntries(entries, from, to)
+	assert.NoError(err)
+
+	_, err = DecryptFields(reEncrypted[0].Fields, from)
+	assert.Error(err)
+
+	decrypted, err := DecryptFields(reEncrypted[0].Fields, to)
+	assert.NoError(err)
+	assert.Equal([]model.CustomField{
+		{Name: "note", Value: "plain"},
+		{Name: "pin", Value: "1234", Secret: true},
+	}, decrypted)
+}
@@ -0,0 +1,71 @@
+package crypt
+
+import (
+	"fmt"
+
+	"go-pass/model"
+)
+
+// ReEncryptEntries decrypts the password, TOTP secret, SSH key and secret
+// fields of every entry with 'from' and encrypts them again with 'to'. The entries are copied, so the originals are
+// left untouched if this fails partway through.
+func ReEncryptEntries(
+	entries []model.VaultEntry,
+	from, to *model.MasterAESKeyManager,
+) ([]model.VaultEntry, error) {
+	reEncrypted := make([]model.VaultEntry, len(entries))
+
+	for i, e := range entries {
+		pass, err := DecryptPassword(e.Password, from)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting password for '%s': %v", e.Name, err)
+		}
+
+		encryptedPass, err := EncryptPassword([]byte(pass), to)
+		if err != nil {
+			return nil, fmt.Errorf("encrypting password for '%s': %v", e.Name, err)
+		}
+
+		e.Password = []byte(encryptedPass)
+
+		if len(e.TOTP) > 0 {
+			totp, err := DecryptPassword(e.TOTP, from)
+			if err != nil {
+				return nil, fmt.Errorf("decrypting TOTP for '%s': %v", e.Name, err)
+			}
+
+			encryptedTOTP, err := EncryptPassword([]byte(totp), to)
+			if err != nil {
+				return nil, fmt.Errorf("encrypting TOTP for '%s': %v", e.Name, err)
+			}
+			e.TOTP = []byte(encryptedTOTP)
+		}
+
+		if len(e.SSHKey) > 0 {
+			sshKey, err := DecryptPassword(e.SSHKey, from)
+			if err != nil {
+				return nil, fmt.Errorf("decrypting SSH key for '%s': %v", e.Name, err)
+			}
+
+			encryptedSSHKey, err := EncryptPassword([]byte(sshKey), to)
+			if err != nil {
+				return nil, fmt.Errorf("encrypting SSH key for '%s': %v", e.Name, err)
+			}
+			e.SSHKey = []byte(encryptedSSHKey)
+		}
+
+		fields, err := DecryptFields(e.Fields, from)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting fields for '%s': %v", e.Name, err)
+		}
+
+		e.Fields, err = EncryptFields(fields, to)
+		if err != nil {
+			return nil, fmt.Errorf("encrypting fields for '%s': %v", e.Name, err)
+		}
+
+		reEncrypted[i] = e
+	}
+
+	return reEncrypted, nil
+}
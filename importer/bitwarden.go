@@ -0,0 +1,164 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"go-pass/model"
+)
+
+// The types of Bitwarden items
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+	bitwardenCard       = 3
+	bitwardenIdentity   = 4
+)
+
+// The types of Bitwarden custom fields
+const (
+	bitwardenFieldText   = 0
+	bitwardenFieldHidden = 1
+)
+
+type bitwardenExport struct {
+	Encrypted bool              `json:"encrypted"`
+	Folders   []bitwardenFolder `json:"folders"`
+	Items     []bitwardenItem   `json:"items"`
+}
+
+type bitwardenFolder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type bitwardenItem struct {
+	Type         int                    `json:"type"`
+	Name         string                 `json:"name"`
+	Notes        string                 `json:"notes"`
+	FolderID     string                 `json:"folderId"`
+	Fields       []bitwardenField       `json:"fields"`
+	Login        *bitwardenLoginDetails `json:"login"`
+	Card         map[string]any         `json:"card"`
+	Identity     map[string]any         `json:"identity"`
+	CreationDate string                 `json:"creationDate"`
+	RevisionDate string                 `json:"revisionDate"`
+}
+
+type bitwardenLoginDetails struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	TOTP     string `json:"totp"`
+	URIs     []struct {
+		URI string `json:"uri"`
+	} `json:"uris"`
+}
+
+type bitwardenField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Type  int    `json:"type"`
+}
+
+// bitwardenSecretKeys are the card and identity details kept as secret fields
+var bitwardenSecretKeys = map[string]bool{
+	"number":         true,
+	"code":           true,
+	"ssn":            true,
+	"passportNumber": true,
+	"licenseNumber":  true,
+}
+
+// ParseBitwardenJSON parses an unencrypted JSON export from Bitwarden. Logins
+// and secure notes are mapped onto entries, and the details of cards and
+// identities become custom fields.
+func ParseBitwardenJSON(r io.Reader) ([]model.DecryptedEntry, error) {
+	var export bitwardenExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("decoding json: %v", err)
+	}
+	if export.Encrypted {
+		return nil, errors.New("encrypted exports are not supported, export as unencrypted json")
+	}
+
+	folders := make(map[string]string, len(export.Folders))
+	for _, f := range export.Folders {
+		folders[f.ID] = f.Name
+	}
+
+	entries := make([]model.DecryptedEntry, 0, len(export.Items))
+	for _, item := range export.Items {
+		e := model.DecryptedEntry{
+			Name:      item.Name,
+			Notes:     item.Notes,
+			CreatedAt: parseRFC3339(item.CreationDate),
+			UpdatedAt: parseRFC3339(item.RevisionDate),
+		}
+		e.Folder = folders[item.FolderID]
+
+		switch item.Type {
+		case bitwardenLogin:
+			if item.Login != nil {
+				e.Username = item.Login.Username
+				e.Password = item.Login.Password
+				e.TOTP = item.Login.TOTP
+				for _, u := range item.Login.URIs {
+					e.URLs = appendURL(e.URLs, u.URI)
+				}
+			}
+		case bitwardenCard:
+			e.Fields = append(e.Fields, bitwardenDetails(item.Card)...)
+		case bitwardenIdentity:
+			e.Fields = append(e.Fields, bitwardenDetails(item.Identity)...)
+		}
+
+		for _, f := range item.Fields {
+			e.SetField(model.CustomField{
+				Name:   f.Name,
+				Value:  f.Value,
+				Secret: f.Type == bitwardenFieldHidden,
+			})
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// bitwardenDetails returns the non-empty details of a card or identity as
+// custom fields, in a stable order
+func bitwardenDetails(details map[string]any) []model.CustomField {
+	keys := make([]string, 0, len(details))
+	for k := range details {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var fields []model.CustomField
+	for _, k := range keys {
+		s, ok := details[k].(string)
+		if !ok || s == "" {
+			continue
+		}
+		fields = append(fields, model.CustomField{
+			Name:   k,
+			Value:  s,
+			Secret: bitwardenSecretKeys[k],
+		})
+	}
+	return fields
+}
+
+// parseRFC3339 parses a timestamp into milliseconds, returning 0 if it can't
+func parseRFC3339(s string) int64 {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0
+	}
+	return t.UnixMilli()
+}
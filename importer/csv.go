@@ -0,0 +1,249 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go-pass/model"
+)
+
+// csvRecord is a row of a CSV export, read by column name
+type csvRecord struct {
+	header map[string]int
+	row    []string
+}
+
+// get returns the value of the first of the columns that the export has
+func (r csvRecord) get(columns ...string) string {
+	for _, c := range columns {
+		if i, ok := r.header[c]; ok && i < len(r.row) {
+			return strings.TrimSpace(r.row[i])
+		}
+	}
+	return ""
+}
+
+// readCSV reads a CSV export with a header row. Column names are matched
+// case-insensitively. 'required' are the columns the export must have.
+func readCSV(r io.Reader, required ...string) ([]string, []csvRecord, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	columns, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading header: %v", err)
+	}
+
+	header := make(map[string]int, len(columns))
+	for i, c := range columns {
+		// Excel adds a byte order mark to the first column
+		c = strings.TrimPrefix(c, "\ufeff")
+		columns[i] = strings.ToLower(strings.TrimSpace(c))
+		header[columns[i]] = i
+	}
+
+	for _, c := range required {
+		if _, ok := header[c]; !ok {
+			return nil, nil, fmt.Errorf("missing column '%s'", c)
+		}
+	}
+
+	var records []csvRecord
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading row: %v", err)
+		}
+		records = append(records, csvRecord{header, row})
+	}
+
+	return columns, records, nil
+}
+
+// ParseChromeCSV parses the passwords exported from Chrome, or any
+// Chromium-based browser
+func ParseChromeCSV(r io.Reader) ([]model.DecryptedEntry, error) {
+	_, records, err := readCSV(r, "url", "username", "password")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]model.DecryptedEntry, 0, len(records))
+	for _, rec := range records {
+		e := model.DecryptedEntry{
+			Name:     rec.get("name"),
+			Username: rec.get("username"),
+			Password: rec.get("password"),
+			Notes:    rec.get("note"),
+		}
+		e.URLs = appendURL(nil, rec.get("url"))
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// ParseFirefoxCSV parses the logins exported from Firefox
+func ParseFirefoxCSV(r io.Reader) ([]model.DecryptedEntry, error) {
+	_, records, err := readCSV(r, "url", "username", "password")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]model.DecryptedEntry, 0, len(records))
+	for _, rec := range records {
+		// Firefox doesn't name logins, so Parse names them by host
+		e := model.DecryptedEntry{
+			Username:  rec.get("username"),
+			Password:  rec.get("password"),
+			CreatedAt: parseMillis(rec.get("timecreated")),
+			UpdatedAt: parseMillis(rec.get("timepasswordchanged")),
+		}
+		e.URLs = appendURL(nil, rec.get("url"))
+		if realm := rec.get("httprealm"); realm != "" {
+			e.Fields = append(e.Fields, model.CustomField{Name: "HTTP realm", Value: realm})
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// lastPassSecureNote is the URL LastPass gives secure notes
+const lastPassSecureNote = "http://sn"
+
+// ParseLastPassCSV parses the CSV exported from LastPass
+func ParseLastPassCSV(r io.Reader) ([]model.DecryptedEntry, error) {
+	_, records, err := readCSV(r, "url", "username", "password", "name")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]model.DecryptedEntry, 0, len(records))
+	for _, rec := range records {
+		e := model.DecryptedEntry{
+			Name:     rec.get("name"),
+			Username: rec.get("username"),
+			Password: rec.get("password"),
+			Notes:    rec.get("extra"),
+			TOTP:     rec.get("totp"),
+		}
+		e.Folder = rec.get("grouping")
+		if u := rec.get("url"); u != lastPassSecureNote {
+			e.URLs = appendURL(nil, u)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// The columns ParseGenericCSV recognises, by the names they commonly go by.
+// Any other column becomes a custom field.
+var (
+	genericName     = []string{"name", "title"}
+	genericUsername = []string{"username", "user", "login", "login_username", "email"}
+	genericPassword = []string{"password", "pass", "login_password"}
+	genericURL      = []string{"url", "urls", "uri", "website", "login_uri"}
+	genericNotes    = []string{"notes", "note", "extra", "comments"}
+	genericTOTP     = []string{"totp", "otp", "login_totp"}
+	genericTags     = []string{"tags", "tag", "labels"}
+	genericFolder   = []string{"folder", "group", "grouping", "path"}
+)
+
+// ParseGenericCSV parses a CSV with a header row naming its columns. Columns
+// are recognised by common names, like 'title' or 'login' for the name and
+// username. URLs and tags may hold several values separated by commas, and any
+// other column becomes a custom field.
+func ParseGenericCSV(r io.Reader) ([]model.DecryptedEntry, error) {
+	columns, records, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[string]bool{}
+	for _, names := range [][]string{
+		genericName, genericUsername, genericPassword, genericURL,
+		genericNotes, genericTOTP, genericTags, genericFolder,
+	} {
+		// Only the first column present is used, the rest are custom fields
+		for _, n := range names {
+			if slices.Contains(columns, n) {
+				known[n] = true
+				break
+			}
+		}
+	}
+	if !known[firstPresent(columns, genericName)] && !known[firstPresent(columns, genericURL)] {
+		return nil, errors.New("needs a name or url column")
+	}
+
+	entries := make([]model.DecryptedEntry, 0, len(records))
+	for _, rec := range records {
+		e := model.DecryptedEntry{
+			Name:     rec.get(genericName...),
+			Username: rec.get(genericUsername...),
+			Password: rec.get(genericPassword...),
+			Notes:    rec.get(genericNotes...),
+			TOTP:     rec.get(genericTOTP...),
+		}
+		for _, u := range splitValues(rec.get(genericURL...)) {
+			e.URLs = appendURL(e.URLs, u)
+		}
+		e.Tags = splitValues(rec.get(genericTags...))
+		e.Folder = rec.get(genericFolder...)
+
+		for i, c := range columns {
+			if known[c] || i >= len(rec.row) || strings.TrimSpace(rec.row[i]) == "" {
+				continue
+			}
+			e.SetField(model.CustomField{Name: c, Value: strings.TrimSpace(rec.row[i])})
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// firstPresent returns the first of 'names' that is one of the columns
+func firstPresent(columns, names []string) string {
+	for _, n := range names {
+		if slices.Contains(columns, n) {
+			return n
+		}
+	}
+	return ""
+}
+
+// splitValues splits a cell holding several values separated by commas
+func splitValues(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// parseMillis parses a timestamp in milliseconds, returning 0 if it can't
+func parseMillis(s string) int64 {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return ms
+}
@@ -0,0 +1,133 @@
+// Package importer parses the exports of other password managers into
+// plain-text entries, ready to be encrypted and added to the vault.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+
+	"go-pass/kdbx"
+	"go-pass/model"
+)
+
+const (
+	FORMAT_BITWARDEN_JSON = "bitwarden-json"
+	FORMAT_CHROME_CSV     = "chrome-csv"
+	FORMAT_FIREFOX_CSV    = "firefox-csv"
+	FORMAT_LASTPASS_CSV   = "lastpass-csv"
+	FORMAT_1PASSWORD_1PUX = "1password-1pux"
+	FORMAT_GENERIC_CSV    = "generic-csv"
+	// FORMAT_KDBX is a KeePass database, which needs a passphrase, so it is
+	// parsed with ParseKDBX rather than Parse
+	FORMAT_KDBX = "kdbx"
+	// FORMAT_PASSWORD_STORE is a directory kept by 'pass', which is parsed with
+	// ParsePasswordStore rather than Parse
+	FORMAT_PASSWORD_STORE = "password-store"
+)
+
+// Parser parses an export into plain-text entries. Secret custom fields are
+// marked Secret but not encrypted.
+type Parser func(r io.Reader) ([]model.DecryptedEntry, error)
+
+var parsers = map[string]Parser{
+	FORMAT_BITWARDEN_JSON: ParseBitwardenJSON,
+	FORMAT_CHROME_CSV:     ParseChromeCSV,
+	FORMAT_FIREFOX_CSV:    ParseFirefoxCSV,
+	FORMAT_LASTPASS_CSV:   ParseLastPassCSV,
+	FORMAT_1PASSWORD_1PUX: Parse1PUX,
+	FORMAT_GENERIC_CSV:    ParseGenericCSV,
+}
+
+// Formats returns the names of the supported formats, sorted
+func Formats() []string {
+	formats := make([]string, 0, len(parsers)+2)
+	for f := range parsers {
+		formats = append(formats, f)
+	}
+	formats = append(formats, FORMAT_KDBX, FORMAT_PASSWORD_STORE)
+	slices.Sort(formats)
+	return formats
+}
+
+// Parse parses 'r' as an export in the given format
+func Parse(format string, r io.Reader) ([]model.DecryptedEntry, error) {
+	switch format {
+	case FORMAT_KDBX:
+		return nil, errors.New("kdbx needs a passphrase, use ParseKDBX")
+	case FORMAT_PASSWORD_STORE:
+		return nil, errors.New("password-store is a directory, use ParsePasswordStore")
+	}
+
+	parse, ok := parsers[format]
+	if !ok {
+		return nil, fmt.Errorf(
+			"unknown format '%s', must be one of: %s",
+			format, strings.Join(Formats(), ", "),
+		)
+	}
+
+	entries, err := parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", format, err)
+	}
+
+	return named(entries), nil
+}
+
+// ParseKDBX parses a KeePass KDBX 4 database protected by the passphrase
+func ParseKDBX(r io.Reader, passphrase []byte) ([]model.DecryptedEntry, error) {
+	entries, err := kdbx.Read(r, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", FORMAT_KDBX, err)
+	}
+	return named(entries), nil
+}
+
+// named gives every entry a name, see entryName
+func named(entries []model.DecryptedEntry) []model.DecryptedEntry {
+	for i := range entries {
+		entries[i].Name = entryName(entries[i])
+	}
+	return entries
+}
+
+// entryName returns the entry's name, falling back to the host of its first
+// URL, then its username, for exports that don't always name entries
+func entryName(e model.DecryptedEntry) string {
+	if name := strings.TrimSpace(e.Name); name != "" {
+		return name
+	}
+
+	for _, u := range e.URLs {
+		if host := hostOf(u); host != "" {
+			return host
+		}
+	}
+
+	if e.Username != "" {
+		return e.Username
+	}
+	return "Untitled"
+}
+
+// hostOf returns the host of a URL, or "" if it doesn't have one
+func hostOf(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// appendURL appends the URL to the list if it is not empty or already there
+func appendURL(urls []string, u string) []string {
+	u = strings.TrimSpace(u)
+	if u == "" || slices.Contains(urls, u) {
+		return urls
+	}
+	return append(urls, u)
+}
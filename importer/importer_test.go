@@ -0,0 +1,374 @@
+package importer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/kdbx"
+	"go-pass/model"
+)
+
+func millis(s string) int64 {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t.UnixMilli()
+}
+
+func TestParse(t *testing.T) {
+	githubTOTP := "otpauth://totp/GitHub:octocat?secret=JBSWY3DPEHPK3PXP&issuer=GitHub"
+
+	tests := []struct {
+		format   string
+		fixture  string
+		expected []model.DecryptedEntry
+	}{
+		{
+			format:  FORMAT_BITWARDEN_JSON,
+			fixture: "bitwarden.json",
+			expected: []model.DecryptedEntry{
+				{
+					Name:     "GitHub",
+					Username: "octocat",
+					Password: "hunter2",
+					Notes:    "recovery codes in the safe",
+					TOTP:     githubTOTP,
+					Metadata: model.Metadata{
+						URLs:   []string{"https://github.com/login", "https://github.com"},
+						Folder: "Work",
+						Fields: []model.CustomField{
+							{Name: "recovery email", Value: "me@example.com"},
+							{Name: "pin", Value: "1234", Secret: true},
+						},
+					},
+					CreatedAt: millis("2024-01-02T03:04:05Z"),
+					UpdatedAt: millis("2024-02-03T04:05:06Z"),
+				},
+				{
+					Name:      "Wifi",
+					Notes:     "password is on the router",
+					CreatedAt: millis("2024-01-02T03:04:05Z"),
+					UpdatedAt: millis("2024-01-02T03:04:05Z"),
+				},
+				{
+					Name: "Visa",
+					Metadata: model.Metadata{
+						Fields: []model.CustomField{
+							{Name: "brand", Value: "Visa"},
+							{Name: "cardholderName", Value: "Mona Lisa"},
+							{Name: "code", Value: "123", Secret: true},
+							{Name: "expMonth", Value: "12"},
+							{Name: "expYear", Value: "2030"},
+							{Name: "number", Value: "4111111111111111", Secret: true},
+						},
+					},
+				},
+			},
+		},
+		{
+			format:  FORMAT_CHROME_CSV,
+			fixture: "chrome.csv",
+			expected: []model.DecryptedEntry{
+				{
+					Name:     "github.com",
+					Username: "octocat",
+					Password: "hunter2",
+					Notes:    "work account",
+					Metadata: model.Metadata{URLs: []string{"https://github.com/login"}},
+				},
+				{
+					Name:     "example.com",
+					Password: "pass,with,commas",
+					Metadata: model.Metadata{URLs: []string{"https://example.com/"}},
+				},
+			},
+		},
+		{
+			format:  FORMAT_FIREFOX_CSV,
+			fixture: "firefox.csv",
+			expected: []model.DecryptedEntry{
+				{
+					Name:      "github.com",
+					Username:  "octocat",
+					Password:  "hunter2",
+					Metadata:  model.Metadata{URLs: []string{"https://github.com"}},
+					CreatedAt: 1704164645000,
+					UpdatedAt: 1706933106000,
+				},
+				{
+					Name:     "intranet.example.com",
+					Username: "admin",
+					Password: "s3cret",
+					Metadata: model.Metadata{
+						URLs:   []string{"https://intranet.example.com"},
+						Fields: []model.CustomField{{Name: "HTTP realm", Value: "Intranet"}},
+					},
+					CreatedAt: 1704164645000,
+					UpdatedAt: 1704164645000,
+				},
+			},
+		},
+		{
+			format:  FORMAT_LASTPASS_CSV,
+			fixture: "lastpass.csv",
+			expected: []model.DecryptedEntry{
+				{
+					Name:     "GitHub",
+					Username: "octocat",
+					Password: "hunter2",
+					Notes:    "work account",
+					TOTP:     "JBSWY3DPEHPK3PXP",
+					Metadata: model.Metadata{
+						URLs:   []string{"https://github.com/login"},
+						Folder: "Work",
+					},
+				},
+				{
+					Name:     "DB server",
+					Notes:    "NoteType:Server\nHostname:db.example.com",
+					Metadata: model.Metadata{Folder: "Servers"},
+				},
+			},
+		},
+		{
+			format:  FORMAT_1PASSWORD_1PUX,
+			fixture: "1password.1pux",
+			expected: []model.DecryptedEntry{
+				{
+					Name:     "GitHub",
+					Username: "octocat",
+					Password: "hunter2",
+					Notes:    "work account",
+					TOTP:     githubTOTP,
+					Metadata: model.Metadata{
+						URLs:   []string{"https://github.com", "https://gist.github.com"},
+						Tags:   []string{"work"},
+						Folder: "Private",
+						Fields: []model.CustomField{
+							{Name: "pin", Value: "1234", Secret: true},
+							{Name: "recovery email", Value: "me@example.com"},
+						},
+					},
+					CreatedAt: 1704164645000,
+					UpdatedAt: 1706933106000,
+				},
+				{
+					Name:      "Router",
+					Password:  "router-pass",
+					Metadata:  model.Metadata{Folder: "Private"},
+					CreatedAt: 1704164645000,
+					UpdatedAt: 1704164645000,
+				},
+			},
+		},
+		{
+			format:  FORMAT_GENERIC_CSV,
+			fixture: "generic.csv",
+			expected: []model.DecryptedEntry{
+				{
+					Name:     "GitHub",
+					Username: "octocat",
+					Password: "hunter2",
+					Notes:    "work account",
+					Metadata: model.Metadata{
+						URLs:   []string{"https://github.com", "https://gist.github.com"},
+						Tags:   []string{"work", "code"},
+						Folder: "Work",
+						Fields: []model.CustomField{{Name: "security question", Value: "first pet"}},
+					},
+				},
+				{
+					Name:     "intranet.example.com",
+					Username: "admin",
+					Password: "s3cret",
+					Metadata: model.Metadata{URLs: []string{"https://intranet.example.com"}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.fixture))
+			assert.NoError(t, err)
+			defer f.Close()
+
+			entries, err := Parse(tt.format, f)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, entries)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+	}{
+		{"unknown format", "keepass-xml", ""},
+		{"kdbx without a passphrase", FORMAT_KDBX, ""},
+		{"password store without a decryptor", FORMAT_PASSWORD_STORE, ""},
+		{"empty csv", FORMAT_CHROME_CSV, ""},
+		{"missing column", FORMAT_CHROME_CSV, "name,url,username\na,b,c\n"},
+		{"no name or url", FORMAT_GENERIC_CSV, "username,password\na,b\n"},
+		{"bad json", FORMAT_BITWARDEN_JSON, "{"},
+		{"encrypted bitwarden", FORMAT_BITWARDEN_JSON, `{"encrypted": true, "items": []}`},
+		{"not a zip", FORMAT_1PASSWORD_1PUX, "not a zip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.format, strings.NewReader(tt.input))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFormats(t *testing.T) {
+	assert.Equal(t, []string{
+		FORMAT_1PASSWORD_1PUX,
+		FORMAT_BITWARDEN_JSON,
+		FORMAT_CHROME_CSV,
+		FORMAT_FIREFOX_CSV,
+		FORMAT_GENERIC_CSV,
+		FORMAT_KDBX,
+		FORMAT_LASTPASS_CSV,
+		FORMAT_PASSWORD_STORE,
+	}, Formats())
+}
+
+func TestParseKDBX(t *testing.T) {
+	assert := assert.New(t)
+
+	var b bytes.Buffer
+	assert.NoError(kdbx.Write(&b, []model.DecryptedEntry{
+		{Name: "GitHub", Username: "octocat", Password: "hunter2"},
+		{Metadata: model.Metadata{URLs: []string{"https://example.com/login"}}},
+	}, []byte("passphrase")))
+	kdbxFile := b.Bytes()
+
+	entries, err := ParseKDBX(bytes.NewReader(kdbxFile), []byte("passphrase"))
+	assert.NoError(err)
+	assert.Len(entries, 2)
+	assert.Equal("GitHub", entries[0].Name)
+	assert.Equal("hunter2", entries[0].Password)
+	// Untitled entries are named by their URL, like the other formats
+	assert.Equal("example.com", entries[1].Name)
+
+	_, err = ParseKDBX(bytes.NewReader(kdbxFile), []byte("wrong"))
+	assert.ErrorIs(err, kdbx.ErrInvalidPassphrase)
+}
+
+// plaintextDecryptor reads password store fixtures, which are not encrypted
+type plaintextDecryptor struct{}
+
+func (plaintextDecryptor) Decrypt(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func TestParsePasswordStore(t *testing.T) {
+	assert := assert.New(t)
+
+	entries, err := ParsePasswordStore(filepath.Join("testdata", "password-store"), plaintextDecryptor{})
+	assert.NoError(err)
+
+	// Times come from the files, which depend on the checkout
+	for i := range entries {
+		assert.NotZero(entries[i].CreatedAt)
+		assert.Equal(entries[i].CreatedAt, entries[i].UpdatedAt)
+		entries[i].CreatedAt, entries[i].UpdatedAt = 0, 0
+	}
+
+	assert.Equal([]model.DecryptedEntry{
+		{
+			Name:     "github.com",
+			Username: "octocat",
+			Password: "hunter2",
+			Notes:    "recovery codes are in the safe",
+			TOTP:     "otpauth://totp/GitHub:octocat?secret=JBSWY3DPEHPK3PXP&issuer=GitHub",
+			Metadata: model.Metadata{URLs: []string{"https://github.com"}},
+		},
+		{
+			Name:     "aws",
+			Username: "admin",
+			Password: "s3cret",
+			Notes:    "MFA is on the yubikey\nrotate quarterly",
+			Metadata: model.Metadata{
+				URLs:   []string{"https://console.aws.amazon.com"},
+				Folder: "work",
+				Fields: []model.CustomField{{Name: "account id", Value: "123456789012"}},
+			},
+		},
+		{
+			Name:     "postgres",
+			Password: "pg-pass",
+			Metadata: model.Metadata{Folder: "work/db"},
+		},
+	}, entries)
+
+	_, err = ParsePasswordStore(filepath.Join("testdata", "chrome.csv"), plaintextDecryptor{})
+	assert.Error(err)
+	_, err = ParsePasswordStore(filepath.Join("testdata", "missing"), plaintextDecryptor{})
+	assert.Error(err)
+}
+
+func TestParsePassFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected model.DecryptedEntry
+	}{
+		{"password only", "pw", model.DecryptedEntry{Password: "pw"}},
+		{"windows line endings", "pw\r\nuser: me\r\n", model.DecryptedEntry{Password: "pw", Username: "me"}},
+		{
+			"first username wins",
+			"pw\nlogin: a\nusername: b",
+			model.DecryptedEntry{
+				Password: "pw",
+				Username: "a",
+				Metadata: model.Metadata{Fields: []model.CustomField{{Name: "username", Value: "b"}}},
+			},
+		},
+		{
+			"totp key",
+			"pw\ntotp: JBSWY3DPEHPK3PXP",
+			model.DecryptedEntry{Password: "pw", TOTP: "JBSWY3DPEHPK3PXP"},
+		},
+		{"colon without a space", "pw\nnote:nospace", model.DecryptedEntry{Password: "pw", Notes: "note:nospace"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parsePassFile(tt.input))
+		})
+	}
+}
+
+func TestGPGDecryptor(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script as a stand-in for gpg")
+	}
+	assert := assert.New(t)
+
+	// A stand-in for gpg that prints the file it is given
+	gpg := filepath.Join(t.TempDir(), "gpg")
+	script := "#!/bin/sh\nfor last; do :; done\nexec cat \"$last\"\n"
+	assert.NoError(os.WriteFile(gpg, []byte(script), 0o700))
+
+	path := filepath.Join("testdata", "password-store", "work", "db", "postgres.gpg")
+	b, err := GPGDecryptor{Program: gpg}.Decrypt(path)
+	assert.NoError(err)
+	assert.Equal("pg-pass\n", string(b))
+
+	_, err = GPGDecryptor{Program: gpg}.Decrypt(filepath.Join("testdata", "missing.gpg"))
+	assert.ErrorContains(err, "missing.gpg")
+}
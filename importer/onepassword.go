@@ -0,0 +1,184 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"go-pass/model"
+)
+
+// onePUXData is the file in a 1PUX archive holding the accounts and items
+const onePUXData = "export.data"
+
+// onePUXActive is the state of items that are not archived
+const onePUXActive = "active"
+
+type onePUXExport struct {
+	Accounts []struct {
+		Vaults []struct {
+			Attrs struct {
+				Name string `json:"name"`
+			} `json:"attrs"`
+			Items []onePUXItem `json:"items"`
+		} `json:"vaults"`
+	} `json:"accounts"`
+}
+
+type onePUXItem struct {
+	State     string `json:"state"`
+	CreatedAt int64  `json:"createdAt"`
+	UpdatedAt int64  `json:"updatedAt"`
+	Overview  struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+		URLs  []struct {
+			URL string `json:"url"`
+		} `json:"urls"`
+		Tags []string `json:"tags"`
+	} `json:"overview"`
+	Details struct {
+		LoginFields []struct {
+			Value       string `json:"value"`
+			Designation string `json:"designation"`
+		} `json:"loginFields"`
+		NotesPlain string `json:"notesPlain"`
+		Password   string `json:"password"`
+		Sections   []struct {
+			Fields []struct {
+				Title string                     `json:"title"`
+				Value map[string]json.RawMessage `json:"value"`
+			} `json:"fields"`
+		} `json:"sections"`
+	} `json:"details"`
+}
+
+// Parse1PUX parses a 1PUX export from 1Password. Each item's 1Password vault
+// becomes its folder, and archived items are left out.
+func Parse1PUX(r io.Reader) ([]model.DecryptedEntry, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading archive: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, fmt.Errorf("opening archive: %v", err)
+	}
+
+	data, err := zr.Open(onePUXData)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %v", onePUXData, err)
+	}
+	defer data.Close()
+
+	var export onePUXExport
+	if err := json.NewDecoder(data).Decode(&export); err != nil {
+		return nil, fmt.Errorf("decoding %s: %v", onePUXData, err)
+	}
+
+	var entries []model.DecryptedEntry
+	for _, account := range export.Accounts {
+		for _, vault := range account.Vaults {
+			for _, item := range vault.Items {
+				if item.State != "" && item.State != onePUXActive {
+					continue
+				}
+
+				e, err := onePUXEntry(item)
+				if err != nil {
+					return nil, fmt.Errorf("item '%s': %v", item.Overview.Title, err)
+				}
+				e.Folder = vault.Attrs.Name
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+// onePUXEntry maps an item onto an entry. Login fields give the username and
+// password, and the fields of the item's sections become custom fields, except
+// for the first one-time password, which becomes the TOTP secret.
+func onePUXEntry(item onePUXItem) (model.DecryptedEntry, error) {
+	e := model.DecryptedEntry{
+		Name:      item.Overview.Title,
+		Notes:     item.Details.NotesPlain,
+		Password:  item.Details.Password,
+		CreatedAt: item.CreatedAt * 1000,
+		UpdatedAt: item.UpdatedAt * 1000,
+	}
+	if len(item.Overview.Tags) > 0 {
+		e.Tags = item.Overview.Tags
+	}
+
+	e.URLs = appendURL(e.URLs, item.Overview.URL)
+	for _, u := range item.Overview.URLs {
+		e.URLs = appendURL(e.URLs, u.URL)
+	}
+
+	for _, f := range item.Details.LoginFields {
+		switch f.Designation {
+		case "username":
+			e.Username = f.Value
+		case "password":
+			e.Password = f.Value
+		}
+	}
+
+	for _, section := range item.Details.Sections {
+		for _, f := range section.Fields {
+			kind, value, err := onePUXValue(f.Value)
+			if err != nil {
+				return e, fmt.Errorf("field '%s': %v", f.Title, err)
+			}
+			if value == "" {
+				continue
+			}
+
+			if kind == "totp" && e.TOTP == "" {
+				e.TOTP = value
+				continue
+			}
+
+			e.SetField(model.CustomField{
+				Name:   f.Title,
+				Value:  value,
+				Secret: kind == "concealed" || kind == "totp",
+			})
+		}
+	}
+
+	return e, nil
+}
+
+// onePUXValue returns the kind and value of a section field. Fields hold an
+// object with a single key naming their kind, like {"concealed": "1234"}.
+func onePUXValue(v map[string]json.RawMessage) (string, string, error) {
+	for kind, raw := range v {
+		switch kind {
+		case "email":
+			var email struct {
+				Address string `json:"email_address"`
+			}
+			if err := json.Unmarshal(raw, &email); err != nil {
+				return "", "", err
+			}
+			return kind, email.Address, nil
+		default:
+			var s string
+			if err := json.Unmarshal(raw, &s); err == nil {
+				return kind, s, nil
+			}
+			if string(raw) == "null" {
+				return kind, "", nil
+			}
+			// Dates and the like are numbers, anything else is kept as is
+			return kind, string(raw), nil
+		}
+	}
+	return "", "", nil
+}
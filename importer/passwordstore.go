@@ -0,0 +1,161 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go-pass/model"
+)
+
+// passwordStoreExt is the extension of the files in a password store
+const passwordStoreExt = ".gpg"
+
+// Decryptor decrypts the files of a password store
+type Decryptor interface {
+	Decrypt(path string) ([]byte, error)
+}
+
+// GPGDecryptor decrypts files with gpg, which asks gpg-agent for the key's
+// passphrase like 'pass' does
+type GPGDecryptor struct {
+	// Program is the gpg binary, "gpg" if empty
+	Program string
+}
+
+// Decrypt decrypts the file with gpg
+func (g GPGDecryptor) Decrypt(path string) ([]byte, error) {
+	program := g.Program
+	if program == "" {
+		program = "gpg"
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(program, "--quiet", "--yes", "--batch", "--use-agent", "--decrypt", path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %v: %s", program, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// ParsePasswordStore parses the password store in 'dir', as kept by 'pass',
+// decrypting each file with 'd'. A file's path gives the entry's folder and
+// name, so 'work/github.com.gpg' is 'github.com' in the folder 'work'.
+//
+// The first line of a file is the password. The lines after it follow the
+// conventions of 'pass': 'login: ' or 'username: ' lines give the username,
+// 'url: ' lines and lines that are a URL give the URLs, and an otpauth:// line
+// gives the TOTP secret. Any other 'key: value' line becomes a custom field,
+// and the rest are the notes.
+func ParsePasswordStore(dir string, d Decryptor) ([]model.DecryptedEntry, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", FORMAT_PASSWORD_STORE, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("parsing %s: '%s' is not a directory", FORMAT_PASSWORD_STORE, dir)
+	}
+
+	var entries []model.DecryptedEntry
+	err = filepath.WalkDir(dir, func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip .git, .extensions and the like
+		if de.IsDir() && path != dir && strings.HasPrefix(de.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if de.IsDir() || filepath.Ext(path) != passwordStoreExt {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(strings.TrimSuffix(rel, passwordStoreExt))
+
+		plain, err := d.Decrypt(path)
+		if err != nil {
+			return fmt.Errorf("decrypting '%s': %v", rel, err)
+		}
+
+		e := parsePassFile(string(plain))
+		e.Name = rel[strings.LastIndex(rel, "/")+1:]
+		if i := strings.LastIndex(rel, "/"); i >= 0 {
+			e.Folder = rel[:i]
+		}
+
+		if info, err := de.Info(); err == nil {
+			e.CreatedAt = info.ModTime().UnixMilli()
+			e.UpdatedAt = e.CreatedAt
+		}
+
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", FORMAT_PASSWORD_STORE, err)
+	}
+
+	return named(entries), nil
+}
+
+// The keys of 'key: value' lines that are not custom fields
+var (
+	passUsernameKeys = []string{"login", "username", "user"}
+	passURLKeys      = []string{"url", "website", "site"}
+	passTOTPKeys     = []string{"otp", "totp"}
+)
+
+// parsePassFile parses the decrypted contents of a password store file
+func parsePassFile(s string) model.DecryptedEntry {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+
+	e := model.DecryptedEntry{Password: lines[0]}
+
+	var notes []string
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimSpace(line)
+		lower := strings.ToLower(trimmed)
+
+		switch {
+		case strings.HasPrefix(lower, "otpauth://") && e.TOTP == "":
+			e.TOTP = trimmed
+			continue
+		case strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://"):
+			e.URLs = appendURL(e.URLs, trimmed)
+			continue
+		}
+
+		// A URL has a colon too, so keys are followed by ': '
+		key, value, ok := strings.Cut(trimmed, ": ")
+		if !ok || strings.TrimSpace(key) == "" {
+			notes = append(notes, line)
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		switch k := strings.ToLower(key); {
+		case slices.Contains(passUsernameKeys, k) && e.Username == "":
+			e.Username = value
+		case slices.Contains(passURLKeys, k):
+			e.URLs = appendURL(e.URLs, value)
+		case slices.Contains(passTOTPKeys, k) && e.TOTP == "":
+			e.TOTP = value
+		default:
+			e.SetField(model.CustomField{Name: key, Value: value})
+		}
+	}
+
+	e.Notes = strings.TrimSpace(strings.Join(notes, "\n"))
+	return e
+}
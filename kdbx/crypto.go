@@ -0,0 +1,277 @@
+package kdbx
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/twofish"
+)
+
+// blockSize is the size of the HMAC blocks written
+const blockSize = 1 << 20
+
+// innerStreamChaCha20 is the inner random stream that protects values in the
+// XML. KDBX 4 files always use ChaCha20.
+const innerStreamChaCha20 = 3
+
+// The fields of the inner header
+const (
+	innerHeaderEnd       = 0
+	innerHeaderStreamID  = 1
+	innerHeaderStreamKey = 2
+	innerHeaderBinary    = 3
+)
+
+// keys are the keys derived from the passphrase and the master seed
+type keys struct {
+	cipher []byte
+	hmac   []byte
+}
+
+func deriveKeys(passphrase []byte, h header) (keys, error) {
+	// The composite key hashes each key component, and a passphrase is the only
+	// component supported
+	pw := sha256.Sum256(passphrase)
+	composite := sha256.Sum256(pw[:])
+
+	transformed, err := h.kdf.transformKey(composite[:])
+	if err != nil {
+		return keys{}, err
+	}
+
+	c := sha256.New()
+	c.Write(h.masterSeed)
+	c.Write(transformed)
+
+	m := sha512.New()
+	m.Write(h.masterSeed)
+	m.Write(transformed)
+	m.Write([]byte{1})
+
+	return keys{cipher: c.Sum(nil), hmac: m.Sum(nil)}, nil
+}
+
+// blockHMAC returns the HMAC of a block, with the key for its index. The
+// header uses the index math.MaxUint64.
+func (k keys) blockHMAC(index uint64, data ...[]byte) []byte {
+	idx := make([]byte, 8)
+	binary.LittleEndian.PutUint64(idx, index)
+
+	key := sha512.New()
+	key.Write(idx)
+	key.Write(k.hmac)
+
+	mac := hmac.New(sha256.New, key.Sum(nil))
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+func (k keys) headerHMAC(raw []byte) []byte {
+	return k.blockHMAC(math.MaxUint64, raw)
+}
+
+// readBlocks reads the HMAC block stream, checking each block
+func readBlocks(r io.Reader, k keys) ([]byte, error) {
+	var out bytes.Buffer
+	for index := uint64(0); ; index++ {
+		var mac [32]byte
+		var size uint32
+		if _, err := io.ReadFull(r, mac[:]); err != nil {
+			return nil, fmt.Errorf("reading block: %v", err)
+		}
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return nil, fmt.Errorf("reading block: %v", err)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, fmt.Errorf("reading block: %v", err)
+		}
+
+		sizeBytes := make([]byte, 4)
+		binary.LittleEndian.PutUint32(sizeBytes, size)
+		idx := make([]byte, 8)
+		binary.LittleEndian.PutUint64(idx, index)
+		if !hmac.Equal(mac[:], k.blockHMAC(index, idx, sizeBytes, data)) {
+			return nil, ErrCorrupt
+		}
+
+		if size == 0 {
+			return out.Bytes(), nil
+		}
+		out.Write(data)
+	}
+}
+
+// writeBlocks writes the data as an HMAC block stream
+func writeBlocks(w io.Writer, data []byte, k keys) error {
+	for index := uint64(0); ; index++ {
+		n := min(len(data), blockSize)
+		block := data[:n]
+		data = data[n:]
+
+		sizeBytes := make([]byte, 4)
+		binary.LittleEndian.PutUint32(sizeBytes, uint32(n))
+		idx := make([]byte, 8)
+		binary.LittleEndian.PutUint64(idx, index)
+
+		for _, b := range [][]byte{k.blockHMAC(index, idx, sizeBytes, block), sizeBytes, block} {
+			if _, err := w.Write(b); err != nil {
+				return err
+			}
+		}
+
+		if n == 0 {
+			return nil
+		}
+	}
+}
+
+// decryptPayload decrypts the payload with the cipher named in the header
+func decryptPayload(h header, k keys, payload []byte) ([]byte, error) {
+	if h.cipher == cipherChaCha20 {
+		return chacha20XOR(k.cipher, h.iv, payload)
+	}
+
+	block, err := blockCipher(h.cipher, k.cipher)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) == 0 || len(payload)%block.BlockSize() != 0 {
+		return nil, ErrCorrupt
+	}
+
+	plain := make([]byte, len(payload))
+	cipher.NewCBCDecrypter(block, h.iv).CryptBlocks(plain, payload)
+
+	// PKCS #7 padding
+	pad := int(plain[len(plain)-1])
+	if pad == 0 || pad > block.BlockSize() {
+		return nil, ErrCorrupt
+	}
+	for _, b := range plain[len(plain)-pad:] {
+		if int(b) != pad {
+			return nil, ErrCorrupt
+		}
+	}
+	return plain[:len(plain)-pad], nil
+}
+
+// encryptPayload encrypts the payload with the cipher named in the header
+func encryptPayload(h header, k keys, plain []byte) ([]byte, error) {
+	if h.cipher == cipherChaCha20 {
+		return chacha20XOR(k.cipher, h.iv, plain)
+	}
+
+	block, err := blockCipher(h.cipher, k.cipher)
+	if err != nil {
+		return nil, err
+	}
+
+	pad := block.BlockSize() - len(plain)%block.BlockSize()
+	padded := append(bytes.Clone(plain), bytes.Repeat([]byte{byte(pad)}, pad)...)
+
+	out := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, h.iv).CryptBlocks(out, padded)
+	return out, nil
+}
+
+func blockCipher(id [16]byte, key []byte) (cipher.Block, error) {
+	switch id {
+	case cipherAES256:
+		return aes.NewCipher(key)
+	case cipherTwofish:
+		return twofish.NewCipher(key)
+	}
+	return nil, errors.New("unsupported cipher")
+}
+
+func chacha20XOR(key, nonce, in []byte) ([]byte, error) {
+	c, err := chacha20.NewUnauthenticatedCipher(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(in))
+	c.XORKeyStream(out, in)
+	return out, nil
+}
+
+// innerStream returns the stream that protected values are XORed with
+func innerStream(id uint32, key []byte) (cipher.Stream, error) {
+	if id != innerStreamChaCha20 {
+		return nil, fmt.Errorf("unsupported inner random stream %d", id)
+	}
+	h := sha512.Sum512(key)
+	return chacha20.NewUnauthenticatedCipher(h[:32], h[32:44])
+}
+
+// readInnerHeader reads the inner header at the start of the decrypted
+// payload, returning the inner random stream and the XML after it
+func readInnerHeader(payload []byte) (cipher.Stream, []byte, error) {
+	r := bytes.NewReader(payload)
+
+	var id uint32
+	var key []byte
+	for {
+		var field uint8
+		var size uint32
+		if err := binary.Read(r, binary.LittleEndian, &field); err != nil {
+			return nil, nil, fmt.Errorf("reading inner header: %v", err)
+		}
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return nil, nil, fmt.Errorf("reading inner header: %v", err)
+		}
+		if int64(size) > int64(r.Len()) {
+			return nil, nil, ErrCorrupt
+		}
+		data := make([]byte, size)
+		io.ReadFull(r, data)
+
+		switch field {
+		case innerHeaderEnd:
+			stream, err := innerStream(id, key)
+			if err != nil {
+				return nil, nil, err
+			}
+			return stream, payload[len(payload)-r.Len():], nil
+		case innerHeaderStreamID:
+			if len(data) != 4 {
+				return nil, nil, ErrCorrupt
+			}
+			id = binary.LittleEndian.Uint32(data)
+		case innerHeaderStreamKey:
+			key = data
+		case innerHeaderBinary:
+			// Attachments are not imported
+		}
+	}
+}
+
+// writeInnerHeader returns the inner header for the stream key
+func writeInnerHeader(key []byte) []byte {
+	var b bytes.Buffer
+	field := func(id uint8, data []byte) {
+		b.WriteByte(id)
+		binary.Write(&b, binary.LittleEndian, uint32(len(data)))
+		b.Write(data)
+	}
+
+	id := make([]byte, 4)
+	binary.LittleEndian.PutUint32(id, innerStreamChaCha20)
+
+	field(innerHeaderStreamID, id)
+	field(innerHeaderStreamKey, key)
+	field(innerHeaderEnd, nil)
+	return b.Bytes()
+}
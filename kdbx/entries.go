@@ -0,0 +1,290 @@
+package kdbx
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-pass/model"
+)
+
+// The standard strings of an entry
+const (
+	keyTitle    = "Title"
+	keyUserName = "UserName"
+	keyPassword = "Password"
+	keyURL      = "URL"
+	keyNotes    = "Notes"
+	// keyOTP is where KeePassXC keeps the otpauth:// URI
+	keyOTP = "otp"
+	// keySSHKey keeps the PEM encoded OpenSSH private key of an entry
+	keySSHKey = "gopass_ssh_key"
+	// keyURLPrefix starts the keys of additional URLs, as KeePassXC and
+	// Keepass2Android use
+	keyURLPrefix = "KP2A_URL"
+)
+
+// The custom data keeping the times of an entry to the millisecond, as KDBX
+// times are in seconds
+const (
+	dataCreatedAt = "gopass_created_at"
+	dataUpdatedAt = "gopass_updated_at"
+)
+
+// rootGroupName is the name of the group the folders of exported entries are in
+const rootGroupName = "gopass"
+
+// fromXML maps the entries of the database onto vault entries. The name of the
+// root group is not part of the folders.
+func fromXML(f xmlFile) []model.DecryptedEntry {
+	var entries []model.DecryptedEntry
+
+	var walk func(g xmlGroup, path []string)
+	walk = func(g xmlGroup, path []string) {
+		for _, e := range g.Entries {
+			entries = append(entries, fromXMLEntry(e, strings.Join(path, "/")))
+		}
+		for _, sub := range g.Groups {
+			if sub.UUID == f.Meta.RecycleBinUUID {
+				continue
+			}
+			walk(sub, append(path[:len(path):len(path)], sub.Name))
+		}
+	}
+
+	for _, root := range f.Root.Groups {
+		walk(root, nil)
+	}
+
+	return entries
+}
+
+func fromXMLEntry(e xmlEntry, folder string) model.DecryptedEntry {
+	d := model.DecryptedEntry{
+		ID:        formatUUID(e.UUID),
+		CreatedAt: preciseTime(parseTime(e.Times.CreationTime), e.CustomData, dataCreatedAt),
+		UpdatedAt: preciseTime(parseTime(e.Times.LastModificationTime), e.CustomData, dataUpdatedAt),
+	}
+	d.Folder = folder
+
+	for _, t := range strings.FieldsFunc(e.Tags, func(r rune) bool { return r == ';' || r == ',' }) {
+		if t = strings.TrimSpace(t); t != "" {
+			d.Tags = append(d.Tags, t)
+		}
+	}
+
+	var url string
+	var extraURLs []string
+	for _, s := range e.Strings {
+		v := s.Value.Text
+		switch {
+		case s.Key == keyTitle:
+			d.Name = v
+		case s.Key == keyUserName:
+			d.Username = v
+		case s.Key == keyPassword:
+			d.Password = v
+		case s.Key == keyURL:
+			url = v
+		case s.Key == keyNotes:
+			d.Notes = v
+		case s.Key == keyOTP:
+			d.TOTP = v
+		case s.Key == keySSHKey:
+			d.SSHKey = v
+		case strings.HasPrefix(s.Key, keyURLPrefix):
+			extraURLs = append(extraURLs, v)
+		default:
+			d.SetField(model.CustomField{
+				Name:   s.Key,
+				Value:  v,
+				Secret: isProtectedValue(s.Value),
+			})
+		}
+	}
+
+	for _, u := range append([]string{url}, extraURLs...) {
+		if u = strings.TrimSpace(u); u != "" {
+			d.URLs = append(d.URLs, u)
+		}
+	}
+
+	return d
+}
+
+// preciseTime returns the time in the custom data with 'key' if it is within
+// the same second as 't', which it isn't if the entry was changed in KeePass
+func preciseTime(t int64, data []xmlItem, key string) int64 {
+	for _, item := range data {
+		if item.Key != key {
+			continue
+		}
+		ms, err := strconv.ParseInt(item.Value, 10, 64)
+		if err == nil && ms/1000 == t/1000 {
+			return ms
+		}
+	}
+	return t
+}
+
+// toXML maps the vault entries onto a database, with a group for each folder
+func toXML(entries []model.DecryptedEntry) (xmlFile, error) {
+	now := time.Now().UnixMilli()
+
+	rootUUID, err := newUUID()
+	if err != nil {
+		return xmlFile{}, err
+	}
+	root := xmlGroup{
+		UUID:       rootUUID,
+		Name:       rootGroupName,
+		Times:      newTimes(now, now),
+		IsExpanded: xmlTrue,
+	}
+
+	for _, d := range entries {
+		g := &root
+		for _, name := range strings.Split(d.Folder, "/") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+
+			i := 0
+			for i < len(g.Groups) && g.Groups[i].Name != name {
+				i++
+			}
+			if i == len(g.Groups) {
+				uuid, err := newUUID()
+				if err != nil {
+					return xmlFile{}, err
+				}
+				g.Groups = append(g.Groups, xmlGroup{
+					UUID:       uuid,
+					Name:       name,
+					Times:      newTimes(now, now),
+					IsExpanded: xmlTrue,
+				})
+			}
+			g = &g.Groups[i]
+		}
+
+		e, err := toXMLEntry(d)
+		if err != nil {
+			return xmlFile{}, err
+		}
+		g.Entries = append(g.Entries, e)
+	}
+
+	return xmlFile{
+		Meta: xmlMeta{
+			Generator:         rootGroupName,
+			DatabaseName:      rootGroupName,
+			RecycleBinEnabled: xmlFalse,
+			RecycleBinUUID:    base64.StdEncoding.EncodeToString(make([]byte, 16)),
+		},
+		Root: xmlRoot{Groups: []xmlGroup{root}},
+	}, nil
+}
+
+func toXMLEntry(d model.DecryptedEntry) (xmlEntry, error) {
+	uuid, ok := parseUUID(d.ID)
+	if !ok {
+		var err error
+		if uuid, err = newUUID(); err != nil {
+			return xmlEntry{}, err
+		}
+	}
+
+	e := xmlEntry{
+		UUID:  uuid,
+		Tags:  strings.Join(d.Tags, ";"),
+		Times: newTimes(d.CreatedAt, d.UpdatedAt),
+		CustomData: []xmlItem{
+			{Key: dataCreatedAt, Value: strconv.FormatInt(d.CreatedAt, 10)},
+			{Key: dataUpdatedAt, Value: strconv.FormatInt(d.UpdatedAt, 10)},
+		},
+	}
+
+	add := func(key, value string, protected bool) {
+		s := xmlString{Key: key, Value: xmlValue{Text: value}}
+		if protected {
+			s.Value.Protected = xmlTrue
+		}
+		e.Strings = append(e.Strings, s)
+	}
+
+	var url string
+	if len(d.URLs) > 0 {
+		url = d.URLs[0]
+	}
+
+	add(keyTitle, d.Name, false)
+	add(keyUserName, d.Username, false)
+	add(keyPassword, d.Password, true)
+	add(keyURL, url, false)
+	add(keyNotes, d.Notes, false)
+	for i := 1; i < len(d.URLs); i++ {
+		add(fmt.Sprintf("%s_%d", keyURLPrefix, i), d.URLs[i], false)
+	}
+	if d.TOTP != "" {
+		add(keyOTP, d.TOTP, true)
+	}
+	if d.SSHKey != "" {
+		add(keySSHKey, d.SSHKey, true)
+	}
+
+	for _, f := range d.Fields {
+		name := f.Name
+		// A field can't share its key with the strings above
+		for isReservedKey(name) {
+			name += " (field)"
+		}
+		add(name, f.Value, f.Secret)
+	}
+
+	return e, nil
+}
+
+func isReservedKey(key string) bool {
+	switch key {
+	case keyTitle, keyUserName, keyPassword, keyURL, keyNotes, keyOTP, keySSHKey:
+		return true
+	}
+	return strings.HasPrefix(key, keyURLPrefix)
+}
+
+func isProtectedValue(v xmlValue) bool {
+	return strings.EqualFold(v.Protected, xmlTrue)
+}
+
+// newUUID returns a random UUID, base64 encoded as KDBX stores them
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating UUID: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// parseUUID returns an entry ID, like model.NewEntryID returns, as a KDBX UUID
+func parseUUID(id string) (string, bool) {
+	b, err := hex.DecodeString(strings.ReplaceAll(id, "-", ""))
+	if err != nil || len(b) != 16 {
+		return "", false
+	}
+	return base64.StdEncoding.EncodeToString(b), true
+}
+
+// formatUUID returns a KDBX UUID in the format of entry IDs, or "" if it isn't
+// a UUID
+func formatUUID(uuid string) string {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(uuid))
+	if err != nil || len(b) != 16 {
+		return ""
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,407 @@
+package kdbx
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+
+	"go-pass/model"
+)
+
+const (
+	signature1   = 0x9AA2D903
+	signature2   = 0xB54BFB67
+	versionMajor = 4
+	versionMinor = 0
+)
+
+// The fields of the outer header
+const (
+	headerEnd              = 0
+	headerCipherID         = 2
+	headerCompressionFlags = 3
+	headerMasterSeed       = 4
+	headerEncryptionIV     = 7
+	headerKdfParameters    = 11
+	headerPublicCustomData = 12
+)
+
+const compressionGzip = 1
+
+// The types of the values in a variant dictionary
+const (
+	variantVersion   = 0x0100
+	variantEnd       = 0x00
+	variantUInt32    = 0x04
+	variantUInt64    = 0x05
+	variantBool      = 0x08
+	variantInt32     = 0x0C
+	variantInt64     = 0x0D
+	variantString    = 0x18
+	variantByteArray = 0x42
+)
+
+func mustUUID(s string) [16]byte {
+	var u [16]byte
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(u) {
+		panic("kdbx: invalid uuid " + s)
+	}
+	copy(u[:], b)
+	return u
+}
+
+var (
+	cipherAES256   = mustUUID("31c1f2e6bf714350be5805216afc5aff")
+	cipherTwofish  = mustUUID("ad68f29f576f4bb9a36ad47af965346c")
+	cipherChaCha20 = mustUUID("d6038a2b8b6f4cb5a524339a31dbb59a")
+
+	kdfAES      = mustUUID("c9d9f39a628a4460bf740d08c18a4fea")
+	kdfArgon2d  = mustUUID("ef636ddf8c29444b91f7a9a403e30a0c")
+	kdfArgon2id = mustUUID("9e298b1956db4773b23dfc3ec6f0a1e6")
+)
+
+// header is the outer, unencrypted header of a KDBX 4 file
+type header struct {
+	cipher      [16]byte
+	compression uint32
+	masterSeed  []byte
+	iv          []byte
+	kdf         kdfParams
+}
+
+// kdfParams are the parameters of the key derivation function, for either
+// AES-KDF or Argon2
+type kdfParams struct {
+	uuid [16]byte
+
+	// AES-KDF
+	rounds uint64
+	seed   []byte
+
+	// Argon2
+	salt        []byte
+	iterations  uint64
+	memory      uint64 // in bytes
+	parallelism uint32
+	version     uint32
+	secret      []byte
+	data        []byte
+}
+
+// readHeader reads the outer header, returning it and the bytes it was read
+// from, which its hash and HMAC are computed over
+func readHeader(r io.Reader) (header, []byte, error) {
+	var h header
+	var raw bytes.Buffer
+	tr := io.TeeReader(r, &raw)
+
+	var sig [3]uint32
+	if err := binary.Read(tr, binary.LittleEndian, &sig); err != nil {
+		return h, nil, ErrNotKDBX
+	}
+	if sig[0] != signature1 || sig[1] != signature2 {
+		return h, nil, ErrNotKDBX
+	}
+	if major := sig[2] >> 16; major != versionMajor {
+		return h, nil, fmt.Errorf("KDBX version %d is not supported, only version 4", major)
+	}
+
+	for {
+		var id uint8
+		var size uint32
+		if err := binary.Read(tr, binary.LittleEndian, &id); err != nil {
+			return h, nil, fmt.Errorf("reading header: %v", err)
+		}
+		if err := binary.Read(tr, binary.LittleEndian, &size); err != nil {
+			return h, nil, fmt.Errorf("reading header: %v", err)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(tr, data); err != nil {
+			return h, nil, fmt.Errorf("reading header: %v", err)
+		}
+
+		switch id {
+		case headerEnd:
+			return h, raw.Bytes(), h.validate()
+		case headerCipherID:
+			if len(data) != len(h.cipher) {
+				return h, nil, errors.New("invalid cipher ID")
+			}
+			copy(h.cipher[:], data)
+		case headerCompressionFlags:
+			if len(data) != 4 {
+				return h, nil, errors.New("invalid compression flags")
+			}
+			h.compression = binary.LittleEndian.Uint32(data)
+		case headerMasterSeed:
+			h.masterSeed = data
+		case headerEncryptionIV:
+			h.iv = data
+		case headerKdfParameters:
+			dict, err := readVariantDict(data)
+			if err != nil {
+				return h, nil, fmt.Errorf("reading KDF parameters: %v", err)
+			}
+			if h.kdf, err = parseKdfParams(dict); err != nil {
+				return h, nil, err
+			}
+		}
+	}
+}
+
+func (h header) validate() error {
+	if len(h.masterSeed) != 32 {
+		return errors.New("invalid master seed")
+	}
+	if h.compression > compressionGzip {
+		return fmt.Errorf("unsupported compression %d", h.compression)
+	}
+	switch h.cipher {
+	case cipherAES256, cipherTwofish:
+		if len(h.iv) != 16 {
+			return errors.New("invalid encryption IV")
+		}
+	case cipherChaCha20:
+		if len(h.iv) != 12 {
+			return errors.New("invalid encryption IV")
+		}
+	default:
+		return fmt.Errorf("unsupported cipher %x", h.cipher)
+	}
+	return nil
+}
+
+// writeHeader returns the outer header
+func writeHeader(h header) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, [3]uint32{
+		signature1, signature2, versionMajor<<16 | versionMinor,
+	})
+
+	field := func(id uint8, data []byte) {
+		b.WriteByte(id)
+		binary.Write(&b, binary.LittleEndian, uint32(len(data)))
+		b.Write(data)
+	}
+
+	compression := make([]byte, 4)
+	binary.LittleEndian.PutUint32(compression, h.compression)
+
+	field(headerCipherID, h.cipher[:])
+	field(headerCompressionFlags, compression)
+	field(headerMasterSeed, h.masterSeed)
+	field(headerEncryptionIV, h.iv)
+	field(headerKdfParameters, writeVariantDict(h.kdf.dict()))
+	field(headerEnd, []byte("\r\n\r\n"))
+
+	return b.Bytes()
+}
+
+// variantItem is a value in a variant dictionary
+type variantItem struct {
+	kind  uint8
+	name  string
+	value []byte
+}
+
+type variantDict []variantItem
+
+func (d variantDict) get(name string) []byte {
+	for _, item := range d {
+		if item.name == name {
+			return item.value
+		}
+	}
+	return nil
+}
+
+func (d variantDict) uint32(name string) uint32 {
+	if v := d.get(name); len(v) == 4 {
+		return binary.LittleEndian.Uint32(v)
+	}
+	return 0
+}
+
+func (d variantDict) uint64(name string) uint64 {
+	if v := d.get(name); len(v) == 8 {
+		return binary.LittleEndian.Uint64(v)
+	}
+	return 0
+}
+
+func readVariantDict(data []byte) (variantDict, error) {
+	r := bytes.NewReader(data)
+
+	var version uint16
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, err
+	}
+	if version&0xFF00 > variantVersion&0xFF00 {
+		return nil, fmt.Errorf("unsupported variant dictionary version %#x", version)
+	}
+
+	var d variantDict
+	for {
+		kind, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if kind == variantEnd {
+			return d, nil
+		}
+
+		name, err := readSized(r)
+		if err != nil {
+			return nil, err
+		}
+		value, err := readSized(r)
+		if err != nil {
+			return nil, err
+		}
+		d = append(d, variantItem{kind, string(name), value})
+	}
+}
+
+// readSized reads a value preceded by its size
+func readSized(r *bytes.Reader) ([]byte, error) {
+	var size int32
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return nil, err
+	}
+	if size < 0 || int(size) > r.Len() {
+		return nil, errors.New("invalid variant dictionary")
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func writeVariantDict(d variantDict) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, uint16(variantVersion))
+	for _, item := range d {
+		b.WriteByte(item.kind)
+		binary.Write(&b, binary.LittleEndian, int32(len(item.name)))
+		b.WriteString(item.name)
+		binary.Write(&b, binary.LittleEndian, int32(len(item.value)))
+		b.Write(item.value)
+	}
+	b.WriteByte(variantEnd)
+	return b.Bytes()
+}
+
+func parseKdfParams(d variantDict) (kdfParams, error) {
+	var p kdfParams
+
+	uuid := d.get("$UUID")
+	if len(uuid) != len(p.uuid) {
+		return p, errors.New("missing KDF UUID")
+	}
+	copy(p.uuid[:], uuid)
+
+	switch p.uuid {
+	case kdfAES:
+		p.rounds = d.uint64("R")
+		p.seed = d.get("S")
+		if len(p.seed) != 32 {
+			return p, errors.New("invalid AES-KDF seed")
+		}
+	case kdfArgon2d, kdfArgon2id:
+		p.salt = d.get("S")
+		p.iterations = d.uint64("I")
+		p.memory = d.uint64("M")
+		p.parallelism = d.uint32("P")
+		p.version = d.uint32("V")
+		p.secret = d.get("K")
+		p.data = d.get("A")
+		if p.version != argon2Version {
+			return p, fmt.Errorf("unsupported Argon2 version %#x", p.version)
+		}
+		if p.iterations == 0 || p.parallelism == 0 || p.memory < 1024 {
+			return p, errors.New("invalid Argon2 parameters")
+		}
+		// The costs come from the file, so a crafted one could otherwise
+		// ask for more memory or time than the machine has
+		if p.iterations > model.ARGON2_MAX_TIME ||
+			p.memory/1024 > model.ARGON2_MAX_MEMORY || p.parallelism > 255 {
+			return p, fmt.Errorf(
+				"the file's Argon2 costs are too high: time=%d memory=%dKiB threads=%d",
+				p.iterations, p.memory/1024, p.parallelism,
+			)
+		}
+	default:
+		return p, fmt.Errorf("unsupported KDF %x", p.uuid)
+	}
+
+	return p, nil
+}
+
+func (p kdfParams) dict() variantDict {
+	u32 := func(name string, v uint32) variantItem {
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint32(b, v)
+		return variantItem{variantUInt32, name, b}
+	}
+	u64 := func(name string, v uint64) variantItem {
+		b := make([]byte, 8)
+		binary.LittleEndian.PutUint64(b, v)
+		return variantItem{variantUInt64, name, b}
+	}
+
+	d := variantDict{{variantByteArray, "$UUID", p.uuid[:]}}
+	if p.uuid == kdfAES {
+		return append(d, u64("R", p.rounds), variantItem{variantByteArray, "S", p.seed})
+	}
+	return append(d,
+		variantItem{variantByteArray, "S", p.salt},
+		u32("P", p.parallelism),
+		u64("M", p.memory),
+		u64("I", p.iterations),
+		u32("V", p.version),
+	)
+}
+
+// transformKey derives the transformed key from the composite key
+func (p kdfParams) transformKey(composite []byte) ([]byte, error) {
+	switch p.uuid {
+	case kdfAES:
+		block, err := aes.NewCipher(p.seed)
+		if err != nil {
+			return nil, err
+		}
+		k := bytes.Clone(composite)
+		for range p.rounds {
+			block.Encrypt(k[:16], k[:16])
+			block.Encrypt(k[16:], k[16:])
+		}
+		sum := sha256.Sum256(k)
+		return sum[:], nil
+	case kdfArgon2id:
+		if len(p.secret) == 0 && len(p.data) == 0 && p.parallelism <= 255 {
+			return argon2.IDKey(
+				composite, p.salt, uint32(p.iterations), uint32(p.memory/1024),
+				uint8(p.parallelism), 32,
+			), nil
+		}
+		return argon2Key(
+			argon2id, composite, p.salt, p.secret, p.data,
+			uint32(p.iterations), uint32(p.memory/1024), p.parallelism, 32,
+		), nil
+	case kdfArgon2d:
+		return argon2Key(
+			argon2d, composite, p.salt, p.secret, p.data,
+			uint32(p.iterations), uint32(p.memory/1024), p.parallelism, 32,
+		), nil
+	}
+	return nil, fmt.Errorf("unsupported KDF %x", p.uuid)
+}
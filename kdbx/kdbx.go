@@ -0,0 +1,204 @@
+// Package kdbx reads and writes KeePass KDBX 4 databases protected by a
+// passphrase, mapping their entries to and from vault entries. Groups are
+// mapped to folders, like 'work/cloud', and strings other than the standard
+// ones to custom fields. Attachments and entry history are not kept.
+package kdbx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+
+	"go-pass/model"
+)
+
+var (
+	// ErrNotKDBX is returned when a file is not a KDBX database
+	ErrNotKDBX = errors.New("not a KDBX file")
+	// ErrInvalidPassphrase is returned when the passphrase does not open the
+	// database
+	ErrInvalidPassphrase = errors.New("invalid passphrase")
+	// ErrCorrupt is returned when the database fails its integrity checks
+	ErrCorrupt = errors.New("KDBX file is corrupt")
+)
+
+// options are the cipher and key derivation that a database is written with
+type options struct {
+	cipher [16]byte
+	kdf    kdfParams
+}
+
+// defaultOptions returns the options Write uses: ChaCha20, with the same
+// Argon2id costs as the vault
+func defaultOptions() (options, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return options{}, err
+	}
+
+	return options{
+		cipher: cipherChaCha20,
+		kdf: kdfParams{
+			uuid:        kdfArgon2id,
+			salt:        salt,
+			iterations:  model.ARGON2_TIME,
+			memory:      model.ARGON2_MEMORY * 1024,
+			parallelism: model.ARGON2_THREADS,
+			version:     argon2Version,
+		},
+	}, nil
+}
+
+// Read reads the entries of a KDBX 4 database. Entries in the recycle bin are
+// left out.
+func Read(r io.Reader, passphrase []byte) ([]model.DecryptedEntry, error) {
+	h, raw, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var sum, mac [32]byte
+	if _, err := io.ReadFull(r, sum[:]); err != nil {
+		return nil, ErrCorrupt
+	}
+	if _, err := io.ReadFull(r, mac[:]); err != nil {
+		return nil, ErrCorrupt
+	}
+	if expected := sha256.Sum256(raw); subtle.ConstantTimeCompare(sum[:], expected[:]) != 1 {
+		return nil, ErrCorrupt
+	}
+
+	k, err := deriveKeys(passphrase, h)
+	if err != nil {
+		return nil, err
+	}
+	// The header HMAC is the first thing a wrong passphrase fails
+	if subtle.ConstantTimeCompare(mac[:], k.headerHMAC(raw)) != 1 {
+		return nil, ErrInvalidPassphrase
+	}
+
+	payload, err := readBlocks(r, k)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err = decryptPayload(h, k, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.compression == compressionGzip {
+		gz, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("decompressing: %v", err)
+		}
+		if payload, err = io.ReadAll(gz); err != nil {
+			return nil, fmt.Errorf("decompressing: %v", err)
+		}
+	}
+
+	stream, doc, err := readInnerHeader(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err = protectValues(doc, stream, false)
+	if err != nil {
+		return nil, fmt.Errorf("reading XML: %v", err)
+	}
+
+	var f xmlFile
+	if err := xml.Unmarshal(doc, &f); err != nil {
+		return nil, fmt.Errorf("reading XML: %v", err)
+	}
+
+	return fromXML(f), nil
+}
+
+// Write writes the entries as a KDBX 4 database protected by the passphrase,
+// encrypted with ChaCha20 and a key derived with Argon2id
+func Write(w io.Writer, entries []model.DecryptedEntry, passphrase []byte) error {
+	opts, err := defaultOptions()
+	if err != nil {
+		return err
+	}
+	return write(w, entries, passphrase, opts)
+}
+
+func write(w io.Writer, entries []model.DecryptedEntry, passphrase []byte, opts options) error {
+	f, err := toXML(entries)
+	if err != nil {
+		return err
+	}
+
+	doc, err := xml.MarshalIndent(f, "", "\t")
+	if err != nil {
+		return fmt.Errorf("writing XML: %v", err)
+	}
+
+	return writeDoc(w, append([]byte(xml.Header), doc...), passphrase, opts)
+}
+
+// writeDoc writes the XML document as a database. Values marked as protected
+// are given in plain text.
+func writeDoc(w io.Writer, doc []byte, passphrase []byte, opts options) error {
+	h := header{
+		cipher:      opts.cipher,
+		compression: compressionGzip,
+		masterSeed:  make([]byte, 32),
+		kdf:         opts.kdf,
+	}
+	if opts.cipher == cipherChaCha20 {
+		h.iv = make([]byte, 12)
+	} else {
+		h.iv = make([]byte, 16)
+	}
+	streamKey := make([]byte, 64)
+	for _, b := range [][]byte{h.masterSeed, h.iv, streamKey} {
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+	}
+
+	stream, err := innerStream(innerStreamChaCha20, streamKey)
+	if err != nil {
+		return err
+	}
+	if doc, err = protectValues(doc, stream, true); err != nil {
+		return fmt.Errorf("writing XML: %v", err)
+	}
+
+	var payload bytes.Buffer
+	gz := gzip.NewWriter(&payload)
+	gz.Write(writeInnerHeader(streamKey))
+	gz.Write(doc)
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("compressing: %v", err)
+	}
+
+	k, err := deriveKeys(passphrase, h)
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := encryptPayload(h, k, payload.Bytes())
+	if err != nil {
+		return err
+	}
+
+	raw := writeHeader(h)
+	sum := sha256.Sum256(raw)
+	for _, b := range [][]byte{raw, sum[:], k.headerHMAC(raw)} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	return writeBlocks(w, encrypted, k)
+}
@@ -0,0 +1,182 @@
+package kdbx
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// epochOffset is the number of seconds from 0001-01-01, where KDBX 4 times
+// start, to the Unix epoch
+const epochOffset = 62135596800
+
+type xmlFile struct {
+	XMLName xml.Name `xml:"KeePassFile"`
+	Meta    xmlMeta  `xml:"Meta"`
+	Root    xmlRoot  `xml:"Root"`
+}
+
+type xmlMeta struct {
+	Generator         string `xml:"Generator"`
+	DatabaseName      string `xml:"DatabaseName"`
+	RecycleBinEnabled string `xml:"RecycleBinEnabled"`
+	RecycleBinUUID    string `xml:"RecycleBinUUID"`
+}
+
+type xmlRoot struct {
+	Groups []xmlGroup `xml:"Group"`
+}
+
+type xmlGroup struct {
+	UUID       string     `xml:"UUID"`
+	Name       string     `xml:"Name"`
+	Times      xmlTimes   `xml:"Times"`
+	IsExpanded string     `xml:"IsExpanded"`
+	Entries    []xmlEntry `xml:"Entry"`
+	Groups     []xmlGroup `xml:"Group"`
+}
+
+type xmlEntry struct {
+	UUID       string      `xml:"UUID"`
+	IconID     int         `xml:"IconID"`
+	Tags       string      `xml:"Tags"`
+	Times      xmlTimes    `xml:"Times"`
+	CustomData []xmlItem   `xml:"CustomData>Item,omitempty"`
+	Strings    []xmlString `xml:"String"`
+}
+
+type xmlItem struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+type xmlString struct {
+	Key   string   `xml:"Key"`
+	Value xmlValue `xml:"Value"`
+}
+
+type xmlValue struct {
+	Protected string `xml:"Protected,attr,omitempty"`
+	Text      string `xml:",chardata"`
+}
+
+type xmlTimes struct {
+	CreationTime         string `xml:"CreationTime"`
+	LastModificationTime string `xml:"LastModificationTime"`
+	LastAccessTime       string `xml:"LastAccessTime"`
+	ExpiryTime           string `xml:"ExpiryTime"`
+	Expires              string `xml:"Expires"`
+	UsageCount           int    `xml:"UsageCount"`
+	LocationChanged      string `xml:"LocationChanged"`
+}
+
+const (
+	xmlTrue  = "True"
+	xmlFalse = "False"
+)
+
+// newTimes returns the times of an item created and modified at the given
+// Unix times, in milliseconds
+func newTimes(created, modified int64) xmlTimes {
+	m := formatTime(modified)
+	return xmlTimes{
+		CreationTime:         formatTime(created),
+		LastModificationTime: m,
+		LastAccessTime:       m,
+		ExpiryTime:           m,
+		Expires:              xmlFalse,
+		LocationChanged:      m,
+	}
+}
+
+// formatTime formats a Unix time in milliseconds as KDBX 4 does, as the
+// base64 of the seconds since 0001-01-01
+func formatTime(ms int64) string {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, uint64(ms/1000+epochOffset))
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// parseTime parses a KDBX time into a Unix time in milliseconds, returning 0 if
+// it can't. Older files use ISO 8601 instead of base64.
+func parseTime(s string) int64 {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UnixMilli()
+	}
+
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(b) != 8 {
+		return 0
+	}
+	return (int64(binary.LittleEndian.Uint64(b)) - epochOffset) * 1000
+}
+
+// protectValues XORs the protected values in the XML with the inner random
+// stream, in document order. When 'protect' is true, the plain values are
+// encrypted and base64 encoded, and otherwise they are decoded and decrypted.
+func protectValues(in []byte, stream cipher.Stream, protect bool) ([]byte, error) {
+	dec := xml.NewDecoder(bytes.NewReader(in))
+	var out bytes.Buffer
+	enc := xml.NewEncoder(&out)
+
+	protected := false
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			protected = t.Name.Local == "Value" && isProtected(t.Attr)
+		case xml.EndElement:
+			protected = false
+		case xml.CharData:
+			if !protected {
+				break
+			}
+
+			var value []byte
+			if protect {
+				value = make([]byte, len(t))
+				stream.XORKeyStream(value, t)
+				value = []byte(base64.StdEncoding.EncodeToString(value))
+			} else {
+				value, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(t)))
+				if err != nil {
+					return nil, err
+				}
+				stream.XORKeyStream(value, value)
+			}
+			tok = xml.CharData(value)
+		}
+
+		if err := enc.EncodeToken(tok); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func isProtected(attrs []xml.Attr) bool {
+	for _, a := range attrs {
+		if a.Name.Local == "Protected" && strings.EqualFold(a.Value, xmlTrue) {
+			return true
+		}
+	}
+	return false
+}
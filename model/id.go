@@ -0,0 +1,40 @@
+package model
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// NewEntryID returns a random (version 4) UUID for a vault entry
+func NewEntryID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generating entry ID: %v", err)
+	}
+
+	// Set the version (4) and variant (RFC 4122) bits
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// AssignIDs gives every entry without an ID a new one, for vaults created
+// before entries had IDs. It returns true if any entry was changed.
+func AssignIDs(entries []VaultEntry) (bool, error) {
+	changed := false
+	for i := range entries {
+		if entries[i].ID != "" {
+			continue
+		}
+
+		id, err := NewEntryID()
+		if err != nil {
+			return false, err
+		}
+		entries[i].ID = id
+		changed = true
+	}
+
+	return changed, nil
+}
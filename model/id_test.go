@@ -0,0 +1,39 @@
+package model
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEntryID(t *testing.T) {
+	assert := assert.New(t)
+	uuidV4 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	id, err := NewEntryID()
+	assert.NoError(err)
+	assert.Regexp(uuidV4, id)
+
+	id2, err := NewEntryID()
+	assert.NoError(err)
+	assert.NotEqual(id, id2)
+}
+
+func TestAssignIDs(t *testing.T) {
+	assert := assert.New(t)
+
+	entries := []VaultEntry{{Name: "a"}, {ID: "existing", Name: "b"}}
+
+	changed, err := AssignIDs(entries)
+	assert.NoError(err)
+	assert.True(changed)
+	assert.NotEmpty(entries[0].ID)
+	assert.Equal("existing", entries[1].ID)
+
+	id := entries[0].ID
+	changed, err = AssignIDs(entries)
+	assert.NoError(err)
+	assert.False(changed)
+	assert.Equal(id, entries[0].ID)
+}
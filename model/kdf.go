@@ -0,0 +1,194 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// KDFID names a key derivation function that can be stored in a file header.
+type KDFID string
+
+const (
+	KDFPBKDF2   KDFID = "pbkdf2-sha256"
+	KDFArgon2id KDFID = "argon2id"
+
+	// Defaults for new vaults. These follow the second recommended option in
+	// RFC 9106.
+	ARGON2_TIME    = 3
+	ARGON2_MEMORY  = 64 * 1024
+	ARGON2_THREADS = 4
+
+	// ARGON2_MAX_TIME and ARGON2_MAX_MEMORY (in KiB, 1 GiB) are the most a
+	// file from elsewhere, like a bundle, may ask Argon2 for, so a crafted
+	// file can't use up the memory or CPU of the machine opening it
+	ARGON2_MAX_TIME   = 100
+	ARGON2_MAX_MEMORY = 1024 * 1024
+
+	// SALT_SIZE is the size of the random salt generated for every new vault
+	SALT_SIZE = 32
+)
+
+// KDFParams is the key derivation function and its parameters. It is stored in
+// the header of every encrypted file so the file can be read back regardless
+// of what the current defaults are.
+type KDFParams struct {
+	ID KDFID `json:"id"`
+	// Iterations is only used by PBKDF2
+	Iterations uint32 `json:"iterations,omitempty"`
+	// Time, Memory (in KiB) and Threads are only used by Argon2id
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+
+	// Salt is the base64-encoded random salt generated when the vault was
+	// created. Files written before it existed leave it empty, and are salted
+	// with SECRET_PASSWORD_KEY instead.
+	Salt string `json:"salt,omitempty"`
+	// Pepper is true when SECRET_PASSWORD_KEY is mixed into the key on top of
+	// the salt.
+	Pepper bool `json:"pepper,omitempty"`
+}
+
+// DefaultKDF returns the KDF used for new vaults, Argon2id.
+func DefaultKDF() KDFParams {
+	return KDFParams{
+		ID:      KDFArgon2id,
+		Time:    ARGON2_TIME,
+		Memory:  ARGON2_MEMORY,
+		Threads: ARGON2_THREADS,
+	}
+}
+
+// testKDF is used by NewTestMasterAESKeyManager to keep the tests fast. It is
+// far too weak for real use.
+var testKDF = KDFParams{
+	ID:      KDFArgon2id,
+	Time:    1,
+	Memory:  1024,
+	Threads: 1,
+}
+
+// LegacyKDF returns the PBKDF2 parameters that every file written before the
+// versioned header was encrypted with.
+func LegacyKDF() KDFParams {
+	return KDFParams{
+		ID:         KDFPBKDF2,
+		Iterations: NUM_ITERATIONS,
+	}
+}
+
+// Validate returns an error if the parameters can't be used to derive a key.
+func (p KDFParams) Validate() error {
+	switch p.ID {
+	case KDFPBKDF2:
+		if p.Iterations == 0 {
+			return fmt.Errorf("pbkdf2 needs at least 1 iteration")
+		}
+	case KDFArgon2id:
+		if p.Time == 0 || p.Memory == 0 || p.Threads == 0 {
+			return fmt.Errorf("argon2id needs a non-zero time, memory and threads")
+		}
+	default:
+		return fmt.Errorf("unknown kdf '%s'", p.ID)
+	}
+	return nil
+}
+
+// WithNewSalt returns a copy of the parameters with a freshly generated random
+// salt. SECRET_PASSWORD_KEY is used as a pepper if it is set.
+func (p KDFParams) WithNewSalt() (KDFParams, error) {
+	salt := make([]byte, SALT_SIZE)
+	if _, err := rand.Read(salt); err != nil {
+		return KDFParams{}, fmt.Errorf("generating salt: %v", err)
+	}
+
+	p.Salt = base64.StdEncoding.EncodeToString(salt)
+	p.Pepper = HasPepper()
+	return p, nil
+}
+
+// Cost returns the parameters without the salt and pepper, for comparing how
+// two sets of parameters derive a key.
+func (p KDFParams) Cost() KDFParams {
+	p.Salt = ""
+	p.Pepper = false
+	return p
+}
+
+// String returns a human readable form of the parameters
+func (p KDFParams) String() string {
+	var s string
+	switch p.ID {
+	case KDFPBKDF2:
+		s = fmt.Sprintf("%s (iterations=%d)", p.ID, p.Iterations)
+	case KDFArgon2id:
+		s = fmt.Sprintf("%s (time=%d, memory=%dKiB, threads=%d)", p.ID, p.Time, p.Memory, p.Threads)
+	default:
+		s = string(p.ID)
+	}
+
+	switch {
+	case p.Salt == "":
+		s += ", salted with " + SECRET_PASSWORD_KEY
+	case p.Pepper:
+		s += ", random salt, peppered with " + SECRET_PASSWORD_KEY
+	default:
+		s += ", random salt"
+	}
+	return s
+}
+
+// saltAndPepper returns the salt and pepper to derive a key with. Without a
+// stored salt, the key is salted with SECRET_PASSWORD_KEY as it was before
+// salts were stored.
+func (p KDFParams) saltAndPepper() (salt, pepper []byte, err error) {
+	if p.Salt == "" {
+		salt, err = GetSalt()
+		return salt, nil, err
+	}
+
+	salt, err = base64.StdEncoding.DecodeString(p.Salt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding salt: %v", err)
+	}
+
+	if p.Pepper {
+		pepper, err = GetPepper()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return salt, pepper, nil
+}
+
+// DeriveKey runs the KDF described by params over the keyring key, the master
+// password and the pepper, if there is one. This is the expensive step that
+// MasterAESKeyManager caches.
+func DeriveKey(baseKey, masterPassword []byte, params KDFParams) ([]byte, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	salt, pepper, err := params.saltAndPepper()
+	if err != nil {
+		return nil, err
+	}
+
+	material := make([]byte, 0, len(baseKey)+len(masterPassword)+len(pepper))
+	material = append(material, baseKey...)
+	material = append(material, masterPassword...)
+	material = append(material, pepper...)
+	defer wipe(material)
+
+	switch params.ID {
+	case KDFArgon2id:
+		return argon2.IDKey(material, salt, params.Time, params.Memory, params.Threads, KEY_SIZE), nil
+	default:
+		return pbkdf2.Key(material, salt, int(params.Iterations), KEY_SIZE, sha256.New), nil
+	}
+}
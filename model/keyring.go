@@ -4,14 +4,11 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
-	"log"
 	"os"
-
-	"github.com/zalando/go-keyring"
-	"golang.org/x/crypto/pbkdf2"
+	"sync"
 )
 
 const (
@@ -21,144 +18,305 @@ const (
 
 	DefaultKeyringService = "gopass"
 	DefaultKeyringAccount = "encryption_key"
+
+	// SECRET_PASSWORD_KEY is the optional environment variable that is used as
+	// a pepper. Older vaults used it as the salt.
+	SECRET_PASSWORD_KEY = "SECRET_PASSWORD_KEY"
 )
 
+// ErrKeyManagerClosed is returned when a MasterAESKeyManager is used after
+// Close has been called.
+var ErrKeyManagerClosed = errors.New("key manager is closed")
+
 // MasterAESKeyManager is the struct that contains the logic to handle the
-// keyring encryption and decryption
+// keyring encryption and decryption. Keys are derived the first time they are
+// needed and cached for the life of the manager, so a session costs one
+// keyring read and one key derivation no matter how many entries it touches.
+// Callers should Close the manager when they are done to zero the keys.
+//
+// Every file records the KDF it was encrypted with. Reading a file switches
+// the manager to that KDF, so that the passwords inside the file and the
+// file written back out use the same key.
 type MasterAESKeyManager struct {
 	Masterpassword string
-	// KeyringService and KeyringAccount allow tests to use isolated keyring entries
-	KeyringService string
-	KeyringAccount string
+	// Store holds the random key that the AES keys are derived from
+	Store KeyStore
+
+	mu     sync.Mutex
+	kdf    KDFParams
+	keys   map[KDFParams]*derivedKey
+	closed bool
+}
+
+// derivedKey is a cached key and the cipher built from it
+type derivedKey struct {
+	key  []byte
+	aead cipher.AEAD
 }
 
 // NewMasterAESKeyManager returns a new MasterAESKeyManager with the passed-in
-// password
+// password. It uses DefaultKDF until a file with a different KDF is read.
+// The key is kept in the OS keyring.
 func NewMasterAESKeyManager(mp string) *MasterAESKeyManager {
+	return NewMasterAESKeyManagerWithStore(
+		mp,
+		NewKeyringStore(DefaultKeyringService, DefaultKeyringAccount),
+	)
+}
+
+// NewMasterAESKeyManagerWithStore returns a new MasterAESKeyManager with the
+// passed-in password that keeps its key in 'store'.
+func NewMasterAESKeyManagerWithStore(mp string, store KeyStore) *MasterAESKeyManager {
 	return &MasterAESKeyManager{
 		Masterpassword: mp,
-		KeyringService: DefaultKeyringService,
-		KeyringAccount: DefaultKeyringAccount,
+		Store:          store,
+		kdf:            DefaultKDF(),
 	}
 }
 
-// NewTestMasterAESKeyManager creates a keyring manager for testing with isolated keyring entries
+// NewTestMasterAESKeyManager creates a keyring manager for testing that keeps
+// its key in memory, so tests don't need an OS keyring
 func NewTestMasterAESKeyManager(mp string) *MasterAESKeyManager {
 	return &MasterAESKeyManager{
 		Masterpassword: mp,
-		KeyringService: "gopass-test",
-		KeyringAccount: "test_encryption_key",
+		Store:          NewMemoryKeyStore("gopass-test"),
+		kdf:            testKDF,
 	}
 }
 
-// InitializeKeychain creates a new keyring and sets it
-func (k *MasterAESKeyManager) InitializeKeychain() error {
-	randomKey := make([]byte, 32)
-	if _, err := rand.Read(randomKey); err != nil {
-		return err
+// NewMasterAESKeyManagerFromKey returns a MasterAESKeyManager that uses an
+// already derived AES key, for the given KDF, instead of deriving one from the
+// keyring and the master password. Files that use any other KDF can't be
+// read with it.
+func NewMasterAESKeyManagerFromKey(key []byte, params KDFParams) *MasterAESKeyManager {
+	return &MasterAESKeyManager{
+		Store: NewKeyringStore(DefaultKeyringService, DefaultKeyringAccount),
+		kdf:   params,
+		keys:  map[KDFParams]*derivedKey{params: {key: key}},
 	}
+}
 
-	encoded := base64.StdEncoding.EncodeToString(randomKey)
+// KDF returns the KDF that the manager currently encrypts and decrypts with.
+func (k *MasterAESKeyManager) KDF() KDFParams {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.kdf
+}
 
-	if err := keyring.Set(k.KeyringService, k.KeyringAccount, encoded); err != nil {
+// UseKDF switches the manager to the given KDF. The key for it is derived the
+// next time it is needed.
+func (k *MasterAESKeyManager) UseKDF(params KDFParams) error {
+	if err := params.Validate(); err != nil {
 		return err
 	}
 
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.kdf = params
 	return nil
 }
 
-// DeleteKeychain removes the keyring entry (useful for tests and cleanup)
-func (k *MasterAESKeyManager) DeleteKeychain() error {
-	return keyring.Delete(k.KeyringService, k.KeyringAccount)
+// WithKDF returns a new manager with the same master password and keystore
+// that uses the given KDF. No cached keys are shared, so both managers
+// must be closed.
+func (k *MasterAESKeyManager) WithKDF(params KDFParams) (*MasterAESKeyManager, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &MasterAESKeyManager{
+		Masterpassword: k.Masterpassword,
+		Store:          k.Store,
+		kdf:            params,
+	}, nil
 }
 
-// GetEncryptionKey returns the encrypted key that is stored in the keyring
-func (k *MasterAESKeyManager) GetEncryptionKey() ([]byte, error) {
-	salt := GetSalt()
+// InitializeKeychain creates a new random key and stores it, and generates a new
+// random salt for everything encrypted after it. Any cached key is wiped, as
+// it was derived from the previous key.
+func (k *MasterAESKeyManager) InitializeKeychain() error {
+	k.Wipe()
 
-	encoded, err := keyring.Get(k.KeyringService, k.KeyringAccount)
+	params, err := k.KDF().WithNewSalt()
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if err := k.UseKDF(params); err != nil {
+		return err
 	}
 
-	baseKey, err := base64.StdEncoding.DecodeString(encoded)
-	if err != nil {
-		return nil, err
+	randomKey := make([]byte, 32)
+	if _, err := rand.Read(randomKey); err != nil {
+		return err
 	}
+	defer wipe(randomKey)
 
-	key := pbkdf2.Key(
-		append(baseKey, []byte(k.Masterpassword)...),
-		salt,
-		NUM_ITERATIONS,
-		KEY_SIZE,
-		sha256.New,
-	)
+	return k.Store.Set(randomKey, []byte(k.Masterpassword))
+}
 
-	return key, nil
+// DeleteKeychain removes the key from the keystore (useful for tests and cleanup)
+func (k *MasterAESKeyManager) DeleteKeychain() error {
+	return k.Store.Delete()
 }
 
-// Encrypt encrypts the []byte using the keyring, and returns the hex-encoded
-// representation of the encrypted text
-func (k *MasterAESKeyManager) Encrypt(plaintext []byte) (string, error) {
-	key, err := k.GetEncryptionKey()
+// GetEncryptionKey returns the AES key derived from the keyring key and the
+// master password with the current KDF. The key is derived once and then
+// served from the cache.
+func (k *MasterAESKeyManager) GetEncryptionKey() ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	dk, err := k.loadKeyLocked()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	return dk.key, nil
+}
 
-	cipherBlock, err := aes.NewCipher(key)
-	if err != nil {
-		return "", fmt.Errorf("creating cipher block: %v", err)
+// loadKeyLocked returns the cached key for the current KDF, deriving it first
+// if needed. k.mu must be held.
+func (k *MasterAESKeyManager) loadKeyLocked() (*derivedKey, error) {
+	if k.closed {
+		return nil, ErrKeyManagerClosed
 	}
 
-	aesgcm, err := cipher.NewGCM(cipherBlock)
-	if err != nil {
-		return "", fmt.Errorf("creating aes gcm: %v", err)
+	if k.keys == nil {
+		k.keys = make(map[KDFParams]*derivedKey)
 	}
 
-	nonce, err := GenerateNonce()
+	dk, ok := k.keys[k.kdf]
+	if !ok {
+		if k.Masterpassword == "" {
+			return nil, fmt.Errorf("no key available for %s", k.kdf)
+		}
+
+		baseKey, err := k.Store.Get([]byte(k.Masterpassword))
+		if err != nil {
+			return nil, err
+		}
+		defer wipe(baseKey)
+
+		key, err := DeriveKey(baseKey, []byte(k.Masterpassword), k.kdf)
+		if err != nil {
+			return nil, err
+		}
+
+		dk = &derivedKey{key: key}
+		k.keys[k.kdf] = dk
+	}
+
+	if dk.aead == nil {
+		cipherBlock, err := aes.NewCipher(dk.key)
+		if err != nil {
+			return nil, fmt.Errorf("creating cipher block: %v", err)
+		}
+
+		aesgcm, err := cipher.NewGCM(cipherBlock)
+		if err != nil {
+			return nil, fmt.Errorf("creating aes gcm: %v", err)
+		}
+		dk.aead = aesgcm
+	}
+
+	return dk, nil
+}
+
+// aesGCM returns the cached AES-GCM cipher, deriving the key if needed.
+func (k *MasterAESKeyManager) aesGCM() (cipher.AEAD, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	dk, err := k.loadKeyLocked()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	return dk.aead, nil
+}
+
+// Wipe zeroes every cached key. The next Encrypt or Decrypt will derive the
+// key again.
+func (k *MasterAESKeyManager) Wipe() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
 
-	cipherText := aesgcm.Seal(nil, nonce, plaintext, nil)
+	for params, dk := range k.keys {
+		wipe(dk.key)
+		delete(k.keys, params)
+	}
+}
 
-	// Appending the nonce to the data bytes
-	cipherText = append(nonce, cipherText...)
+// Close zeroes the cached keys and drops the master password. The manager
+// cannot be used after it has been closed.
+func (k *MasterAESKeyManager) Close() error {
+	k.Wipe()
 
-	return base64.StdEncoding.EncodeToString(cipherText), nil
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.Masterpassword = ""
+	k.closed = true
+	return nil
 }
 
-// Decrypt decrypts the passed-in ciphertext using the keyring, and returns the
-// []byte represnetatino of the text. This []byte representation can be used in
-// conjunctino with `string()` to have it be in string form
-func (k *MasterAESKeyManager) Decrypt(ciphertext string) ([]byte, error) {
-	key, err := k.GetEncryptionKey()
+// Seal encrypts plaintext with AES-256-GCM, authenticating additionalData
+// along with it, and returns the nonce followed by the ciphertext.
+func (k *MasterAESKeyManager) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	aesgcm, err := k.aesGCM()
 	if err != nil {
 		return nil, err
 	}
 
-	decoded, err := base64.StdEncoding.DecodeString(ciphertext)
+	nonce, err := GenerateNonce()
 	if err != nil {
 		return nil, err
 	}
 
-	cipherBlock, err := aes.NewCipher(key)
+	// Appending the ciphertext to the nonce
+	return aesgcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Open reverses Seal. additionalData has to match what was passed to Seal.
+func (k *MasterAESKeyManager) Open(sealed, additionalData []byte) ([]byte, error) {
+	aesgcm, err := k.aesGCM()
 	if err != nil {
 		return nil, err
 	}
 
-	aesgcm, err := cipher.NewGCM(cipherBlock)
+	if len(sealed) < NONCE_SIZE {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, cipherText := sealed[:NONCE_SIZE], sealed[NONCE_SIZE:]
+	return aesgcm.Open(nil, nonce, cipherText, additionalData)
+}
+
+// Encrypt encrypts the []byte using the keyring, and returns the base64-encoded
+// representation of the encrypted text
+func (k *MasterAESKeyManager) Encrypt(plaintext []byte) (string, error) {
+	cipherText, err := k.Seal(plaintext, nil)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	nonce, cipher := decoded[:NONCE_SIZE], decoded[NONCE_SIZE:]
-	plaintext, err := aesgcm.Open(nil, nonce, cipher, nil)
+	return base64.StdEncoding.EncodeToString(cipherText), nil
+}
+
+// Decrypt decrypts the passed-in ciphertext using the keyring, and returns the
+// []byte represnetatino of the text. This []byte representation can be used in
+// conjunctino with `string()` to have it be in string form
+func (k *MasterAESKeyManager) Decrypt(ciphertext string) ([]byte, error) {
+	decoded, err := base64.StdEncoding.DecodeString(ciphertext)
 	if err != nil {
 		return nil, err
 	}
 
-	return plaintext, nil
+	return k.Open(decoded, nil)
+}
+
+// wipe zeroes b in place
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
 }
 
 // GenerateNonce generates a Number Once, used for AES-256 encryption.
@@ -174,14 +332,33 @@ func GenerateNonce() ([]byte, error) {
 	return nonce, nil
 }
 
-// GetSalt is a helper function that gets the key from the environment
-// variable called 'SECRET_PASSWORD_KEY'. 'SECRET_PASSWORD_KEY' should be a
-// strong, 32-byte password that's unique. You can generate a strong password
-// from something like: 'https://passwords-generator.org/32-character'
-func GetSalt() []byte {
-	key := []byte(os.Getenv("SECRET_PASSWORD_KEY"))
+// GetSalt returns SECRET_PASSWORD_KEY, which salted every key before a random
+// salt was stored in the file header. It has to be exactly 32 bytes.
+func GetSalt() ([]byte, error) {
+	key := []byte(os.Getenv(SECRET_PASSWORD_KEY))
+	if len(key) == 0 {
+		return nil, fmt.Errorf(
+			"%s is not set. It is needed to read files from before the salt was stored, see 'gopass vault upgrade-kdf'",
+			SECRET_PASSWORD_KEY,
+		)
+	}
 	if len(key) != KEY_SIZE {
-		log.Fatal("Salt not appropriate length or not present. Please set.")
+		return nil, fmt.Errorf("%s must be exactly %d characters", SECRET_PASSWORD_KEY, KEY_SIZE)
 	}
-	return key
+	return key, nil
+}
+
+// GetPepper returns SECRET_PASSWORD_KEY for vaults that use it as a pepper.
+// Any non-empty value works.
+func GetPepper() ([]byte, error) {
+	pepper := os.Getenv(SECRET_PASSWORD_KEY)
+	if pepper == "" {
+		return nil, fmt.Errorf("this vault is peppered with %s, but it is not set", SECRET_PASSWORD_KEY)
+	}
+	return []byte(pepper), nil
+}
+
+// HasPepper returns true if SECRET_PASSWORD_KEY is set
+func HasPepper() bool {
+	return os.Getenv(SECRET_PASSWORD_KEY) != ""
 }
@@ -0,0 +1,311 @@
+package model
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	// KEYSTORE_KEYRING keeps the key in the OS keyring
+	KEYSTORE_KEYRING = "keyring"
+	// KEYSTORE_FILE keeps the key in a file encrypted with the master password
+	KEYSTORE_FILE = "file"
+	// KEYSTORE_MEMORY keeps the key in memory, for tests
+	KEYSTORE_MEMORY = "memory"
+)
+
+// ErrKeyNotFound is returned by a KeyStore that has no key stored
+var ErrKeyNotFound = errors.New("key not found in keystore")
+
+// KeyStore stores the random key that, along with the master password, every
+// AES key is derived from. The master password is passed in for stores that
+// protect the key with it; others ignore it.
+type KeyStore interface {
+	// Get returns the stored key
+	Get(masterPassword []byte) ([]byte, error)
+	// Set stores the key, replacing any key already stored
+	Set(key, masterPassword []byte) error
+	// Delete removes the stored key
+	Delete() error
+}
+
+// KeyringStore stores the key in the OS keyring, base64-encoded.
+type KeyringStore struct {
+	Service string
+	Account string
+}
+
+// NewKeyringStore returns a KeyringStore for the given keyring entry
+func NewKeyringStore(service, account string) *KeyringStore {
+	return &KeyringStore{Service: service, Account: account}
+}
+
+func (s *KeyringStore) Get(_ []byte) ([]byte, error) {
+	encoded, err := keyring.Get(s.Service, s.Account)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+func (s *KeyringStore) Set(key, _ []byte) error {
+	return keyring.Set(s.Service, s.Account, base64.StdEncoding.EncodeToString(key))
+}
+
+func (s *KeyringStore) Delete() error {
+	return keyring.Delete(s.Service, s.Account)
+}
+
+// FileKeyStore stores the key in a file, encrypted with a key derived from the
+// master password alone. It is meant for machines without an OS keyring, such
+// as headless servers and SSH sessions.
+type FileKeyStore struct {
+	Path string
+	// KDF is used to derive the key that wraps the stored key. The salt is
+	// generated on every Set.
+	KDF KDFParams
+}
+
+// keyFile is the on-disk form of a FileKeyStore
+type keyFile struct {
+	KDF  KDFParams `json:"kdf"`
+	Data string    `json:"data"`
+}
+
+// NewFileKeyStore returns a FileKeyStore that keeps the key at 'path'
+func NewFileKeyStore(path string) *FileKeyStore {
+	return &FileKeyStore{Path: path, KDF: DefaultKDF()}
+}
+
+func (s *FileKeyStore) Get(masterPassword []byte) ([]byte, error) {
+	contents, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %v", err)
+	}
+
+	var kf keyFile
+	if err := json.Unmarshal(contents, &kf); err != nil {
+		return nil, fmt.Errorf("unmarshaling key file: %v", err)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(kf.Data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding key file: %v", err)
+	}
+	if len(sealed) < NONCE_SIZE {
+		return nil, errors.New("key file too short")
+	}
+
+	aesgcm, err := wrappingCipher(masterPassword, kf.KDF)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, cipherText := sealed[:NONCE_SIZE], sealed[NONCE_SIZE:]
+	key, err := aesgcm.Open(nil, nonce, cipherText, nil)
+	if err != nil {
+		return nil, errors.New("incorrect master password")
+	}
+	return key, nil
+}
+
+func (s *FileKeyStore) Set(key, masterPassword []byte) error {
+	params, err := s.KDF.WithNewSalt()
+	if err != nil {
+		return err
+	}
+	// The pepper is mixed in when the vault keys are derived, not here
+	params.Pepper = false
+
+	aesgcm, err := wrappingCipher(masterPassword, params)
+	if err != nil {
+		return err
+	}
+
+	nonce, err := GenerateNonce()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(keyFile{
+		KDF:  params,
+		Data: base64.StdEncoding.EncodeToString(aesgcm.Seal(nonce, nonce, key, nil)),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(path.Dir(s.Path), 0o700); err != nil {
+		return err
+	}
+
+	// Write to a temp file and rename, so a failed write can't lose the key
+	tmpFile, err := os.CreateTemp(path.Dir(s.Path), "key_*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(b); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), s.Path)
+}
+
+func (s *FileKeyStore) Delete() error {
+	err := os.Remove(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrKeyNotFound
+	}
+	return err
+}
+
+// wrappingCipher derives the AES-GCM cipher that a FileKeyStore encrypts the
+// key with
+func wrappingCipher(masterPassword []byte, params KDFParams) (cipher.AEAD, error) {
+	if len(masterPassword) == 0 {
+		return nil, errors.New("the file keystore needs the master password")
+	}
+
+	key, err := DeriveKey(nil, masterPassword, params)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher block: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// memoryKeys backs every MemoryKeyStore, so that stores with the same name
+// share a key the same way keyring entries do
+var (
+	memoryMu   sync.Mutex
+	memoryKeys = map[string][]byte{}
+)
+
+// MemoryKeyStore stores the key in memory for the life of the process. It is
+// used by tests, so they can run without an OS keyring.
+type MemoryKeyStore struct {
+	Name string
+}
+
+// NewMemoryKeyStore returns a MemoryKeyStore. Stores with the same name share
+// the same key.
+func NewMemoryKeyStore(name string) *MemoryKeyStore {
+	return &MemoryKeyStore{Name: name}
+}
+
+func (s *MemoryKeyStore) Get(_ []byte) ([]byte, error) {
+	memoryMu.Lock()
+	defer memoryMu.Unlock()
+
+	key, ok := memoryKeys[s.Name]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	cp := make([]byte, len(key))
+	copy(cp, key)
+	return cp, nil
+}
+
+func (s *MemoryKeyStore) Set(key, _ []byte) error {
+	memoryMu.Lock()
+	defer memoryMu.Unlock()
+
+	cp := make([]byte, len(key))
+	copy(cp, key)
+	memoryKeys[s.Name] = cp
+	return nil
+}
+
+func (s *MemoryKeyStore) Delete() error {
+	memoryMu.Lock()
+	defer memoryMu.Unlock()
+
+	key, ok := memoryKeys[s.Name]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	wipe(key)
+	delete(memoryKeys, s.Name)
+	return nil
+}
+
+// StaticKeyStore holds a key in memory that hasn't been stored anywhere yet,
+// such as the key a vault is being re-encrypted under.
+type StaticKeyStore struct {
+	mu  sync.Mutex
+	key []byte
+}
+
+// NewStaticKeyStore returns a StaticKeyStore holding a copy of 'key'
+func NewStaticKeyStore(key []byte) *StaticKeyStore {
+	s := &StaticKeyStore{}
+	s.Set(key, nil)
+	return s
+}
+
+func (s *StaticKeyStore) Get(_ []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.key == nil {
+		return nil, ErrKeyNotFound
+	}
+
+	cp := make([]byte, len(s.key))
+	copy(cp, s.key)
+	return cp, nil
+}
+
+func (s *StaticKeyStore) Set(key, _ []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wipe(s.key)
+	s.key = make([]byte, len(key))
+	copy(s.key, key)
+	return nil
+}
+
+func (s *StaticKeyStore) Delete() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.key == nil {
+		return ErrKeyNotFound
+	}
+	wipe(s.key)
+	s.key = nil
+	return nil
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileKeyStore(t *testing.T) {
+	assert := assert.New(t)
+	store := NewFileKeyStore(path.Join(t.TempDir(), "gopass", "key.enc"))
+	store.KDF = testKDF
+	key := bytes.Repeat([]byte{5}, KEY_SIZE)
+
+	_, err := store.Get([]byte("mastahpass"))
+	assert.ErrorIs(err, ErrKeyNotFound)
+
+	assert.NoError(store.Set(key, []byte("mastahpass")))
+
+	info, err := os.Stat(store.Path)
+	assert.NoError(err)
+	assert.Equal(os.FileMode(0o600), info.Mode().Perm())
+
+	// The key is not stored in the clear
+	contents, err := os.ReadFile(store.Path)
+	assert.NoError(err)
+	assert.NotContains(string(contents), string(key))
+
+	got, err := store.Get([]byte("mastahpass"))
+	assert.NoError(err)
+	assert.Equal(key, got)
+
+	_, err = store.Get([]byte("wrong"))
+	assert.Error(err)
+	_, err = store.Get(nil)
+	assert.Error(err)
+
+	assert.NoError(store.Delete())
+	_, err = store.Get([]byte("mastahpass"))
+	assert.ErrorIs(err, ErrKeyNotFound)
+}
+
+func TestMemoryKeyStore(t *testing.T) {
+	assert := assert.New(t)
+	key := bytes.Repeat([]byte{6}, KEY_SIZE)
+
+	store := NewMemoryKeyStore("TestMemoryKeyStore")
+	assert.NoError(store.Set(key, nil))
+
+	// Stores with the same name share the key, like keyring entries do
+	got, err := NewMemoryKeyStore("TestMemoryKeyStore").Get(nil)
+	assert.NoError(err)
+	assert.Equal(key, got)
+
+	_, err = NewMemoryKeyStore("other").Get(nil)
+	assert.ErrorIs(err, ErrKeyNotFound)
+
+	assert.NoError(store.Delete())
+	assert.ErrorIs(store.Delete(), ErrKeyNotFound)
+}
+
+func TestManagerUsesKeyStore(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv(SECRET_PASSWORD_KEY, "")
+
+	store := NewFileKeyStore(path.Join(t.TempDir(), "key.enc"))
+	store.KDF = testKDF
+
+	km := NewMasterAESKeyManagerWithStore("mastahpass", store)
+	assert.NoError(km.UseKDF(testKDF))
+	assert.NoError(km.InitializeKeychain())
+
+	ciphertext, err := km.Encrypt([]byte("secret"))
+	assert.NoError(err)
+	params := km.KDF()
+	assert.NoError(km.Close())
+
+	// A new manager reads the key back from the file
+	km = NewMasterAESKeyManagerWithStore("mastahpass", store)
+	defer km.Close()
+	assert.NoError(km.UseKDF(params))
+
+	plaintext, err := km.Decrypt(ciphertext)
+	assert.NoError(err)
+	assert.Equal("secret", string(plaintext))
+}
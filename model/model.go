@@ -9,6 +9,9 @@ const (
 )
 
 type VaultEntry struct {
+	// ID is a random UUID, assigned when the entry is added, that never
+	// changes. Unlike Name, it is unique.
+	ID string `json:"id,omitempty"`
 	// Name is the source name for the login info
 	Name string `json:"name"`
 	// Username is the username for the login
@@ -18,10 +21,69 @@ type VaultEntry struct {
 	// Notes is a section that can be empty that the user can add extra notes
 	// about needing to login.
 	Notes string `json:"notes,omitempty"`
-	// UpdatedAt is the timestamp when the entry was created, in milliseconds
+	// TOTP is an encrypted otpauth:// URI, encrypted the same way as Password.
+	// It is empty if the entry has no TOTP secret.
+	TOTP []byte `json:"totp,omitempty"`
+	// SSHKey is an encrypted OpenSSH private key, encrypted the same way as
+	// Password. It is empty unless the entry is an SSH key, which
+	// 'ssh-agent' serves.
+	SSHKey []byte `json:"ssh_key,omitempty"`
+	// Metadata is embedded so its fields sit at the top level of the entry's
+	// JSON, next to the fields above.
+	Metadata
+	// CreatedAt is the timestamp when the entry was created, in milliseconds.
+	// It is 0 for entries created before it was recorded.
+	CreatedAt int64 `json:"created_at,omitempty"`
+	// UpdatedAt is the timestamp when the entry was last updated, in
+	// milliseconds
 	UpdatedAt int64 `json:"updated_at"`
 }
 
+// Metadata is the optional information about a login, beyond the username,
+// password and notes.
+type Metadata struct {
+	// URLs are the addresses the login is used at
+	URLs []string `json:"urls,omitempty"`
+	// Tags are free-form labels for grouping entries
+	Tags []string `json:"tags,omitempty"`
+	// Folder is an optional path, like "work/cloud", for organizing entries
+	Folder string `json:"folder,omitempty"`
+	// Fields are any other key/value pairs, like security questions or API
+	// keys
+	Fields []CustomField `json:"fields,omitempty"`
+}
+
+// CustomField is a user-defined key/value pair on an entry. The value of a
+// secret field is encrypted the same way as the password.
+type CustomField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// SetField adds the field, replacing any field with the same name
+func (m *Metadata) SetField(f CustomField) {
+	for i, existing := range m.Fields {
+		if existing.Name == f.Name {
+			m.Fields[i] = f
+			return
+		}
+	}
+	m.Fields = append(m.Fields, f)
+}
+
+// RemoveField removes the field with the given name. It returns false if
+// there was no such field.
+func (m *Metadata) RemoveField(name string) bool {
+	for i, f := range m.Fields {
+		if f.Name == name {
+			m.Fields = append(m.Fields[:i], m.Fields[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 type Config struct {
 	// MasterPassword is a bcrypt-hashed password that the user will need to
 	// input in to use the app.
@@ -36,6 +98,9 @@ type Config struct {
 	// Timeout is the number of minutes that the user will have to re-input the
 	// password after.
 	Timeout int64 `json:"timeout"`
+	// KeyRotatedAt is the time in UnixMilli when the keyring key was last
+	// rotated with 'config rotate-key'. It is 0 if it never has been.
+	KeyRotatedAt int64 `json:"key_rotated_at,omitempty"`
 }
 
 type UserInput struct {
@@ -46,14 +111,30 @@ type UserInput struct {
 	Password []byte
 	// Notes is the single string of notes that we get from the user.
 	Notes string
+	// TOTP is the encrypted otpauth:// URI, if one was given
+	TOTP []byte
+	// SSHKey is the encrypted OpenSSH private key, if one was given
+	SSHKey []byte
+	// Metadata is the optional metadata, with any secret fields already
+	// encrypted
+	Metadata
 }
 
 // DecryptedEntry is the decrypted vault entry, including password in plain text
-// This is most likely a placeholder of sorts
+// This is most likely a placeholder of sorts. It is what bundles hold, so its
+// JSON keys must not change.
 type DecryptedEntry struct {
-	Name      string
-	Username  string
-	Password  string
-	Notes     string
-	UpdatedAt int64
+	ID       string `json:"id,omitempty"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Notes    string `json:"notes,omitempty"`
+	// TOTP is the otpauth:// URI, if the entry has one
+	TOTP string `json:"totp,omitempty"`
+	// SSHKey is the PEM encoded OpenSSH private key, if the entry has one
+	SSHKey string `json:"ssh_key,omitempty"`
+	// Metadata has the values of any secret fields decrypted
+	Metadata
+	CreatedAt int64 `json:"created_at,omitempty"`
+	UpdatedAt int64 `json:"updated_at"`
 }
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"encoding/json"
+
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -19,3 +21,196 @@ func TestGenerateNonce(t *testing.T) {
 	assert.NoError(err)
 	assert.NotEqual(nonce, nonce2)
 }
+
+func TestMasterAESKeyManagerFromKey(t *testing.T) {
+	assert := assert.New(t)
+	key := make([]byte, KEY_SIZE)
+	km := NewMasterAESKeyManagerFromKey(key, DefaultKDF())
+
+	got, err := km.GetEncryptionKey()
+	assert.NoError(err)
+	assert.Equal(key, got)
+
+	ciphertext, err := km.Encrypt([]byte("secret"))
+	assert.NoError(err)
+
+	plaintext, err := km.Decrypt(ciphertext)
+	assert.NoError(err)
+	assert.Equal("secret", string(plaintext))
+}
+
+func TestMasterAESKeyManagerClose(t *testing.T) {
+	assert := assert.New(t)
+	key := make([]byte, KEY_SIZE)
+	for i := range key {
+		key[i] = byte(i + 1)
+	}
+	km := NewMasterAESKeyManagerFromKey(key, DefaultKDF())
+
+	_, err := km.Encrypt([]byte("secret"))
+	assert.NoError(err)
+
+	assert.NoError(km.Close())
+
+	// The cached key is zeroed in place
+	assert.Equal(make([]byte, KEY_SIZE), key)
+
+	_, err = km.Encrypt([]byte("secret"))
+	assert.ErrorIs(err, ErrKeyManagerClosed)
+	_, err = km.GetEncryptionKey()
+	assert.ErrorIs(err, ErrKeyManagerClosed)
+}
+
+func TestDeriveKey(t *testing.T) {
+	assert := assert.New(t)
+	baseKey := make([]byte, KEY_SIZE)
+
+	t.Setenv(SECRET_PASSWORD_KEY, "")
+	params, err := LegacyKDF().WithNewSalt()
+	assert.NoError(err)
+	assert.False(params.Pepper)
+
+	k1, err := DeriveKey(baseKey, []byte("one"), params)
+	assert.NoError(err)
+	k2, err := DeriveKey(baseKey, []byte("one"), params)
+	assert.NoError(err)
+	k3, err := DeriveKey(baseKey, []byte("two"), params)
+	assert.NoError(err)
+
+	assert.Len(k1, KEY_SIZE)
+	assert.Equal(k1, k2)
+	assert.NotEqual(k1, k3)
+
+	// A different salt gives a different key
+	other, err := LegacyKDF().WithNewSalt()
+	assert.NoError(err)
+	assert.NotEqual(params.Salt, other.Salt)
+	k4, err := DeriveKey(baseKey, []byte("one"), other)
+	assert.NoError(err)
+	assert.NotEqual(k1, k4)
+}
+
+func TestDeriveKeyPepper(t *testing.T) {
+	assert := assert.New(t)
+	baseKey := make([]byte, KEY_SIZE)
+
+	t.Setenv(SECRET_PASSWORD_KEY, "pepper")
+	params, err := LegacyKDF().WithNewSalt()
+	assert.NoError(err)
+	assert.True(params.Pepper)
+
+	peppered, err := DeriveKey(baseKey, []byte("one"), params)
+	assert.NoError(err)
+
+	unpeppered := params
+	unpeppered.Pepper = false
+	k, err := DeriveKey(baseKey, []byte("one"), unpeppered)
+	assert.NoError(err)
+	assert.NotEqual(peppered, k)
+
+	t.Setenv(SECRET_PASSWORD_KEY, "")
+	_, err = DeriveKey(baseKey, []byte("one"), params)
+	assert.Error(err, "a peppered vault can't be read without the pepper")
+}
+
+func TestGetSalt(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv(SECRET_PASSWORD_KEY, "")
+	_, err := GetSalt()
+	assert.Error(err)
+
+	// Without a stored salt, the env var is needed and returns an error
+	// rather than exiting
+	_, err = DeriveKey(make([]byte, KEY_SIZE), []byte("one"), LegacyKDF())
+	assert.Error(err)
+
+	t.Setenv(SECRET_PASSWORD_KEY, "too short")
+	_, err = GetSalt()
+	assert.Error(err)
+
+	t.Setenv(SECRET_PASSWORD_KEY, "abcdefghijklmnopqrstuvwxyz012345")
+	salt, err := GetSalt()
+	assert.NoError(err)
+	assert.Equal([]byte("abcdefghijklmnopqrstuvwxyz012345"), salt)
+}
+
+func TestVaultEntryBackwardCompatible(t *testing.T) {
+	assert := assert.New(t)
+
+	// An entry written before metadata existed
+	old := `{"name":"github","username":"me","password":"cGFzcw==","notes":"n","updated_at":5}`
+
+	var ve VaultEntry
+	assert.NoError(json.Unmarshal([]byte(old), &ve))
+	assert.Equal(VaultEntry{
+		Name:      "github",
+		Username:  "me",
+		Password:  []byte("pass"),
+		Notes:     "n",
+		UpdatedAt: 5,
+	}, ve)
+
+	// Empty metadata isn't written, so the JSON is unchanged
+	b, err := json.Marshal(ve)
+	assert.NoError(err)
+	assert.JSONEq(old, string(b))
+
+	ve.URLs = []string{"https://github.com"}
+	ve.Tags = []string{"work"}
+	ve.Folder = "work/code"
+	ve.Fields = []CustomField{{Name: "pin", Value: "enc", Secret: true}}
+	ve.CreatedAt = 1
+
+	b, err = json.Marshal(ve)
+	assert.NoError(err)
+	assert.JSONEq(`{
+		"name":"github","username":"me","password":"cGFzcw==","notes":"n",
+		"urls":["https://github.com"],"tags":["work"],"folder":"work/code",
+		"fields":[{"name":"pin","value":"enc","secret":true}],
+		"created_at":1,"updated_at":5
+	}`, string(b))
+}
+
+func TestDecryptedEntryJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	d := DecryptedEntry{
+		ID:        "id",
+		Name:      "github",
+		Username:  "me",
+		Password:  "pass",
+		Notes:     "n",
+		TOTP:      "otpauth://totp/x?secret=A",
+		SSHKey:    "key",
+		Metadata:  Metadata{Tags: []string{"work"}},
+		CreatedAt: 1,
+		UpdatedAt: 5,
+	}
+
+	// Bundles hold these, so the keys can't change
+	b, err := json.Marshal(d)
+	assert.NoError(err)
+	assert.JSONEq(`{
+		"id":"id","name":"github","username":"me","password":"pass","notes":"n",
+		"totp":"otpauth://totp/x?secret=A","ssh_key":"key","tags":["work"],
+		"created_at":1,"updated_at":5
+	}`, string(b))
+}
+
+func TestMetadataFields(t *testing.T) {
+	assert := assert.New(t)
+
+	var m Metadata
+	m.SetField(CustomField{Name: "a", Value: "1"})
+	m.SetField(CustomField{Name: "b", Value: "2"})
+	m.SetField(CustomField{Name: "a", Value: "3", Secret: true})
+	assert.Equal([]CustomField{
+		{Name: "a", Value: "3", Secret: true},
+		{Name: "b", Value: "2"},
+	}, m.Fields)
+
+	assert.True(m.RemoveField("a"))
+	assert.False(m.RemoveField("a"))
+	assert.Equal([]CustomField{{Name: "b", Value: "2"}}, m.Fields)
+}
@@ -0,0 +1,228 @@
+// Package otp generates time-based one-time passwords, as described in RFC 6238.
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ALGORITHM_SHA1   = "SHA1"
+	ALGORITHM_SHA256 = "SHA256"
+	ALGORITHM_SHA512 = "SHA512"
+
+	DEFAULT_DIGITS = 6
+	DEFAULT_PERIOD = 30
+)
+
+// Key is a TOTP secret along with the parameters codes are generated with
+type Key struct {
+	// Secret is the shared secret, decoded from base32
+	Secret []byte
+	// Algorithm is the HMAC hash, ALGORITHM_SHA1, ALGORITHM_SHA256 or
+	// ALGORITHM_SHA512
+	Algorithm string
+	// Digits is the length of a code, 6 to 8
+	Digits int
+	// Period is how long a code is valid for, in seconds
+	Period int
+	// Issuer and Account are the optional label of an otpauth:// URI
+	Issuer  string
+	Account string
+}
+
+// Parse parses either a base32 secret, which uses the default parameters, or
+// an otpauth://totp/ URI.
+func Parse(s string) (Key, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(strings.ToLower(s), "otpauth://") {
+		return parseURI(s)
+	}
+
+	secret, err := decodeSecret(s)
+	if err != nil {
+		return Key{}, err
+	}
+
+	return Key{
+		Secret:    secret,
+		Algorithm: ALGORITHM_SHA1,
+		Digits:    DEFAULT_DIGITS,
+		Period:    DEFAULT_PERIOD,
+	}, nil
+}
+
+// parseURI parses an otpauth://totp/ URI
+func parseURI(s string) (Key, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return Key{}, fmt.Errorf("parsing otpauth URI: %v", err)
+	}
+	if !strings.EqualFold(u.Host, "totp") {
+		return Key{}, fmt.Errorf("unsupported OTP type '%s', only 'totp' is supported", u.Host)
+	}
+
+	q := u.Query()
+
+	secret, err := decodeSecret(q.Get("secret"))
+	if err != nil {
+		return Key{}, err
+	}
+
+	k := Key{
+		Secret:    secret,
+		Algorithm: ALGORITHM_SHA1,
+		Digits:    DEFAULT_DIGITS,
+		Period:    DEFAULT_PERIOD,
+		Issuer:    q.Get("issuer"),
+	}
+
+	// The label is "issuer:account" or just "account"
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		if k.Issuer == "" {
+			k.Issuer = strings.TrimSpace(issuer)
+		}
+		k.Account = strings.TrimSpace(account)
+	} else {
+		k.Account = label
+	}
+
+	if alg := q.Get("algorithm"); alg != "" {
+		k.Algorithm = strings.ToUpper(alg)
+	}
+	if digits := q.Get("digits"); digits != "" {
+		if k.Digits, err = strconv.Atoi(digits); err != nil {
+			return Key{}, fmt.Errorf("invalid digits '%s'", digits)
+		}
+	}
+	if period := q.Get("period"); period != "" {
+		if k.Period, err = strconv.Atoi(period); err != nil {
+			return Key{}, fmt.Errorf("invalid period '%s'", period)
+		}
+	}
+
+	if err := k.validate(); err != nil {
+		return Key{}, err
+	}
+	return k, nil
+}
+
+// decodeSecret decodes a base32 secret, ignoring case, spaces and padding
+func decodeSecret(s string) ([]byte, error) {
+	s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	s = strings.TrimRight(s, "=")
+	if s == "" {
+		return nil, errors.New("TOTP secret is empty")
+	}
+
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(s)
+	if err != nil {
+		return nil, errors.New("TOTP secret must be base32 or an otpauth:// URI")
+	}
+	return secret, nil
+}
+
+func (k Key) validate() error {
+	if _, err := k.hash(); err != nil {
+		return err
+	}
+	if k.Digits < 6 || k.Digits > 8 {
+		return fmt.Errorf("invalid digits %d, must be 6 to 8", k.Digits)
+	}
+	if k.Period <= 0 {
+		return fmt.Errorf("invalid period %d, must be positive", k.Period)
+	}
+	return nil
+}
+
+func (k Key) hash() (func() hash.Hash, error) {
+	switch k.Algorithm {
+	case ALGORITHM_SHA1:
+		return sha1.New, nil
+	case ALGORITHM_SHA256:
+		return sha256.New, nil
+	case ALGORITHM_SHA512:
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm '%s'", k.Algorithm)
+	}
+}
+
+// URI returns the key as an otpauth://totp/ URI, which is how it is stored
+func (k Key) URI() string {
+	label := k.Account
+	if k.Issuer != "" {
+		label = k.Issuer + ":" + k.Account
+	}
+
+	q := url.Values{}
+	q.Set("secret", base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(k.Secret))
+	if k.Issuer != "" {
+		q.Set("issuer", k.Issuer)
+	}
+	q.Set("algorithm", k.Algorithm)
+	q.Set("digits", strconv.Itoa(k.Digits))
+	q.Set("period", strconv.Itoa(k.Period))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + label,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
+
+// Code returns the code for the time 't'
+func (k Key) Code(t time.Time) (string, error) {
+	if err := k.validate(); err != nil {
+		return "", err
+	}
+	h, _ := k.hash()
+
+	counter := uint64(t.Unix()) / uint64(k.Period)
+	return hotp(k.Secret, counter, k.Digits, h), nil
+}
+
+// Remaining returns how long the code for the time 't' is still valid for
+func (k Key) Remaining(t time.Time) time.Duration {
+	if k.Period <= 0 {
+		return 0
+	}
+	period := int64(k.Period)
+	return time.Duration(period-t.Unix()%period) * time.Second
+}
+
+// hotp is the HOTP algorithm from RFC 4226, with the hash that RFC 6238 allows
+// choosing
+func hotp(secret []byte, counter uint64, digits int, h func() hash.Hash) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(h, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for range digits {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, code%mod)
+}
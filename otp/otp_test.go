@@ -0,0 +1,130 @@
+package otp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The seeds from RFC 6238 appendix B, one for each hash
+var (
+	seedSHA1   = []byte("12345678901234567890")
+	seedSHA256 = []byte("12345678901234567890123456789012")
+	seedSHA512 = []byte("1234567890123456789012345678901234567890123456789012345678901234")
+)
+
+func TestCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix   int64
+		sha1   string
+		sha256 string
+		sha512 string
+	}{
+		{59, "94287082", "46119246", "90693936"},
+		{1111111109, "07081804", "68084774", "25091201"},
+		{1111111111, "14050471", "67062674", "99943326"},
+		{1234567890, "89005924", "91819424", "93441116"},
+		{2000000000, "69279037", "90698825", "38618901"},
+		{20000000000, "65353130", "77737706", "47863826"},
+	}
+
+	for _, tt := range tests {
+		t.Run(time.Unix(tt.unix, 0).UTC().String(), func(t *testing.T) {
+			assert := assert.New(t)
+			now := time.Unix(tt.unix, 0)
+
+			for _, k := range []struct {
+				key  Key
+				want string
+			}{
+				{Key{Secret: seedSHA1, Algorithm: ALGORITHM_SHA1, Digits: 8, Period: 30}, tt.sha1},
+				{Key{Secret: seedSHA256, Algorithm: ALGORITHM_SHA256, Digits: 8, Period: 30}, tt.sha256},
+				{Key{Secret: seedSHA512, Algorithm: ALGORITHM_SHA512, Digits: 8, Period: 30}, tt.sha512},
+			} {
+				code, err := k.key.Code(now)
+				assert.NoError(err)
+				assert.Equal(k.want, code, k.key.Algorithm)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString(seedSHA1)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    Key
+		wantErr bool
+	}{
+		{
+			name:  "base32 secret",
+			input: secret,
+			want:  Key{Secret: seedSHA1, Algorithm: ALGORITHM_SHA1, Digits: 6, Period: 30},
+		},
+		{
+			name:  "lowercase with spaces and no padding",
+			input: "gezd gnbv gy3t qojq gezd gnbv gy3t qojq",
+			want:  Key{Secret: seedSHA1, Algorithm: ALGORITHM_SHA1, Digits: 6, Period: 30},
+		},
+		{
+			name:  "otpauth URI",
+			input: "otpauth://totp/Example:alice@example.com?secret=" + secret + "&algorithm=sha256&digits=8&period=60",
+			want: Key{
+				Secret:    seedSHA1,
+				Algorithm: ALGORITHM_SHA256,
+				Digits:    8,
+				Period:    60,
+				Issuer:    "Example",
+				Account:   "alice@example.com",
+			},
+		},
+		{
+			name:  "otpauth URI with defaults",
+			input: "otpauth://totp/alice?secret=" + secret + "&issuer=Example",
+			want: Key{
+				Secret:    seedSHA1,
+				Algorithm: ALGORITHM_SHA1,
+				Digits:    6,
+				Period:    30,
+				Issuer:    "Example",
+				Account:   "alice",
+			},
+		},
+		{name: "empty", input: "", wantErr: true},
+		{name: "not base32", input: "not-base32!", wantErr: true},
+		{name: "hotp", input: "otpauth://hotp/alice?secret=" + secret, wantErr: true},
+		{name: "bad algorithm", input: "otpauth://totp/a?secret=" + secret + "&algorithm=MD5", wantErr: true},
+		{name: "bad digits", input: "otpauth://totp/a?secret=" + secret + "&digits=4", wantErr: true},
+		{name: "bad period", input: "otpauth://totp/a?secret=" + secret + "&period=0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			k, err := Parse(tt.input)
+			if tt.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tt.want, k)
+
+			// Round trips through the stored form
+			again, err := Parse(k.URI())
+			assert.NoError(err)
+			assert.Equal(k, again)
+		})
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	k := Key{Secret: seedSHA1, Algorithm: ALGORITHM_SHA1, Digits: 6, Period: 30}
+
+	assert.Equal(t, 30*time.Second, k.Remaining(time.Unix(60, 0)))
+	assert.Equal(t, time.Second, k.Remaining(time.Unix(59, 0)))
+}
@@ -0,0 +1,238 @@
+package sshagent
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"go-pass/utils"
+)
+
+const (
+	// SocketEnv is the environment variable that overrides the default socket
+	// path
+	SocketEnv = "GOPASS_SSH_AGENT_SOCK"
+	// SocketName is the file name of the socket inside of the socket dir.
+	SocketName = "ssh-agent.sock"
+)
+
+var (
+	// ErrDenied is returned to a client when the use of a key wasn't
+	// confirmed
+	ErrDenied = errors.New("use of key was not confirmed")
+	// ErrReadOnly is returned to a client that tries to add a key. Keys are
+	// only ever added to the vault.
+	ErrReadOnly = errors.New("keys can only be added to the vault")
+)
+
+// Key is a private key to serve, along with the comment clients are shown for
+// it
+type Key struct {
+	PrivateKey crypto.PrivateKey
+	Comment    string
+}
+
+// ConfirmFunc is asked before every use of a key, when keys need confirming.
+// The key is used only if it returns true.
+type ConfirmFunc func(comment string, key ssh.PublicKey) bool
+
+// Agent is an ssh-agent that serves a fixed set of keys. The keys are held in
+// memory until the lifetime runs out, or the agent is stopped, and then
+// removed.
+type Agent struct {
+	keyring agent.ExtendedAgent
+	confirm ConfirmFunc
+	// confirmMu makes sure only one confirmation is asked for at a time
+	confirmMu sync.Mutex
+
+	mu       sync.Mutex
+	listener net.Listener
+	timer    *time.Timer
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewAgent returns an Agent holding 'keys'. If 'lifetime' isn't 0, the keys
+// are removed and the agent stopped once it has passed. If 'confirm' isn't
+// nil, it is asked before every use of a key.
+func NewAgent(keys []Key, lifetime time.Duration, confirm ConfirmFunc) (*Agent, error) {
+	a := &Agent{
+		keyring: agent.NewKeyring().(agent.ExtendedAgent),
+		confirm: confirm,
+		done:    make(chan struct{}),
+	}
+
+	for _, k := range keys {
+		err := a.keyring.Add(agent.AddedKey{
+			PrivateKey:   k.PrivateKey,
+			Comment:      k.Comment,
+			LifetimeSecs: uint32(lifetime.Seconds()),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("adding '%s': %v", k.Comment, err)
+		}
+	}
+
+	if lifetime > 0 {
+		a.mu.Lock()
+		a.timer = time.AfterFunc(lifetime, a.Stop)
+		a.mu.Unlock()
+	}
+
+	return a, nil
+}
+
+// SocketPath returns the path to the per-user socket the ssh-agent listens on.
+// It prefers GOPASS_SSH_AGENT_SOCK, then $XDG_RUNTIME_DIR/gopass, and falls
+// back to the gopass config directory.
+func SocketPath() string {
+	return utils.SocketPath(SocketEnv, SocketName)
+}
+
+// Serve accepts connections on l until Stop is called or l fails.
+func (a *Agent) Serve(l net.Listener) error {
+	a.mu.Lock()
+	a.listener = l
+	a.mu.Unlock()
+
+	// Stop may have been called before there was a listener to close
+	select {
+	case <-a.done:
+		l.Close()
+		return nil
+	default:
+	}
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			select {
+			case <-a.done:
+				return nil
+			default:
+				return err
+			}
+		}
+
+		go func() {
+			defer conn.Close()
+			agent.ServeAgent(a, conn)
+		}()
+	}
+}
+
+// Stop removes the keys and closes the listener.
+func (a *Agent) Stop() {
+	a.stopOnce.Do(func() {
+		close(a.done)
+		a.keyring.RemoveAll()
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.timer != nil {
+			a.timer.Stop()
+		}
+		if a.listener != nil {
+			a.listener.Close()
+		}
+	})
+}
+
+// Done is closed once Stop has been called.
+func (a *Agent) Done() <-chan struct{} {
+	return a.done
+}
+
+// List returns the identities of the keys the agent holds.
+func (a *Agent) List() ([]*agent.Key, error) {
+	return a.keyring.List()
+}
+
+// Sign signs 'data' with 'key', once its use is confirmed.
+func (a *Agent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+// SignWithFlags signs 'data' with 'key', once its use is confirmed.
+func (a *Agent) SignWithFlags(
+	key ssh.PublicKey,
+	data []byte,
+	flags agent.SignatureFlags,
+) (*ssh.Signature, error) {
+	if err := a.confirmUse(key); err != nil {
+		return nil, err
+	}
+	return a.keyring.SignWithFlags(key, data, flags)
+}
+
+// confirmUse asks the ConfirmFunc, if there is one, whether 'key' may be used.
+// A key the agent doesn't hold is left for the keyring to refuse.
+func (a *Agent) confirmUse(key ssh.PublicKey) error {
+	if a.confirm == nil {
+		return nil
+	}
+
+	keys, err := a.keyring.List()
+	if err != nil {
+		return err
+	}
+
+	wire := key.Marshal()
+	for _, k := range keys {
+		if !bytes.Equal(k.Blob, wire) {
+			continue
+		}
+
+		a.confirmMu.Lock()
+		defer a.confirmMu.Unlock()
+		if !a.confirm(k.Comment, key) {
+			return ErrDenied
+		}
+		return nil
+	}
+	return nil
+}
+
+// Add refuses to add the key, see ErrReadOnly.
+func (a *Agent) Add(key agent.AddedKey) error {
+	return ErrReadOnly
+}
+
+// Remove removes the key until the agent is started again.
+func (a *Agent) Remove(key ssh.PublicKey) error {
+	return a.keyring.Remove(key)
+}
+
+// RemoveAll removes every key until the agent is started again.
+func (a *Agent) RemoveAll() error {
+	return a.keyring.RemoveAll()
+}
+
+// Lock locks the agent with a passphrase, so it serves no keys until it is
+// unlocked with the same passphrase.
+func (a *Agent) Lock(passphrase []byte) error {
+	return a.keyring.Lock(passphrase)
+}
+
+// Unlock undoes Lock.
+func (a *Agent) Unlock(passphrase []byte) error {
+	return a.keyring.Unlock(passphrase)
+}
+
+// Signers is not supported, as signing outside of Sign would skip the
+// confirmation.
+func (a *Agent) Signers() ([]ssh.Signer, error) {
+	return nil, errors.New("signers are not supported")
+}
+
+// Extension reports that no extensions are supported.
+func (a *Agent) Extension(extensionType string, contents []byte) ([]byte, error) {
+	return nil, agent.ErrExtensionUnsupported
+}
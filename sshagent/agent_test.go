@@ -0,0 +1,113 @@
+package sshagent
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"go-pass/utils"
+)
+
+// startTestAgent serves an agent holding a new ed25519 key, named "github",
+// on a temporary socket
+func startTestAgent(
+	t *testing.T,
+	lifetime time.Duration,
+	confirm ConfirmFunc,
+) (*Agent, agent.ExtendedAgent, ssh.PublicKey) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	pub, err := PublicKey(priv)
+	assert.NoError(t, err)
+
+	a, err := NewAgent([]Key{{PrivateKey: priv, Comment: "github"}}, lifetime, confirm)
+	assert.NoError(t, err)
+
+	// Unix socket paths have a short length limit, so t.TempDir() is too long
+	dir, err := os.MkdirTemp("", "gopass-ssh-agent")
+	assert.NoError(t, err)
+	socketPath := path.Join(dir, SocketName)
+
+	l, err := utils.ListenUnix(socketPath)
+	assert.NoError(t, err)
+	go a.Serve(l)
+
+	conn, err := net.Dial("unix", socketPath)
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		a.Stop()
+		os.RemoveAll(dir)
+	})
+
+	return a, agent.NewClient(conn), pub
+}
+
+func TestAgentSign(t *testing.T) {
+	assert := assert.New(t)
+	_, client, pub := startTestAgent(t, 0, nil)
+
+	keys, err := client.List()
+	assert.NoError(err)
+	assert.Len(keys, 1)
+	assert.Equal("github", keys[0].Comment)
+	assert.Equal(pub.Marshal(), keys[0].Blob)
+
+	sig, err := client.Sign(pub, []byte("challenge"))
+	assert.NoError(err)
+	assert.NoError(pub.Verify([]byte("challenge"), sig))
+
+	// Keys come from the vault only
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(err)
+	assert.Error(client.Add(agent.AddedKey{PrivateKey: priv}))
+
+	assert.NoError(client.RemoveAll())
+	keys, err = client.List()
+	assert.NoError(err)
+	assert.Empty(keys)
+}
+
+func TestAgentConfirm(t *testing.T) {
+	assert := assert.New(t)
+
+	allow := false
+	var asked []string
+	_, client, pub := startTestAgent(t, 0, func(comment string, key ssh.PublicKey) bool {
+		asked = append(asked, comment)
+		return allow
+	})
+
+	_, err := client.Sign(pub, []byte("challenge"))
+	assert.Error(err)
+
+	allow = true
+	sig, err := client.Sign(pub, []byte("challenge"))
+	assert.NoError(err)
+	assert.NoError(pub.Verify([]byte("challenge"), sig))
+	assert.Equal([]string{"github", "github"}, asked)
+}
+
+func TestAgentLifetime(t *testing.T) {
+	assert := assert.New(t)
+	a, _, _ := startTestAgent(t, 50*time.Millisecond, nil)
+
+	select {
+	case <-a.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent was not stopped after its lifetime")
+	}
+
+	keys, err := a.List()
+	assert.NoError(err)
+	assert.Empty(keys)
+}
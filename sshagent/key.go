@@ -0,0 +1,59 @@
+package sshagent
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// PassphraseFunc is asked for the passphrase of a private key that has one
+type PassphraseFunc func() ([]byte, error)
+
+// ParsePrivateKey parses a PEM encoded private key, in any format ssh-keygen
+// writes. If the key is protected by a passphrase, 'passphrase' is asked for
+// it.
+func ParsePrivateKey(pemBytes []byte, passphrase PassphraseFunc) (crypto.PrivateKey, error) {
+	key, err := ssh.ParseRawPrivateKey(pemBytes)
+
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		pw, perr := passphrase()
+		if perr != nil {
+			return nil, perr
+		}
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase(pemBytes, pw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing SSH key: %v", err)
+	}
+
+	// OpenSSH ed25519 keys are parsed to a pointer, which nothing else
+	// accepts
+	if k, ok := key.(*ed25519.PrivateKey); ok {
+		key = *k
+	}
+	return key, nil
+}
+
+// MarshalPrivateKey returns the key as an unencrypted, PEM encoded OpenSSH
+// private key, which is how it is kept in the vault
+func MarshalPrivateKey(key crypto.PrivateKey, comment string) ([]byte, error) {
+	block, err := ssh.MarshalPrivateKey(key, comment)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling SSH key: %v", err)
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// PublicKey returns the public half of the private key
+func PublicKey(key crypto.PrivateKey) (ssh.PublicKey, error) {
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return signer.PublicKey(), nil
+}
@@ -0,0 +1,59 @@
+package sshagent
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParsePrivateKey(t *testing.T) {
+	assert := assert.New(t)
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(err)
+
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(priv, "me@laptop", []byte("hunter2"))
+	assert.NoError(err)
+	protected := pem.EncodeToMemory(block)
+
+	asked := 0
+	passphrase := func(pw string) PassphraseFunc {
+		return func() ([]byte, error) {
+			asked++
+			return []byte(pw), nil
+		}
+	}
+
+	_, err = ParsePrivateKey(protected, passphrase("wrong"))
+	assert.Error(err)
+
+	key, err := ParsePrivateKey(protected, passphrase("hunter2"))
+	assert.NoError(err)
+	assert.Equal(priv, key)
+	assert.Equal(2, asked)
+
+	_, err = ParsePrivateKey(protected, func() ([]byte, error) {
+		return nil, errors.New("no terminal")
+	})
+	assert.EqualError(err, "no terminal")
+
+	// The key is kept unencrypted, so it is parsed again without asking
+	unencrypted, err := MarshalPrivateKey(key, "github")
+	assert.NoError(err)
+	again, err := ParsePrivateKey(unencrypted, passphrase("unused"))
+	assert.NoError(err)
+	assert.Equal(priv, again)
+	assert.Equal(2, asked)
+
+	pub, err := PublicKey(again)
+	assert.NoError(err)
+	assert.Equal(ssh.KeyAlgoED25519, pub.Type())
+
+	_, err = ParsePrivateKey([]byte("not a key"), passphrase("unused"))
+	assert.Error(err)
+}
@@ -1,7 +1,6 @@
 package utils
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"io"
@@ -16,6 +15,7 @@ type ConfirmationPrompt string
 const (
 	DeletePrompt ConfirmationPrompt = "DELETE"
 	CleanPrompt  ConfirmationPrompt = "CLEAN"
+	ExportPrompt ConfirmationPrompt = "EXPORT"
 )
 
 func (c ConfirmationPrompt) String() string {
@@ -23,22 +23,36 @@ func (c ConfirmationPrompt) String() string {
 }
 
 // GetInputFromUser reads the input from the user and returns the string form
-// of that input
+// of that input. Only the line is read from 'r', so the prompts that follow
+// can read the next lines from the same piped input.
 func GetInputFromUser(r io.Reader, field string) (string, error) {
-	br := bufio.NewReader(r)
 	fmt.Printf("%s: ", field)
-	username, err := br.ReadString('\n')
+	input, err := readLine(r)
 	if err != nil {
 		return "", err
 	}
 
-	return cleanString(username), nil
+	return cleanString(input), nil
 }
 
-// GetPasswordFromUser reads the password using the 'term' package from r.
-// 'again' is an optional parameter specifically used by change_masterpass to
-// change the prompt.
+// GetPasswordFromUser reads the password using the 'term' package from r, or
+// a line from r if it isn't a terminal. 'again' is an optional parameter
+// specifically used by change_masterpass to change the prompt. The first time
+// the Master Password is asked for, the one given to SetMasterPassword is
+// used instead, if there is one.
 func GetPasswordFromUser(master bool, r io.Reader, again ...bool) ([]byte, error) {
+	return PromptPassword(os.Stdout, master, r, again...)
+}
+
+// PromptPassword is GetPasswordFromUser with the prompt written to 'w', for
+// the commands whose stdout is their output
+func PromptPassword(w io.Writer, master bool, r io.Reader, again ...bool) ([]byte, error) {
+	if master {
+		if b := takeMasterPassword(); b != nil {
+			return b, nil
+		}
+	}
+
 	phrase := "Password: "
 	if master {
 		phrase = "Master Password: "
@@ -48,17 +62,103 @@ func GetPasswordFromUser(master bool, r io.Reader, again ...bool) ([]byte, error
 		}
 	}
 
-	fmt.Print(phrase)
-	fd, ok := (r).(*os.File)
-	if !ok {
-		return nil, errors.New("cannot read from source")
+	fmt.Fprint(w, phrase)
+	b, err := readHidden(w, r)
+	if err != nil {
+		return nil, err
 	}
-	b, err := term.ReadPassword(int(fd.Fd()))
-	fmt.Println()
 	if len(b) == 0 {
 		return nil, errors.New("must enter a password")
 	}
-	return b, err
+	return b, nil
+}
+
+// GetSecretFromUser reads a secret value for 'field', such as a secret custom
+// field, without echoing it
+func GetSecretFromUser(r io.Reader, field string) ([]byte, error) {
+	return PromptSecret(os.Stdout, r, field)
+}
+
+// PromptSecret is GetSecretFromUser with the prompt written to 'w'
+func PromptSecret(w io.Writer, r io.Reader, field string) ([]byte, error) {
+	fmt.Fprintf(w, "%s (hidden): ", field)
+	b, err := readHidden(w, r)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("must enter a value for '%s'", field)
+	}
+	return b, nil
+}
+
+// readHidden reads a line from the terminal 'r' without echoing it, and ends
+// the prompt line on 'w'. If 'r' isn't a terminal, like a pipe in a script,
+// the line is read as is.
+func readHidden(w io.Writer, r io.Reader) ([]byte, error) {
+	defer fmt.Fprintln(w)
+
+	if f, ok := r.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		return term.ReadPassword(int(f.Fd()))
+	}
+
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.TrimSuffix(line, "\r")), nil
+}
+
+// readLine reads a line from 'r', without the line break. It reads a byte at
+// a time, so nothing past the line is taken from 'r' and lost to the next
+// read. A last line without a line break is returned too, and io.EOF only if
+// there was nothing left to read.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		var err error
+		if br, ok := r.(io.ByteReader); ok {
+			b[0], err = br.ReadByte()
+		} else {
+			_, err = io.ReadFull(r, b)
+		}
+
+		if errors.Is(err, io.EOF) && len(line) > 0 {
+			return string(line), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if b[0] == '\n' {
+			return string(line), nil
+		}
+		line = append(line, b[0])
+	}
+}
+
+// SplitList splits a 'sep'-separated list, trimming the spaces around each
+// item and dropping empty ones
+func SplitList(s, sep string) []string {
+	var items []string
+	for _, item := range strings.Split(s, sep) {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ParseField parses a custom field given as 'name=value'. The value may
+// contain '=', but the name may not be empty.
+func ParseField(s string) (string, string, error) {
+	name, value, ok := strings.Cut(s, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("invalid field '%s', must be 'name=value'", s)
+	}
+	return name, strings.TrimSpace(value), nil
 }
 
 func cleanString(s string) string {
@@ -70,10 +170,9 @@ func cleanString(s string) string {
 func ConfirmPrompt(confType ConfirmationPrompt, prompt string, r io.Reader) (bool, error) {
 	switch confType.String() {
 	case "DELETE":
-		br := bufio.NewReader(r)
 		response := fmt.Sprintf("Are you sure you want to delete '%s'? (y/n) ", prompt)
 		fmt.Print(response)
-		confirm, err := br.ReadString('\n')
+		confirm, err := readLine(r)
 		if err != nil {
 			return false, fmt.Errorf("failed to read input: %v", err)
 		}
@@ -89,10 +188,28 @@ func ConfirmPrompt(confType ConfirmationPrompt, prompt string, r io.Reader) (boo
 	case "CLEAN":
 		response := "Are you sure you want to delete everything? This includes your config and vault? (y/n) "
 
-		br := bufio.NewReader(r)
 		fmt.Print(response)
 
-		confirm, err := br.ReadString('\n')
+		confirm, err := readLine(r)
+		if err != nil {
+			return false, fmt.Errorf("failed to read input: %v", err)
+		}
+
+		confirm = cleanString(confirm)
+		if strings.EqualFold(confirm, "y") {
+			return true, nil
+		} else if strings.EqualFold(confirm, "n") {
+			return false, nil
+		} else {
+			return false, fmt.Errorf("invalid input")
+		}
+	case "EXPORT":
+		// The prompt goes to stderr, so it doesn't end up in an export
+		// written to stdout
+		response := fmt.Sprintf("Are you sure you want to write your passwords unencrypted to %s? (y/n) ", prompt)
+		fmt.Fprint(os.Stderr, response)
+
+		confirm, err := readLine(r)
 		if err != nil {
 			return false, fmt.Errorf("failed to read input: %v", err)
 		}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"io"
 	"strings"
 	"testing"
 
@@ -19,10 +20,30 @@ func TestGetInputFromUser(t *testing.T) {
 	assert.Equal("test", user)
 }
 
-// Because of the use of github.com/x/term and trying to avoid complexity for
-// a fairly simple function, we are just seeing if there's an error. The 'term'
-// package relies specifically on the interface with the terminal, and mocking
-// that would make this overly complex for what this function actually does.
+func TestGetInputFromUserSharedReader(t *testing.T) {
+	assert := assert.New(t)
+
+	// Each prompt takes only its own line, so piped input works for several
+	r := strings.NewReader("me\n\nlast")
+
+	user, err := GetInputFromUser(r, "Username")
+	assert.NoError(err)
+	assert.Equal("me", user)
+
+	notes, err := GetInputFromUser(r, "Notes")
+	assert.NoError(err)
+	assert.Equal("", notes)
+
+	last, err := GetInputFromUser(r, "Last")
+	assert.NoError(err)
+	assert.Equal("last", last)
+
+	_, err = GetInputFromUser(r, "Nothing left")
+	assert.ErrorIs(err, io.EOF)
+}
+
+// A reader that isn't a terminal, like a pipe in a script, gives the password
+// as a plain line
 func TestGetPasswordFromUser(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -40,17 +61,30 @@ func TestGetPasswordFromUser(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			r := strings.NewReader("test\n")
+			r := strings.NewReader("test\r\nnext\n")
 
-			user, err := GetPasswordFromUser(false, r)
+			pass, err := GetPasswordFromUser(tt.master, r)
 
 			assert := assert.New(t)
+			assert.NoError(err)
+			assert.Equal([]byte("test"), pass)
+
+			_, err = GetPasswordFromUser(tt.master, strings.NewReader("\n"))
 			assert.Error(err)
-			assert.Nil(user)
 		})
 	}
 }
 
+func TestPromptPassword(t *testing.T) {
+	assert := assert.New(t)
+
+	var w strings.Builder
+	pass, err := PromptPassword(&w, true, strings.NewReader("test\n"))
+	assert.NoError(err)
+	assert.Equal([]byte("test"), pass)
+	assert.Equal("Master Password: \n", w.String())
+}
+
 func TestConfirmPrompt(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -108,6 +142,30 @@ func TestConfirmPrompt(t *testing.T) {
 			want:        false,
 			shouldError: true,
 		},
+		{
+			name:        "export yes",
+			conf:        ExportPrompt,
+			prompt:      "'out.csv'",
+			input:       "y",
+			want:        true,
+			shouldError: false,
+		},
+		{
+			name:        "export no",
+			conf:        ExportPrompt,
+			prompt:      "'out.csv'",
+			input:       "n",
+			want:        false,
+			shouldError: false,
+		},
+		{
+			name:        "export bad input",
+			conf:        ExportPrompt,
+			prompt:      "'out.csv'",
+			input:       "bad",
+			want:        false,
+			shouldError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -127,3 +185,41 @@ func TestConfirmPrompt(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitList(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{"a", "b c", "d"}, SplitList(" a, b c ,, d ", ","))
+	assert.Nil(SplitList("", ","))
+	assert.Nil(SplitList(" ; ", ";"))
+}
+
+func TestParseField(t *testing.T) {
+	tests := []struct {
+		input     string
+		wantName  string
+		wantValue string
+		wantErr   bool
+	}{
+		{input: "pin=1234", wantName: "pin", wantValue: "1234"},
+		{input: " api key = a=b ", wantName: "api key", wantValue: "a=b"},
+		{input: "empty=", wantName: "empty", wantValue: ""},
+		{input: "novalue", wantErr: true},
+		{input: "=value", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert := assert.New(t)
+
+			name, value, err := ParseField(tt.input)
+			if tt.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tt.wantName, name)
+			assert.Equal(tt.wantValue, value)
+		})
+	}
+}
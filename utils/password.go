@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// PASSWORD_FILE_ENV is the environment variable naming a file to read the
+// Master Password from, for scripts
+const PASSWORD_FILE_ENV = "GOPASS_PASSWORD_FILE"
+
+var (
+	masterPasswordMu sync.Mutex
+	masterPassword   []byte
+)
+
+// SetMasterPassword makes the next GetPasswordFromUser for the Master Password
+// return 'password' instead of prompting for it. Prompts after that one, like
+// for the new password in 'config change-masterpass', are asked as usual.
+func SetMasterPassword(password []byte) {
+	masterPasswordMu.Lock()
+	defer masterPasswordMu.Unlock()
+	masterPassword = password
+}
+
+// takeMasterPassword returns the password given to SetMasterPassword, once
+func takeMasterPassword() []byte {
+	masterPasswordMu.Lock()
+	defer masterPasswordMu.Unlock()
+
+	b := masterPassword
+	masterPassword = nil
+	return b
+}
+
+// ReadPassword reads a password from the first line of 'r'. Nothing past the
+// line is read, so the rest of 'r' is left for the prompts that follow.
+func ReadPassword(r io.Reader) ([]byte, error) {
+	line, err := readLine(r)
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("no password given")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading password: %v", err)
+	}
+
+	line = strings.TrimSuffix(line, "\r")
+	if line == "" {
+		return nil, errors.New("no password given")
+	}
+	return []byte(line), nil
+}
+
+// ReadPasswordFD reads a password from the file descriptor 'fd', which a
+// script opened for gopass, like '3<password.txt'
+func ReadPasswordFD(fd int) ([]byte, error) {
+	if fd < 0 {
+		return nil, fmt.Errorf("invalid file descriptor %d", fd)
+	}
+
+	f := os.NewFile(uintptr(fd), fmt.Sprintf("fd %d", fd))
+	if _, err := f.Stat(); err != nil {
+		return nil, fmt.Errorf("file descriptor %d is not open: %v", fd, err)
+	}
+	defer f.Close()
+
+	return ReadPassword(f)
+}
+
+// ReadPasswordFile reads a password from the file at 'p'. The file has to be
+// readable only by its owner, like ssh expects of private keys.
+func ReadPasswordFile(p string) ([]byte, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, fmt.Errorf("opening password file: %v", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("opening password file: %v", err)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return nil, fmt.Errorf(
+			"password file '%s' can be read by others, it must be 0600 or stricter",
+			p,
+		)
+	}
+
+	return ReadPassword(f)
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetMasterPassword(t *testing.T) {
+	assert := assert.New(t)
+	defer SetMasterPassword(nil)
+
+	SetMasterPassword([]byte("preset"))
+
+	// The first prompt for the Master Password is answered without reading
+	r := strings.NewReader("typed\n")
+	pass, err := GetPasswordFromUser(true, r)
+	assert.NoError(err)
+	assert.Equal([]byte("preset"), pass)
+
+	// Other passwords are still read, as are later Master Password prompts
+	SetMasterPassword([]byte("preset"))
+	pass, err = GetPasswordFromUser(false, r)
+	assert.NoError(err)
+	assert.Equal([]byte("typed"), pass)
+
+	pass, err = GetPasswordFromUser(true, strings.NewReader("new\n"))
+	assert.NoError(err)
+	assert.Equal([]byte("preset"), pass)
+	pass, err = GetPasswordFromUser(true, strings.NewReader("new\n"))
+	assert.NoError(err)
+	assert.Equal([]byte("new"), pass)
+}
+
+func TestReadPassword(t *testing.T) {
+	assert := assert.New(t)
+
+	r := strings.NewReader("s3cret\nme\n")
+	pass, err := ReadPassword(r)
+	assert.NoError(err)
+	assert.Equal([]byte("s3cret"), pass)
+
+	// The rest is left for the prompts
+	user, err := GetInputFromUser(r, "Username")
+	assert.NoError(err)
+	assert.Equal("me", user)
+
+	_, err = ReadPassword(strings.NewReader(""))
+	assert.ErrorContains(err, "no password given")
+	_, err = ReadPassword(strings.NewReader("\n"))
+	assert.ErrorContains(err, "no password given")
+}
+
+func TestReadPasswordFD(t *testing.T) {
+	assert := assert.New(t)
+
+	r, w, err := os.Pipe()
+	assert.NoError(err)
+	_, err = w.WriteString("from-fd\n")
+	assert.NoError(err)
+	w.Close()
+
+	pass, err := ReadPasswordFD(int(r.Fd()))
+	assert.NoError(err)
+	assert.Equal([]byte("from-fd"), pass)
+
+	_, err = ReadPasswordFD(-1)
+	assert.Error(err)
+}
+
+func TestReadPasswordFile(t *testing.T) {
+	assert := assert.New(t)
+	p := path.Join(t.TempDir(), "password")
+
+	assert.NoError(os.WriteFile(p, []byte("from-file\n"), 0o600))
+	pass, err := ReadPasswordFile(p)
+	assert.NoError(err)
+	assert.Equal([]byte("from-file"), pass)
+
+	assert.NoError(os.Chmod(p, 0o644))
+	_, err = ReadPasswordFile(p)
+	assert.ErrorContains(err, "can be read by others")
+
+	_, err = ReadPasswordFile(path.Join(t.TempDir(), "missing"))
+	assert.Error(err)
+}
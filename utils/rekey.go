@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"go-pass/crypt"
+	"go-pass/model"
+)
+
+// reEncryptedFile is a file that has been re-encrypted in memory and is waiting
+// to be written. original holds what was on disk, for rolling back.
+type reEncryptedFile struct {
+	path       string
+	kind       model.TempFileKind
+	ciphertext string
+	original   []byte
+}
+
+// ReEncryptFiles decrypts the config, the vault with every password in it, and
+// every backup with 'from', and encrypts them all again with 'to'. If
+// 'updateCfg' isn't nil, it is called on the config before it is encrypted.
+// Everything is decrypted before anything is written, and if a write fails the
+// files already written are rolled back, so a failure leaves every file as it
+// was.
+func ReEncryptFiles(
+	cfgName string,
+	from, to *model.MasterAESKeyManager,
+	updateCfg func(*model.Config),
+) error {
+	files, err := reEncryptAll(cfgName, from, to, updateCfg)
+	if err != nil {
+		return err
+	}
+
+	return writeAll(files)
+}
+
+// ReKeyFiles re-encrypts every file, like ReEncryptFiles, with a key derived
+// from 'masterPassword' and 'baseKey' using the current KDF. Once every file has
+// been written, 'baseKey' is stored in from.Store under 'masterPassword'. If
+// that fails, the files are rolled back, so the keystore and the files always
+// agree.
+func ReKeyFiles(
+	cfgName string,
+	from *model.MasterAESKeyManager,
+	masterPassword string,
+	baseKey []byte,
+	updateCfg func(*model.Config),
+) error {
+	// Reading the config switches 'from' to the KDF the files use
+	if _, err := CheckConfig(cfgName, from); err != nil {
+		return err
+	}
+
+	staged := model.NewStaticKeyStore(baseKey)
+	defer staged.Delete()
+
+	to := model.NewMasterAESKeyManagerWithStore(masterPassword, staged)
+	defer to.Close()
+	if err := to.UseKDF(from.KDF()); err != nil {
+		return err
+	}
+
+	files, err := reEncryptAll(cfgName, from, to, updateCfg)
+	if err != nil {
+		return err
+	}
+
+	if err := writeAll(files); err != nil {
+		return err
+	}
+
+	if err := from.Store.Set(baseKey, []byte(masterPassword)); err != nil {
+		if rbErr := rollback(files); rbErr != nil {
+			return fmt.Errorf("storing key: %v. rolling back also failed: %v", err, rbErr)
+		}
+		return fmt.Errorf("storing key: %v", err)
+	}
+
+	return nil
+}
+
+// reEncryptAll re-encrypts the vault, the backups and the config in memory
+func reEncryptAll(
+	cfgName string,
+	from, to *model.MasterAESKeyManager,
+	updateCfg func(*model.Config),
+) ([]reEncryptedFile, error) {
+	cfg, err := CheckConfig(cfgName, from)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []reEncryptedFile
+
+	vaultF, err := OpenVault(cfg.VaultName)
+	if err != nil {
+		return nil, err
+	}
+	defer vaultF.Close()
+
+	vault, err := reEncryptFile(vaultF.Name(), model.FileVault, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %v", err)
+	}
+	files = append(files, vault)
+
+	backups, err := os.ReadDir(BACKUP_PATH)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, b := range backups {
+		if b.IsDir() {
+			continue
+		}
+
+		backup, err := reEncryptFile(path.Join(BACKUP_PATH, b.Name()), model.FileBackup, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("backup '%s': %v", b.Name(), err)
+		}
+		files = append(files, backup)
+	}
+
+	cfgPath := configPath(cfgName)
+	original, err := os.ReadFile(cfgPath)
+	if err != nil {
+		return nil, fmt.Errorf("config: %v", err)
+	}
+
+	if updateCfg != nil {
+		updateCfg(cfg)
+	}
+
+	cfgCiphertext, err := crypt.EncryptConfig(cfg, to)
+	if err != nil {
+		return nil, fmt.Errorf("config: %v", err)
+	}
+	files = append(files, reEncryptedFile{cfgPath, model.FileConfig, cfgCiphertext, original})
+
+	return files, nil
+}
+
+// reEncryptFile re-encrypts a vault or backup, and every password in it
+func reEncryptFile(
+	fn string,
+	kind model.TempFileKind,
+	from, to *model.MasterAESKeyManager,
+) (reEncryptedFile, error) {
+	contents, err := os.ReadFile(fn)
+	if err != nil {
+		return reEncryptedFile{}, err
+	}
+
+	plaintext, err := crypt.Open(contents, from)
+	if err != nil {
+		return reEncryptedFile{}, err
+	}
+
+	var entries []model.VaultEntry
+	if err := json.Unmarshal(plaintext, &entries); err != nil {
+		return reEncryptedFile{}, fmt.Errorf("unmarshaling: %v", err)
+	}
+
+	entries, err = crypt.ReEncryptEntries(entries, from, to)
+	if err != nil {
+		return reEncryptedFile{}, err
+	}
+
+	ciphertext, err := crypt.EncryptVault(entries, to)
+	if err != nil {
+		return reEncryptedFile{}, err
+	}
+
+	return reEncryptedFile{fn, kind, ciphertext, contents}, nil
+}
+
+// writeAll writes every file. If one fails, the files written before it are
+// rolled back.
+func writeAll(files []reEncryptedFile) error {
+	for i, f := range files {
+		if err := WriteToFile(f.path, f.kind, f.ciphertext); err != nil {
+			err = fmt.Errorf("writing %s: %v", f.path, err)
+			if rbErr := rollback(files[:i]); rbErr != nil {
+				return fmt.Errorf("%v. rolling back also failed: %v", err, rbErr)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rollback writes back the original contents of every file
+func rollback(files []reEncryptedFile) error {
+	var errs []error
+	for i := len(files) - 1; i >= 0; i-- {
+		f := files[i]
+		if err := WriteToFile(f.path, f.kind, string(f.original)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", f.path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// configPath returns the full path of the config file, the same way
+// OpenConfig resolves it.
+func configPath(fn string) string {
+	if fn != "" {
+		return path.Join(CONFIG_PATH, fn)
+	}
+	return CONFIG_FILE
+}
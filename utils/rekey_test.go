@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/crypt"
+	"go-pass/model"
+	"go-pass/testutils"
+)
+
+// failingKeyStore is a KeyStore that can't store a key
+type failingKeyStore struct {
+	model.KeyStore
+}
+
+func (failingKeyStore) Set(_, _ []byte) error {
+	return errors.New("keystore unavailable")
+}
+
+// setupReKeyTest creates a config, a vault with one entry and a backup, and
+// returns the key manager they are encrypted with
+func setupReKeyTest(t *testing.T) *model.MasterAESKeyManager {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	t.Cleanup(func() { testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD)) })
+
+	oldBackupPath := BACKUP_PATH
+	BACKUP_PATH = t.TempDir()
+	t.Cleanup(func() { BACKUP_PATH = oldBackupPath })
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(t, err)
+
+	cfgFile, err := CreateConfig(
+		testutils.TEST_VAULT_NAME,
+		testutils.TEST_MASTER_PASSWORD,
+		testutils.TEST_CONFIG_NAME,
+		key,
+	)
+	assert.NoError(t, err)
+	cfgFile.Close()
+
+	pass, err := crypt.EncryptPassword([]byte("hunter2"), key)
+	assert.NoError(t, err)
+
+	vault, err := crypt.EncryptVault([]model.VaultEntry{{Name: "test1", Password: []byte(pass)}}, key)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.MkdirAll(VAULT_PATH, 0o700))
+	assert.NoError(t, WriteToFile(path.Join(VAULT_PATH, testutils.TEST_VAULT_NAME), model.FileVault, vault))
+	assert.NoError(t, os.WriteFile(path.Join(BACKUP_PATH, "backup.json"), []byte(vault), 0o600))
+
+	return key
+}
+
+// readReKeyFiles returns the contents of the vault, backup and config
+func readReKeyFiles(t *testing.T) [][]byte {
+	var contents [][]byte
+	for _, fn := range []string{
+		path.Join(VAULT_PATH, testutils.TEST_VAULT_NAME),
+		path.Join(BACKUP_PATH, "backup.json"),
+		path.Join(CONFIG_PATH, testutils.TEST_CONFIG_NAME),
+	} {
+		b, err := os.ReadFile(fn)
+		assert.NoError(t, err)
+		contents = append(contents, b)
+	}
+	return contents
+}
+
+// assertReadable checks that the config, vault, backup and password can be
+// decrypted with 'key'
+func assertReadable(t *testing.T, key *model.MasterAESKeyManager) {
+	assert := assert.New(t)
+
+	_, err := CheckConfig(testutils.TEST_CONFIG_NAME, key)
+	assert.NoError(err)
+
+	for _, fn := range []string{
+		path.Join(VAULT_PATH, testutils.TEST_VAULT_NAME),
+		path.Join(BACKUP_PATH, "backup.json"),
+	} {
+		f, err := os.Open(fn)
+		assert.NoError(err)
+		entries, err := crypt.DecryptVault(f, key)
+		f.Close()
+		assert.NoError(err)
+		assert.Len(entries, 1)
+
+		pass, err := crypt.DecryptPassword(entries[0].Password, key)
+		assert.NoError(err)
+		assert.Equal("hunter2", pass)
+	}
+}
+
+func TestReKeyFiles(t *testing.T) {
+	assert := assert.New(t)
+	key := setupReKeyTest(t)
+	defer key.Close()
+
+	baseKey, err := key.Store.Get(nil)
+	assert.NoError(err)
+
+	err = ReKeyFiles(testutils.TEST_CONFIG_NAME, key, "newpass", baseKey, func(cfg *model.Config) {
+		cfg.Timeout = 1234
+	})
+	assert.NoError(err)
+
+	newKey := model.NewTestMasterAESKeyManager("newpass")
+	defer newKey.Close()
+	assertReadable(t, newKey)
+
+	cfg, err := CheckConfig(testutils.TEST_CONFIG_NAME, newKey)
+	assert.NoError(err)
+	assert.Equal(int64(1234), cfg.Timeout)
+
+	oldKey := model.NewTestMasterAESKeyManager(string(testutils.TEST_MASTER_PASSWORD))
+	defer oldKey.Close()
+	_, err = CheckConfig(testutils.TEST_CONFIG_NAME, oldKey)
+	assert.Error(err, "the old password no longer works")
+}
+
+func TestReKeyFilesNewBaseKey(t *testing.T) {
+	assert := assert.New(t)
+	key := setupReKeyTest(t)
+	defer key.Close()
+
+	oldBaseKey, err := key.Store.Get(nil)
+	assert.NoError(err)
+	newBaseKey := bytes.Repeat([]byte{9}, model.KEY_SIZE)
+
+	err = ReKeyFiles(testutils.TEST_CONFIG_NAME, key, key.Masterpassword, newBaseKey, nil)
+	assert.NoError(err)
+
+	stored, err := key.Store.Get(nil)
+	assert.NoError(err)
+	assert.Equal(newBaseKey, stored)
+	assert.NotEqual(oldBaseKey, stored)
+
+	newKey := model.NewTestMasterAESKeyManager(string(testutils.TEST_MASTER_PASSWORD))
+	defer newKey.Close()
+	assertReadable(t, newKey)
+}
+
+func TestReKeyFilesRollsBack(t *testing.T) {
+	assert := assert.New(t)
+	key := setupReKeyTest(t)
+	defer key.Close()
+
+	before := readReKeyFiles(t)
+
+	baseKey, err := key.Store.Get(nil)
+	assert.NoError(err)
+
+	// Every file is written before the keystore fails, so all of them have to
+	// be rolled back
+	key.Store = failingKeyStore{key.Store}
+	err = ReKeyFiles(testutils.TEST_CONFIG_NAME, key, "newpass", baseKey, nil)
+	assert.Error(err)
+
+	assert.Equal(before, readReKeyFiles(t))
+
+	oldKey := model.NewTestMasterAESKeyManager(string(testutils.TEST_MASTER_PASSWORD))
+	defer oldKey.Close()
+	assertReadable(t, oldKey)
+}
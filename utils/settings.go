@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"go-pass/model"
+)
+
+var (
+	// SETTINGS_FILE holds the settings that are needed before the config can
+	// be decrypted. It is not encrypted, so it must not hold anything secret.
+	SETTINGS_FILE = path.Join(CONFIG_PATH, "settings.json")
+	// KEY_FILE is where the file keystore keeps the encrypted key
+	KEY_FILE = path.Join(CONFIG_PATH, "key.enc")
+)
+
+// Settings are the unencrypted settings read before the config.
+type Settings struct {
+	// KeyStore is where the key is kept, model.KEYSTORE_KEYRING or
+	// model.KEYSTORE_FILE
+	KeyStore string `json:"keystore"`
+}
+
+// DefaultSettings returns the settings used when there is no settings file
+func DefaultSettings() Settings {
+	return Settings{KeyStore: model.KEYSTORE_KEYRING}
+}
+
+// ReadSettings reads the settings file. A missing file returns
+// DefaultSettings.
+func ReadSettings() (Settings, error) {
+	settings := DefaultSettings()
+
+	contents, err := os.ReadFile(SETTINGS_FILE)
+	if errors.Is(err, os.ErrNotExist) {
+		return settings, nil
+	}
+	if err != nil {
+		return Settings{}, fmt.Errorf("reading settings: %v", err)
+	}
+
+	if err := json.Unmarshal(contents, &settings); err != nil {
+		return Settings{}, fmt.Errorf("unmarshaling settings: %v", err)
+	}
+	return settings, nil
+}
+
+// WriteSettings writes the settings file
+func WriteSettings(settings Settings) error {
+	if _, err := NewKeyStore(settings.KeyStore); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(CONFIG_PATH, 0o700); err != nil {
+		return fmt.Errorf("creating dir: %v", err)
+	}
+
+	b, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return WriteToFile(SETTINGS_FILE, model.FileConfig, string(b))
+}
+
+// NewKeyStore returns the keystore with the given name
+func NewKeyStore(name string) (model.KeyStore, error) {
+	switch name {
+	case model.KEYSTORE_KEYRING, "":
+		return model.NewKeyringStore(model.DefaultKeyringService, model.DefaultKeyringAccount), nil
+	case model.KEYSTORE_FILE:
+		return model.NewFileKeyStore(KEY_FILE), nil
+	default:
+		return nil, fmt.Errorf(
+			"unknown keystore '%s', must be '%s' or '%s'",
+			name, model.KEYSTORE_KEYRING, model.KEYSTORE_FILE,
+		)
+	}
+}
+
+// NewKeyManager returns a MasterAESKeyManager for the master password that
+// uses the keystore from the settings file
+func NewKeyManager(masterPassword string) (*model.MasterAESKeyManager, error) {
+	settings, err := ReadSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := NewKeyStore(settings.KeyStore)
+	if err != nil {
+		return nil, err
+	}
+
+	return model.NewMasterAESKeyManagerWithStore(masterPassword, store), nil
+}
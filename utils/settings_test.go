@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go-pass/model"
+)
+
+// useTempSettings points the settings file at a temporary directory for the
+// length of the test
+func useTempSettings(t *testing.T) {
+	oldConfigPath, oldSettings := CONFIG_PATH, SETTINGS_FILE
+	CONFIG_PATH = t.TempDir()
+	SETTINGS_FILE = path.Join(CONFIG_PATH, "settings.json")
+	t.Cleanup(func() {
+		CONFIG_PATH, SETTINGS_FILE = oldConfigPath, oldSettings
+	})
+}
+
+func TestReadSettingsDefault(t *testing.T) {
+	useTempSettings(t)
+
+	settings, err := ReadSettings()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultSettings(), settings)
+}
+
+func TestWriteSettings(t *testing.T) {
+	assert := assert.New(t)
+	useTempSettings(t)
+
+	assert.NoError(WriteSettings(Settings{KeyStore: model.KEYSTORE_FILE}))
+	assert.FileExists(SETTINGS_FILE)
+
+	settings, err := ReadSettings()
+	assert.NoError(err)
+	assert.Equal(model.KEYSTORE_FILE, settings.KeyStore)
+
+	km, err := NewKeyManager("mastahpass")
+	assert.NoError(err)
+	defer km.Close()
+	assert.IsType(&model.FileKeyStore{}, km.Store)
+
+	assert.Error(WriteSettings(Settings{KeyStore: "nope"}))
+}
+
+func TestNewKeyStore(t *testing.T) {
+	assert := assert.New(t)
+
+	store, err := NewKeyStore(model.KEYSTORE_KEYRING)
+	assert.NoError(err)
+	assert.IsType(&model.KeyringStore{}, store)
+
+	store, err = NewKeyStore(model.KEYSTORE_FILE)
+	assert.NoError(err)
+	assert.Equal(KEY_FILE, store.(*model.FileKeyStore).Path)
+
+	// The memory keystore is only for tests
+	_, err = NewKeyStore(model.KEYSTORE_MEMORY)
+	assert.Error(err)
+}
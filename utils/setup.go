@@ -1,7 +1,6 @@
 package utils
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -49,16 +48,12 @@ func CreateVault(name string, key *model.MasterAESKeyManager) (*os.File, error)
 
 		if fileStat.Size() == 0 {
 			ve := []model.VaultEntry{}
-			b, err := json.Marshal(ve)
-			if err != nil {
-				return nil, err
-			}
-			plaintext, err := key.Encrypt(b)
+			ciphertext, err := crypt.EncryptVault(ve, key)
 			if err != nil {
 				return nil, err
 			}
 
-			if err := WriteToFile(f.Name(), model.FileVault, plaintext); err != nil {
+			if err := WriteToFile(f.Name(), model.FileVault, ciphertext); err != nil {
 				return nil, err
 			}
 
@@ -98,6 +93,81 @@ func OpenVault(name string) (*os.File, error) {
 	return f, nil
 }
 
+// ReadBackups returns the backups in BACKUP_PATH. Directories, such as the
+// copy of a legacy vault 'migrate' keeps, aren't backups and are left out.
+func ReadBackups() ([]os.DirEntry, error) {
+	dirEntries, err := os.ReadDir(BACKUP_PATH)
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []os.DirEntry{}
+	for _, e := range dirEntries {
+		if !e.IsDir() {
+			backups = append(backups, e)
+		}
+	}
+	return backups, nil
+}
+
+// LoadVault opens, decrypts and closes the vault without ever writing to it.
+// Unlike ReadVault, entries from before entries had IDs are left without one,
+// so it is safe for callers that may only read the vault.
+func LoadVault(name string, key *model.MasterAESKeyManager) ([]model.VaultEntry, error) {
+	f, err := OpenVault(name)
+	if err != nil {
+		return nil, fmt.Errorf("opening vault: %v", err)
+	}
+	defer f.Close()
+
+	entries, err := crypt.DecryptVault(f, key)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting vault: %v", err)
+	}
+	return entries, nil
+}
+
+// ReadVault opens and decrypts the vault. Entries from before entries had IDs
+// are given one and the vault is saved, so an ID never changes once it has been
+// shown. It is up to the caller to close the returned file, and to write to
+// its name rather than the file itself, as WriteToFile replaces the file.
+func ReadVault(
+	name string,
+	key *model.MasterAESKeyManager,
+) (*os.File, []model.VaultEntry, error) {
+	f, err := OpenVault(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening vault: %v", err)
+	}
+
+	entries, err := crypt.DecryptVault(f, key)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("decrypting vault: %v", err)
+	}
+
+	changed, err := model.AssignIDs(entries)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	if changed {
+		ciphertext, err := crypt.EncryptVault(entries, key)
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("encrypting vault: %v", err)
+		}
+
+		if err := WriteToFile(f.Name(), model.FileVault, ciphertext); err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("saving entry IDs: %v", err)
+		}
+	}
+
+	return f, entries, nil
+}
+
 // WriteToFile takes a file name as a stringand the contents wanted in the file,
 // in []byte, and writes it to the file. It is up to the caller of this function
 // that the file is closed. The caller of this function will also need to
@@ -248,13 +318,21 @@ func CheckConfig(fn string, key *model.MasterAESKeyManager) (*model.Config, erro
 
 	cfgFile, ok, err := OpenConfig(fn)
 	if ok && err == nil {
-		fmt.Println("A file is not found. Need to init.")
+		fmt.Fprintln(os.Stderr, "A file is not found. Need to init.")
 		return nil, fmt.Errorf("file needs to be created")
 	}
 	defer cfgFile.Close()
-	cfg, err := crypt.DecryptConfig(cfgFile, key, false)
+	cfg, err := crypt.DecryptConfig(cfgFile, key)
 	if err != nil {
 		return nil, fmt.Errorf("decryping config: %w", err)
 	}
+
+	if key.KDF().Salt == "" {
+		fmt.Fprintf(
+			os.Stderr,
+			"Your vault is salted with %s. Run 'gopass vault upgrade-kdf' to switch to a random salt.\n",
+			model.SECRET_PASSWORD_KEY,
+		)
+	}
 	return cfg, nil
 }
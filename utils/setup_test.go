@@ -9,6 +9,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"go-pass/crypt"
 	"go-pass/model"
 	"go-pass/testutils"
 )
@@ -65,6 +66,42 @@ func TestOpenVault(t *testing.T) {
 	assert.NotZero(stat.Size())
 }
 
+func TestReadVaultAssignsIDs(t *testing.T) {
+	testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer testutils.TestCleanup(string(testutils.TEST_MASTER_PASSWORD))
+	defer cleanup()
+	assert := assert.New(t)
+
+	key, err := testutils.InitTestKeyring(string(testutils.TEST_MASTER_PASSWORD))
+	assert.NoError(err)
+
+	f, err := CreateVault(TEST_FILE_NAME, key)
+	assert.NoError(err)
+	f.Close()
+
+	// A vault from before entries had IDs
+	ciphertext, err := crypt.EncryptVault([]model.VaultEntry{
+		{Name: "a", Username: "a"},
+		{ID: "existing", Name: "b", Username: "b"},
+	}, key)
+	assert.NoError(err)
+	assert.NoError(WriteToFile(path.Join(VAULT_PATH, TEST_FILE_NAME), model.FileVault, ciphertext))
+
+	f, entries, err := ReadVault(TEST_FILE_NAME, key)
+	assert.NoError(err)
+	f.Close()
+
+	assert.Len(entries, 2)
+	assert.NotEmpty(entries[0].ID)
+	assert.Equal("existing", entries[1].ID)
+
+	// The new ID was saved, so it is the same the next time
+	f, again, err := ReadVault(TEST_FILE_NAME, key)
+	assert.NoError(err)
+	f.Close()
+	assert.Equal(entries, again)
+}
+
 func TestIsAccessBeforeLogin(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -174,3 +211,19 @@ func TestCheckConfig(t *testing.T) {
 		time.Sleep(time.Millisecond * 100)
 	}
 }
+
+func TestReadBackups(t *testing.T) {
+	assert := assert.New(t)
+	oldBackupPath := BACKUP_PATH
+	BACKUP_PATH = t.TempDir()
+	defer func() { BACKUP_PATH = oldBackupPath }()
+
+	assert.NoError(os.WriteFile(path.Join(BACKUP_PATH, "backup.json"), nil, 0o600))
+	assert.NoError(os.Mkdir(path.Join(BACKUP_PATH, "legacy__2025-01-02_15-04-05"), 0o700))
+
+	backups, err := ReadBackups()
+	assert.NoError(err)
+	if assert.Len(backups, 1) {
+		assert.Equal("backup.json", backups[0].Name())
+	}
+}
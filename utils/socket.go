@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"syscall"
+)
+
+var (
+	// ErrSocketInUse is returned by ListenUnix when something is still
+	// listening on the socket
+	ErrSocketInUse = errors.New("something is already listening on the socket")
+	// ErrNotSocket is returned by ListenUnix when the path is a file that
+	// isn't a socket, which is never removed
+	ErrNotSocket = errors.New("the path exists and is not a socket")
+)
+
+// SocketPath returns the path to the per-user socket with the file name
+// 'name'. It prefers the path in the environment variable 'env', then
+// $XDG_RUNTIME_DIR/gopass, and falls back to the gopass config directory.
+func SocketPath(env, name string) string {
+	if p := os.Getenv(env); p != "" {
+		return p
+	}
+
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return path.Join(runtimeDir, "gopass", name)
+	}
+
+	return path.Join(CONFIG_PATH, name)
+}
+
+// ListenUnix creates the unix socket at socketPath and makes sure only the
+// current user can reach it. A stale socket is removed, but one something is
+// still listening on, or a path that isn't a socket, is never replaced.
+func ListenUnix(socketPath string) (net.Listener, error) {
+	if err := os.MkdirAll(path.Dir(socketPath), 0o700); err != nil {
+		return nil, fmt.Errorf("creating socket dir: %v", err)
+	}
+
+	if info, err := os.Lstat(socketPath); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s: %w", socketPath, ErrNotSocket)
+		}
+		if conn, err := net.Dial("unix", socketPath); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s: %w", socketPath, ErrSocketInUse)
+		}
+		if err := os.Remove(socketPath); err != nil {
+			return nil, fmt.Errorf("removing stale socket: %v", err)
+		}
+	}
+
+	// The socket is created without group and other permissions, so it is
+	// never reachable by anyone else, even before the chmod below
+	oldMask := syscall.Umask(0o177)
+	l, err := net.Listen("unix", socketPath)
+	syscall.Umask(oldMask)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(socketPath, 0o600); err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"net"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenUnix(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := os.MkdirTemp("", "gopass-socket")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	socketPath := path.Join(dir, "test.sock")
+
+	l, err := ListenUnix(socketPath)
+	assert.NoError(err)
+
+	info, err := os.Stat(socketPath)
+	assert.NoError(err)
+	assert.Equal(os.FileMode(0o600), info.Mode().Perm())
+
+	// A live socket is left alone
+	_, err = ListenUnix(socketPath)
+	assert.ErrorIs(err, ErrSocketInUse)
+
+	// A stale one is replaced
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+	l, err = ListenUnix(socketPath)
+	assert.NoError(err)
+	l.Close()
+
+	// A file that isn't a socket is left alone
+	notSocket := path.Join(dir, "notes.txt")
+	assert.NoError(os.WriteFile(notSocket, []byte("notes"), 0o600))
+	_, err = ListenUnix(notSocket)
+	assert.ErrorIs(err, ErrNotSocket)
+	assert.FileExists(notSocket)
+}
+
+func TestSocketPath(t *testing.T) {
+	t.Setenv("GOPASS_TEST_SOCK", "/tmp/custom.sock")
+	assert.Equal(t, "/tmp/custom.sock", SocketPath("GOPASS_TEST_SOCK", "test.sock"))
+
+	t.Setenv("GOPASS_TEST_SOCK", "")
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	assert.Equal(t, "/run/user/1000/gopass/test.sock", SocketPath("GOPASS_TEST_SOCK", "test.sock"))
+
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	assert.Equal(t, path.Join(CONFIG_PATH, "test.sock"), SocketPath("GOPASS_TEST_SOCK", "test.sock"))
+}